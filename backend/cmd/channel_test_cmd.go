@@ -0,0 +1,60 @@
+// Package cmd provides CLI commands for the application.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"markpost/internal/config"
+	"markpost/internal/infra"
+	deliverysvc "markpost/internal/service/delivery"
+)
+
+// RunChannelTest sends a synthetic post through one of the user's delivery
+// channels, bypassing the attempt queue, and prints what the upstream
+// answered. It returns an error when the send fails so the exit status
+// reflects the outcome.
+func RunChannelTest(configPath, username string, channelID int) error {
+	if err := config.Load(configPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg := config.Get()
+
+	dbInstance, err := infra.New(cfg.DB.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer func() {
+		if err := dbInstance.Close(); err != nil {
+			log.Printf("Failed to close database: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	userRepo := infra.NewUserRepository(dbInstance.DB(), cfg.PostKeyLength)
+	u, err := userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return fmt.Errorf("user '%s' not found: %w", username, err)
+	}
+
+	deliverySvc := deliverysvc.NewService(infra.NewDeliveryChannelRepository(dbInstance.DB()), nil).
+		WithTester(deliverysvc.NewPostDeliveryService())
+	result, err := deliverySvc.TestSend(ctx, u.ID, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to test channel %d: %w", channelID, err)
+	}
+
+	if result.StatusCode != 0 {
+		fmt.Printf("Upstream status: %d\n", result.StatusCode)
+	}
+	if result.Body != "" {
+		fmt.Printf("Upstream body: %s\n", result.Body)
+	}
+	if !result.Delivered {
+		return fmt.Errorf("test message to channel %d failed: %s", channelID, result.Error)
+	}
+	fmt.Printf("Test message delivered to channel %d.\n", channelID)
+	return nil
+}
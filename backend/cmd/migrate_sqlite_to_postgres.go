@@ -16,14 +16,20 @@ import (
 	"gorm.io/gorm"
 )
 
-// sqliteModels is the per-table model set used to scan the source SQLite file,
-// matching allModels in infra/db.go. Declared separately so this command stays
-// decoupled from the migrate target's internal ordering.
+// sqliteModels is the per-table model set used to scan the source SQLite file:
+// allModels in infra/db.go minus the delivery queue and its logs (attempts,
+// digest items, history, tries, recorded messages and routing decisions).
+// Those are left behind on purpose — pending deliveries would be re-sent from
+// the new server, and the logs age out within the history retention window.
+// Declared separately so this command stays decoupled from the migrate
+// target's internal ordering; a revision is copied after the post it belongs
+// to.
 var sqliteModels = []any{
 	&user.User{},
 	&user.RefreshToken{},
 	&user.TokenBlacklist{},
 	&post.Post{},
+	&post.Revision{},
 	&delivery.Channel{},
 }
 
@@ -183,6 +189,8 @@ func tableName(model any) string {
 		return "token_blacklist"
 	case *post.Post:
 		return "posts"
+	case *post.Revision:
+		return "post_revisions"
 	case *delivery.Channel:
 		return "delivery_channels"
 	default:
@@ -202,6 +210,8 @@ func newModel(model any) any {
 		return &user.TokenBlacklist{}
 	case *post.Post:
 		return &post.Post{}
+	case *post.Revision:
+		return &post.Revision{}
 	case *delivery.Channel:
 		return &delivery.Channel{}
 	default:
@@ -7,9 +7,13 @@ import (
 
 	"markpost/internal/config"
 	"markpost/internal/infra"
+	deliverysvc "markpost/internal/service/delivery"
+	postsvc "markpost/internal/service/post"
 )
 
-// RunPruneExpiredPosts prunes expired posts from the database.
+// RunPruneExpiredPosts prunes expired posts from the database. Channels
+// subscribed to the expired event get an attempt per pruned post, which the
+// server's dispatcher sends.
 func RunPruneExpiredPosts(configPath string, dryRun bool, batchSize int) error {
 	if err := config.Load(configPath); err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -36,11 +40,16 @@ func RunPruneExpiredPosts(configPath string, dryRun bool, batchSize int) error {
 		return nil
 	}
 
-	pruned, err := postRepo.PruneExpired(context.Background(), retentionDays, batchSize)
+	// The dispatcher only enqueues here; it is never started, so nothing is
+	// sent from this process, and Stop just releases its worker pool.
+	db := dbInstance.DB()
+	dispatcher := deliverysvc.NewDispatcher(infra.NewAttemptRepository(db), infra.NewDeliveryChannelRepository(db), postRepo, nil)
+	defer dispatcher.Stop()
+	pruned, err := postsvc.NewService(postRepo, dispatcher).PruneExpired(context.Background(), retentionDays, batchSize)
 	if err != nil {
 		return fmt.Errorf("failed to cleanup expired posts: %w", err)
 	}
 
-	fmt.Printf("Pruning expired posts completed (%d deleted)\n", len(pruned))
+	fmt.Printf("Pruning expired posts completed (%d deleted)\n", pruned)
 	return nil
 }
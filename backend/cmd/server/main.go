@@ -105,6 +105,26 @@ func main() {
 					return cmd.RunResetPassword(c.String("config"), c.String("username"), c.String("password"))
 				},
 			},
+			{
+				Name:  "channel-test",
+				Usage: "Send a test message through a user's delivery channel and print the upstream response",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "username",
+						Aliases:  []string{"u"},
+						Usage:    "Username of the channel owner",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:     "channel",
+						Usage:    "ID of the delivery channel to test",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return cmd.RunChannelTest(c.String("config"), c.String("username"), c.Int("channel"))
+				},
+			},
 			{
 				Name:  "import-fake-posts",
 				Usage: "Import fake posts from a JSON file (for load-test seeding)",
@@ -304,18 +324,27 @@ func serve(configPath string) {
 
 	deliveryRepo := infra.NewDeliveryChannelRepository(dbInstance.DB())
 	attemptRepo := infra.NewAttemptRepository(dbInstance.DB())
-	deliverySvc := deliverysvc.NewService(deliveryRepo, attemptRepo)
+	deliverySvc := deliverysvc.NewService(deliveryRepo, attemptRepo).
+		WithRetryPolicy(deliverysvc.RetryPolicyFromConfig(cfg.Delivery.Retry)).
+		WithPosts(postRepo)
 
 	postDeliverySvc := deliverysvc.NewPostDeliveryService()
+	deliverySvc.WithTester(postDeliverySvc)
 	deliveryDispatcher := deliverysvc.NewDispatcher(attemptRepo, deliveryRepo, postRepo, postDeliverySvc)
+	postSvc = postsvc.NewService(postRepo, deliveryDispatcher)
+	// Email bodies reuse the post page's sanitized HTML, so the renderer is
+	// wired in before the dispatcher starts sending.
+	postDeliverySvc.WithRenderer(postSvc)
+
 	dispatcherCtx, dispatcherCancel := context.WithCancel(context.Background())
 	defer dispatcherCancel()
 	deliveryDispatcher.Start(dispatcherCtx)
 	defer deliveryDispatcher.Stop()
+	if listener := infra.NewDeliveryListener(dbInstance.DB()); listener != nil {
+		go listener.Listen(dispatcherCtx, deliveryDispatcher.Wake)
+	}
 
-	postSvc = postsvc.NewService(postRepo, deliveryDispatcher)
-
-	adminSvc := admin.NewService(userRepo, postSvc, deliverySvc, attemptRepo)
+	adminSvc := admin.NewService(userRepo, postSvc, deliverySvc, attemptRepo, attemptRepo)
 
 	// gin.New (not gin.Default): we install otelgin for HTTP spans and our own
 	// Fallback panic recovery, replacing gin's built-in Logger/Recovery
@@ -423,6 +452,9 @@ func SetupRoutes(r *gin.Engine, deliverySvc *deliverysvc.Service, adminSvc *admi
 	{
 		jwtAuth.GET("/post-key", v1.QueryPostKey(authSvc))
 		jwtAuth.GET("/posts", v1.PostsList(postSvc))
+		jwtAuth.GET("/posts/:id/routes", v1.ListPostRoutes(deliverySvc))
+		jwtAuth.GET("/posts/:id/revisions", v1.ListPostRevisions(postSvc))
+		jwtAuth.GET("/posts/:id/revisions/diff", v1.DiffPostRevisions(postSvc))
 
 		// L3: authenticated state changes keyed on user_id (from JWT). Reads
 		// (GET) stay outside the limiter so listing does not consume the write
@@ -432,6 +464,8 @@ func SetupRoutes(r *gin.Engine, deliverySvc *deliverysvc.Service, adminSvc *admi
 		{
 			jwtWrite.POST("/auth/logout", v1.Logout(authSvc))
 			jwtWrite.POST("/auth/change-password", v1.ChangePassword(authSvc))
+			jwtWrite.PATCH("/posts/:id", v1.UpdateOwnPost(postSvc))
+			jwtWrite.POST("/posts/:id/revisions/:rev/restore", v1.RestorePostRevision(postSvc))
 			jwtWrite.DELETE("/posts/:id", v1.DeleteOwnPost(postSvc))
 		}
 
@@ -441,16 +475,23 @@ func SetupRoutes(r *gin.Engine, deliverySvc *deliverysvc.Service, adminSvc *admi
 			deliveryGroup.POST("", middleware.RateLimitByUserID(l3Write), v1.CreateDeliveryChannel(deliverySvc))
 			deliveryGroup.PATCH("/:id", middleware.RateLimitByUserID(l3Write), v1.UpdateDeliveryChannel(deliverySvc))
 			deliveryGroup.DELETE("/:id", middleware.RateLimitByUserID(l3Write), v1.DeleteDeliveryChannel(deliverySvc))
+			deliveryGroup.POST("/:id/test", middleware.RateLimitByUserID(l3Write), v1.TestDeliveryChannel(deliverySvc))
 		}
+		jwtAuth.GET("/delivery/kinds", v1.ListDeliveryKinds())
+		jwtAuth.POST("/delivery/filter/explain", v1.ExplainDeliveryFilter(deliverySvc))
 		jwtAuth.GET("/delivery/history", v1.ListDeliveryHistory(deliverySvc))
+		jwtAuth.POST("/delivery/history/:id/retry", middleware.RateLimitByUserID(l3Write), v1.RetryDeliveryHistory(deliverySvc))
 
 		adminGroup := jwtAuth.Group("/admin")
 		adminGroup.Use(middleware.RequireAdmin())
 		{
 			adminGroup.GET("/users", v1.AdminListUsers(adminSvc))
 			adminGroup.GET("/posts", v1.AdminListPosts(adminSvc))
+			adminGroup.GET("/posts/:id/routes", v1.AdminListPostRoutes(adminSvc))
 			adminGroup.GET("/delivery/channels", v1.AdminListChannels(adminSvc))
 			adminGroup.GET("/delivery/history", v1.AdminListDeliveryHistory(adminSvc))
+			adminGroup.GET("/delivery/leases", v1.AdminListDeliveryLeases(adminSvc))
+			adminGroup.POST("/delivery/history/retry", middleware.RateLimitByUserID(l3Write), v1.AdminRetryDeliveryHistory(deliverySvc))
 			adminGroup.DELETE("/posts/:id", middleware.RateLimitByUserID(l3Write), v1.DeleteAnyPost(postSvc))
 		}
 	}
@@ -458,6 +499,7 @@ func SetupRoutes(r *gin.Engine, deliverySvc *deliverysvc.Service, adminSvc *admi
 	// L2: public writes keyed on user_id, resolved by PostKey. The 10/min and
 	// 1000/day limiters chain so both must pass.
 	r.POST("/:post_key", middleware.PostKey(userRepo), middleware.RateLimitByUserID(l2Write, l2Daily), v1.CreatePost(postSvc))
+	r.PUT("/:post_key/:qid", middleware.PostKey(userRepo), middleware.RateLimitByUserID(l2Write, l2Daily), v1.ReplacePost(postSvc))
 	r.GET("/static/:filename", v1.StaticCSS())
 	// L1: public reads keyed on client IP.
 	r.GET("/:id", middleware.RateLimitByIP(l1Read), v1.RenderPost(postSvc))
@@ -2,11 +2,15 @@ package v1
 
 import (
 	"context"
+	"net/http"
+	"time"
 
-	_ "markpost/internal/apierr"
+	"markpost/internal/apierr"
 	"markpost/internal/domain/delivery"
 	"markpost/internal/domain/post"
 	"markpost/internal/domain/user"
+	"markpost/internal/service/admin"
+	delivery_svc "markpost/internal/service/delivery"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +21,14 @@ type AdminService interface {
 	ListAllPosts(ctx context.Context, search string, offset, limit int) ([]post.Post, int64, error)
 	ListAllDeliveryChannels(ctx context.Context, offset, limit int) ([]delivery.Channel, int64, error)
 	ListAllDeliveryHistory(ctx context.Context, offset, limit int) ([]*delivery.HistoryRow, int64, error)
+	ListDeliveryLeases(ctx context.Context) ([]admin.LeaseHolder, error)
+	ListPostRoutes(ctx context.Context, postQID string) ([]*delivery.RouteRow, error)
+}
+
+// AdminDeliveryRetrier defines the admin write operation on delivery history.
+// It is kept apart from AdminService, which is a read-only view.
+type AdminDeliveryRetrier interface {
+	RetryHistoryBulk(ctx context.Context, ids []int64) ([]delivery_svc.RetryOutcome, error)
 }
 
 // AdminListUsers godoc
@@ -107,3 +119,86 @@ func AdminListDeliveryHistory(adminSvc AdminService) gin.HandlerFunc {
 		)
 	}
 }
+
+// AdminListDeliveryLeases godoc
+// @Summary List which dispatcher instance holds which delivery attempts (admin)
+// @Description Groups the leased delivery attempts by instance. Expired leases belong to an instance that stopped without releasing them; any instance re-claims those rows.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} v1.AdminDeliveryLeasesResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 403 {object} apierr.ErrorResponse
+// @Router /api/v1/admin/delivery/leases [get]
+func AdminListDeliveryLeases(adminSvc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		holders, err := adminSvc.ListDeliveryLeases(c.Request.Context())
+		if err != nil {
+			apierr.RespondError(c, err)
+			return
+		}
+
+		now := time.Now()
+		writeList(c, holders,
+			func(h admin.LeaseHolder) AdminLeaseHolder { return newAdminLeaseHolder(h, now) },
+			func(items []AdminLeaseHolder) any {
+				return AdminDeliveryLeasesResponse{Instances: items}
+			},
+		)
+	}
+}
+
+// AdminListPostRoutes godoc
+// @Summary List the delivery routing decisions for any post (admin)
+// @Description For each channel of the post's author, whether each lifecycle event of the post matched it, and if not, why. Newest event first.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Success 200 {object} v1.DeliveryRoutesResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 403 {object} apierr.ErrorResponse
+// @Router /api/v1/admin/posts/{id}/routes [get]
+func AdminListPostRoutes(adminSvc AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		routes, err := adminSvc.ListPostRoutes(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			apierr.RespondError(c, err)
+			return
+		}
+
+		writeList(c, routes, newDeliveryRouteItem, func(items []DeliveryRouteItem) any {
+			return DeliveryRoutesResponse{Items: items}
+		})
+	}
+}
+
+// AdminRetryDeliveryHistory godoc
+// @Summary Retry failed or expired deliveries in bulk (admin)
+// @Description Re-enqueues a fresh attempt for each listed history entry, across all users. Entries that cannot be retried are reported per item.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body AdminRetryDeliveryRequest true "Delivery history IDs"
+// @Success 200 {object} AdminRetryDeliveryResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 403 {object} apierr.ErrorResponse
+// @Router /api/v1/admin/delivery/history/retry [post]
+func AdminRetryDeliveryHistory(retrier AdminDeliveryRetrier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AdminRetryDeliveryRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+
+		outcomes, err := retrier.RetryHistoryBulk(c.Request.Context(), req.IDs)
+		if err != nil {
+			apierr.RespondError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newAdminRetryDeliveryResponse(outcomes))
+	}
+}
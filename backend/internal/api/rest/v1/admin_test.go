@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"markpost/internal/domain/delivery"
 	"markpost/internal/domain/post"
 	"markpost/internal/domain/user"
 	"markpost/internal/infra"
+	"markpost/internal/service"
 	"markpost/internal/service/admin"
+	delivery_svc "markpost/internal/service/delivery"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,6 +33,7 @@ func setupAdminHandlerDeps(t *testing.T) (*admin.Service, user.Repository, post.
 		&postListerAdapter{repo: postRepo},
 		&channelListerAdapter{repo: channelRepo},
 		attemptRepo,
+		attemptRepo,
 	)
 	return svc, userRepo, postRepo, channelRepo
 }
@@ -217,3 +222,101 @@ func TestAdminListChannels_Success(t *testing.T) {
 		t.Errorf("expected 2 channels, got %d", len(channels))
 	}
 }
+
+type stubLeaseLister struct {
+	attempts []*delivery.Attempt
+}
+
+func (s stubLeaseLister) ListLeased(_ context.Context, _ int) ([]*delivery.Attempt, error) {
+	return s.attempts, nil
+}
+
+func TestAdminListDeliveryLeases_GroupsByInstance(t *testing.T) {
+	live := time.Now().Add(time.Minute).UnixMilli()
+	stale := time.Now().Add(-time.Minute).UnixMilli()
+	svc := admin.NewService(nil, nil, nil, nil, stubLeaseLister{attempts: []*delivery.Attempt{
+		{ID: 1, LeaseOwner: "web-1", LeaseExpiresAt: live},
+		{ID: 2, LeaseOwner: "web-1", LeaseExpiresAt: stale},
+		{ID: 3, LeaseOwner: "web-2", LeaseExpiresAt: live},
+	}})
+
+	router := newTestEngine()
+	router.GET("/admin/delivery/leases", AdminListDeliveryLeases(svc))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/delivery/leases", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp AdminDeliveryLeasesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Instances) != 2 {
+		t.Fatalf("expected 2 instances, got %+v", resp.Instances)
+	}
+	first := resp.Instances[0]
+	if first.Owner != "web-1" || first.Held != 1 || first.Expired != 1 || len(first.Attempts) != 2 || !first.Attempts[1].Expired {
+		t.Errorf("unexpected web-1 holder: %+v", first)
+	}
+	if resp.Instances[1].Owner != "web-2" || resp.Instances[1].Held != 1 {
+		t.Errorf("unexpected web-2 holder: %+v", resp.Instances[1])
+	}
+}
+
+type stubRetrier struct {
+	ids []int64
+}
+
+func (s *stubRetrier) RetryHistoryBulk(_ context.Context, ids []int64) ([]delivery_svc.RetryOutcome, error) {
+	s.ids = ids
+	return []delivery_svc.RetryOutcome{
+		{HistoryID: 1, Attempt: &delivery.Attempt{ID: 10}},
+		{HistoryID: 2, Err: service.New(delivery_svc.ErrDeliveryTargetGone, "channel was deleted")},
+	}, nil
+}
+
+func TestAdminRetryDeliveryHistory_Success(t *testing.T) {
+	retrier := &stubRetrier{}
+	router := newTestEngine()
+	router.POST("/admin/delivery/history/retry", AdminRetryDeliveryHistory(retrier))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/delivery/history/retry", strings.NewReader(`{"ids":[1,2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp AdminRetryDeliveryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(retrier.ids) != 2 {
+		t.Errorf("expected 2 ids passed through, got %v", retrier.ids)
+	}
+	if resp.Retried != 1 || len(resp.Items) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if !resp.Items[0].Retried || resp.Items[0].AttemptID == nil || *resp.Items[0].AttemptID != 10 {
+		t.Errorf("unexpected first item: %+v", resp.Items[0])
+	}
+	if resp.Items[1].Retried || resp.Items[1].Code != "delivery_target_gone" {
+		t.Errorf("unexpected second item: %+v", resp.Items[1])
+	}
+}
+
+func TestAdminRetryDeliveryHistory_EmptyIDs(t *testing.T) {
+	router := newTestEngine()
+	router.POST("/admin/delivery/history/retry", AdminRetryDeliveryHistory(&stubRetrier{}))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/delivery/history/retry", strings.NewReader(`{"ids":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code == http.StatusOK {
+		t.Errorf("expected a validation error, got %d", w.Code)
+	}
+}
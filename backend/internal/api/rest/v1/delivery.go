@@ -19,6 +19,29 @@ type DeliveryService interface {
 	Update(ctx context.Context, userID int, id int, params delivery_svc.UpdateChannelParams) (*delivery.Channel, error)
 	Delete(ctx context.Context, userID int, id int) error
 	ListHistory(ctx context.Context, userID, channelID, offset, limit int) ([]*delivery.HistoryRow, int64, error)
+	TestSend(ctx context.Context, userID, id int) (*delivery_svc.TestSendResult, error)
+	RetryHistory(ctx context.Context, userID int, id int64) (*delivery.Attempt, error)
+	ExplainFilter(ctx context.Context, userID int, params delivery_svc.ExplainFilterParams) (*delivery_svc.FilterExplanation, error)
+	ListRoutes(ctx context.Context, userID int, postQID string) ([]*delivery.RouteRow, error)
+}
+
+// ListDeliveryKinds godoc
+// @Summary List the supported delivery channel kinds
+// @Description Returns every registered channel kind with its configuration field schema. Secret fields are write-only.
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} DeliveryKindsResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Router /api/v1/delivery/kinds [get]
+func ListDeliveryKinds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeList(c, delivery_svc.Drivers(), newDeliveryKindItem,
+			func(items []DeliveryKindItem) any {
+				return DeliveryKindsResponse{Items: items}
+			},
+		)
+	}
 }
 
 // ListDeliveryChannels godoc
@@ -133,6 +156,32 @@ func DeleteDeliveryChannel(deliverySvc DeliveryService) gin.HandlerFunc {
 	}
 }
 
+// TestDeliveryChannel godoc
+// @Summary Send a test message through a delivery channel
+// @Description Sends a synthetic post through the channel immediately, bypassing the delivery queue. A failed send still returns 200 with delivered=false.
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Channel ID"
+// @Success 200 {object} ChannelTestResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Router /api/v1/delivery/channels/{id}/test [post]
+func TestDeliveryChannel(deliverySvc DeliveryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUserAndID(c, func(u *user.User, id int) {
+			result, err := deliverySvc.TestSend(c.Request.Context(), u.ID, id)
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, newChannelTestResponse(result))
+		})
+	}
+}
+
 // ListDeliveryHistory godoc
 // @Summary List the current user's delivery history
 // @Tags delivery
@@ -162,3 +211,88 @@ func ListDeliveryHistory(deliverySvc DeliveryService) gin.HandlerFunc {
 		writePaginatedList(c, items, total, query, newDeliveryHistoryItem, paginatedWrap[DeliveryHistoryItem]("history"))
 	}
 }
+
+// RetryDeliveryHistory godoc
+// @Summary Retry a failed or expired delivery
+// @Description Re-enqueues a fresh attempt for the history entry's post and channel. The history entry itself is kept.
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Delivery history ID"
+// @Success 201 {object} RetryDeliveryResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Failure 409 {object} apierr.ErrorResponse
+// @Failure 410 {object} apierr.ErrorResponse
+// @Router /api/v1/delivery/history/{id}/retry [post]
+func RetryDeliveryHistory(deliverySvc DeliveryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUserAndID(c, func(u *user.User, id int) {
+			attempt, err := deliverySvc.RetryHistory(c.Request.Context(), u.ID, int64(id))
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusCreated, newRetryDeliveryResponse(attempt))
+		})
+	}
+}
+
+// ExplainDeliveryFilter godoc
+// @Summary Explain a keyword filter expression
+// @Description Parses the expression and evaluates it against a sample title/body, returning the expression tree with each node's outcome, and optionally replays it against the caller's most recent posts. A malformed expression returns 200 with error set. Nothing is stored or sent.
+// @Tags delivery
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body ExplainFilterRequest true "Expression, sample post and replay count"
+// @Success 200 {object} FilterExplainResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Router /api/v1/delivery/filter/explain [post]
+func ExplainDeliveryFilter(deliverySvc DeliveryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			var req ExplainFilterRequest
+			if !bindJSON(c, &req) {
+				return
+			}
+
+			result, err := deliverySvc.ExplainFilter(c.Request.Context(), u.ID, req.toParams(u.Username))
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, newFilterExplainResponse(result))
+		})
+	}
+}
+
+// ListPostRoutes godoc
+// @Summary List the delivery routing decisions for a post
+// @Description For each of the caller's channels, whether each lifecycle event of the post matched it, and if not, why (disabled, unsubscribed, invalid_filter, unmatched). Newest event first. Kept as long as delivery history.
+// @Tags delivery
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Success 200 {object} DeliveryRoutesResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Router /api/v1/posts/{id}/routes [get]
+func ListPostRoutes(deliverySvc DeliveryService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			routes, err := deliverySvc.ListRoutes(c.Request.Context(), u.ID, c.Param("id"))
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			writeList(c, routes, newDeliveryRouteItem, func(items []DeliveryRouteItem) any {
+				return DeliveryRoutesResponse{Items: items}
+			})
+		})
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
 	"markpost/internal/service"
 	delivery_svc "markpost/internal/service/delivery"
 
@@ -37,15 +40,20 @@ func newTestChannel(overrides ...func(*delivery.Channel)) *delivery.Channel {
 }
 
 type mockDeliveryService struct {
-	channels map[int]*delivery.Channel
-	nextID   int
-	err      error
+	channels  map[int]*delivery.Channel
+	histories map[int64]*delivery.History
+	nextID    int
+	err       error
+	explained delivery_svc.ExplainFilterParams
+	routes    map[string][]*delivery.RouteRow // keyed by "<userID>/<qid>"
 }
 
 func newMockDeliveryService() *mockDeliveryService {
 	return &mockDeliveryService{
-		channels: make(map[int]*delivery.Channel),
-		nextID:   1,
+		channels:  make(map[int]*delivery.Channel),
+		histories: make(map[int64]*delivery.History),
+		routes:    make(map[string][]*delivery.RouteRow),
+		nextID:    1,
 	}
 }
 
@@ -133,6 +141,46 @@ func (m *mockDeliveryService) Delete(_ context.Context, userID int, id int) erro
 	return nil
 }
 
+func (m *mockDeliveryService) TestSend(_ context.Context, userID, id int) (*delivery_svc.TestSendResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	ch, ok := m.channels[id]
+	if !ok || ch.UserID != userID {
+		return nil, service.New(service.ErrNotFound, "channel not found")
+	}
+	return &delivery_svc.TestSendResult{Delivered: false, StatusCode: 403, Body: `{"code":19021}`, Error: "feishu webhook status=403"}, nil
+}
+
+func (m *mockDeliveryService) RetryHistory(_ context.Context, userID int, id int64) (*delivery.Attempt, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	h, ok := m.histories[id]
+	if !ok || h.UserID == nil || *h.UserID != userID {
+		return nil, service.New(service.ErrNotFound, "delivery history not found")
+	}
+	if h.Status == delivery.StatusDelivered {
+		return nil, service.New(delivery_svc.ErrDeliveryNotRetryable, "only failed or expired deliveries can be retried")
+	}
+	return &delivery.Attempt{ID: 7, UserID: userID, PostID: h.PostID, ChannelID: *h.ChannelID, NextAt: 1000}, nil
+}
+
+func (m *mockDeliveryService) ExplainFilter(ctx context.Context, userID int, params delivery_svc.ExplainFilterParams) (*delivery_svc.FilterExplanation, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.explained = params
+	return delivery_svc.NewService(nil, nil).ExplainFilter(ctx, userID, params)
+}
+
+func (m *mockDeliveryService) ListRoutes(_ context.Context, userID int, postQID string) ([]*delivery.RouteRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.routes[fmt.Sprintf("%d/%s", userID, postQID)], nil
+}
+
 func TestParsePathID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -186,6 +234,45 @@ func TestParsePathID(t *testing.T) {
 	}
 }
 
+func TestListDeliveryKinds(t *testing.T) {
+	router := newTestEngine()
+	router.GET("/kinds", withTestUser(1), ListDeliveryKinds())
+
+	req := httptest.NewRequest(http.MethodGet, "/kinds", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp DeliveryKindsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Items) != len(delivery_svc.Drivers()) {
+		t.Fatalf("expected %d kinds, got %d", len(delivery_svc.Drivers()), len(resp.Items))
+	}
+	var webhook *DeliveryKindItem
+	for i := range resp.Items {
+		if resp.Items[i].Kind == delivery.ChannelKindWebhook {
+			webhook = &resp.Items[i]
+		}
+	}
+	if webhook == nil {
+		t.Fatal("expected webhook kind to be listed")
+	}
+	var secretField bool
+	for _, f := range webhook.Fields {
+		if f.Key == "secret" {
+			secretField = f.Secret && f.Required
+		}
+	}
+	if !secretField {
+		t.Errorf("expected webhook secret field to be required and secret, got %+v", webhook.Fields)
+	}
+}
+
 func TestListDeliveryChannels_Success(t *testing.T) {
 	mockSvc := newMockDeliveryService()
 	mockSvc.channels[1] = newTestChannel()
@@ -213,6 +300,27 @@ func TestListDeliveryChannels_Success(t *testing.T) {
 	}
 }
 
+func TestListDeliveryChannels_RedactsSecret(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.channels[1] = newTestChannel(func(ch *delivery.Channel) {
+		ch.Configuration["secret"] = "feishu-secret"
+	})
+
+	router := newTestEngine()
+	router.GET("/channels", withTestUser(1), ListDeliveryChannels(mockSvc))
+
+	req := httptest.NewRequest(http.MethodGet, "/channels", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "feishu-secret") {
+		t.Errorf("response leaks channel secret: %s", w.Body.String())
+	}
+}
+
 func TestListDeliveryChannels_NoUser(t *testing.T) {
 	mockSvc := newMockDeliveryService()
 	router := newTestEngine()
@@ -508,3 +616,194 @@ func derefStr(s *string) string {
 	}
 	return *s
 }
+
+func TestTestDeliveryChannel_Success(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.channels[1] = newTestChannel()
+
+	router := newTestEngine()
+	router.POST("/channels/:id/test", withTestUser(1), TestDeliveryChannel(mockSvc))
+
+	req := httptest.NewRequest(http.MethodPost, "/channels/1/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp ChannelTestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Delivered || resp.StatusCode != 403 || resp.Body != `{"code":19021}` || resp.Error == "" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestTestDeliveryChannel_NotFound(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.channels[1] = newTestChannel(func(ch *delivery.Channel) { ch.UserID = 2 })
+
+	router := newTestEngine()
+	router.POST("/channels/:id/test", withTestUser(1), TestDeliveryChannel(mockSvc))
+
+	req := httptest.NewRequest(http.MethodPost, "/channels/1/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func newTestHistory(userID int, status delivery.Status) *delivery.History {
+	postID, channelID := 3, 4
+	return &delivery.History{ID: 1, UserID: &userID, PostID: &postID, ChannelID: &channelID, Status: status}
+}
+
+func TestRetryDeliveryHistory_Success(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.histories[1] = newTestHistory(1, delivery.StatusFailed)
+
+	router := newTestEngine()
+	router.POST("/history/:id/retry", withTestUser(1), RetryDeliveryHistory(mockSvc))
+
+	req := httptest.NewRequest(http.MethodPost, "/history/1/retry", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var resp RetryDeliveryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.AttemptID != 7 || resp.PostID == nil || *resp.PostID != 3 || resp.ChannelID != 4 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestRetryDeliveryHistory_NotFound(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.histories[1] = newTestHistory(2, delivery.StatusFailed)
+
+	router := newTestEngine()
+	router.POST("/history/:id/retry", withTestUser(1), RetryDeliveryHistory(mockSvc))
+
+	req := httptest.NewRequest(http.MethodPost, "/history/1/retry", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRetryDeliveryHistory_NotRetryable(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	mockSvc.histories[1] = newTestHistory(1, delivery.StatusDelivered)
+
+	router := newTestEngine()
+	router.POST("/history/:id/retry", withTestUser(1), RetryDeliveryHistory(mockSvc))
+
+	req := httptest.NewRequest(http.MethodPost, "/history/1/retry", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestExplainDeliveryFilter(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	router := newTestEngine()
+	router.POST("/filter/explain", withTestUser(1), ExplainDeliveryFilter(mockSvc))
+
+	post := func(body string) (int, FilterExplainResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/filter/explain", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp FilterExplainResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	code, resp := post(`{"expression": "body:deploy & !author:=user1", "title": "t", "body": "deploy done"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if mockSvc.explained.Author != "user1" {
+		t.Errorf("author = %q, want the caller's username", mockSvc.explained.Author)
+	}
+	tree := resp.Tree
+	if resp.Error != nil || resp.Matched || tree == nil || tree.Op != "and" || len(tree.Children) != 2 {
+		t.Fatalf("response = %+v, want an unmatched and tree", resp)
+	}
+	if kw := tree.Children[0]; kw.Op != "keyword" || kw.Field != "body" || kw.Value != "deploy" || !kw.Matched {
+		t.Errorf("first operand = %+v", kw)
+	}
+	if word := tree.Children[1].Children[0]; word.Op != "word" || word.Field != "author" || !word.Matched {
+		t.Errorf("negated operand = %+v", word)
+	}
+	if resp.Replay == nil {
+		t.Error("replay should be an empty list, not null")
+	}
+
+	code, resp = post(`{"expression": "a &"}`)
+	if code != http.StatusOK || resp.Error == nil || resp.Error.Pos != 3 || resp.Tree != nil {
+		t.Errorf("malformed expression = %d %+v, want 200 with a parse error at 3", code, resp)
+	}
+
+	if code, _ = post(`{"expression": "a", "replay": 51}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("replay over the cap = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestListPostRoutes(t *testing.T) {
+	mockSvc := newMockDeliveryService()
+	ops := "ops"
+	channelID := 3
+	mockSvc.routes["1/p-1"] = []*delivery.RouteRow{
+		{Event: post.EventCreated, ChannelID: &channelID, ChannelName: &ops, Decision: delivery.DecisionInvalidFilter, Detail: "filter: parse error at pos 2: unexpected end of expression"},
+		{Event: post.EventCreated, Decision: delivery.DecisionMatched},
+	}
+	router := newTestEngine()
+	router.GET("/posts/:id/routes", withTestUser(1), ListPostRoutes(mockSvc))
+
+	get := func(qid string) DeliveryRoutesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/"+qid+"/routes", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp DeliveryRoutesResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("p-1")
+	if len(resp.Items) != 2 {
+		t.Fatalf("items = %+v, want 2", resp.Items)
+	}
+	if r := resp.Items[0]; r.Decision != "invalid_filter" || r.ChannelName == nil || *r.ChannelName != "ops" || r.Detail == "" {
+		t.Errorf("first route = %+v", r)
+	}
+	if r := resp.Items[1]; r.Decision != "matched" || r.ChannelID != nil || r.ChannelName != nil {
+		t.Errorf("route of a deleted channel = %+v", r)
+	}
+
+	if resp := get("p-2"); resp.Items == nil || len(resp.Items) != 0 {
+		t.Errorf("unknown post = %+v, want an empty list", resp.Items)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"html/template"
 	"net/http"
+	"strconv"
 	"time"
 
 	"markpost/internal/apierr"
 	"markpost/internal/domain/post"
 	"markpost/internal/domain/user"
+	"markpost/internal/service"
+	postsvc "markpost/internal/service/post"
 	"markpost/internal/web"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +20,16 @@ import (
 // PostService defines the interface for post-related operations.
 type PostService interface {
 	CreatePost(ctx context.Context, title, body string, userID int) (string, error)
-	RenderPostHTML(ctx context.Context, qid string) (title, html, etag string, createdAt time.Time, err error)
-	GetPostMarkdown(ctx context.Context, qid string) (title, body, etag string, createdAt time.Time, err error)
+	UpdatePost(ctx context.Context, qid string, ownerID int, params postsvc.UpdatePostParams) (*post.Post, error)
+	RenderPostHTML(ctx context.Context, qid string) (title, html, etag string, updatedAt time.Time, err error)
+	GetPostMarkdown(ctx context.Context, qid string) (title, body, etag string, updatedAt time.Time, err error)
 	GetUserPosts(ctx context.Context, userID int, offset, limit int) ([]post.Post, int64, error)
 	DeletePostByQID(ctx context.Context, qid string, ownerID int) error
+	ListRevisions(ctx context.Context, qid string, ownerID int) (*postsvc.Revisions, error)
+	DiffRevisions(ctx context.Context, qid string, ownerID, from, to int) (string, error)
+	RestoreRevision(ctx context.Context, qid string, ownerID, number int) (*post.Post, error)
+	RenderRevisionHTML(ctx context.Context, qid string, number int) (title, html, etag string, writtenAt time.Time, err error)
+	GetRevisionMarkdown(ctx context.Context, qid string, number int) (title, body, etag string, writtenAt time.Time, err error)
 }
 
 // CreatePost godoc
@@ -53,13 +62,48 @@ func CreatePost(postSvc PostService) gin.HandlerFunc {
 	}
 }
 
+// ReplacePost godoc
+// @Summary Replace the title and body of a post with a post key
+// @Description Only the owner of the post key can edit the post. The post keeps its QID; delivery channels subscribed to updates are notified.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Param post_key path string true "Post key used for authentication"
+// @Param qid path string true "Post QID"
+// @Param body body PostRequest true "New post title and markdown body"
+// @Success 200 {object} SinglePostResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Router /{post_key}/{qid} [put]
+func ReplacePost(postSvc PostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			var req PostRequest
+			if !bindJSON(c, &req) {
+				return
+			}
+
+			p, err := postSvc.UpdatePost(c.Request.Context(), c.Param("qid"), u.ID, postsvc.UpdatePostParams{Title: &req.Title, Body: &req.Body})
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, SinglePostResponse{Post: newPostResponse(p)})
+		})
+	}
+}
+
 // RenderPost godoc
 // @Summary Render a post as HTML or raw markdown
 // @Tags posts
 // @Produce html
 // @Param id path string true "Post QID"
 // @Param format query string false "Response format (raw returns markdown)"
+// @Param rev query int false "Serve this version of the post instead of the live one"
 // @Success 200 {string} string ""
+// @Failure 400 {object} apierr.ErrorResponse
 // @Failure 404 {object} apierr.ErrorResponse
 // @Router /{id} [get]
 func RenderPost(postSvc PostService) gin.HandlerFunc {
@@ -67,23 +111,37 @@ func RenderPost(postSvc PostService) gin.HandlerFunc {
 		id := c.Param("id")
 		isRaw := c.Query("format") == "raw"
 
-		setCacheHeaders := func(etag string, createdAt time.Time) {
+		getMarkdown, renderHTML := postSvc.GetPostMarkdown, postSvc.RenderPostHTML
+		if raw, ok := c.GetQuery("rev"); ok {
+			rev, ok := parseRevision(c, raw)
+			if !ok {
+				return
+			}
+			getMarkdown = func(ctx context.Context, qid string) (string, string, string, time.Time, error) {
+				return postSvc.GetRevisionMarkdown(ctx, qid, rev)
+			}
+			renderHTML = func(ctx context.Context, qid string) (string, string, string, time.Time, error) {
+				return postSvc.RenderRevisionHTML(ctx, qid, rev)
+			}
+		}
+
+		setCacheHeaders := func(etag string, updatedAt time.Time) {
 			c.Header("ETag", `"`+etag+`"`)
 			c.Header("Cache-Control", "public, max-age=300, s-maxage=3600")
 			c.Header("Cache-Tag", "post-"+id)
 			c.Header("Vary", "Accept-Encoding")
-			if !createdAt.IsZero() {
-				c.Header("Last-Modified", createdAt.UTC().Format(http.TimeFormat))
+			if !updatedAt.IsZero() {
+				c.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
 			}
 		}
 
 		if isRaw {
-			title, body, etag, createdAt, err := postSvc.GetPostMarkdown(c.Request.Context(), id)
+			title, body, etag, updatedAt, err := getMarkdown(c.Request.Context(), id)
 			if err != nil {
 				apierr.RespondError(c, err)
 				return
 			}
-			setCacheHeaders(etag, createdAt)
+			setCacheHeaders(etag, updatedAt)
 			if etagMatch(c.GetHeader("If-None-Match"), etag) {
 				c.AbortWithStatus(http.StatusNotModified)
 				return
@@ -92,12 +150,12 @@ func RenderPost(postSvc PostService) gin.HandlerFunc {
 			return
 		}
 
-		title, htmlContent, etag, createdAt, err := postSvc.RenderPostHTML(c.Request.Context(), id)
+		title, htmlContent, etag, updatedAt, err := renderHTML(c.Request.Context(), id)
 		if err != nil {
 			apierr.RespondError(c, err)
 			return
 		}
-		setCacheHeaders(etag, createdAt)
+		setCacheHeaders(etag, updatedAt)
 		if etagMatch(c.GetHeader("If-None-Match"), etag) {
 			c.AbortWithStatus(http.StatusNotModified)
 			return
@@ -128,6 +186,141 @@ func PostsList(postSvc PostService) gin.HandlerFunc {
 	}
 }
 
+// UpdateOwnPost godoc
+// @Summary Edit a post owned by the current user
+// @Description Partial update: omitted fields are left unchanged. The post keeps its QID; delivery channels subscribed to updates are notified.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Param body body UpdatePostRequest true "Fields to change"
+// @Success 200 {object} SinglePostResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Router /api/v1/posts/{id} [patch]
+func UpdateOwnPost(postSvc PostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			var req UpdatePostRequest
+			if !bindJSON(c, &req) {
+				return
+			}
+
+			p, err := postSvc.UpdatePost(c.Request.Context(), c.Param("id"), u.ID, req.toParams())
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, SinglePostResponse{Post: newPostResponse(p)})
+		})
+	}
+}
+
+// ListPostRevisions godoc
+// @Summary List the earlier versions of a post owned by the current user
+// @Description Each edit archives the title and body it replaced as a numbered revision; version 1 is the post as created and current is the number of the live version. Bodies are not listed; read one with GET /{id}?rev=N.
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Success 200 {object} PostRevisionsResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Router /api/v1/posts/{id}/revisions [get]
+func ListPostRevisions(postSvc PostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			revs, err := postSvc.ListRevisions(c.Request.Context(), c.Param("id"), u.ID)
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			writeList(c, revs.Items, newPostRevisionItem, func(items []PostRevisionItem) any {
+				return PostRevisionsResponse{Current: revs.Current, Items: items}
+			})
+		})
+	}
+}
+
+// DiffPostRevisions godoc
+// @Summary Diff two versions of a post owned by the current user
+// @Description Unified diff of the raw markdown ("# title" then the body) of two versions; either may be the live version.
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Param from query int true "Version to diff from"
+// @Param to query int true "Version to diff to"
+// @Success 200 {object} PostRevisionDiffResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Failure 422 {object} apierr.ErrorResponse
+// @Router /api/v1/posts/{id}/revisions/diff [get]
+func DiffPostRevisions(postSvc PostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			var q PostRevisionDiffQuery
+			if err := c.ShouldBindQuery(&q); err != nil {
+				writeBindingError(c, &q, err)
+				return
+			}
+
+			diff, err := postSvc.DiffRevisions(c.Request.Context(), c.Param("id"), u.ID, q.From, q.To)
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, PostRevisionDiffResponse{From: q.From, To: q.To, Diff: diff})
+		})
+	}
+}
+
+// RestorePostRevision godoc
+// @Summary Restore an earlier version of a post owned by the current user
+// @Description The version's title and body become the live content as an ordinary edit, so the version it replaces is archived in turn and delivery channels subscribed to updates are notified.
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post QID"
+// @Param rev path int true "Version to restore"
+// @Success 200 {object} SinglePostResponse
+// @Failure 400 {object} apierr.ErrorResponse
+// @Failure 401 {object} apierr.ErrorResponse
+// @Failure 404 {object} apierr.ErrorResponse
+// @Router /api/v1/posts/{id}/revisions/{rev}/restore [post]
+func RestorePostRevision(postSvc PostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withUser(c, func(u *user.User) {
+			rev, ok := parseRevision(c, c.Param("rev"))
+			if !ok {
+				return
+			}
+
+			p, err := postSvc.RestoreRevision(c.Request.Context(), c.Param("id"), u.ID, rev)
+			if err != nil {
+				apierr.RespondError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, SinglePostResponse{Post: newPostResponse(p)})
+		})
+	}
+}
+
+func parseRevision(c *gin.Context, raw string) (int, bool) {
+	rev, err := strconv.Atoi(raw)
+	if err != nil {
+		apierr.RespondError(c, service.New(service.ErrInvalidRequest, "invalid revision"))
+		return 0, false
+	}
+	return rev, true
+}
+
 // DeleteOwnPost godoc
 // @Summary Delete a post owned by the current user
 // @Tags posts
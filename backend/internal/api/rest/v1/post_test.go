@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -20,7 +21,8 @@ import (
 )
 
 type mockPostService struct {
-	posts map[string]*post.Post
+	posts     map[string]*post.Post
+	revisions map[string][]post.Revision // oldest first
 }
 
 func fmtEtag(s string) string {
@@ -29,7 +31,8 @@ func fmtEtag(s string) string {
 
 func newMockPostService() *mockPostService {
 	return &mockPostService{
-		posts: make(map[string]*post.Post),
+		posts:     make(map[string]*post.Post),
+		revisions: make(map[string][]post.Revision),
 	}
 }
 
@@ -42,26 +45,90 @@ func (m *mockPostService) CreatePost(_ context.Context, title, body string, user
 		Body:      body,
 		UserID:    userID,
 		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 	return qid, nil
 }
 
+func (m *mockPostService) UpdatePost(_ context.Context, qid string, ownerID int, params postsvc.UpdatePostParams) (*post.Post, error) {
+	p, ok := m.posts[qid]
+	if !ok || p.UserID != ownerID {
+		return nil, service.New(service.ErrNotFound, "post not found")
+	}
+	m.revisions[qid] = append(m.revisions[qid], post.Revision{
+		Number: len(m.revisions[qid]) + 1, Title: p.Title, Body: p.Body, CreatedAt: p.UpdatedAt.Add(time.Hour),
+	})
+	if params.Title != nil {
+		p.Title = *params.Title
+	}
+	if params.Body != nil {
+		p.Body = *params.Body
+	}
+	p.UpdatedAt = p.UpdatedAt.Add(time.Hour)
+	return p, nil
+}
+
 func (m *mockPostService) RenderPostHTML(_ context.Context, qid string) (string, string, string, time.Time, error) {
 	if p, ok := m.posts[qid]; ok {
 		html := "<h1>" + p.Title + "</h1><p>" + p.Body + "</p>"
 		etag := fmtEtag(html)
-		return p.Title, html, etag, p.CreatedAt, nil
+		return p.Title, html, etag, p.UpdatedAt, nil
 	}
 	return "", "", "", time.Time{}, service.New(service.ErrNotFound, "post not found")
 }
 
 func (m *mockPostService) GetPostMarkdown(_ context.Context, qid string) (string, string, string, time.Time, error) {
 	if p, ok := m.posts[qid]; ok {
-		return p.Title, p.Body, fmtEtag("# " + p.Title + "\n\n" + p.Body), p.CreatedAt, nil
+		return p.Title, p.Body, fmtEtag("# " + p.Title + "\n\n" + p.Body), p.UpdatedAt, nil
 	}
 	return "", "", "", time.Time{}, service.New(service.ErrNotFound, "post not found")
 }
 
+func (m *mockPostService) ListRevisions(_ context.Context, qid string, ownerID int) (*postsvc.Revisions, error) {
+	if p, ok := m.posts[qid]; !ok || p.UserID != ownerID {
+		return nil, service.New(service.ErrNotFound, "post not found")
+	}
+	revs := &postsvc.Revisions{Current: len(m.revisions[qid]) + 1}
+	for _, r := range slices.Backward(m.revisions[qid]) {
+		revs.Items = append(revs.Items, r)
+	}
+	return revs, nil
+}
+
+func (m *mockPostService) DiffRevisions(_ context.Context, qid string, ownerID, from, to int) (string, error) {
+	if p, ok := m.posts[qid]; !ok || p.UserID != ownerID {
+		return "", service.New(service.ErrNotFound, "post not found")
+	}
+	return fmt.Sprintf("--- %s?rev=%d\n+++ %s?rev=%d\n", qid, from, qid, to), nil
+}
+
+func (m *mockPostService) RestoreRevision(ctx context.Context, qid string, ownerID, number int) (*post.Post, error) {
+	revs := m.revisions[qid]
+	if number < 1 || number > len(revs) {
+		return nil, service.New(service.ErrNotFound, "revision not found")
+	}
+	r := revs[number-1]
+	return m.UpdatePost(ctx, qid, ownerID, postsvc.UpdatePostParams{Title: &r.Title, Body: &r.Body})
+}
+
+func (m *mockPostService) RenderRevisionHTML(ctx context.Context, qid string, number int) (string, string, string, time.Time, error) {
+	title, body, _, writtenAt, err := m.GetRevisionMarkdown(ctx, qid, number)
+	html := "<p>" + body + "</p>"
+	return title, html, fmtEtag(html), writtenAt, err
+}
+
+func (m *mockPostService) GetRevisionMarkdown(ctx context.Context, qid string, number int) (string, string, string, time.Time, error) {
+	revs := m.revisions[qid]
+	if number == len(revs)+1 {
+		return m.GetPostMarkdown(ctx, qid)
+	}
+	if number < 1 || number > len(revs) {
+		return "", "", "", time.Time{}, service.New(service.ErrNotFound, "revision not found")
+	}
+	r := revs[number-1]
+	return r.Title, r.Body, fmtEtag("# " + r.Title + "\n\n" + r.Body), r.CreatedAt, nil
+}
+
 func (m *mockPostService) GetUserPosts(_ context.Context, userID int, _, _ int) ([]post.Post, int64, error) {
 	var result []post.Post
 	for _, p := range m.posts {
@@ -353,6 +420,148 @@ func TestDeleteOwnPost(t *testing.T) {
 	})
 }
 
+func TestUpdateOwnPost(t *testing.T) {
+	patch := func(t *testing.T, mockSvc *mockPostService, userID int, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		router := newTestEngine(withValidators(postValidators...))
+		router.PATCH("/posts/:id", withTestUser(userID), UpdateOwnPost(mockSvc))
+		req := httptest.NewRequest(http.MethodPatch, "/posts/test-qid", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("omitted fields are left unchanged", func(t *testing.T) {
+		mockSvc := newMockPostService()
+		_, _ = mockSvc.CreatePost(context.Background(), "Titel", "Body", 7)
+
+		w := patch(t, mockSvc, 7, `{"title": "Title"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp SinglePostResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if resp.Post.QID != "test-qid" || resp.Post.Title != "Title" || !resp.Post.UpdatedAt.After(resp.Post.CreatedAt) {
+			t.Errorf("post = %+v", resp.Post)
+		}
+		if p := mockSvc.posts["test-qid"]; p.Body != "Body" {
+			t.Errorf("body = %q, want it unchanged", p.Body)
+		}
+	})
+
+	t.Run("wrong owner returns 404", func(t *testing.T) {
+		mockSvc := newMockPostService()
+		_, _ = mockSvc.CreatePost(context.Background(), "T", "B", 7)
+		if w := patch(t, mockSvc, 99, `{"body": "x"}`); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for wrong owner, got %d", w.Code)
+		}
+	})
+
+	t.Run("empty title is rejected", func(t *testing.T) {
+		mockSvc := newMockPostService()
+		_, _ = mockSvc.CreatePost(context.Background(), "T", "B", 7)
+		if w := patch(t, mockSvc, 7, `{"title": ""}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422 for an empty title, got %d", w.Code)
+		}
+	})
+}
+
+func TestReplacePost(t *testing.T) {
+	mockSvc := newMockPostService()
+	_, _ = mockSvc.CreatePost(context.Background(), "T", "B", 7)
+	router := newTestEngine(withValidators(postValidators...))
+	router.PUT("/:post_key/:qid", withTestUser(7), ReplacePost(mockSvc))
+	router.GET("/:id", RenderPost(mockSvc))
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/pk/test-qid", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put(`{"title": "New"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("PUT without a body = %d, want 422", code)
+	}
+	if code := put(`{"title": "New", "body": "Text"}`); code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200", code)
+	}
+
+	p := mockSvc.posts["test-qid"]
+	if p.Title != "New" || p.Body != "Text" {
+		t.Errorf("post = %+v, want title and body replaced", p)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test-qid?format=raw", nil))
+	if got, want := w.Header().Get("Last-Modified"), p.UpdatedAt.UTC().Format(http.TimeFormat); got != want {
+		t.Errorf("Last-Modified = %q, want the update time %q", got, want)
+	}
+}
+
+func TestPostRevisions(t *testing.T) {
+	mockSvc := newMockPostService()
+	_, _ = mockSvc.CreatePost(context.Background(), "v1", "first", 7)
+	for _, title := range []string{"v2", "v3"} {
+		_, _ = mockSvc.UpdatePost(context.Background(), "test-qid", 7, postsvc.UpdatePostParams{Title: &title})
+	}
+	router := newTestEngine()
+	router.LoadHTMLGlob("../../../../templates/*")
+	router.GET("/posts/:id/revisions", withTestUser(7), ListPostRevisions(mockSvc))
+	router.GET("/posts/:id/revisions/diff", withTestUser(7), DiffPostRevisions(mockSvc))
+	router.POST("/posts/:id/revisions/:rev/restore", withTestUser(7), RestorePostRevision(mockSvc))
+	router.GET("/:id", RenderPost(mockSvc))
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	w := serve(http.MethodGet, "/posts/test-qid/revisions")
+	var list PostRevisionsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list = %d %s", w.Code, w.Body.String())
+	}
+	if list.Current != 3 || len(list.Items) != 2 || list.Items[0].Number != 2 || list.Items[0].Title != "v2" {
+		t.Errorf("list = %+v, want current 3 over revisions 2 and 1", list)
+	}
+
+	if w := serve(http.MethodGet, "/posts/test-qid/revisions/diff?from=1"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("diff without to = %d, want 422", w.Code)
+	}
+	w = serve(http.MethodGet, "/posts/test-qid/revisions/diff?from=1&to=3")
+	var diff PostRevisionDiffResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil || diff.From != 1 || diff.To != 3 || !strings.HasPrefix(diff.Diff, "--- test-qid?rev=1") {
+		t.Errorf("diff = %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(http.MethodGet, "/test-qid?rev=1&format=raw")
+	if w.Code != http.StatusOK || w.Body.String() != "# v1\n\nfirst" || w.Header().Get("Cache-Tag") != "post-test-qid" {
+		t.Errorf("rev=1 = %d %q, headers %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := serve(http.MethodGet, "/test-qid?rev=2"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "first") {
+		t.Errorf("rev=2 html = %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/test-qid?rev=x"); w.Code != http.StatusBadRequest {
+		t.Errorf("rev=x = %d, want 400", w.Code)
+	}
+	if w := serve(http.MethodGet, "/test-qid?rev=9&format=raw"); w.Code != http.StatusNotFound {
+		t.Errorf("rev=9 = %d, want 404", w.Code)
+	}
+
+	if w := serve(http.MethodPost, "/posts/test-qid/revisions/1/restore"); w.Code != http.StatusOK {
+		t.Fatalf("restore = %d %s", w.Code, w.Body.String())
+	}
+	if p := mockSvc.posts["test-qid"]; p.Title != "v1" {
+		t.Errorf("title after restore = %q, want v1", p.Title)
+	}
+	if w := serve(http.MethodPost, "/posts/test-qid/revisions/one/restore"); w.Code != http.StatusBadRequest {
+		t.Errorf("restore one = %d, want 400", w.Code)
+	}
+}
+
 func TestDeleteAnyPost_AdminDeletesAnyOwner(t *testing.T) {
 	mockSvc := newMockPostService()
 	router := newTestEngine()
@@ -392,6 +601,9 @@ type errorPostService struct {
 func (m *errorPostService) CreatePost(_ context.Context, _, _ string, _ int) (string, error) {
 	return "", m.err
 }
+func (m *errorPostService) UpdatePost(_ context.Context, _ string, _ int, _ postsvc.UpdatePostParams) (*post.Post, error) {
+	return nil, m.err
+}
 func (m *errorPostService) RenderPostHTML(_ context.Context, _ string) (string, string, string, time.Time, error) {
 	return "", "", "", time.Time{}, nil
 }
@@ -405,6 +617,22 @@ func (m *errorPostService) DeletePostByQID(_ context.Context, _ string, _ int) e
 	return m.err
 }
 
+func (m *errorPostService) ListRevisions(_ context.Context, _ string, _ int) (*postsvc.Revisions, error) {
+	return nil, m.err
+}
+func (m *errorPostService) DiffRevisions(_ context.Context, _ string, _, _, _ int) (string, error) {
+	return "", m.err
+}
+func (m *errorPostService) RestoreRevision(_ context.Context, _ string, _, _ int) (*post.Post, error) {
+	return nil, m.err
+}
+func (m *errorPostService) RenderRevisionHTML(_ context.Context, _ string, _ int) (string, string, string, time.Time, error) {
+	return "", "", "", time.Time{}, m.err
+}
+func (m *errorPostService) GetRevisionMarkdown(_ context.Context, _ string, _ int) (string, string, string, time.Time, error) {
+	return "", "", "", time.Time{}, m.err
+}
+
 func TestPostsList_PaginationError(t *testing.T) {
 	mockSvc := newMockPostService()
 	router := newTestEngine()
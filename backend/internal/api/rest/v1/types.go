@@ -7,7 +7,11 @@ import (
 	"markpost/internal/domain/delivery"
 	"markpost/internal/domain/post"
 	"markpost/internal/domain/user"
+	"markpost/internal/service"
+	"markpost/internal/service/admin"
 	delivery_svc "markpost/internal/service/delivery"
+	"markpost/internal/service/delivery/filter"
+	postsvc "markpost/internal/service/post"
 	"markpost/pkg/utils"
 )
 
@@ -111,6 +115,41 @@ type PostRequest struct {
 	Body  string `json:"body" binding:"required,bodysize"`
 }
 
+// UpdatePostRequest represents the request body for a partial post edit.
+// Omitted fields are left unchanged; a given field may not be empty.
+type UpdatePostRequest struct {
+	Title *string `json:"title" binding:"omitempty,min=1,titlesize"`
+	Body  *string `json:"body" binding:"omitempty,min=1,bodysize"`
+}
+
+func (r UpdatePostRequest) toParams() postsvc.UpdatePostParams {
+	return postsvc.UpdatePostParams{Title: r.Title, Body: r.Body}
+}
+
+// PostResponse represents a post in API responses.
+type PostResponse struct {
+	ID        int       `json:"id"`
+	QID       string    `json:"qid"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SinglePostResponse represents a response containing a single post.
+type SinglePostResponse struct {
+	Post PostResponse `json:"post"`
+}
+
+func newPostResponse(p *post.Post) PostResponse {
+	return PostResponse{
+		ID:        p.ID,
+		QID:       p.QID,
+		Title:     p.Title,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
 func newPostListItem(p post.Post) PostListItem {
 	return PostListItem{
 		ID:        p.ID,
@@ -120,9 +159,48 @@ func newPostListItem(p post.Post) PostListItem {
 	}
 }
 
+// PostRevisionItem is an archived version of a post. Editor is who replaced
+// it, at CreatedAt; nil once that user is deleted.
+type PostRevisionItem struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Editor    *string   `json:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newPostRevisionItem(r post.Revision) PostRevisionItem {
+	item := PostRevisionItem{Number: r.Number, Title: r.Title, CreatedAt: r.CreatedAt}
+	if r.Editor != nil {
+		item.Editor = &r.Editor.Username
+	}
+	return item
+}
+
+// PostRevisionsResponse lists a post's archived versions, newest first, with
+// the number of its live version.
+type PostRevisionsResponse struct {
+	Current int                `json:"current"`
+	Items   []PostRevisionItem `json:"items"`
+}
+
+// PostRevisionDiffQuery binds the two version numbers of a revision diff.
+type PostRevisionDiffQuery struct {
+	From int `form:"from" binding:"required,min=1"`
+	To   int `form:"to" binding:"required,min=1"`
+}
+
+// PostRevisionDiffResponse is the unified diff between two versions of a
+// post; empty when they are identical.
+type PostRevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
 // --- Delivery types ---
 
-// ChannelResponse represents a delivery channel in API responses.
+// ChannelResponse represents a delivery channel in API responses. Signing
+// secrets are write-only and never included in Configuration.
 type ChannelResponse struct {
 	ID            int                           `json:"id"`
 	Kind          delivery.ChannelKind          `json:"kind"`
@@ -130,23 +208,76 @@ type ChannelResponse struct {
 	Enabled       bool                          `json:"enabled"`
 	Configuration delivery.ChannelConfiguration `json:"configuration"`
 	Keywords      string                        `json:"keywords"`
+	Template      string                        `json:"template"`
+	RetryPolicy   *delivery.RetryPolicy         `json:"retry_policy"`
+	Digest        *delivery.DigestPolicy        `json:"digest"`
+	Schedule      *delivery.Schedule            `json:"schedule"`
+	Events        delivery.EventSet             `json:"events"`
+	Health        ChannelHealth                 `json:"health"`
 	CreatedAt     time.Time                     `json:"created_at"`
 	UpdatedAt     time.Time                     `json:"updated_at"`
 }
 
+// ChannelHealth is a channel's circuit-breaker state. PausedUntil is set while
+// the breaker holds the channel's deliveries back; DisabledReason explains an
+// automatic disable.
+type ChannelHealth struct {
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	PausedUntil         *time.Time `json:"paused_until"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+}
+
+func newChannelHealth(ch delivery.Channel) ChannelHealth {
+	h := ChannelHealth{
+		ConsecutiveFailures: ch.ConsecutiveFailures,
+		LastSuccessAt:       ch.LastSuccessAt,
+		LastFailureAt:       ch.LastFailureAt,
+		DisabledReason:      ch.DisabledReason,
+	}
+	if ch.PausedUntil > 0 && time.UnixMilli(ch.PausedUntil).After(time.Now()) {
+		until := time.UnixMilli(ch.PausedUntil)
+		h.PausedUntil = &until
+	}
+	return h
+}
+
 func newChannelResponse(ch delivery.Channel) ChannelResponse {
 	return ChannelResponse{
 		ID:            ch.ID,
 		Kind:          ch.Kind,
 		Name:          ch.Name,
 		Enabled:       ch.Enabled,
-		Configuration: ch.Configuration,
+		Configuration: delivery_svc.RedactConfiguration(ch.Kind, ch.Configuration),
 		Keywords:      ch.Keywords,
+		Template:      ch.Template,
+		RetryPolicy:   ch.RetryPolicy,
+		Digest:        ch.Digest,
+		Schedule:      ch.Schedule,
+		Events:        ch.Events,
+		Health:        newChannelHealth(ch),
 		CreatedAt:     ch.CreatedAt,
 		UpdatedAt:     ch.UpdatedAt,
 	}
 }
 
+// DeliveryKindItem describes a registered channel kind and its configuration
+// schema.
+type DeliveryKindItem struct {
+	Kind   delivery.ChannelKind       `json:"kind"`
+	Fields []delivery_svc.ConfigField `json:"fields"`
+}
+
+func newDeliveryKindItem(d delivery_svc.ChannelDriver) DeliveryKindItem {
+	return DeliveryKindItem{Kind: d.Kind(), Fields: d.Fields()}
+}
+
+// DeliveryKindsResponse represents the list of registered channel kinds.
+type DeliveryKindsResponse struct {
+	Items []DeliveryKindItem `json:"items"`
+}
+
 // ChannelsListResponse represents a list of delivery channels.
 type ChannelsListResponse struct {
 	Items []ChannelResponse `json:"items"`
@@ -157,12 +288,120 @@ type SingleChannelResponse struct {
 	Channel ChannelResponse `json:"channel"`
 }
 
+// ChannelTestResponse is the outcome of a channel test send: the upstream
+// HTTP status (0 if none arrived) and the start of its response body.
+type ChannelTestResponse struct {
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"status_code"`
+	Body       string `json:"body"`
+	Error      string `json:"error,omitempty"`
+}
+
+func newChannelTestResponse(r *delivery_svc.TestSendResult) ChannelTestResponse {
+	return ChannelTestResponse{
+		Delivered:  r.Delivered,
+		StatusCode: r.StatusCode,
+		Body:       r.Body,
+		Error:      r.Error,
+	}
+}
+
+// ExplainFilterRequest represents the request body for explaining a keyword
+// filter expression. Replay is how many of the caller's most recent posts to
+// also evaluate (0 = none).
+type ExplainFilterRequest struct {
+	Expression string `json:"expression"`
+	Title      string `json:"title"`
+	Body       string `json:"body"`
+	Replay     int    `json:"replay" binding:"min=0,max=50"`
+}
+
+func (r ExplainFilterRequest) toParams(author string) delivery_svc.ExplainFilterParams {
+	return delivery_svc.ExplainFilterParams{
+		Expression: r.Expression,
+		Title:      r.Title,
+		Body:       r.Body,
+		Author:     author,
+		Replay:     r.Replay,
+	}
+}
+
+// FilterExplainResponse is the explanation of a keyword filter expression.
+// When the expression is malformed only Error is set; otherwise Tree is the
+// parsed expression with each node's outcome against the sample post and
+// Replay the verdict for each replayed post, newest first.
+type FilterExplainResponse struct {
+	Error   *FilterParseErrorResponse `json:"error"`
+	Matched bool                      `json:"matched"`
+	Tree    *FilterNodeResponse       `json:"tree"`
+	Replay  []FilterReplayItem        `json:"replay"`
+}
+
+// FilterParseErrorResponse locates a syntax error in an expression. Pos is a
+// byte offset.
+type FilterParseErrorResponse struct {
+	Pos     int    `json:"pos"`
+	Message string `json:"message"`
+}
+
+// FilterNodeResponse is one node of an explained expression: an operator
+// (or, and, not) with its operands, or a terminal (keyword, word, regex, all)
+// with the field and value it matches.
+type FilterNodeResponse struct {
+	Op       string               `json:"op"`
+	Field    string               `json:"field,omitempty"`
+	Value    string               `json:"value,omitempty"`
+	Matched  bool                 `json:"matched"`
+	Children []FilterNodeResponse `json:"children,omitempty"`
+}
+
+// FilterReplayItem is whether a channel with the explained expression would
+// have received one of the caller's posts.
+type FilterReplayItem struct {
+	QID       string    `json:"qid"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	Matched   bool      `json:"matched"`
+}
+
+func newFilterExplainResponse(e *delivery_svc.FilterExplanation) FilterExplainResponse {
+	resp := FilterExplainResponse{Matched: e.Matched, Replay: make([]FilterReplayItem, 0, len(e.Replay))}
+	if e.Error != nil {
+		resp.Error = &FilterParseErrorResponse{Pos: e.Error.Pos, Message: e.Error.Msg}
+	}
+	if e.Trace != nil {
+		tree := newFilterNodeResponse(e.Trace)
+		resp.Tree = &tree
+	}
+	for _, r := range e.Replay {
+		resp.Replay = append(resp.Replay, FilterReplayItem{QID: r.QID, Title: r.Title, CreatedAt: r.CreatedAt, Matched: r.Matched})
+	}
+	return resp
+}
+
+func newFilterNodeResponse(t *filter.Trace) FilterNodeResponse {
+	node := FilterNodeResponse{Op: t.Op, Value: t.Value, Matched: t.Matched}
+	switch t.Op {
+	case filter.OpKeyword, filter.OpWord, filter.OpRegex:
+		node.Field = t.Field.String()
+	}
+	for _, child := range t.Children {
+		node.Children = append(node.Children, newFilterNodeResponse(child))
+	}
+	return node
+}
+
 // CreateDeliveryChannelRequest represents the request body for creating a delivery channel.
 type CreateDeliveryChannelRequest struct {
-	Kind          string          `json:"kind" binding:"required"`
-	Name          string          `json:"name" binding:"required"`
-	Configuration json.RawMessage `json:"configuration" binding:"required"`
-	Keywords      string          `json:"keywords"`
+	Kind          string                 `json:"kind" binding:"required"`
+	Name          string                 `json:"name" binding:"required"`
+	Configuration json.RawMessage        `json:"configuration" binding:"required"`
+	Keywords      string                 `json:"keywords"`
+	Template      string                 `json:"template"`
+	RetryPolicy   *delivery.RetryPolicy  `json:"retry_policy"`
+	Digest        *delivery.DigestPolicy `json:"digest"`
+	Schedule      *delivery.Schedule     `json:"schedule"`
+	Events        *delivery.EventSet     `json:"events"`
 }
 
 func (r CreateDeliveryChannelRequest) toParams() delivery_svc.UpdateChannelParams {
@@ -171,24 +410,39 @@ func (r CreateDeliveryChannelRequest) toParams() delivery_svc.UpdateChannelParam
 		Name:          r.Name,
 		Configuration: r.Configuration,
 		Keywords:      &r.Keywords,
+		Template:      &r.Template,
+		RetryPolicy:   r.RetryPolicy,
+		Digest:        r.Digest,
+		Schedule:      r.Schedule,
+		Events:        r.Events,
 	}
 }
 
 // UpdateDeliveryChannelRequest represents the request body for updating a delivery channel.
 type UpdateDeliveryChannelRequest struct {
-	Kind          *string          `json:"kind"`
-	Name          *string          `json:"name"`
-	Configuration *json.RawMessage `json:"configuration"`
-	Keywords      *string          `json:"keywords"`
-	Enabled       *bool            `json:"enabled"`
+	Kind          *string                `json:"kind"`
+	Name          *string                `json:"name"`
+	Configuration *json.RawMessage       `json:"configuration"`
+	Keywords      *string                `json:"keywords"`
+	Template      *string                `json:"template"`
+	RetryPolicy   *delivery.RetryPolicy  `json:"retry_policy"`
+	Digest        *delivery.DigestPolicy `json:"digest"`
+	Schedule      *delivery.Schedule     `json:"schedule"`
+	Events        *delivery.EventSet     `json:"events"`
+	Enabled       *bool                  `json:"enabled"`
 }
 
 func (r UpdateDeliveryChannelRequest) toParams() delivery_svc.UpdateChannelParams {
 	params := delivery_svc.UpdateChannelParams{
-		Kind:     utils.Deref(r.Kind),
-		Name:     utils.Deref(r.Name),
-		Keywords: r.Keywords,
-		Enabled:  r.Enabled,
+		Kind:        utils.Deref(r.Kind),
+		Name:        utils.Deref(r.Name),
+		Keywords:    r.Keywords,
+		Template:    r.Template,
+		RetryPolicy: r.RetryPolicy,
+		Digest:      r.Digest,
+		Schedule:    r.Schedule,
+		Events:      r.Events,
+		Enabled:     r.Enabled,
 	}
 	if r.Configuration != nil {
 		params.Configuration = *r.Configuration
@@ -200,19 +454,45 @@ func (r UpdateDeliveryChannelRequest) toParams() delivery_svc.UpdateChannelParam
 // nullable pointers reflect ON DELETE SET NULL: a nil field means the referenced
 // post/channel/user was deleted.
 type DeliveryHistoryItem struct {
-	ID          int64     `json:"id"`
-	Status      string    `json:"status"`
-	LastError   string    `json:"last_error"`
-	CreatedAt   time.Time `json:"created_at"`
-	PostTitle   *string   `json:"post_title"`
-	PostQID     *string   `json:"post_qid"`
-	ChannelName *string   `json:"channel_name"`
-	Username    *string   `json:"username"`
+	ID          int64      `json:"id"`
+	Event       post.Event `json:"event"`
+	Status      string     `json:"status"`
+	LastError   string     `json:"last_error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PostTitle   *string    `json:"post_title"`
+	PostQID     *string    `json:"post_qid"`
+	ChannelName *string    `json:"channel_name"`
+	Username    *string    `json:"username"`
+	// Tries is the entry's delivery timeline, oldest first.
+	Tries []DeliveryTryItem `json:"tries"`
+}
+
+// DeliveryTryItem is one send of a delivery: what the upstream answered and
+// how long it took. StatusCode is 0 when no HTTP response came back.
+type DeliveryTryItem struct {
+	Number     int       `json:"number"`
+	StatusCode int       `json:"status_code"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Body       string    `json:"body"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 func newDeliveryHistoryItem(h *delivery.HistoryRow) DeliveryHistoryItem {
+	tries := make([]DeliveryTryItem, 0, len(h.Tries))
+	for _, t := range h.Tries {
+		tries = append(tries, DeliveryTryItem{
+			Number:     t.Number,
+			StatusCode: t.StatusCode,
+			LatencyMs:  t.LatencyMs,
+			Body:       t.Body,
+			Error:      t.Error,
+			CreatedAt:  t.CreatedAt,
+		})
+	}
 	return DeliveryHistoryItem{
 		ID:          h.ID,
+		Event:       h.Event,
 		Status:      deliveryStatusName(h.Status),
 		LastError:   h.LastError,
 		CreatedAt:   h.CreatedAt,
@@ -220,6 +500,7 @@ func newDeliveryHistoryItem(h *delivery.HistoryRow) DeliveryHistoryItem {
 		PostQID:     h.PostQID,
 		ChannelName: h.ChannelName,
 		Username:    h.Username,
+		Tries:       tries,
 	}
 }
 
@@ -242,6 +523,53 @@ type DeliveryHistoryListResponse struct {
 	Pagination Pagination            `json:"pagination"`
 }
 
+// DeliveryRouteItem is what routing one event of a post decided for one
+// channel. ChannelID and ChannelName are nil once the channel is deleted;
+// Detail carries the parse error of an invalid_filter decision.
+type DeliveryRouteItem struct {
+	Event       post.Event `json:"event"`
+	ChannelID   *int       `json:"channel_id"`
+	ChannelName *string    `json:"channel_name"`
+	Username    *string    `json:"username"`
+	Decision    string     `json:"decision"`
+	Detail      string     `json:"detail,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func newDeliveryRouteItem(r *delivery.RouteRow) DeliveryRouteItem {
+	return DeliveryRouteItem{
+		Event:       r.Event,
+		ChannelID:   r.ChannelID,
+		ChannelName: r.ChannelName,
+		Username:    r.Username,
+		Decision:    string(r.Decision),
+		Detail:      r.Detail,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// DeliveryRoutesResponse lists the routing decisions recorded for a post.
+type DeliveryRoutesResponse struct {
+	Items []DeliveryRouteItem `json:"items"`
+}
+
+// RetryDeliveryResponse describes the attempt enqueued by a delivery retry.
+type RetryDeliveryResponse struct {
+	AttemptID int64 `json:"attempt_id"`
+	PostID    *int  `json:"post_id"`
+	ChannelID int   `json:"channel_id"`
+	NextAt    int64 `json:"next_at"`
+}
+
+func newRetryDeliveryResponse(a *delivery.Attempt) RetryDeliveryResponse {
+	return RetryDeliveryResponse{
+		AttemptID: a.ID,
+		PostID:    a.PostID,
+		ChannelID: a.ChannelID,
+		NextAt:    a.NextAt,
+	}
+}
+
 // --- Admin types ---
 
 // AdminUserItem represents a user entry in the admin user list.
@@ -302,7 +630,7 @@ func newAdminChannelItem(ch delivery.Channel) AdminChannelItem {
 		Kind:          string(ch.Kind),
 		Enabled:       ch.Enabled,
 		UserID:        ch.UserID,
-		Configuration: ch.Configuration,
+		Configuration: delivery_svc.RedactConfiguration(ch.Kind, ch.Configuration),
 		CreatedAt:     ch.CreatedAt,
 	}
 }
@@ -345,3 +673,102 @@ type PaginatedChannels struct {
 type HealthResponse struct {
 	Status string `json:"status"`
 }
+
+// AdminRetryDeliveryRequest represents the request body for a bulk delivery
+// retry.
+type AdminRetryDeliveryRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,max=500"`
+}
+
+// AdminRetryItem is the outcome of retrying one history entry. On failure
+// Code and Error carry the reason (e.g. delivery_target_gone).
+type AdminRetryItem struct {
+	ID        int64  `json:"id"`
+	Retried   bool   `json:"retried"`
+	AttemptID *int64 `json:"attempt_id,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func newAdminRetryItem(o delivery_svc.RetryOutcome) AdminRetryItem {
+	item := AdminRetryItem{ID: o.HistoryID, Retried: o.Err == nil}
+	if o.Attempt != nil {
+		item.AttemptID = &o.Attempt.ID
+	}
+	if o.Err != nil {
+		item.Error = o.Err.Error()
+		if se, ok := service.AsError(o.Err); ok {
+			item.Code = se.Code.Value
+			item.Error = se.Description
+		}
+	}
+	return item
+}
+
+// AdminRetryDeliveryResponse lists the per-entry outcomes of a bulk retry and
+// how many entries were re-enqueued.
+type AdminRetryDeliveryResponse struct {
+	Items   []AdminRetryItem `json:"items"`
+	Retried int              `json:"retried"`
+}
+
+func newAdminRetryDeliveryResponse(outcomes []delivery_svc.RetryOutcome) AdminRetryDeliveryResponse {
+	resp := AdminRetryDeliveryResponse{Items: make([]AdminRetryItem, 0, len(outcomes))}
+	for _, o := range outcomes {
+		item := newAdminRetryItem(o)
+		if item.Retried {
+			resp.Retried++
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp
+}
+
+// AdminDeliveryLeasesResponse lists the dispatcher instances holding delivery
+// attempts.
+type AdminDeliveryLeasesResponse struct {
+	Instances []AdminLeaseHolder `json:"instances"`
+}
+
+// AdminLeaseHolder is one dispatcher instance's leased attempts. Expired
+// counts the leases that ran out without being renewed.
+type AdminLeaseHolder struct {
+	Owner    string               `json:"owner"`
+	Held     int                  `json:"held"`
+	Expired  int                  `json:"expired"`
+	Attempts []AdminLeasedAttempt `json:"attempts"`
+}
+
+// AdminLeasedAttempt is one delivery attempt under a lease.
+type AdminLeasedAttempt struct {
+	ID             int64     `json:"id"`
+	UserID         int       `json:"user_id"`
+	PostID         *int      `json:"post_id"`
+	ChannelID      int       `json:"channel_id"`
+	Attempts       int       `json:"attempts"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	Expired        bool      `json:"expired"`
+}
+
+func newAdminLeaseHolder(h admin.LeaseHolder, now time.Time) AdminLeaseHolder {
+	resp := AdminLeaseHolder{Owner: h.Owner, Attempts: make([]AdminLeasedAttempt, 0, len(h.Attempts))}
+	for _, a := range h.Attempts {
+		expiresAt := time.UnixMilli(a.LeaseExpiresAt)
+		expired := !expiresAt.After(now)
+		if expired {
+			resp.Expired++
+		} else {
+			resp.Held++
+		}
+		resp.Attempts = append(resp.Attempts, AdminLeasedAttempt{
+			ID:             a.ID,
+			UserID:         a.UserID,
+			PostID:         a.PostID,
+			ChannelID:      a.ChannelID,
+			Attempts:       a.Attempts,
+			LeaseExpiresAt: expiresAt,
+			Expired:        expired,
+		})
+	}
+	return resp
+}
@@ -108,10 +108,9 @@ type RateLimitConfig struct {
 	DailyBurst  int     `mapstructure:"daily_burst" validate:"gte=0"`
 }
 
-// DeliveryConfig holds delivery-related configuration. The retry sequence and
-// expiry wall are NOT here — they are hardcoded constants in
-// internal/service/delivery/backoff.go (one channel kind today, so per-channel
-// retry tuning has no consumer; see specs/backend/delivery.md Decision 4).
+// DeliveryConfig holds delivery-related configuration. The expiry wall is not
+// configured directly: it is derived from the retry policy per attempt (see
+// internal/service/delivery/backoff.go).
 type DeliveryConfig struct {
 	BodyPreviewChars int           `mapstructure:"body_preview_chars" validate:"gte=0"`
 	RequestTimeout   time.Duration `mapstructure:"request_timeout" validate:"required"`
@@ -119,6 +118,49 @@ type DeliveryConfig struct {
 	QueueSize        int           `mapstructure:"queue_size" validate:"gte=0"`
 	ScanInterval     time.Duration `mapstructure:"scan_interval" validate:"required"`
 	HistoryRetention time.Duration `mapstructure:"history_retention" validate:"required"`
+	InstanceID       string        `mapstructure:"instance_id" validate:"max=64"`
+	LeaseTTL         time.Duration `mapstructure:"lease_ttl" validate:"required,gte=3s,gtfield=RequestTimeout"`
+	Retry            RetryConfig   `mapstructure:"retry"`
+	Breaker          BreakerConfig `mapstructure:"breaker"`
+	SMTP             SMTPConfig    `mapstructure:"smtp"`
+}
+
+// RetryConfig is the instance-wide delivery retry policy; a channel may
+// override it. Mode "sequence" waits Sequence[i] before retry i+1;
+// "exponential" waits Base·2^i capped at Max. MaxAttempts counts every try
+// including the first (0 derives it from the mode). Jitter spreads each delay
+// by ±that fraction.
+type RetryConfig struct {
+	Mode        string          `mapstructure:"mode" validate:"oneof=sequence exponential"`
+	Sequence    []time.Duration `mapstructure:"sequence" validate:"dive,gt=0"`
+	Base        time.Duration   `mapstructure:"base" validate:"gte=0"`
+	Max         time.Duration   `mapstructure:"max" validate:"gte=0"`
+	MaxAttempts int             `mapstructure:"max_attempts" validate:"gte=0,lte=50"`
+	Jitter      float64         `mapstructure:"jitter" validate:"gte=0,lte=1"`
+}
+
+// BreakerConfig is the per-channel circuit breaker. Threshold consecutive
+// failed tries (across all of a channel's deliveries) pause the channel for
+// Cooldown; DisableAfter consecutive failures disable it. A zero Threshold or
+// DisableAfter turns that stage off.
+type BreakerConfig struct {
+	Threshold    int           `mapstructure:"threshold" validate:"gte=0"`
+	Cooldown     time.Duration `mapstructure:"cooldown" validate:"gte=0"`
+	DisableAfter int           `mapstructure:"disable_after" validate:"gte=0"`
+}
+
+// SMTPConfig holds the server-wide SMTP relay used by email delivery channels.
+// An empty Host leaves the relay unconfigured: email channels can still be
+// saved, but every send fails permanently until an operator sets it up.
+// TLS is "starttls" (upgrade a plain connection, required), "implicit"
+// (TLS from the first byte, usually port 465) or "none" (local relays only).
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port" validate:"gte=0,lte=65535"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from" validate:"required_with=Host"`
+	TLS      string `mapstructure:"tls" validate:"oneof=starttls implicit none"`
 }
 
 // RenderConfig holds configuration for the in-process render cache
@@ -289,6 +331,23 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("delivery.queue_size", 1024)
 	v.SetDefault("delivery.scan_interval", "1s")
 	v.SetDefault("delivery.history_retention", "168h")
+	v.SetDefault("delivery.instance_id", "")
+	v.SetDefault("delivery.lease_ttl", "30s")
+	v.SetDefault("delivery.retry.mode", "sequence")
+	v.SetDefault("delivery.retry.sequence", []string{"1m", "5m", "10m", "20m"})
+	v.SetDefault("delivery.retry.base", "1m")
+	v.SetDefault("delivery.retry.max", "1h")
+	v.SetDefault("delivery.retry.max_attempts", 0)
+	v.SetDefault("delivery.retry.jitter", 0.0)
+	v.SetDefault("delivery.breaker.threshold", 5)
+	v.SetDefault("delivery.breaker.cooldown", "10m")
+	v.SetDefault("delivery.breaker.disable_after", 50)
+	v.SetDefault("delivery.smtp.host", "")
+	v.SetDefault("delivery.smtp.port", 587)
+	v.SetDefault("delivery.smtp.username", "")
+	v.SetDefault("delivery.smtp.password", "")
+	v.SetDefault("delivery.smtp.from", "")
+	v.SetDefault("delivery.smtp.tls", "starttls")
 	v.SetDefault("render.enabled", true)
 	v.SetDefault("render.cache_size_bytes", 134217728) // 128 MiB
 	v.SetDefault("render.num_counters", 100000)        // ~10x expected key count
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 const testConfigToml = `
@@ -95,6 +96,16 @@ func TestDefaults(t *testing.T) {
 	if cfg.Post.BodyMaxBytes != 32768 {
 		t.Fatalf("expected default BodyMaxBytes 32768, got %d", cfg.Post.BodyMaxBytes)
 	}
+	if cfg.Delivery.SMTP.Host != "" || cfg.Delivery.SMTP.Port != 587 || cfg.Delivery.SMTP.TLS != "starttls" {
+		t.Fatalf("unexpected SMTP defaults: %+v", cfg.Delivery.SMTP)
+	}
+	retry := cfg.Delivery.Retry
+	if retry.Mode != "sequence" || len(retry.Sequence) != 4 || retry.Sequence[3] != 20*time.Minute || retry.Max != time.Hour {
+		t.Fatalf("unexpected retry defaults: %+v", retry)
+	}
+	if b := cfg.Delivery.Breaker; b.Threshold != 5 || b.Cooldown != 10*time.Minute || b.DisableAfter != 50 {
+		t.Fatalf("unexpected breaker defaults: %+v", b)
+	}
 }
 
 func TestFileExists(t *testing.T) {
@@ -179,6 +190,32 @@ request_timeout = "5s"
 	}
 }
 
+// TestLoad_LeaseTTLBounds checks that a lease outlives a send: a lease shorter
+// than request_timeout could be reclaimed by another instance mid-send.
+func TestLoad_LeaseTTLBounds(t *testing.T) {
+	cases := []struct {
+		leaseTTL string
+		ok       bool
+	}{
+		{"1ns", false},
+		{"2s", false},
+		{"5s", false},
+		{"6s", true},
+	}
+	for _, c := range cases {
+		ResetForTest()
+		path := filepath.Join(t.TempDir(), "config.toml")
+		content := testConfigToml + "lease_ttl = \"" + c.leaseTTL + "\"\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if err := Load(path); (err == nil) != c.ok {
+			t.Errorf("lease_ttl = %s with request_timeout = 5s: err = %v, want ok = %v", c.leaseTTL, err, c.ok)
+		}
+	}
+	ResetForTest()
+}
+
 func writeNamedConfig(t *testing.T, dir, name string) {
 	t.Helper()
 	if err := os.WriteFile(filepath.Join(dir, name), []byte(testConfigToml), 0o644); err != nil {
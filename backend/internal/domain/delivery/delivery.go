@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	"markpost/internal/domain/post"
@@ -14,23 +15,24 @@ import (
 // ChannelKind represents the type of delivery channel.
 type ChannelKind string
 
-// Supported delivery channel kinds.
+// Built-in delivery channel kinds. Which kinds are accepted, and how each one
+// is configured and sent, is decided by the driver registry in
+// internal/service/delivery; these constants only name them.
 const (
-	ChannelKindFeishu ChannelKind = "feishu"
+	ChannelKindFeishu   ChannelKind = "feishu"
+	ChannelKindSlack    ChannelKind = "slack"
+	ChannelKindWebhook  ChannelKind = "webhook"
+	ChannelKindDingTalk ChannelKind = "dingtalk"
+	ChannelKindWeCom    ChannelKind = "wecom"
+	ChannelKindEmail    ChannelKind = "email"
+	ChannelKindTelegram ChannelKind = "telegram"
+	ChannelKindDiscord  ChannelKind = "discord"
+	ChannelKindTeams    ChannelKind = "teams"
 )
 
-var validChannelKinds = map[ChannelKind]bool{
-	ChannelKindFeishu: true,
-}
-
 // TableName returns the database table name for Channel.
 func (Channel) TableName() string { return "delivery_channels" }
 
-// IsValid reports whether the ChannelKind is a recognized value.
-func (k ChannelKind) IsValid() bool {
-	return validChannelKinds[k]
-}
-
 // Status is the lifecycle state of a delivery attempt or history row. It is an
 // int8 (not a string) so the column maps to the smallest integer type on each
 // dialect via GORM's size-based logic: tinyint on MySQL (1B), smallint on
@@ -63,20 +65,95 @@ func (Attempt) TableName() string { return "delivery_attempts" }
 // delivery is in progress (at most the expiry wall); on any terminal state it
 // is archived to History and deleted in the same transaction.
 type Attempt struct {
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"not null;column:user_id;index"`
+	PostID    *int       `json:"post_id" gorm:"column:post_id;index"` // nil for an event about a removed post
+	ChannelID int        `json:"channel_id" gorm:"not null;column:channel_id;index"`
+	Event     post.Event `json:"event" gorm:"not null;size:16;default:'created'"`
+	Status    Status     `json:"status" gorm:"not null;default:0"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"`
+	NextAt    int64      `json:"next_at" gorm:"not null"`              // epoch ms; when the next attempt may run
+	ExpiresAt int64      `json:"expires_at" gorm:"not null;default:0"` // epoch ms; the expiry wall, 0 = none
+	LastError string     `json:"last_error" gorm:"not null;type:text;default:''"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Subject is what an attempt announcing a removed post (Event deleted or
+	// expired) knows of it, captured when the post was removed; nil for
+	// every other attempt, which loads its post by PostID.
+	Subject *PostSnapshot `json:"subject,omitempty" gorm:"type:text"`
+
+	// Lease: the dispatcher instance that claimed the attempt and until when
+	// (epoch ms) it holds it. A lease that has run out may be re-claimed by any
+	// instance; an empty owner means never claimed or released.
+	LeaseOwner     string `json:"lease_owner" gorm:"not null;size:64;default:'';index"`
+	LeaseExpiresAt int64  `json:"lease_expires_at" gorm:"not null;default:0"`
+
+	// DigestSize is the number of posts a digest attempt carries in one
+	// combined message (see DigestItem); 0 means a single-post attempt for
+	// PostID. A digest attempt's PostID is its oldest post.
+	DigestSize int `json:"digest_size" gorm:"not null;default:0"`
+
+	User    user.User  `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Post    *post.Post `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Channel Channel    `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"`
+}
+
+// PostSnapshot is the part of a removed post its lifecycle notices need.
+type PostSnapshot struct {
+	QID       string    `json:"qid"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Value implements the driver.Valuer interface for database serialization.
+func (s PostSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("marshal post snapshot: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface for database deserialization.
+func (s *PostSnapshot) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("cannot scan %T into PostSnapshot", value)
+	}
+	if err := json.Unmarshal(bytes, s); err != nil {
+		return fmt.Errorf("unmarshal post snapshot: %w", err)
+	}
+	return nil
+}
+
+// TableName returns the database table name for DigestItem.
+func (DigestItem) TableName() string { return "delivery_digest_items" }
+
+// DigestItem is a post waiting in a channel's digest. Items of a channel with
+// AttemptID nil form its open digest; every item of an open digest shares its
+// FlushAt, fixed when the digest's first item arrived. At FlushAt (or once the
+// digest is full) the dispatcher seals the open items into one Attempt, which
+// sends a single combined message. The items live until that attempt is
+// archived, which writes one History row per item.
+type DigestItem struct {
 	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
 	UserID    int       `json:"user_id" gorm:"not null;column:user_id;index"`
-	PostID    int       `json:"post_id" gorm:"not null;column:post_id;index"`
 	ChannelID int       `json:"channel_id" gorm:"not null;column:channel_id;index"`
-	Status    Status    `json:"status" gorm:"not null;default:0"`
-	Attempts  int       `json:"attempts" gorm:"not null;default:0"`
-	NextAt    int64     `json:"next_at" gorm:"not null"` // epoch ms; when the next attempt may run
-	LastError string    `json:"last_error" gorm:"not null;type:text;default:''"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"` // drives the expiry wall
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	PostID    int       `json:"post_id" gorm:"not null;column:post_id;index"`
+	AttemptID *int64    `json:"attempt_id" gorm:"column:attempt_id;index"` // nil while the digest is open
+	FlushAt   int64     `json:"flush_at" gorm:"not null;index"`            // epoch ms; when the open digest is sealed
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 
 	User    user.User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Post    post.Post `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
 	Channel Channel   `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"`
+	Attempt *Attempt  `json:"-" gorm:"foreignKey:AttemptID;constraint:OnDelete:CASCADE"`
 }
 
 // TableName returns the database table name for History.
@@ -88,13 +165,18 @@ func (History) TableName() string { return "delivery_history" }
 // the history row (which could lock a large row set) — the reference is
 // nulled and the row is preserved as an anonymous record.
 type History struct {
-	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    *int      `json:"user_id" gorm:"column:user_id;index"`       // nullable; ON DELETE SET NULL
-	PostID    *int      `json:"post_id" gorm:"column:post_id;index"`       // nullable; ON DELETE SET NULL
-	ChannelID *int      `json:"channel_id" gorm:"column:channel_id;index"` // nullable; ON DELETE SET NULL
-	Status    Status    `json:"status" gorm:"not null"`                    // delivered | failed | expired
-	LastError string    `json:"last_error" gorm:"not null;type:text;default:''"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    *int       `json:"user_id" gorm:"column:user_id;index"`       // nullable; ON DELETE SET NULL
+	PostID    *int       `json:"post_id" gorm:"column:post_id;index"`       // nullable; ON DELETE SET NULL
+	ChannelID *int       `json:"channel_id" gorm:"column:channel_id;index"` // nullable; ON DELETE SET NULL
+	Event     post.Event `json:"event" gorm:"not null;size:16;default:'created'"`
+	Status    Status     `json:"status" gorm:"not null"` // delivered | failed | expired
+	LastError string     `json:"last_error" gorm:"not null;type:text;default:''"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// Subject is the attempt's snapshot of a removed post, kept so that a
+	// failed removal notice can be retried; nil for every other event.
+	Subject *PostSnapshot `json:"subject,omitempty" gorm:"type:text"`
 
 	User    *user.User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL"`
 	Post    *post.Post `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:SET NULL"`
@@ -106,34 +188,171 @@ type History struct {
 // names are JOINed, not snapshotted). The nullable pointers reflect
 // ON DELETE SET NULL: a nil field means the referenced row was deleted.
 type HistoryRow struct {
-	ID          int64     `json:"id" gorm:"column:id"`
-	Status      Status    `json:"status" gorm:"column:status"`
-	LastError   string    `json:"last_error" gorm:"column:last_error"`
-	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
-	PostTitle   *string   `json:"post_title" gorm:"column:post_title"`
-	PostQID     *string   `json:"post_qid" gorm:"column:post_qid"`
-	ChannelName *string   `json:"channel_name" gorm:"column:channel_name"`
-	Username    *string   `json:"username" gorm:"column:username"`
+	ID          int64      `json:"id" gorm:"column:id"`
+	Event       post.Event `json:"event" gorm:"column:event"`
+	Status      Status     `json:"status" gorm:"column:status"`
+	LastError   string     `json:"last_error" gorm:"column:last_error"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+	PostTitle   *string    `json:"post_title" gorm:"column:post_title"`
+	PostQID     *string    `json:"post_qid" gorm:"column:post_qid"`
+	ChannelName *string    `json:"channel_name" gorm:"column:channel_name"`
+	Username    *string    `json:"username" gorm:"column:username"`
+	// Tries is the entry's delivery timeline, oldest first, loaded from
+	// delivery_tries after the row itself.
+	Tries []Try `json:"tries" gorm:"-"`
+}
+
+// TableName returns the database table name for Try.
+func (Try) TableName() string { return "delivery_tries" }
+
+// MaxTryBodyBytes caps the upstream response body and the error a Try keeps.
+const MaxTryBodyBytes = 1024
+
+// Try is one send of a delivery attempt: what the upstream answered, how long
+// it took, and the error if the send failed. A try is written against its
+// attempt while the attempt is queued (AttemptID) and handed to the attempt's
+// History rows when it is archived (HistoryID), so history keeps the whole
+// timeline. Tries are pruned with their history row.
+type Try struct {
+	ID         int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	AttemptID  *int64    `json:"-" gorm:"column:attempt_id;index"`           // set while the attempt is queued
+	HistoryID  *int64    `json:"-" gorm:"column:history_id;index"`           // set once it is archived
+	Number     int       `json:"number" gorm:"not null"`                     // 1-based try number within the attempt
+	StatusCode int       `json:"status_code" gorm:"not null;default:0"`      // 0 = no HTTP response arrived
+	LatencyMs  int64     `json:"latency_ms" gorm:"not null;default:0"`       // time the send took
+	Body       string    `json:"body" gorm:"not null;type:text;default:''"`  // first MaxTryBodyBytes of the response
+	Error      string    `json:"error" gorm:"not null;type:text;default:''"` // empty for a successful send
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Attempt *Attempt `json:"-" gorm:"foreignKey:AttemptID;constraint:OnDelete:CASCADE"`
+	History *History `json:"-" gorm:"foreignKey:HistoryID;constraint:OnDelete:CASCADE"`
 }
 
-// FeishuConfiguration holds the configuration for a Feishu delivery channel.
-type FeishuConfiguration struct {
-	WebhookURL  string `json:"webhook_url"`
-	CardLinkURL string `json:"card_link_url"`
+// TableName returns the database table name for Message.
+func (Message) TableName() string { return "delivery_messages" }
+
+// Message is a platform message a delivery posted, recorded when the
+// channel's platform returned an ID for it, so later lifecycle events of the
+// post can edit the message in place instead of posting another. It is keyed
+// by post QID rather than post ID because it must outlive the post: a delete
+// notice looks it up after the post row is gone.
+type Message struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChannelID int       `json:"channel_id" gorm:"not null;column:channel_id;index"`
+	PostQID   string    `json:"post_qid" gorm:"not null;size:64;column:post_qid;index"`
+	MessageID string    `json:"message_id" gorm:"not null;size:128"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Channel Channel `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:CASCADE"`
 }
 
-// ChannelConfiguration stores arbitrary key-value pairs for channel settings.
-type ChannelConfiguration map[string]any
+// Decision is what enqueueing a post event decided for one of the owner's
+// channels. The values are stored in delivery_routes, so they must never
+// change.
+type Decision string
+
+// Routing decisions, in the order Enqueue checks them. Enqueue no longer
+// writes DecisionMatched, which only older rows carry; DecisionSummary heads
+// each event's rows instead.
+const (
+	DecisionDisabled      Decision = "disabled"       // the channel is disabled
+	DecisionUnsubscribed  Decision = "unsubscribed"   // the channel does not hear of the event
+	DecisionInvalidFilter Decision = "invalid_filter" // the stored keywords expression does not compile
+	DecisionUnmatched     Decision = "unmatched"      // the keywords expression rejected the post
+	DecisionMatched       Decision = "matched"        // the post was queued or added to a digest
+	DecisionSummary       Decision = "summary"        // the event's channel-less row; detail counts the matches
+)
+
+// TableName returns the database table name for Route.
+func (Route) TableName() string { return "delivery_routes" }
+
+// Route records why a post event was not queued for one channel, so an owner
+// can tell why a post never reached a channel. Each time the post is routed,
+// Enqueue writes one summary row with no channel, then one row per channel
+// that did not match; matching channels show up in the queue and history
+// instead. It is keyed by post QID and outlives the post; routes are pruned
+// with delivery_history's retention.
+type Route struct {
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    *int       `json:"user_id" gorm:"column:user_id;index"` // nullable; ON DELETE SET NULL
+	PostQID   string     `json:"post_qid" gorm:"not null;size:64;column:post_qid;index"`
+	ChannelID *int       `json:"channel_id" gorm:"column:channel_id;index"` // nullable; ON DELETE SET NULL
+	Event     post.Event `json:"event" gorm:"not null;size:16;default:'created'"`
+	Decision  Decision   `json:"decision" gorm:"not null;size:16"`
+	Detail    string     `json:"detail" gorm:"not null;type:text;default:''"` // the parse error of an invalid filter
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	User    *user.User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL"`
+	Channel *Channel   `json:"-" gorm:"foreignKey:ChannelID;constraint:OnDelete:SET NULL"`
+}
 
-// Feishu parses and returns the Feishu-specific configuration.
-func (c ChannelConfiguration) Feishu() FeishuConfiguration {
-	return FeishuConfiguration{
-		WebhookURL:  c.stringField("webhook_url"),
-		CardLinkURL: c.stringField("card_link_url"),
+// RouteRow is the read projection of a delivery_routes row with its channel's
+// name JOINed at read time. ChannelName is nil once the channel is deleted.
+type RouteRow struct {
+	ID          int64      `json:"id" gorm:"column:id"`
+	Event       post.Event `json:"event" gorm:"column:event"`
+	Decision    Decision   `json:"decision" gorm:"column:decision"`
+	Detail      string     `json:"detail" gorm:"column:detail"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+	ChannelID   *int       `json:"channel_id" gorm:"column:channel_id"`
+	ChannelName *string    `json:"channel_name" gorm:"column:channel_name"`
+	Username    *string    `json:"username" gorm:"column:username"`
+}
+
+// EventSet is the lifecycle events a channel subscribes to. A nil set means
+// the default, post creation only. The service layer validates it.
+type EventSet []post.Event
+
+// Has reports whether the set includes e.
+func (s EventSet) Has(e post.Event) bool {
+	if s == nil {
+		return e == post.EventCreated
+	}
+	return slices.Contains(s, e)
+}
+
+// Value implements the driver.Valuer interface for database serialization.
+// A nil set is stored as NULL.
+func (s EventSet) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
 	}
+	b, err := json.Marshal([]post.Event(s))
+	if err != nil {
+		return nil, fmt.Errorf("marshal event set: %w", err)
+	}
+	return string(b), nil
 }
 
-func (c ChannelConfiguration) stringField(key string) string {
+// Scan implements the sql.Scanner interface for database deserialization.
+func (s *EventSet) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("cannot scan %T into EventSet", value)
+	}
+	var events []post.Event
+	if err := json.Unmarshal(bytes, &events); err != nil {
+		return fmt.Errorf("unmarshal event set: %w", err)
+	}
+	*s = events
+	return nil
+}
+
+// ChannelConfiguration stores arbitrary key-value pairs for channel settings.
+// Its keys are defined per kind by the channel's driver.
+type ChannelConfiguration map[string]any
+
+// String returns the string value stored under key, or "" when the key is
+// absent or holds another type.
+func (c ChannelConfiguration) String(key string) string {
 	v, ok := c[key]
 	if !ok {
 		return ""
@@ -145,6 +364,26 @@ func (c ChannelConfiguration) stringField(key string) string {
 	return s
 }
 
+// Strings returns a string-list value. JSON decoding yields []any, while a
+// configuration built in code may hold []string; both are accepted and
+// non-string elements are skipped.
+func (c ChannelConfiguration) Strings(key string) []string {
+	switch v := c[key].(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
 // Value implements the driver.Valuer interface for database serialization.
 func (c ChannelConfiguration) Value() (driver.Value, error) {
 	if c == nil {
@@ -187,6 +426,121 @@ func (c *ChannelConfiguration) Scan(value any) error {
 	return nil
 }
 
+// RetryPolicy is a channel's override of the instance retry policy
+// ([delivery.retry]). Mode is "sequence" (Sequence lists the delays) or
+// "exponential" (Base doubled per retry, capped at Max); delays are Go
+// duration strings such as "90s" or "5m". A zero MaxAttempts is derived from
+// the mode. The service layer validates and interprets it.
+type RetryPolicy struct {
+	Mode        string   `json:"mode"`
+	Sequence    []string `json:"sequence,omitempty"`
+	Base        string   `json:"base,omitempty"`
+	Max         string   `json:"max,omitempty"`
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	Jitter      float64  `json:"jitter,omitempty"`
+}
+
+// Value implements the driver.Valuer interface for database serialization.
+func (p RetryPolicy) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal retry policy: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface for database deserialization.
+func (p *RetryPolicy) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("cannot scan %T into RetryPolicy", value)
+	}
+	if err := json.Unmarshal(bytes, p); err != nil {
+		return fmt.Errorf("unmarshal retry policy: %w", err)
+	}
+	return nil
+}
+
+// DigestPolicy switches a channel to digest delivery: matched posts
+// accumulate for Window (a Go duration string such as "30m") and are then
+// sent as one combined message listing every title and link. MaxPosts sends
+// the digest early once it holds that many posts; 0 means the service's cap.
+// The service layer validates and interprets it.
+type DigestPolicy struct {
+	Window   string `json:"window"`
+	MaxPosts int    `json:"max_posts,omitempty"`
+}
+
+// Value implements the driver.Valuer interface for database serialization.
+func (p DigestPolicy) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal digest policy: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface for database deserialization.
+func (p *DigestPolicy) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("cannot scan %T into DigestPolicy", value)
+	}
+	if err := json.Unmarshal(bytes, p); err != nil {
+		return fmt.Errorf("unmarshal digest policy: %w", err)
+	}
+	return nil
+}
+
+// Schedule limits a channel's deliveries to a daily window in Timezone (an
+// IANA name such as "Asia/Shanghai"; empty = UTC). The window runs from Start
+// to End ("HH:MM", End may be "24:00") on each of Days ("mon" … "sun"; empty =
+// every day); an End not after Start makes it run past midnight into the next
+// day. Outside the window attempts are held rather than sent. The service
+// layer validates and interprets it.
+type Schedule struct {
+	Timezone string   `json:"timezone,omitempty"`
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+}
+
+// Value implements the driver.Valuer interface for database serialization.
+func (s Schedule) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schedule: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface for database deserialization.
+func (s *Schedule) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		return fmt.Errorf("cannot scan %T into Schedule", value)
+	}
+	if err := json.Unmarshal(bytes, s); err != nil {
+		return fmt.Errorf("unmarshal schedule: %w", err)
+	}
+	return nil
+}
+
 // Channel represents a delivery channel linked to a user.
 type Channel struct {
 	ID            int                  `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -197,6 +551,19 @@ type Channel struct {
 	Enabled       bool                 `json:"enabled" gorm:"not null;default:true"`
 	Configuration ChannelConfiguration `json:"configuration" gorm:"not null;type:text;column:configuration;default:'{}'"`
 	Keywords      string               `json:"keywords" gorm:"not null;type:text;default:''"`
-	CreatedAt     time.Time            `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+	Template      string               `json:"template" gorm:"not null;type:text;default:''"` // text/template; empty = kind's default layout
+	RetryPolicy   *RetryPolicy         `json:"retry_policy" gorm:"type:text"`                 // nil = instance retry policy
+	Digest        *DigestPolicy        `json:"digest" gorm:"type:text"`                       // nil = one message per post
+	Schedule      *Schedule            `json:"schedule" gorm:"type:text"`                     // nil = deliver at any time
+	Events        EventSet             `json:"events" gorm:"type:text"`                       // nil = post creation only
+
+	// Health, maintained by the dispatcher's circuit breaker.
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	PausedUntil         int64      `json:"paused_until" gorm:"not null;default:0"`               // epoch ms the circuit stays open until; 0 = closed
+	DisabledReason      string     `json:"disabled_reason" gorm:"not null;type:text;default:''"` // why the breaker disabled the channel; empty otherwise
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
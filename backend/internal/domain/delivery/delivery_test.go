@@ -2,26 +2,6 @@ package delivery
 
 import "testing"
 
-func TestChannelKind_IsValid(t *testing.T) {
-	tests := []struct {
-		kind ChannelKind
-		want bool
-	}{
-		{ChannelKindFeishu, true},
-		{"slack", false},
-		{"", false},
-		{"FEISHU", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(string(tt.kind), func(t *testing.T) {
-			if got := tt.kind.IsValid(); got != tt.want {
-				t.Errorf("IsValid(%q) = %v, want %v", tt.kind, got, tt.want)
-			}
-		})
-	}
-}
-
 func TestChannel_TableName(t *testing.T) {
 	ch := Channel{}
 	if ch.TableName() != "delivery_channels" {
@@ -67,9 +47,8 @@ func TestChannelConfiguration_Scan(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := c.Feishu()
-		if feishu.WebhookURL != "https://example.com" {
-			t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://example.com")
+		if got := c.String("webhook_url"); got != "https://example.com" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://example.com")
 		}
 	})
 
@@ -104,30 +83,33 @@ func TestChannelConfiguration_Scan(t *testing.T) {
 	})
 }
 
-func TestChannelConfiguration_Feishu(t *testing.T) {
-	c := ChannelConfiguration{
-		"webhook_url":   "https://hook.example.com",
-		"card_link_url": "https://custom.example.com/{{.QID}}",
+func TestChannelConfiguration_Strings(t *testing.T) {
+	decoded := ChannelConfiguration{"recipients": []any{"a@example.com", 42, "b@example.com"}}
+	got := decoded.Strings("recipients")
+	if len(got) != 2 || got[0] != "a@example.com" || got[1] != "b@example.com" {
+		t.Errorf("recipients = %v, want [a@example.com b@example.com]", got)
 	}
-	feishu := c.Feishu()
-	if feishu.WebhookURL != "https://hook.example.com" {
-		t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://hook.example.com")
+
+	built := ChannelConfiguration{"recipients": []string{"c@example.com"}}
+	if got := built.Strings("recipients"); len(got) != 1 || got[0] != "c@example.com" {
+		t.Errorf("recipients = %v, want [c@example.com]", got)
 	}
-	if feishu.CardLinkURL != "https://custom.example.com/{{.QID}}" {
-		t.Errorf("card_link_url = %q, want %q", feishu.CardLinkURL, "https://custom.example.com/{{.QID}}")
+
+	if got := (ChannelConfiguration{}).Strings("recipients"); got != nil {
+		t.Errorf("recipients = %v, want nil", got)
 	}
 }
 
-func TestChannelConfiguration_stringField(t *testing.T) {
+func TestChannelConfiguration_String(t *testing.T) {
 	c := ChannelConfiguration{"key": "value"}
-	if got := c.stringField("key"); got != "value" {
+	if got := c.String("key"); got != "value" {
 		t.Errorf("got %q, want %q", got, "value")
 	}
-	if got := c.stringField("missing"); got != "" {
+	if got := c.String("missing"); got != "" {
 		t.Errorf("got %q, want empty", got)
 	}
 	c["non_string"] = 42
-	if got := c.stringField("non_string"); got != "" {
+	if got := c.String("non_string"); got != "" {
 		t.Errorf("got %q, want empty for non-string", got)
 	}
 }
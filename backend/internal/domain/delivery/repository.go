@@ -3,6 +3,8 @@ package delivery
 import (
 	"context"
 	"time"
+
+	"markpost/internal/domain/post"
 )
 
 // Repository defines the interface for delivery channel data access.
@@ -10,10 +12,27 @@ type Repository interface {
 	GetByUserID(ctx context.Context, userID int) ([]Channel, error)
 	GetByIDAndUserID(ctx context.Context, id int, userID int) (*Channel, error)
 	Create(ctx context.Context, channel *Channel) error
+	// Update writes a channel's owner-editable settings; it does not touch
+	// enabled or the breaker's health columns.
 	Update(ctx context.Context, channel *Channel) error
+	// SetEnabled turns a channel on or off. Turning a disabled channel on
+	// also clears its failure streak, pause and disabled reason.
+	SetEnabled(ctx context.Context, id int, enabled bool) error
 	DeleteByIDAndUserID(ctx context.Context, id int, userID int) (int64, error)
 	ListAll(ctx context.Context, offset, limit int) ([]Channel, error)
 	CountAll(ctx context.Context) (int64, error)
+	// RecordSuccess ends a channel's failure streak: it zeroes
+	// consecutive_failures, closes the circuit and stamps last_success_at.
+	RecordSuccess(ctx context.Context, id int, at time.Time) error
+	// RecordFailure extends a channel's failure streak by one, stamps
+	// last_failure_at and returns the new streak length.
+	RecordFailure(ctx context.Context, id int, at time.Time) (int, error)
+	// Pause opens a channel's circuit until untilMs (epoch ms).
+	Pause(ctx context.Context, id int, untilMs int64) error
+	// AutoDisable disables a channel and records why.
+	AutoDisable(ctx context.Context, id int, reason string) error
+	// HasSubscribers reports whether any enabled channel subscribes to event.
+	HasSubscribers(ctx context.Context, event post.Event) (bool, error)
 }
 
 // AttemptRepository defines persistence for the delivery best-effort retry
@@ -23,37 +42,98 @@ type Repository interface {
 type AttemptRepository interface {
 	// Create inserts one or more pending attempts.
 	Create(ctx context.Context, attempts []*Attempt) error
-	// ClaimDue atomically claims up to limit due attempts (status=pending and
-	// next_at <= now) and reserves each past the request timeout by advancing
-	// next_at. FOR UPDATE SKIP LOCKED is used on Postgres/MySQL; on SQLite it
-	// is omitted (single-connection serialization prevents double-claim).
-	ClaimDue(ctx context.Context, now, reserveUntilMs int64, limit int) ([]*Attempt, error)
+	// ClaimDue atomically claims up to limit due attempts (status=pending,
+	// next_at <= now, and no live lease) and leases each to owner until
+	// leaseUntilMs. FOR UPDATE SKIP LOCKED is used on Postgres/MySQL; on SQLite
+	// it is omitted (single-connection serialization prevents double-claim).
+	ClaimDue(ctx context.Context, owner string, now, leaseUntilMs int64, limit int) ([]*Attempt, error)
 	// MarkRetry records a failed (non-terminal) attempt: bumps the attempt
-	// count, sets last_error, and schedules the next attempt at nextAtMs.
-	MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs int64) error
-	// MarkExpired transitions up to batchSize pending attempts whose
-	// created_at is past the wall to expired, returning the claimed rows for
-	// archival. It is called repeatedly by the scheduler until it returns none.
-	MarkExpired(ctx context.Context, wallBeforeMs int64, batchSize int) ([]*Attempt, error)
+	// count, sets last_error, schedules the next attempt at nextAtMs with its
+	// expiry wall at expiresAtMs, and releases the lease.
+	MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs, expiresAtMs int64) error
+	// Hold puts an attempt back in the queue until nextAtMs without counting
+	// a try, moves its expiry wall to expiresAtMs, and releases the lease.
+	Hold(ctx context.Context, id int64, nextAtMs, expiresAtMs int64) error
+	// RenewLeases extends owner's lease on those of ids that are still
+	// pending and leased to it until untilMs, returning how many were renewed.
+	RenewLeases(ctx context.Context, owner string, ids []int64, untilMs int64) (int64, error)
+	// ReleaseLease hands one pending attempt leased to owner back to the
+	// queue.
+	ReleaseLease(ctx context.Context, id int64, owner string) error
+	// ReleaseLeases hands every pending attempt leased to owner back to the
+	// queue, returning how many were released.
+	ReleaseLeases(ctx context.Context, owner string) (int64, error)
+	// ListLeased returns up to limit attempts that carry a lease owner — live
+	// or run out — ordered by owner, for the admin view.
+	ListLeased(ctx context.Context, limit int) ([]*Attempt, error)
+	// MarkExpired transitions up to batchSize pending, unleased attempts whose
+	// expires_at wall has passed (0 < expires_at <= nowMs) to expired,
+	// returning the claimed rows for archival. It is called repeatedly by the
+	// scheduler until it returns none.
+	MarkExpired(ctx context.Context, nowMs int64, batchSize int) ([]*Attempt, error)
 	// ArchiveAndDelete writes a History row for the attempt's terminal state
-	// and deletes the attempt row in a single transaction.
+	// and deletes the attempt row in a single transaction. A digest attempt
+	// writes one History row per post it carries and deletes its items. The
+	// attempt's tries move to its History rows.
 	ArchiveAndDelete(ctx context.Context, attempt *Attempt, status Status, lastError string) error
+	// RecordTry stores one send of a queued attempt.
+	RecordTry(ctx context.Context, t *Try) error
 	// CountByStatus returns the count of attempts in each status, for
 	// observability.
 	CountByStatus(ctx context.Context) (map[Status]int64, error)
 	// PruneHistory deletes delivery_history rows older than the retention
 	// window in batches of batchSize, returning the total deleted. It uses the
-	// portable subquery-LIMIT form.
+	// portable subquery-LIMIT form. The rows' tries go with them, and routing
+	// decisions and recorded messages of posts that no longer exist are pruned
+	// with the same window.
 	PruneHistory(ctx context.Context, retention time.Duration, batchSize int) (int64, error)
 	// ListHistory returns delivery history (newest first), paginated, with the
-	// post title/qid, channel name, and username JOINed at read time. filter
-	// scopes the result: OwnerID > 0 limits to one user (NULL user_id rows are
-	// excluded from a user's own page); OwnerID == 0 lists all rows including
-	// anonymized ones (admin view). ChannelID > 0 further limits to one channel.
+	// post title/qid, channel name, and username JOINed at read time and each
+	// row's tries attached. filter scopes the result: OwnerID > 0 limits to
+	// one user (NULL user_id rows are excluded from a user's own page);
+	// OwnerID == 0 lists all rows including anonymized ones (admin view).
+	// ChannelID > 0 further limits to one channel.
 	ListHistory(ctx context.Context, filter HistoryFilter, offset, limit int) ([]*HistoryRow, error)
 	// CountHistory returns the total row count matching the same filter as
 	// ListHistory, for pagination.
 	CountHistory(ctx context.Context, filter HistoryFilter) (int64, error)
+	// GetHistory returns one delivery_history row. ownerID > 0 scopes the
+	// lookup to that user; a missing or foreign row is domain.ErrNotFound.
+	GetHistory(ctx context.Context, id int64, ownerID int) (*History, error)
+	// HasPending reports whether an attempt announcing the same event of the
+	// same post to the same channel as a is still in the queue. A removed
+	// post is matched by the QID of a's Subject.
+	HasPending(ctx context.Context, a *Attempt) (bool, error)
+	// DeferChannel pushes every pending attempt of the channel due before
+	// untilMs to untilMs, without counting a try and moving each expiry wall
+	// forward by as much, and returns how many moved.
+	DeferChannel(ctx context.Context, channelID int, untilMs int64) (int64, error)
+	// AddToDigest puts item into its channel's open digest. If the channel
+	// has none, item opens one that closes at item.FlushAt; otherwise item
+	// takes the open digest's FlushAt. Once the open digest holds maxPosts
+	// items it is made due at once and full is true.
+	AddToDigest(ctx context.Context, item *DigestItem, maxPosts int) (full bool, err error)
+	// ListDueDigests returns up to limit items of open digests whose
+	// flush_at has passed (<= nowMs), ordered by channel then id.
+	ListDueDigests(ctx context.Context, nowMs int64, limit int) ([]*DigestItem, error)
+	// SealDigest inserts attempt and assigns it those of itemIDs that are
+	// still open, in one transaction, returning how many it took. Zero means
+	// another instance sealed them first; nothing is inserted then.
+	SealDigest(ctx context.Context, attempt *Attempt, itemIDs []int64) (int64, error)
+	// DigestPostIDs returns the posts sealed into a digest attempt, oldest
+	// first.
+	DigestPostIDs(ctx context.Context, attemptID int64) ([]int, error)
+	// RecordMessage stores the platform message a delivery posted.
+	RecordMessage(ctx context.Context, m *Message) error
+	// FindMessage returns the latest message recorded for the post on the
+	// channel; none is domain.ErrNotFound.
+	FindMessage(ctx context.Context, channelID int, postQID string) (*Message, error)
+	// RecordRoutes stores the routing decisions of one post event.
+	RecordRoutes(ctx context.Context, routes []*Route) error
+	// ListRoutes returns the routing decisions recorded for a post, newest
+	// event first, with channel name and username JOINed at read time.
+	// ownerID > 0 limits to that user's rows; 0 lists all (admin view).
+	ListRoutes(ctx context.Context, postQID string, ownerID int) ([]*RouteRow, error)
 }
 
 // HistoryFilter scopes a delivery_history read. A zero value selects every row
@@ -1,15 +1,47 @@
 package post
 
+import (
+	"context"
+	"time"
+)
+
+// Event is a post lifecycle event that delivery channels can subscribe to.
+// The values are stored in channel subscriptions and delivery rows, so they
+// must never change.
+type Event string
+
+// Post lifecycle events. EventCreated is the only one a channel receives
+// unless it subscribes to others.
+const (
+	EventCreated Event = "created"
+	EventUpdated Event = "updated"
+	EventDeleted Event = "deleted"
+	EventExpired Event = "expired"
+)
+
+// Events lists every lifecycle event in display order.
+var Events = []Event{EventCreated, EventUpdated, EventDeleted, EventExpired}
+
+// Removed reports whether the event announces that the post is gone, so
+// deliveries of it cannot load the post and carry what they need instead.
+func (e Event) Removed() bool {
+	return e == EventDeleted || e == EventExpired
+}
+
 // DeliveryJob is the post aggregate's delivery contract: the pure-data
 // description of a post that needs to be pushed to delivery channels. It lives
 // in the domain so the delivery service can consume it without a service-layer
-// import (architecture.md 偏离点 #3). Fields are all basic types.
+// import (architecture.md 偏离点 #3). Fields are plain values. An empty
+// Event means EventCreated; for a removed post PostID names a row that no
+// longer exists and the other fields are all that is left of it.
 type DeliveryJob struct {
-	UserID  int
-	PostID  int
-	PostQID string
-	Title   string
-	Body    string
+	Event     Event
+	UserID    int
+	PostID    int
+	PostQID   string
+	Title     string
+	Body      string
+	CreatedAt time.Time
 }
 
 // DeliveryEnqueuer is the port the post aggregate exposes for enqueueing a
@@ -20,3 +52,10 @@ type DeliveryJob struct {
 type DeliveryEnqueuer interface {
 	Enqueue(job DeliveryJob)
 }
+
+// SubscriptionChecker is implemented by a DeliveryEnqueuer that can tell
+// whether any delivery channel subscribes to an event, so a caller can skip
+// loading what a job would carry when nobody would receive it.
+type SubscriptionChecker interface {
+	HasSubscribers(ctx context.Context, event Event) bool
+}
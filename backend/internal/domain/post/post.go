@@ -18,3 +18,25 @@ type Post struct {
 	UserID    int       `json:"user_id" gorm:"index;not null;column:user_id"`
 	User      user.User `json:"user" gorm:"constraint:OnDelete:CASCADE"`
 }
+
+// TableName returns the database table name for Revision.
+func (Revision) TableName() string { return "post_revisions" }
+
+// Revision is a prior version of a post's title and body, archived when an
+// edit replaced it. Number counts the post's versions from 1, the content it
+// was created with; the live post is the version after its latest revision.
+// CreatedAt is when the replacing edit was saved and EditorID who saved it,
+// so version N+1 was written at revision N's CreatedAt. Revisions are
+// deleted with their post.
+type Revision struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PostID    int       `json:"post_id" gorm:"not null;column:post_id;uniqueIndex:idx_post_revisions_number"`
+	Number    int       `json:"number" gorm:"not null;uniqueIndex:idx_post_revisions_number"`
+	Title     string    `json:"title" gorm:"not null"`
+	Body      string    `json:"body" gorm:"not null;type:text"`
+	EditorID  *int      `json:"editor_id" gorm:"column:editor_id;index"` // nullable; ON DELETE SET NULL
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Post   Post       `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	Editor *user.User `json:"-" gorm:"foreignKey:EditorID;constraint:OnDelete:SET NULL"`
+}
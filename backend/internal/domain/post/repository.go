@@ -8,6 +8,17 @@ type Repository interface {
 	CreateBatch(ctx context.Context, posts []Post) (int, error)
 	GetByQID(ctx context.Context, qid string) (*Post, error)
 	GetByID(ctx context.Context, id int) (*Post, error)
+	// Update writes p's title and body and stamps UpdatedAt, archiving the
+	// stored title and body as the post's next Revision, edited by editorID,
+	// in the same transaction. A post that no longer exists is
+	// domain.ErrNotFound.
+	Update(ctx context.Context, p *Post, editorID int) error
+	// ListRevisions returns a post's revisions newest first, without their
+	// bodies, with the editor loaded.
+	ListRevisions(ctx context.Context, postID int) ([]Revision, error)
+	// GetRevision returns revision number of a post; a missing one is
+	// domain.ErrNotFound.
+	GetRevision(ctx context.Context, postID, number int) (*Revision, error)
 	CountByUserID(ctx context.Context, userID int) (int64, error)
 	GetByUserID(ctx context.Context, userID int, offset int, limit int) ([]Post, error)
 	ListAll(ctx context.Context, search string, offset int, limit int) ([]Post, error)
@@ -17,6 +28,9 @@ type Repository interface {
 	// is only deleted if it belongs to that owner (returns affected=0 otherwise);
 	// an ownerID of 0 (admin path) deletes by QID with no owner constraint.
 	DeleteByQID(ctx context.Context, qid string, ownerID int) (int64, error)
-	PruneExpired(ctx context.Context, retentionDays int, batchSize int) ([]string, error)
+	// PruneExpired deletes expired posts and their revisions in batches of
+	// batchSize, passes each deleted batch to each, and returns how many
+	// posts it deleted. Post bodies are loaded only when withBody is set.
+	PruneExpired(ctx context.Context, retentionDays int, batchSize int, withBody bool, each func([]Post)) (int, error)
 	CountExpired(ctx context.Context, retentionDays int) (int64, error)
 }
@@ -29,9 +29,14 @@ var allModels = []any{
 	&user.RefreshToken{},
 	&user.TokenBlacklist{},
 	&post.Post{},
+	&post.Revision{},
 	&delivery.Channel{},
 	&delivery.Attempt{},
+	&delivery.DigestItem{},
 	&delivery.History{},
+	&delivery.Try{},
+	&delivery.Message{},
+	&delivery.Route{},
 }
 
 // Database wraps a GORM database connection.
@@ -107,6 +112,14 @@ func New(dsn string) (*Database, error) {
 		sqlDB.SetConnMaxLifetime(30 * time.Minute)
 	}
 
+	database := &Database{db: db}
+
+	// Runs ahead of AutoMigrate, which would add the column without the
+	// backfill.
+	if err := database.addAttemptExpiry(); err != nil {
+		return nil, fmt.Errorf("NewDatabase add attempt expiry: %w", err)
+	}
+
 	if err = db.AutoMigrate(allModels...); err != nil {
 		return nil, fmt.Errorf("NewDatabase auto migrate: %w", err)
 	}
@@ -115,8 +128,6 @@ func New(dsn string) (*Database, error) {
 		db.Exec("PRAGMA foreign_keys = ON")
 	}
 
-	database := &Database{db: db}
-
 	if err := database.migratePasswordColumn(); err != nil {
 		return nil, fmt.Errorf("NewDatabase migrate password column: %w", err)
 	}
@@ -285,6 +296,52 @@ func (d *Database) migrateChannelConfiguration() error {
 	return nil
 }
 
+// legacyExpiryWall is the expiry wall every attempt had before it was stored
+// per attempt: the default backoff sequence [1m,5m,10m,20m] rounded up to 10m.
+const legacyExpiryWall = 40 * time.Minute
+
+// addAttemptExpiry adds delivery_attempts.expires_at to a database that
+// predates it, giving each pending attempt the wall it was enqueued under,
+// counted from created_at. The column defaults to 0, which the expiry sweep
+// reads as no wall, so such attempts would otherwise retry without limit.
+//
+// Only the startup that adds the column can tell them apart from a no-retry
+// policy's own 0, so the column and the backfill go in one transaction: if
+// the UPDATE fails, the column is rolled back with it and the next start
+// tries again. MySQL commits DDL implicitly, which leaves only the single
+// UPDATE statement outside that guarantee there.
+func (d *Database) addAttemptExpiry() error {
+	m := d.db.Migrator()
+	if !m.HasTable(&delivery.Attempt{}) || m.HasColumn(&delivery.Attempt{}, "expires_at") {
+		return nil
+	}
+
+	var createdAtMs string
+	switch d.db.Name() {
+	case "postgres":
+		createdAtMs = "CAST(EXTRACT(EPOCH FROM created_at) * 1000 AS BIGINT)"
+	case "mysql":
+		createdAtMs = "CAST(UNIX_TIMESTAMP(created_at) * 1000 AS SIGNED)"
+	default:
+		createdAtMs = "CAST(ROUND((julianday(created_at) - 2440587.5) * 86400000) AS INTEGER)"
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&delivery.Attempt{}, "ExpiresAt"); err != nil {
+			return fmt.Errorf("add expires_at column: %w", err)
+		}
+		result := tx.Exec("UPDATE delivery_attempts SET expires_at = "+createdAtMs+" + ? WHERE status = ? AND expires_at = 0",
+			legacyExpiryWall.Milliseconds(), delivery.StatusPending)
+		if result.Error != nil {
+			return fmt.Errorf("backfill expires_at: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("backfilled expires_at on %d pending delivery attempts", result.RowsAffected)
+		}
+		return nil
+	})
+}
+
 // migratePostBodyCompressionLZ4 switches the posts.body TOAST compressor to
 // lz4 on Postgres 14+. lz4 decompresses ~3x faster than the default pglz at a
 // comparable ratio.
@@ -1,11 +1,15 @@
 package infra
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -174,6 +178,74 @@ func TestMigrateQIDPrefix(t *testing.T) {
 	}
 }
 
+func TestAddAttemptExpiry(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(allModels...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Roll the table back to before expires_at existed.
+	if err := db.Migrator().DropColumn(&delivery.Attempt{}, "expires_at"); err != nil {
+		t.Fatalf("drop expires_at: %v", err)
+	}
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	insert := "INSERT INTO delivery_attempts (user_id, channel_id, status, next_at, created_at, updated_at) VALUES (1, 1, ?, 0, ?, ?)"
+	for _, status := range []delivery.Status{delivery.StatusPending, delivery.StatusExpired} {
+		if err := db.Exec(insert, status, created, created).Error; err != nil {
+			t.Fatalf("seed attempt: %v", err)
+		}
+	}
+
+	// An interrupted backfill leaves no column behind, so the next start
+	// still knows to run it.
+	failBackfill := func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "UPDATE delivery_attempts") {
+			_ = tx.AddError(errors.New("interrupted"))
+		}
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("test:fail_backfill", failBackfill); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	database := &Database{db: db}
+	if err := database.addAttemptExpiry(); err == nil {
+		t.Fatal("expected the interrupted backfill to fail")
+	}
+	if db.Migrator().HasColumn(&delivery.Attempt{}, "expires_at") {
+		t.Fatal("expires_at added although the backfill failed")
+	}
+	if err := db.Callback().Raw().Remove("test:fail_backfill"); err != nil {
+		t.Fatalf("remove callback: %v", err)
+	}
+
+	if err := database.addAttemptExpiry(); err != nil {
+		t.Fatalf("addAttemptExpiry error: %v", err)
+	}
+	var walls []int64
+	if err := db.Table("delivery_attempts").Order("id").Pluck("expires_at", &walls).Error; err != nil {
+		t.Fatalf("read walls: %v", err)
+	}
+	want := []int64{created.Add(legacyExpiryWall).UnixMilli(), 0}
+	if len(walls) != 2 || walls[0] != want[0] || walls[1] != want[1] {
+		t.Errorf("expires_at = %v, want %v (pending backfilled, expired left alone)", walls, want)
+	}
+
+	// Once the column exists a 0 is a no-retry wall and is left alone.
+	if err := db.Exec("UPDATE delivery_attempts SET expires_at = 0").Error; err != nil {
+		t.Fatalf("reset walls: %v", err)
+	}
+	if err := database.addAttemptExpiry(); err != nil {
+		t.Fatalf("addAttemptExpiry rerun error: %v", err)
+	}
+	var backfilled int64
+	db.Table("delivery_attempts").Where("expires_at <> 0").Count(&backfilled)
+	if backfilled != 0 {
+		t.Errorf("rerun backfilled %d attempts, want none", backfilled)
+	}
+}
+
 func TestDropStaleChannelsTable(t *testing.T) {
 	t.Run("no stale table", func(t *testing.T) {
 		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"markpost/internal/domain"
 	"markpost/internal/domain/delivery"
 
 	"gorm.io/gorm"
@@ -24,7 +25,8 @@ func NewAttemptRepository(db *gorm.DB) delivery.AttemptRepository {
 	return &AttemptRepository{db: db}
 }
 
-// Create inserts one or more pending attempts.
+// Create inserts one or more pending attempts. On Postgres the insert is
+// announced with NOTIFY so dispatchers on every instance wake at once.
 func (r *AttemptRepository) Create(ctx context.Context, attempts []*delivery.Attempt) error {
 	if len(attempts) == 0 {
 		return nil
@@ -32,13 +34,15 @@ func (r *AttemptRepository) Create(ctx context.Context, attempts []*delivery.Att
 	if err := r.db.WithContext(ctx).Create(&attempts).Error; err != nil {
 		return fmt.Errorf("AttemptRepository.Create: %w", err)
 	}
+	notifyAttempts(ctx, r.db)
 	return nil
 }
 
-// ClaimDue atomically claims up to limit due attempts (status=pending and
-// next_at <= nowMs) and reserves each past the request timeout by advancing
-// next_at to reserveUntilMs. This makes in-flight rows invisible to the next
-// scheduler tick, preventing double-claim.
+// ClaimDue atomically claims up to limit due attempts (status=pending,
+// next_at <= nowMs, and no live lease) and leases each to owner until
+// leaseUntilMs. The lease makes in-flight rows invisible to every scheduler,
+// preventing double-claim, and records which instance holds them; a lease
+// that runs out (its holder crashed) makes the row claimable again.
 //
 // The claim body (UPDATE ... WHERE id IN (SELECT ... LIMIT) RETURNING *) is
 // identical across dialects; only the locking clause differs:
@@ -48,24 +52,24 @@ func (r *AttemptRepository) Create(ctx context.Context, attempts []*delivery.Att
 //     (it is a parse-time syntax error). Production SQLite pins
 //     SetMaxOpenConns(1), so writes serialize through one connection and there
 //     is no concurrent claimer to exclude.
-func (r *AttemptRepository) ClaimDue(ctx context.Context, nowMs, reserveUntilMs int64, limit int) ([]*delivery.Attempt, error) {
+func (r *AttemptRepository) ClaimDue(ctx context.Context, owner string, nowMs, leaseUntilMs int64, limit int) ([]*delivery.Attempt, error) {
 	if limit <= 0 {
 		return nil, nil
 	}
 
-	selectClause := "SELECT id FROM delivery_attempts WHERE status = ? AND next_at <= ? ORDER BY next_at LIMIT ?"
+	selectClause := "SELECT id FROM delivery_attempts WHERE status = ? AND next_at <= ? AND lease_expires_at <= ? ORDER BY next_at LIMIT ?"
 	if r.rowLockingDialect() {
 		selectClause += " FOR UPDATE SKIP LOCKED"
 	}
 
 	sql := fmt.Sprintf(
-		"UPDATE delivery_attempts SET next_at = ? WHERE id IN (%s) RETURNING *",
+		"UPDATE delivery_attempts SET lease_owner = ?, lease_expires_at = ? WHERE id IN (%s) RETURNING *",
 		selectClause,
 	)
 
 	var claimed []*delivery.Attempt
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return tx.Raw(sql, reserveUntilMs, delivery.StatusPending, nowMs, limit).Scan(&claimed).Error
+		return tx.Raw(sql, owner, leaseUntilMs, delivery.StatusPending, nowMs, nowMs, limit).Scan(&claimed).Error
 	})
 	if err != nil {
 		return nil, fmt.Errorf("AttemptRepository.ClaimDue: %w", err)
@@ -74,14 +78,18 @@ func (r *AttemptRepository) ClaimDue(ctx context.Context, nowMs, reserveUntilMs
 }
 
 // MarkRetry records a failed (non-terminal) attempt: bumps the attempt count,
-// stores the last error, and schedules the next attempt at nextAtMs.
-func (r *AttemptRepository) MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs int64) error {
+// stores the last error, schedules the next attempt at nextAtMs with its
+// expiry wall at expiresAtMs, and releases the lease.
+func (r *AttemptRepository) MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs, expiresAtMs int64) error {
 	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
 		Where("id = ?", id).
 		Updates(map[string]any{
-			"attempts":   attempts,
-			"last_error": lastError,
-			"next_at":    nextAtMs,
+			"attempts":         attempts,
+			"last_error":       lastError,
+			"next_at":          nextAtMs,
+			"expires_at":       expiresAtMs,
+			"lease_owner":      "",
+			"lease_expires_at": 0,
 		})
 	if result.Error != nil {
 		return fmt.Errorf("AttemptRepository.MarkRetry: %w", result.Error)
@@ -89,36 +97,106 @@ func (r *AttemptRepository) MarkRetry(ctx context.Context, id int64, attempts in
 	return nil
 }
 
-// MarkExpired transitions up to batchSize pending attempts whose created_at is
-// past the wall (created_at < wallBefore) to expired, returning the claimed
-// rows so the caller can archive them. It is called repeatedly by the
-// scheduler until it returns an empty slice. Bounding the batch keeps each
-// tick's lock scope and dead-tuple volume bounded even under a large pending
-// backlog.
-func (r *AttemptRepository) MarkExpired(ctx context.Context, wallBeforeMs int64, batchSize int) ([]*delivery.Attempt, error) {
+// Hold reschedules an attempt to nextAtMs with its expiry wall at
+// expiresAtMs, leaving the attempt count and last error as they are, and
+// releases the lease. It is used for attempts claimed outside their channel's
+// delivery window.
+func (r *AttemptRepository) Hold(ctx context.Context, id int64, nextAtMs, expiresAtMs int64) error {
+	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"next_at":          nextAtMs,
+			"expires_at":       expiresAtMs,
+			"lease_owner":      "",
+			"lease_expires_at": 0,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("AttemptRepository.Hold: %w", result.Error)
+	}
+	return nil
+}
+
+// RenewLeases extends owner's lease on the given pending attempts until
+// untilMs — the dispatcher's heartbeat, covering sends and pool-queued
+// attempts that outlast one lease. Only the IDs the dispatcher is executing
+// are renewed, so a row it lost track of still runs out its lease.
+func (r *AttemptRepository) RenewLeases(ctx context.Context, owner string, ids []int64, untilMs int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
+		Where("id IN ? AND lease_owner = ? AND status = ?", ids, owner, delivery.StatusPending).
+		Update("lease_expires_at", untilMs)
+	if result.Error != nil {
+		return 0, fmt.Errorf("AttemptRepository.RenewLeases: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ReleaseLease hands one pending attempt leased to owner back to the queue,
+// e.g. when the worker pool refused it, so it is claimable at once.
+func (r *AttemptRepository) ReleaseLease(ctx context.Context, id int64, owner string) error {
+	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
+		Where("id = ? AND lease_owner = ? AND status = ?", id, owner, delivery.StatusPending).
+		Updates(map[string]any{"lease_owner": "", "lease_expires_at": 0})
+	if result.Error != nil {
+		return fmt.Errorf("AttemptRepository.ReleaseLease: %w", result.Error)
+	}
+	return nil
+}
+
+// ReleaseLeases hands every pending attempt leased to owner back to the queue,
+// so another instance can claim it at once instead of waiting for the lease
+// to run out.
+func (r *AttemptRepository) ReleaseLeases(ctx context.Context, owner string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
+		Where("lease_owner = ? AND status = ?", owner, delivery.StatusPending).
+		Updates(map[string]any{"lease_owner": "", "lease_expires_at": 0})
+	if result.Error != nil {
+		return 0, fmt.Errorf("AttemptRepository.ReleaseLeases: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListLeased returns up to limit attempts that carry a lease owner, ordered by
+// owner then id. Leases that have run out are included; the caller compares
+// lease_expires_at with the current time.
+func (r *AttemptRepository) ListLeased(ctx context.Context, limit int) ([]*delivery.Attempt, error) {
+	var attempts []*delivery.Attempt
+	err := r.db.WithContext(ctx).
+		Where("lease_owner <> ''").
+		Order("lease_owner asc, id asc").
+		Limit(limit).
+		Find(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("AttemptRepository.ListLeased: %w", err)
+	}
+	return attempts, nil
+}
+
+// MarkExpired transitions up to batchSize pending attempts whose expiry wall
+// has passed (0 < expires_at <= nowMs) to expired, returning the claimed rows
+// so the caller can archive them. It is called repeatedly by the scheduler
+// until it returns an empty slice. Bounding the batch keeps each tick's lock
+// scope and dead-tuple volume bounded even under a large pending backlog.
+// Attempts under a live lease are skipped: their send is in flight and will
+// archive or reschedule them itself.
+func (r *AttemptRepository) MarkExpired(ctx context.Context, nowMs int64, batchSize int) ([]*delivery.Attempt, error) {
 	if batchSize <= 0 {
 		return nil, nil
 	}
 
-	// created_at is a timestamp column. Compare against a time value in the same
-	// form GORM stores (the driver's default location) rather than forcing UTC,
-	// so the comparison is correct across dialects: Postgres/MySQL timestamps
-	// are timezone-aware (any zone compares correctly), while SQLite stores a
-	// formatted string and only compares correctly when the bound value uses the
-	// same offset the stored rows use.
-	wallBefore := time.UnixMilli(wallBeforeMs)
-
 	sql := `UPDATE delivery_attempts SET status = ?, updated_at = ?
 	        WHERE id IN (
 	            SELECT id FROM delivery_attempts
-	            WHERE status = ? AND created_at < ?
-	            ORDER BY created_at LIMIT ?
+	            WHERE status = ? AND expires_at > 0 AND expires_at <= ? AND lease_expires_at <= ?
+	            ORDER BY expires_at LIMIT ?
 	        )
 	        RETURNING *`
 
 	var expired []*delivery.Attempt
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return tx.Raw(sql, delivery.StatusExpired, time.Now(), delivery.StatusPending, wallBefore, batchSize).Scan(&expired).Error
+		return tx.Raw(sql, delivery.StatusExpired, time.Now(), delivery.StatusPending, nowMs, nowMs, batchSize).Scan(&expired).Error
 	})
 	if err != nil {
 		return nil, fmt.Errorf("AttemptRepository.MarkExpired: %w", err)
@@ -128,20 +206,42 @@ func (r *AttemptRepository) MarkExpired(ctx context.Context, wallBeforeMs int64,
 
 // ArchiveAndDelete writes a History row for the attempt's terminal state and
 // deletes the attempt row in a single transaction, so the archive and the
-// queue removal are atomic.
+// queue removal are atomic. A digest attempt is archived as one History row
+// per post it carried, and its digest items are deleted with it. The
+// attempt's tries move to its History rows (see moveTries).
 func (r *AttemptRepository) ArchiveAndDelete(ctx context.Context, attempt *delivery.Attempt, status delivery.Status, lastError string) error {
-	history := &delivery.History{
-		UserID:    &attempt.UserID,
-		PostID:    &attempt.PostID,
-		ChannelID: &attempt.ChannelID,
-		Status:    status,
-		LastError: lastError,
-		CreatedAt: attempt.CreatedAt,
-	}
-
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(history).Error; err != nil {
-			return fmt.Errorf("insert history: %w", err)
+		postIDs := []*int{attempt.PostID}
+		if attempt.DigestSize > 0 {
+			postIDs = nil
+			if err := tx.Model(&delivery.DigestItem{}).Where("attempt_id = ?", attempt.ID).
+				Order("id asc").Pluck("post_id", &postIDs).Error; err != nil {
+				return fmt.Errorf("list digest posts: %w", err)
+			}
+			if err := tx.Where("attempt_id = ?", attempt.ID).Delete(&delivery.DigestItem{}).Error; err != nil {
+				return fmt.Errorf("delete digest items: %w", err)
+			}
+		}
+		history := make([]*delivery.History, 0, len(postIDs))
+		for _, postID := range postIDs {
+			history = append(history, &delivery.History{
+				UserID:    &attempt.UserID,
+				PostID:    postID,
+				ChannelID: &attempt.ChannelID,
+				Event:     attempt.Event,
+				Status:    status,
+				LastError: lastError,
+				CreatedAt: attempt.CreatedAt,
+				Subject:   attempt.Subject,
+			})
+		}
+		if len(history) > 0 {
+			if err := tx.Create(&history).Error; err != nil {
+				return fmt.Errorf("insert history: %w", err)
+			}
+		}
+		if err := moveTries(tx, attempt.ID, history); err != nil {
+			return err
 		}
 		if err := tx.Where("id = ?", attempt.ID).Delete(&delivery.Attempt{}).Error; err != nil {
 			return fmt.Errorf("delete attempt: %w", err)
@@ -180,11 +280,16 @@ func (r *AttemptRepository) CountByStatus(ctx context.Context) (map[delivery.Sta
 // in batches of batchSize, returning the total deleted. It uses the portable
 // subquery-LIMIT form (bare DELETE ... LIMIT is a Postgres syntax error;
 // SQLite supports it only when the driver is compiled with the right flag).
+// The rows' tries are deleted first; routing decisions and recorded messages
+// of removed posts follow with the same cutoff.
 func (r *AttemptRepository) PruneHistory(ctx context.Context, retention time.Duration, batchSize int) (int64, error) {
 	if batchSize <= 0 {
 		batchSize = 1000
 	}
 	cutoff := time.Now().Add(-retention)
+	if err := r.pruneTries(ctx, cutoff, batchSize); err != nil {
+		return 0, fmt.Errorf("AttemptRepository.PruneHistory: %w", err)
+	}
 
 	var total int64
 	for {
@@ -200,6 +305,12 @@ func (r *AttemptRepository) PruneHistory(ctx context.Context, retention time.Dur
 			break
 		}
 	}
+	if err := r.pruneMessages(ctx, cutoff, batchSize); err != nil {
+		return total, fmt.Errorf("AttemptRepository.PruneHistory: %w", err)
+	}
+	if err := r.pruneRoutes(ctx, cutoff, batchSize); err != nil {
+		return total, fmt.Errorf("AttemptRepository.PruneHistory: %w", err)
+	}
 	return total, nil
 }
 
@@ -210,7 +321,7 @@ func (r *AttemptRepository) PruneHistory(ctx context.Context, retention time.Dur
 // result (see delivery.HistoryFilter).
 func (r *AttemptRepository) ListHistory(ctx context.Context, filter delivery.HistoryFilter, offset, limit int) ([]*delivery.HistoryRow, error) {
 	q := r.db.WithContext(ctx).Table("delivery_history AS h").
-		Select(`h.id, h.status, h.last_error, h.created_at,
+		Select(`h.id, h.event, h.status, h.last_error, h.created_at,
 		        p.title AS post_title, p.qid AS post_qid,
 		        c.name AS channel_name,
 		        u.username AS username`).
@@ -228,6 +339,9 @@ func (r *AttemptRepository) ListHistory(ctx context.Context, filter delivery.His
 	if err := q.Offset(offset).Limit(limit).Scan(&rows).Error; err != nil {
 		return nil, fmt.Errorf("AttemptRepository.ListHistory: %w", err)
 	}
+	if err := r.attachTries(ctx, rows); err != nil {
+		return nil, fmt.Errorf("AttemptRepository.ListHistory: %w", err)
+	}
 	return rows, nil
 }
 
@@ -248,6 +362,67 @@ func (r *AttemptRepository) CountHistory(ctx context.Context, filter delivery.Hi
 	return count, nil
 }
 
+// GetHistory returns one delivery_history row. ownerID > 0 scopes the lookup
+// to that user.
+func (r *AttemptRepository) GetHistory(ctx context.Context, id int64, ownerID int) (*delivery.History, error) {
+	q := r.db.Where("id = ?", id)
+	if ownerID > 0 {
+		q = q.Where("user_id = ?", ownerID)
+	}
+	h, err := findFirst[delivery.History](ctx, q, domain.ErrNotFound)
+	if err != nil {
+		return nil, fmt.Errorf("AttemptRepository.GetHistory: %w", err)
+	}
+	return h, nil
+}
+
+// HasPending reports whether an attempt announcing the same event of the same
+// post to the same channel as a is still in the queue. Notices of a removed
+// post have no post_id; their snapshots are few per channel, so they are
+// compared by QID here rather than by matching JSON in SQL.
+func (r *AttemptRepository) HasPending(ctx context.Context, a *delivery.Attempt) (bool, error) {
+	q := r.db.Model(&delivery.Attempt{}).Where("channel_id = ? AND event = ?", a.ChannelID, a.Event)
+	if a.PostID != nil {
+		count, err := countQuery(ctx, q.Where("post_id = ?", *a.PostID), "AttemptRepository.HasPending")
+		return count > 0, err
+	}
+	if a.Subject == nil {
+		return false, nil
+	}
+
+	var subjects []delivery.PostSnapshot
+	if err := q.WithContext(ctx).Where("post_id IS NULL AND subject IS NOT NULL").
+		Pluck("subject", &subjects).Error; err != nil {
+		return false, fmt.Errorf("AttemptRepository.HasPending: %w", err)
+	}
+	for _, s := range subjects {
+		if s.QID == a.Subject.QID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeferChannel pushes every pending attempt of the channel due before untilMs
+// to untilMs. Attempts and last_error are left as they are, so a paused
+// channel does not burn its deliveries' retry budget, and an expiry wall
+// moves forward by as much as next_at does, so the pause does not use up the
+// attempt's lifetime. (Updates writes columns in name order, so expires_at is
+// computed from the old next_at even on MySQL, which evaluates SET clauses
+// left to right.)
+func (r *AttemptRepository) DeferChannel(ctx context.Context, channelID int, untilMs int64) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&delivery.Attempt{}).
+		Where("channel_id = ? AND status = ? AND next_at < ?", channelID, delivery.StatusPending, untilMs).
+		Updates(map[string]any{
+			"expires_at": gorm.Expr("CASE WHEN expires_at > 0 THEN expires_at + (? - next_at) ELSE 0 END", untilMs),
+			"next_at":    untilMs,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("AttemptRepository.DeferChannel: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // rowLockingDialect reports whether the active DB dialect supports
 // FOR UPDATE SKIP LOCKED. Postgres and MySQL 8.0+ do; SQLite does not (it is a
 // parse-time syntax error), and its production pool pins MaxOpenConns(1) so
@@ -2,9 +2,11 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"markpost/internal/domain"
 	"markpost/internal/domain/delivery"
 	"markpost/internal/domain/post"
 	"markpost/internal/domain/user"
@@ -31,9 +33,9 @@ func setupAttemptRepoTestDB(t *testing.T) (*AttemptRepository, []delivery.Attemp
 	now := time.Now()
 	old := now.Add(-2 * time.Hour)
 	attempts := []delivery.Attempt{
-		{UserID: u.ID, PostID: p.ID, ChannelID: ch.ID, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: old, UpdatedAt: old},
-		{UserID: u.ID, PostID: p.ID, ChannelID: ch.ID, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now},
-		{UserID: u.ID, PostID: p.ID, ChannelID: ch.ID, Status: delivery.StatusDelivered, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now},
+		{UserID: u.ID, PostID: &p.ID, ChannelID: ch.ID, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: old, UpdatedAt: old},
+		{UserID: u.ID, PostID: &p.ID, ChannelID: ch.ID, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now},
+		{UserID: u.ID, PostID: &p.ID, ChannelID: ch.ID, Status: delivery.StatusDelivered, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now},
 	}
 	if err := db.Create(&attempts).Error; err != nil {
 		t.Fatalf("seed attempts: %v", err)
@@ -45,19 +47,17 @@ func TestAttemptRepository_MarkExpiredBatchesOldPending(t *testing.T) {
 	repo, attempts := setupAttemptRepoTestDB(t)
 	ctx := context.Background()
 
-	// Force the first attempt's created_at into the past via raw SQL — GORM's
-	// autoCreateTime populates created_at on Create and autoUpdateTime on
-	// Updates, so a struct/Update path cannot set a historical timestamp.
-	old := time.Now().Add(-2 * time.Hour)
-	if err := repo.db.Exec(
-		"UPDATE delivery_attempts SET created_at = ? WHERE id = ?",
-		old, attempts[0].ID,
-	).Error; err != nil {
-		t.Fatalf("set old created_at: %v", err)
+	// The first attempt's wall has passed; the second's is still ahead; the
+	// third has no wall at all.
+	now := time.Now()
+	walls := []int64{now.Add(-time.Minute).UnixMilli(), now.Add(time.Hour).UnixMilli(), 0}
+	for i, wall := range walls {
+		if err := repo.db.Exec("UPDATE delivery_attempts SET expires_at = ? WHERE id = ?", wall, attempts[i].ID).Error; err != nil {
+			t.Fatalf("set expires_at: %v", err)
+		}
 	}
 
-	wallBefore := time.Now().Add(-1 * time.Hour).UnixMilli()
-	expired, err := repo.MarkExpired(ctx, wallBefore, 64)
+	expired, err := repo.MarkExpired(ctx, now.UnixMilli(), 64)
 	if err != nil {
 		t.Fatalf("MarkExpired: %v", err)
 	}
@@ -65,7 +65,7 @@ func TestAttemptRepository_MarkExpiredBatchesOldPending(t *testing.T) {
 		t.Fatalf("expected 1 expired, got %d", len(expired))
 	}
 	if expired[0].ID != attempts[0].ID {
-		t.Errorf("expired id = %d, want %d (the old pending row)", expired[0].ID, attempts[0].ID)
+		t.Errorf("expired id = %d, want %d (the row past its wall)", expired[0].ID, attempts[0].ID)
 	}
 
 	var got delivery.Attempt
@@ -357,3 +357,321 @@ func TestAttemptRepository_ListHistoryByChannel(t *testing.T) {
 		t.Errorf("all rows = %d, want 3", len(allRows))
 	}
 }
+
+func TestAttemptRepository_GetHistoryScopesByOwner(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+
+	if err := repo.ArchiveAndDelete(ctx, &attempts[0], delivery.StatusFailed, "boom"); err != nil {
+		t.Fatalf("ArchiveAndDelete: %v", err)
+	}
+	rows, err := repo.ListHistory(ctx, delivery.HistoryFilter{}, 0, 10)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("ListHistory: rows=%d err=%v", len(rows), err)
+	}
+
+	h, err := repo.GetHistory(ctx, rows[0].ID, attempts[0].UserID)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if h.Status != delivery.StatusFailed || h.PostID == nil || *h.PostID != *attempts[0].PostID {
+		t.Errorf("unexpected history row: %+v", h)
+	}
+	if _, err := repo.GetHistory(ctx, rows[0].ID, 0); err != nil {
+		t.Errorf("GetHistory unscoped: %v", err)
+	}
+	if _, err := repo.GetHistory(ctx, rows[0].ID, attempts[0].UserID+1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetHistory foreign owner err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttemptRepository_HasPending(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+
+	pending, err := repo.HasPending(ctx, &attempts[0])
+	if err != nil || !pending {
+		t.Fatalf("HasPending = %v, %v; want true", pending, err)
+	}
+	other := attempts[0]
+	other.ChannelID++
+	pending, err = repo.HasPending(ctx, &other)
+	if err != nil || pending {
+		t.Errorf("HasPending other channel = %v, %v; want false", pending, err)
+	}
+	other = attempts[0]
+	other.Event = post.EventUpdated
+	pending, err = repo.HasPending(ctx, &other)
+	if err != nil || pending {
+		t.Errorf("HasPending other event = %v, %v; want false", pending, err)
+	}
+
+	notice := delivery.Attempt{UserID: attempts[0].UserID, ChannelID: attempts[0].ChannelID, Event: post.EventDeleted,
+		Subject: &delivery.PostSnapshot{QID: "p-gone"}, Status: delivery.StatusPending}
+	if err := repo.db.Create(&notice).Error; err != nil {
+		t.Fatalf("seed notice: %v", err)
+	}
+	pending, err = repo.HasPending(ctx, &delivery.Attempt{ChannelID: notice.ChannelID, Event: post.EventDeleted, Subject: &delivery.PostSnapshot{QID: "p-gone"}})
+	if err != nil || !pending {
+		t.Errorf("HasPending removed post = %v, %v; want true", pending, err)
+	}
+	pending, err = repo.HasPending(ctx, &delivery.Attempt{ChannelID: notice.ChannelID, Event: post.EventDeleted, Subject: &delivery.PostSnapshot{QID: "p-other"}})
+	if err != nil || pending {
+		t.Errorf("HasPending other removed post = %v, %v; want false", pending, err)
+	}
+}
+
+func TestAttemptRepository_DeferChannel(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+	until := time.Now().Add(10 * time.Minute).UnixMilli()
+	walled := attempts[1]
+	repo.db.Model(walled).Update("expires_at", walled.NextAt+1000)
+
+	// Two pending attempts move; the delivered one is left alone.
+	moved, err := repo.DeferChannel(ctx, attempts[0].ChannelID, until)
+	if err != nil || moved != 2 {
+		t.Fatalf("DeferChannel = %d, %v; want 2", moved, err)
+	}
+	var got delivery.Attempt
+	repo.db.First(&got, attempts[0].ID)
+	if got.NextAt != until || got.Attempts != 0 {
+		t.Errorf("next_at=%d attempts=%d, want %d and an unchanged try count", got.NextAt, got.Attempts, until)
+	}
+	if got.ExpiresAt != 0 {
+		t.Errorf("expires_at = %d, want an attempt without a wall to keep none", got.ExpiresAt)
+	}
+	var withWall delivery.Attempt
+	repo.db.First(&withWall, walled.ID)
+	if withWall.ExpiresAt != until+1000 {
+		t.Errorf("expires_at = %d, want the wall moved with next_at to %d", withWall.ExpiresAt, until+1000)
+	}
+	var delivered delivery.Attempt
+	repo.db.First(&delivered, attempts[2].ID)
+	if delivered.NextAt == until {
+		t.Error("non-pending attempt was deferred")
+	}
+}
+
+func TestNewDeliveryListener_PostgresOnly(t *testing.T) {
+	// SQLite has no LISTEN/NOTIFY; its dispatchers rely on the in-process
+	// wake-up and the scan tick.
+	if l := NewDeliveryListener(SetupTestDB(t)); l != nil {
+		t.Errorf("expected no listener on sqlite, got %+v", l)
+	}
+}
+
+func TestAttemptRepository_Leases(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	nowMs := now.UnixMilli()
+
+	claimed, err := repo.ClaimDue(ctx, "web-1", nowMs, now.Add(30*time.Second).UnixMilli(), 1)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDue = %d rows, %v; want 1", len(claimed), err)
+	}
+	held := claimed[0]
+	if held.LeaseOwner != "web-1" {
+		t.Errorf("lease_owner = %q, want web-1", held.LeaseOwner)
+	}
+
+	// A live lease hides the row from other instances; a run-out one does not.
+	others, _ := repo.ClaimDue(ctx, "web-2", nowMs, now.Add(30*time.Second).UnixMilli(), 10)
+	for _, a := range others {
+		if a.ID == held.ID {
+			t.Fatal("web-2 claimed a row under web-1's live lease")
+		}
+	}
+	stolen, _ := repo.ClaimDue(ctx, "web-2", now.Add(time.Minute).UnixMilli(), now.Add(2*time.Minute).UnixMilli(), 10)
+	if len(stolen) != 2 {
+		t.Fatalf("expected both pending rows re-claimable once their leases ran out, got %d", len(stolen))
+	}
+
+	ids := []int64{stolen[0].ID, stolen[1].ID}
+	if n, err := repo.RenewLeases(ctx, "web-1", ids, now.Add(time.Hour).UnixMilli()); err != nil || n != 0 {
+		t.Errorf("RenewLeases by another owner = %d, %v; want 0", n, err)
+	}
+	if n, err := repo.RenewLeases(ctx, "web-2", ids[:1], now.Add(time.Hour).UnixMilli()); err != nil || n != 1 {
+		t.Errorf("RenewLeases = %d, %v; want 1", n, err)
+	}
+
+	// A released row is claimable at once; the other keeps its lease.
+	if err := repo.ReleaseLease(ctx, ids[1], "web-2"); err != nil {
+		t.Fatalf("ReleaseLease: %v", err)
+	}
+	reclaimed, _ := repo.ClaimDue(ctx, "web-2", now.Add(time.Minute).UnixMilli(), now.Add(time.Hour).UnixMilli(), 10)
+	if len(reclaimed) != 1 || reclaimed[0].ID != ids[1] {
+		t.Fatalf("expected only the released row re-claimable, got %+v", reclaimed)
+	}
+	leased, err := repo.ListLeased(ctx, 10)
+	if err != nil || len(leased) != 2 || leased[0].LeaseOwner != "web-2" {
+		t.Errorf("ListLeased = %+v, %v", leased, err)
+	}
+
+	// An expired wall does not expire a row whose send is in flight.
+	repo.db.Exec("UPDATE delivery_attempts SET expires_at = ? WHERE id = ?", nowMs-1, attempts[0].ID)
+	if expired, _ := repo.MarkExpired(ctx, nowMs, 10); len(expired) != 0 {
+		t.Errorf("MarkExpired took %d leased rows", len(expired))
+	}
+
+	if n, err := repo.ReleaseLeases(ctx, "web-2"); err != nil || n != 2 {
+		t.Errorf("ReleaseLeases = %d, %v; want 2", n, err)
+	}
+	if leased, _ := repo.ListLeased(ctx, 10); len(leased) != 0 {
+		t.Errorf("expected no leases after release, got %d", len(leased))
+	}
+}
+
+func TestAttemptRepository_Digest(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+	seed := attempts[0]
+	now := time.Now()
+	nowMs := now.UnixMilli()
+
+	add := func(flushAt int64, maxPosts int) (*delivery.DigestItem, bool) {
+		t.Helper()
+		item := &delivery.DigestItem{UserID: seed.UserID, ChannelID: seed.ChannelID, PostID: *seed.PostID, FlushAt: flushAt, CreatedAt: now}
+		full, err := repo.AddToDigest(ctx, item, maxPosts)
+		if err != nil {
+			t.Fatalf("AddToDigest: %v", err)
+		}
+		return item, full
+	}
+
+	first, _ := add(nowMs+60_000, 3)
+	second, full := add(nowMs+120_000, 3)
+	if second.FlushAt != first.FlushAt || full {
+		t.Errorf("second item flush_at = %d (full=%v), want the open digest's %d", second.FlushAt, full, first.FlushAt)
+	}
+	if due, _ := repo.ListDueDigests(ctx, nowMs, 10); len(due) != 0 {
+		t.Errorf("listed %d items before the digest was due", len(due))
+	}
+	if _, full = add(nowMs+120_000, 3); !full {
+		t.Error("third item did not fill a 3-post digest")
+	}
+	due, err := repo.ListDueDigests(ctx, nowMs, 10)
+	if err != nil || len(due) != 3 {
+		t.Fatalf("ListDueDigests = %d items, %v; want the full digest", len(due), err)
+	}
+
+	ids := []int64{due[0].ID, due[1].ID, due[2].ID}
+	attempt := &delivery.Attempt{UserID: seed.UserID, PostID: seed.PostID, ChannelID: seed.ChannelID, NextAt: nowMs, CreatedAt: now, UpdatedAt: now}
+	if n, err := repo.SealDigest(ctx, attempt, ids); err != nil || n != 3 || attempt.DigestSize != 3 {
+		t.Fatalf("SealDigest = %d, %v (size %d); want 3", n, err, attempt.DigestSize)
+	}
+	// A second sealer finds the items taken and inserts nothing.
+	var before int64
+	repo.db.Model(&delivery.Attempt{}).Count(&before)
+	if n, err := repo.SealDigest(ctx, &delivery.Attempt{UserID: seed.UserID, PostID: seed.PostID, ChannelID: seed.ChannelID}, ids); err != nil || n != 0 {
+		t.Errorf("second SealDigest = %d, %v; want 0", n, err)
+	}
+	var after int64
+	repo.db.Model(&delivery.Attempt{}).Count(&after)
+	if after != before {
+		t.Errorf("a losing SealDigest left an attempt behind")
+	}
+
+	if postIDs, err := repo.DigestPostIDs(ctx, attempt.ID); err != nil || len(postIDs) != 3 {
+		t.Errorf("DigestPostIDs = %v, %v", postIDs, err)
+	}
+	if err := repo.RecordTry(ctx, &delivery.Try{AttemptID: &attempt.ID, Number: 1, StatusCode: 500, Error: "boom"}); err != nil {
+		t.Fatalf("RecordTry: %v", err)
+	}
+	if err := repo.ArchiveAndDelete(ctx, attempt, delivery.StatusFailed, "boom"); err != nil {
+		t.Fatalf("ArchiveAndDelete: %v", err)
+	}
+	var history, items int64
+	repo.db.Model(&delivery.History{}).Where("status = ?", delivery.StatusFailed).Count(&history)
+	repo.db.Model(&delivery.DigestItem{}).Count(&items)
+	if history != 3 || items != 0 {
+		t.Errorf("after archive: %d failed history rows, %d digest items; want 3 and 0", history, items)
+	}
+	// Every post's row keeps its own copy of the digest's tries.
+	var tries int64
+	repo.db.Model(&delivery.Try{}).Where("history_id IS NOT NULL AND attempt_id IS NULL").Count(&tries)
+	if tries != 3 {
+		t.Errorf("archived tries = %d, want one per history row", tries)
+	}
+}
+
+func TestAttemptRepository_Messages(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewAttemptRepository(db).(*AttemptRepository)
+	ctx := context.Background()
+
+	if _, err := repo.FindMessage(ctx, 1, "p-live"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("FindMessage before any = %v, want ErrNotFound", err)
+	}
+	for _, id := range []string{"om-1", "om-2"} {
+		if err := repo.RecordMessage(ctx, &delivery.Message{ChannelID: 1, PostQID: "p-live", MessageID: id, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("RecordMessage: %v", err)
+		}
+	}
+	m, err := repo.FindMessage(ctx, 1, "p-live")
+	if err != nil || m.MessageID != "om-2" {
+		t.Fatalf("FindMessage = %+v, %v; want the latest", m, err)
+	}
+
+	// Past the cutoff, only messages whose post is gone are pruned.
+	if err := db.Create(&post.Post{QID: "p-live", Title: "t", Body: "b", UserID: 1}).Error; err != nil {
+		t.Fatalf("seed post: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := db.Exec("INSERT INTO delivery_messages (channel_id, post_qid, message_id, created_at) VALUES (1, 'p-gone', 'om-3', ?)", old).Error; err != nil {
+		t.Fatalf("seed message: %v", err)
+	}
+	db.Model(&delivery.Message{}).Where("post_qid = ?", "p-live").Update("created_at", old)
+	if _, err := repo.PruneHistory(ctx, 24*time.Hour, 1000); err != nil {
+		t.Fatalf("PruneHistory: %v", err)
+	}
+	var left []delivery.Message
+	db.Order("id").Find(&left)
+	if len(left) != 2 || left[0].PostQID != "p-live" || left[1].PostQID != "p-live" {
+		t.Errorf("messages left = %+v, want those of the live post", left)
+	}
+}
+
+func TestAttemptRepository_Tries(t *testing.T) {
+	repo, attempts := setupAttemptRepoTestDB(t)
+	ctx := context.Background()
+	archived, queued := &attempts[0], &attempts[1]
+
+	for _, try := range []*delivery.Try{
+		{AttemptID: &archived.ID, Number: 1, StatusCode: 503, LatencyMs: 40, Body: "busy", Error: "upstream 503", CreatedAt: time.Now()},
+		{AttemptID: &archived.ID, Number: 2, StatusCode: 200, LatencyMs: 25, Body: "ok", CreatedAt: time.Now()},
+		{AttemptID: &queued.ID, Number: 1, StatusCode: 500, CreatedAt: time.Now()},
+	} {
+		if err := repo.RecordTry(ctx, try); err != nil {
+			t.Fatalf("RecordTry: %v", err)
+		}
+	}
+	if err := repo.ArchiveAndDelete(ctx, archived, delivery.StatusDelivered, ""); err != nil {
+		t.Fatalf("ArchiveAndDelete: %v", err)
+	}
+
+	rows, err := repo.ListHistory(ctx, delivery.HistoryFilter{}, 0, 50)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("ListHistory = %d rows, %v; want 1", len(rows), err)
+	}
+	tries := rows[0].Tries
+	if len(tries) != 2 || tries[0].Number != 1 || tries[0].StatusCode != 503 || tries[0].Error != "upstream 503" || tries[1].Body != "ok" {
+		t.Fatalf("history tries = %+v, want both sends oldest first", tries)
+	}
+	var pending int64
+	repo.db.Model(&delivery.Try{}).Where("attempt_id = ?", queued.ID).Count(&pending)
+	if pending != 1 {
+		t.Errorf("queued attempt has %d tries, want its own 1", pending)
+	}
+
+	repo.db.Model(&delivery.History{}).Where("id = ?", rows[0].ID).Update("created_at", time.Now().Add(-48*time.Hour))
+	if n, err := repo.PruneHistory(ctx, 24*time.Hour, 1); err != nil || n != 1 {
+		t.Fatalf("PruneHistory = %d, %v; want 1", n, err)
+	}
+	var left []delivery.Try
+	repo.db.Find(&left)
+	if len(left) != 1 || left[0].AttemptID == nil || *left[0].AttemptID != queued.ID {
+		t.Errorf("tries left = %+v, want only the queued attempt's", left)
+	}
+}
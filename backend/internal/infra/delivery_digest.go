@@ -0,0 +1,140 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"markpost/internal/domain/delivery"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errDigestSealed rolls back a SealDigest whose items were all taken by
+// another instance in the meantime.
+var errDigestSealed = errors.New("digest already sealed")
+
+// AddToDigest puts item into its channel's open digest: the channel's items
+// not yet sealed into an attempt. The first item of a digest keeps the
+// FlushAt it was given; later items take the open digest's, so the whole
+// digest comes due together. When the digest reaches maxPosts every open item
+// is made due at item.CreatedAt and full is true. The channel row is locked
+// first so concurrent adds to one digest queue up behind each other instead
+// of counting the same open items.
+func (r *AttemptRepository) AddToDigest(ctx context.Context, item *delivery.DigestItem, maxPosts int) (bool, error) {
+	var full bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var channel delivery.Channel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id = ?", item.ChannelID).First(&channel).Error; err != nil {
+			return fmt.Errorf("lock channel: %w", err)
+		}
+		var open struct {
+			Count   int64  `gorm:"column:count"`
+			FlushAt *int64 `gorm:"column:flush_at"`
+		}
+		if err := tx.Model(&delivery.DigestItem{}).
+			Select("COUNT(*) AS count, MIN(flush_at) AS flush_at").
+			Where("channel_id = ? AND attempt_id IS NULL", item.ChannelID).
+			Scan(&open).Error; err != nil {
+			return fmt.Errorf("read open digest: %w", err)
+		}
+		if open.Count > 0 && open.FlushAt != nil {
+			item.FlushAt = *open.FlushAt
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return fmt.Errorf("insert digest item: %w", err)
+		}
+
+		if maxPosts <= 0 || open.Count+1 < int64(maxPosts) {
+			return nil
+		}
+		full = true
+		due := item.CreatedAt.UnixMilli()
+		if err := tx.Model(&delivery.DigestItem{}).
+			Where("channel_id = ? AND attempt_id IS NULL", item.ChannelID).
+			Update("flush_at", due).Error; err != nil {
+			return fmt.Errorf("close full digest: %w", err)
+		}
+		item.FlushAt = due
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("AttemptRepository.AddToDigest: %w", err)
+	}
+	return full, nil
+}
+
+// ListDueDigests returns up to limit items of open digests whose flush_at has
+// passed, ordered by channel then id so each channel's items are adjacent.
+func (r *AttemptRepository) ListDueDigests(ctx context.Context, nowMs int64, limit int) ([]*delivery.DigestItem, error) {
+	var items []*delivery.DigestItem
+	err := r.db.WithContext(ctx).
+		Where("attempt_id IS NULL AND flush_at <= ?", nowMs).
+		Order("channel_id asc, id asc").
+		Limit(limit).
+		Find(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("AttemptRepository.ListDueDigests: %w", err)
+	}
+	return items, nil
+}
+
+// SealDigest inserts attempt and assigns it the items of itemIDs that are
+// still open. The conditional update makes concurrent sealers safe without row
+// locks: items another instance sealed first are skipped, and if it took all
+// of them the transaction rolls back and 0 is returned. attempt.DigestSize is
+// set to the number of items sealed. On Postgres the new attempt is announced
+// with NOTIFY, as Create does.
+func (r *AttemptRepository) SealDigest(ctx context.Context, attempt *delivery.Attempt, itemIDs []int64) (int64, error) {
+	if len(itemIDs) == 0 {
+		return 0, nil
+	}
+
+	var sealed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt.DigestSize = len(itemIDs)
+		if err := tx.Create(attempt).Error; err != nil {
+			return fmt.Errorf("insert attempt: %w", err)
+		}
+		result := tx.Model(&delivery.DigestItem{}).
+			Where("id IN ? AND attempt_id IS NULL", itemIDs).
+			Update("attempt_id", attempt.ID)
+		if result.Error != nil {
+			return fmt.Errorf("seal digest items: %w", result.Error)
+		}
+		sealed = result.RowsAffected
+		if sealed == 0 {
+			return errDigestSealed
+		}
+		if sealed < int64(len(itemIDs)) {
+			attempt.DigestSize = int(sealed)
+			if err := tx.Model(attempt).Update("digest_size", sealed).Error; err != nil {
+				return fmt.Errorf("update digest size: %w", err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errDigestSealed) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("AttemptRepository.SealDigest: %w", err)
+	}
+	notifyAttempts(ctx, r.db)
+	return sealed, nil
+}
+
+// DigestPostIDs returns the posts sealed into a digest attempt, oldest first.
+func (r *AttemptRepository) DigestPostIDs(ctx context.Context, attemptID int64) ([]int, error) {
+	var postIDs []int
+	err := r.db.WithContext(ctx).Model(&delivery.DigestItem{}).
+		Where("attempt_id = ?", attemptID).
+		Order("id asc").
+		Pluck("post_id", &postIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("AttemptRepository.DigestPostIDs: %w", err)
+	}
+	return postIDs, nil
+}
@@ -0,0 +1,50 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"markpost/internal/domain"
+	"markpost/internal/domain/delivery"
+)
+
+// RecordMessage stores the platform message a delivery posted.
+func (r *AttemptRepository) RecordMessage(ctx context.Context, m *delivery.Message) error {
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return fmt.Errorf("AttemptRepository.RecordMessage: %w", err)
+	}
+	return nil
+}
+
+// FindMessage returns the latest message recorded for the post on the
+// channel, or domain.ErrNotFound.
+func (r *AttemptRepository) FindMessage(ctx context.Context, channelID int, postQID string) (*delivery.Message, error) {
+	q := r.db.Where("channel_id = ? AND post_qid = ?", channelID, postQID).Order("id desc")
+	m, err := findFirst[delivery.Message](ctx, q, domain.ErrNotFound)
+	if err != nil {
+		return nil, fmt.Errorf("AttemptRepository.FindMessage: %w", err)
+	}
+	return m, nil
+}
+
+// pruneMessages deletes recorded messages older than cutoff whose post no
+// longer exists, in batches of batchSize. Messages of live posts are kept
+// however old, so a post that is edited or removed late can still update its
+// announcement.
+func (r *AttemptRepository) pruneMessages(ctx context.Context, cutoff time.Time, batchSize int) error {
+	sql := `DELETE FROM delivery_messages WHERE id IN (
+	            SELECT m.id FROM delivery_messages m
+	            WHERE m.created_at < ? AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.qid = m.post_qid)
+	            ORDER BY m.created_at LIMIT ?
+	        )`
+	for {
+		result := r.db.WithContext(ctx).Exec(sql, cutoff, batchSize)
+		if result.Error != nil {
+			return fmt.Errorf("prune messages: %w", result.Error)
+		}
+		if result.RowsAffected < int64(batchSize) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package infra
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// deliveryNotifyChannel is the Postgres NOTIFY channel on which new delivery
+// attempts are announced, so every instance's dispatcher can claim them
+// without waiting for its next scan tick.
+const deliveryNotifyChannel = "markpost_delivery"
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// notifyAttempts announces freshly inserted attempts on Postgres. Other
+// dialects have no cross-connection notification; their dispatchers rely on
+// the in-process wake-up and the scan tick. A failed NOTIFY only costs
+// latency, so it is logged, not returned.
+func notifyAttempts(ctx context.Context, db *gorm.DB) {
+	if db.Name() != "postgres" {
+		return
+	}
+	if err := db.WithContext(ctx).Exec("SELECT pg_notify(?, '')", deliveryNotifyChannel).Error; err != nil {
+		log.Printf("delivery notify: err=%v", err)
+	}
+}
+
+// DeliveryListener relays Postgres notifications on deliveryNotifyChannel to a
+// wake-up callback. It holds one pooled connection for as long as it listens.
+type DeliveryListener struct {
+	db *gorm.DB
+}
+
+// NewDeliveryListener returns a listener for a Postgres database, or nil for
+// any other dialect.
+func NewDeliveryListener(db *gorm.DB) *DeliveryListener {
+	if db.Name() != "postgres" {
+		return nil
+	}
+	return &DeliveryListener{db: db}
+}
+
+// Listen calls wake for every notification until ctx is done. A dropped
+// connection is re-established with capped exponential backoff; wake-ups
+// missed meanwhile are picked up by the dispatcher's scan tick. The backoff
+// starts over once a session gets as far as LISTEN, so only failures in a row
+// lengthen it.
+func (l *DeliveryListener) Listen(ctx context.Context, wake func()) {
+	retry := listenRetryMin
+	for {
+		listened, err := l.listen(ctx, wake)
+		if ctx.Err() != nil {
+			return
+		}
+		if listened {
+			retry = listenRetryMin
+		}
+		log.Printf("delivery listen: reconnect in %s err=%v", retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, listenRetryMax)
+	}
+}
+
+// listen runs one LISTEN session on a dedicated pool connection and reports
+// whether LISTEN succeeded before the session ended. The connection is always
+// discarded afterwards rather than returned to the pool still subscribed.
+func (l *DeliveryListener) listen(ctx context.Context, wake func()) (bool, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return false, fmt.Errorf("DeliveryListener: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("DeliveryListener: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var (
+		listened  bool
+		listenErr error
+	)
+	_ = conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("DeliveryListener: unexpected driver connection %T", driverConn)
+			return driver.ErrBadConn
+		}
+		pc := sc.Conn()
+		if _, err := pc.Exec(ctx, "LISTEN "+deliveryNotifyChannel); err != nil {
+			listenErr = fmt.Errorf("DeliveryListener: %w", err)
+			return driver.ErrBadConn
+		}
+		listened = true
+		for {
+			if _, err := pc.WaitForNotification(ctx); err != nil {
+				listenErr = fmt.Errorf("DeliveryListener: %w", err)
+				return driver.ErrBadConn
+			}
+			wake()
+		}
+	})
+	if listenErr == nil {
+		listenErr = errors.New("DeliveryListener: listen session ended")
+	}
+	return listened, listenErr
+}
@@ -3,9 +3,11 @@ package infra
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"markpost/internal/domain"
 	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
 
 	"gorm.io/gorm"
 )
@@ -24,9 +26,10 @@ func scopedByIDAndUserID(db *gorm.DB, id, userID int) *gorm.DB {
 	return db.Where("id = ? AND user_id = ?", id, userID)
 }
 
-// GetByUserID retrieves all delivery channels for a user.
+// GetByUserID retrieves all delivery channels for a user, with the user
+// loaded (the dispatcher matches author: filters against its username).
 func (r *DeliveryChannelRepository) GetByUserID(ctx context.Context, userID int) ([]delivery.Channel, error) {
-	return findAll[delivery.Channel](ctx, r.db.Where("user_id = ?", userID).Order("id asc"), "GetByUserID")
+	return findAll[delivery.Channel](ctx, r.db.Preload("User").Where("user_id = ?", userID).Order("id asc"), "GetByUserID")
 }
 
 // GetByIDAndUserID retrieves a delivery channel by ID and user ID.
@@ -46,18 +49,49 @@ func (r *DeliveryChannelRepository) Create(ctx context.Context, channel *deliver
 	return nil
 }
 
-// Update updates an existing delivery channel.
+// Update writes the owner-editable settings of a channel. The enabled flag
+// and the breaker's health columns are left alone: the dispatcher updates
+// them concurrently, and writing back the snapshot the edit started from
+// would undo its changes. SetEnabled changes the flag.
 func (r *DeliveryChannelRepository) Update(ctx context.Context, channel *delivery.Channel) error {
 	updates := map[string]any{
 		"kind":          channel.Kind,
 		"name":          channel.Name,
-		"enabled":       channel.Enabled,
 		"configuration": channel.Configuration,
 		"keywords":      channel.Keywords,
+		"template":      channel.Template,
+		"retry_policy":  channel.RetryPolicy,
+		"digest":        channel.Digest,
+		"schedule":      channel.Schedule,
+		"events":        channel.Events,
 	}
 	return updateByID[delivery.Channel](ctx, r.db, channel.ID, updates, "Update")
 }
 
+// SetEnabled turns a channel on or off. Turning on a disabled channel gives
+// it a fresh start, clearing the breaker's streak, pause and reason; the
+// reset is guarded by enabled = false so it never clears the state of a
+// channel that is already on. Turning off leaves the health columns as they
+// are.
+func (r *DeliveryChannelRepository) SetEnabled(ctx context.Context, id int, enabled bool) error {
+	q := r.db.WithContext(ctx).Model(&delivery.Channel{}).Where("id = ?", id)
+	var err error
+	if enabled {
+		err = q.Where("enabled = ?", false).Updates(map[string]any{
+			"enabled":              true,
+			"consecutive_failures": 0,
+			"paused_until":         0,
+			"disabled_reason":      "",
+		}).Error
+	} else {
+		err = q.Update("enabled", false).Error
+	}
+	if err != nil {
+		return fmt.Errorf("SetEnabled: %w", err)
+	}
+	return nil
+}
+
 // DeleteByIDAndUserID deletes a delivery channel by ID and user ID.
 func (r *DeliveryChannelRepository) DeleteByIDAndUserID(ctx context.Context, id int, userID int) (int64, error) {
 	n, err := deleteWhere[delivery.Channel](ctx, scopedByIDAndUserID(r.db, id, userID))
@@ -76,3 +110,63 @@ func (r *DeliveryChannelRepository) ListAll(ctx context.Context, offset, limit i
 func (r *DeliveryChannelRepository) CountAll(ctx context.Context) (int64, error) {
 	return countQuery(ctx, r.db.Model(&delivery.Channel{}), "CountAll")
 }
+
+// RecordSuccess zeroes the channel's failure streak, closes its circuit and
+// stamps last_success_at.
+func (r *DeliveryChannelRepository) RecordSuccess(ctx context.Context, id int, at time.Time) error {
+	updates := map[string]any{
+		"consecutive_failures": 0,
+		"paused_until":         0,
+		"last_success_at":      at,
+	}
+	return updateByID[delivery.Channel](ctx, r.db, id, updates, "RecordSuccess")
+}
+
+// RecordFailure increments the channel's failure streak in place, so
+// concurrent workers do not lose counts, and returns the new value.
+func (r *DeliveryChannelRepository) RecordFailure(ctx context.Context, id int, at time.Time) (int, error) {
+	updates := map[string]any{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		"last_failure_at":      at,
+	}
+	if err := updateByID[delivery.Channel](ctx, r.db, id, updates, "RecordFailure"); err != nil {
+		return 0, err
+	}
+	var streak []int
+	if err := r.db.WithContext(ctx).Model(&delivery.Channel{}).Where("id = ?", id).
+		Pluck("consecutive_failures", &streak).Error; err != nil {
+		return 0, fmt.Errorf("RecordFailure: %w", err)
+	}
+	if len(streak) == 0 {
+		return 0, fmt.Errorf("RecordFailure: %w", domain.ErrNotFound)
+	}
+	return streak[0], nil
+}
+
+// Pause opens the channel's circuit until untilMs.
+func (r *DeliveryChannelRepository) Pause(ctx context.Context, id int, untilMs int64) error {
+	return updateByID[delivery.Channel](ctx, r.db, id, map[string]any{"paused_until": untilMs}, "Pause")
+}
+
+// HasSubscribers reports whether any enabled channel subscribes to event. A
+// channel with no stored event set takes post creation only.
+func (r *DeliveryChannelRepository) HasSubscribers(ctx context.Context, event post.Event) (bool, error) {
+	pattern := `%"` + string(event) + `"%`
+	q := r.db.Model(&delivery.Channel{}).Where("enabled = ?", true)
+	if event == post.EventCreated {
+		q = q.Where("events IS NULL OR events LIKE ?", pattern)
+	} else {
+		q = q.Where("events LIKE ?", pattern)
+	}
+	count, err := countQuery(ctx, q, "HasSubscribers")
+	return count > 0, err
+}
+
+// AutoDisable disables the channel and records the reason.
+func (r *DeliveryChannelRepository) AutoDisable(ctx context.Context, id int, reason string) error {
+	updates := map[string]any{
+		"enabled":         false,
+		"disabled_reason": reason,
+	}
+	return updateByID[delivery.Channel](ctx, r.db, id, updates, "AutoDisable")
+}
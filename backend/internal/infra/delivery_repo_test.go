@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"markpost/internal/domain"
 	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
 )
 
 func createTestDeliveryChannel(ctx context.Context, repo delivery.Repository, userID int, name string) *delivery.Channel {
@@ -123,12 +125,11 @@ func TestDeliveryChannelRepository_Update(t *testing.T) {
 	if fetched.Name != "New" {
 		t.Errorf("name = %q, want %q", fetched.Name, "New")
 	}
-	feishuConfig := fetched.Configuration.Feishu()
-	if feishuConfig.WebhookURL != "https://new.com" {
-		t.Errorf("webhook_url = %q, want %q", feishuConfig.WebhookURL, "https://new.com")
+	if got := fetched.Configuration.String("webhook_url"); got != "https://new.com" {
+		t.Errorf("webhook_url = %q, want %q", got, "https://new.com")
 	}
-	if feishuConfig.CardLinkURL != "https://custom.com/{{.QID}}" {
-		t.Errorf("card_link_url = %q, want %q", feishuConfig.CardLinkURL, "https://custom.com/{{.QID}}")
+	if got := fetched.Configuration.String("card_link_url"); got != "https://custom.com/{{.QID}}" {
+		t.Errorf("card_link_url = %q, want %q", got, "https://custom.com/{{.QID}}")
 	}
 }
 
@@ -193,3 +194,110 @@ func TestDeliveryChannelRepository_CountAll(t *testing.T) {
 		t.Errorf("count = %d, want 2", count)
 	}
 }
+
+func TestDeliveryChannelRepository_Health(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewDeliveryChannelRepository(db)
+	ctx := context.Background()
+	ch := createTestDeliveryChannel(ctx, repo, 1, "Ch1")
+	now := time.Now()
+
+	for want := 1; want <= 3; want++ {
+		streak, err := repo.RecordFailure(ctx, ch.ID, now)
+		if err != nil || streak != want {
+			t.Fatalf("RecordFailure = %d, %v; want %d", streak, err, want)
+		}
+	}
+	if err := repo.Pause(ctx, ch.ID, now.Add(time.Minute).UnixMilli()); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if err := repo.RecordSuccess(ctx, ch.ID, now); err != nil {
+		t.Fatalf("RecordSuccess: %v", err)
+	}
+	got, _ := repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	if got.ConsecutiveFailures != 0 || got.PausedUntil != 0 || got.LastSuccessAt == nil || got.LastFailureAt == nil {
+		t.Errorf("unexpected health after success: %+v", got)
+	}
+
+	if err := repo.AutoDisable(ctx, ch.ID, "too many failures"); err != nil {
+		t.Fatalf("AutoDisable: %v", err)
+	}
+	got, _ = repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	if got.Enabled || got.DisabledReason != "too many failures" {
+		t.Errorf("expected disabled with reason, got enabled=%v reason=%q", got.Enabled, got.DisabledReason)
+	}
+
+	if _, err := repo.RecordFailure(ctx, 9999, now); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("RecordFailure on missing channel = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeliveryChannelRepository_UpdateKeepsHealth(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewDeliveryChannelRepository(db)
+	ctx := context.Background()
+	ch := createTestDeliveryChannel(ctx, repo, 1, "Ch1")
+
+	// An owner edit that read the channel before the breaker tripped must
+	// not write the stale health back.
+	snapshot, _ := repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	_, _ = repo.RecordFailure(ctx, ch.ID, time.Now())
+	_ = repo.Pause(ctx, ch.ID, 42)
+	_ = repo.AutoDisable(ctx, ch.ID, "too many failures")
+	snapshot.Name = "Renamed"
+	if err := repo.Update(ctx, snapshot); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ := repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	if got.Name != "Renamed" || got.Enabled || got.ConsecutiveFailures != 1 || got.PausedUntil != 42 || got.DisabledReason != "too many failures" {
+		t.Errorf("after a stale edit: %+v", got)
+	}
+
+	if err := repo.SetEnabled(ctx, ch.ID, true); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	got, _ = repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	if !got.Enabled || got.ConsecutiveFailures != 0 || got.PausedUntil != 0 || got.DisabledReason != "" {
+		t.Errorf("after re-enable: %+v", got)
+	}
+
+	// Enabling a channel that is already on keeps its live streak.
+	_, _ = repo.RecordFailure(ctx, ch.ID, time.Now())
+	_ = repo.SetEnabled(ctx, ch.ID, true)
+	if got, _ = repo.GetByIDAndUserID(ctx, ch.ID, 1); got.ConsecutiveFailures != 1 {
+		t.Errorf("enabling an enabled channel reset its streak to %d", got.ConsecutiveFailures)
+	}
+}
+
+func TestDeliveryChannelRepository_HasSubscribers(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewDeliveryChannelRepository(db)
+	ctx := context.Background()
+
+	has := func(e post.Event) bool {
+		t.Helper()
+		ok, err := repo.HasSubscribers(ctx, e)
+		if err != nil {
+			t.Fatalf("HasSubscribers(%s): %v", e, err)
+		}
+		return ok
+	}
+
+	if has(post.EventCreated) {
+		t.Error("no channels, yet created has subscribers")
+	}
+	_ = createTestDeliveryChannel(ctx, repo, 1, "Default")
+	if !has(post.EventCreated) || has(post.EventExpired) {
+		t.Error("a channel without an event set must take creation only")
+	}
+
+	ch := createTestDeliveryChannel(ctx, repo, 1, "Expiry")
+	db.Model(&delivery.Channel{}).Where("id = ?", ch.ID).Update("events", delivery.EventSet{post.EventExpired})
+	if !has(post.EventExpired) || has(post.EventUpdated) {
+		t.Error("expected only expired subscribed")
+	}
+	_ = repo.SetEnabled(ctx, ch.ID, false)
+	if has(post.EventExpired) {
+		t.Error("a disabled channel still counts as a subscriber")
+	}
+}
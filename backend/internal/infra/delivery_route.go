@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// RecordRoutes stores the routing decisions of one post event.
+func (r *AttemptRepository) RecordRoutes(ctx context.Context, routes []*delivery.Route) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&routes).Error; err != nil {
+		return fmt.Errorf("AttemptRepository.RecordRoutes: %w", err)
+	}
+	return nil
+}
+
+// ListRoutes returns the routing decisions recorded for a post, newest event
+// first and, within an event, the summary before the channels in order, with
+// the channel name and username JOINed at read time. ownerID > 0 limits the
+// result to that user's rows; 0 lists every row (admin view).
+func (r *AttemptRepository) ListRoutes(ctx context.Context, postQID string, ownerID int) ([]*delivery.RouteRow, error) {
+	q := r.db.WithContext(ctx).Table("delivery_routes AS r").
+		Select(`r.id, r.event, r.decision, r.detail, r.created_at, r.channel_id,
+		        c.name AS channel_name,
+		        u.username AS username`).
+		Joins("LEFT JOIN delivery_channels c ON c.id = r.channel_id").
+		Joins("LEFT JOIN users u ON u.id = r.user_id").
+		Where("r.post_qid = ?", postQID).
+		Order("r.created_at DESC").
+		Order("r.id ASC")
+	if ownerID > 0 {
+		q = q.Where("r.user_id = ?", ownerID)
+	}
+	var rows []*delivery.RouteRow
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("AttemptRepository.ListRoutes: %w", err)
+	}
+	return rows, nil
+}
+
+// pruneRoutes deletes routing decisions older than cutoff in batches of
+// batchSize.
+func (r *AttemptRepository) pruneRoutes(ctx context.Context, cutoff time.Time, batchSize int) error {
+	sql := `DELETE FROM delivery_routes WHERE id IN (
+	            SELECT id FROM delivery_routes WHERE created_at < ? ORDER BY created_at LIMIT ?
+	        )`
+	for {
+		result := r.db.WithContext(ctx).Exec(sql, cutoff, batchSize)
+		if result.Error != nil {
+			return fmt.Errorf("prune routes: %w", result.Error)
+		}
+		if result.RowsAffected < int64(batchSize) {
+			return nil
+		}
+	}
+}
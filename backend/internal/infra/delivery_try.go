@@ -0,0 +1,90 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"markpost/internal/domain/delivery"
+
+	"gorm.io/gorm"
+)
+
+// RecordTry stores one send of a queued attempt.
+func (r *AttemptRepository) RecordTry(ctx context.Context, t *delivery.Try) error {
+	if err := r.db.WithContext(ctx).Create(t).Error; err != nil {
+		return fmt.Errorf("AttemptRepository.RecordTry: %w", err)
+	}
+	return nil
+}
+
+// moveTries hands a queued attempt's tries to the History rows archiving it,
+// inside the archive transaction. Every row after the first gets a copy; the
+// first takes the tries themselves, which detaches them from the attempt so
+// deleting it does not cascade to them.
+func moveTries(tx *gorm.DB, attemptID int64, history []*delivery.History) error {
+	if len(history) == 0 {
+		return nil
+	}
+	for _, h := range history[1:] {
+		sql := `INSERT INTO delivery_tries (history_id, number, status_code, latency_ms, body, error, created_at)
+		        SELECT ?, number, status_code, latency_ms, body, error, created_at
+		        FROM delivery_tries WHERE attempt_id = ?`
+		if err := tx.Exec(sql, h.ID, attemptID).Error; err != nil {
+			return fmt.Errorf("copy tries: %w", err)
+		}
+	}
+	err := tx.Model(&delivery.Try{}).Where("attempt_id = ?", attemptID).
+		Updates(map[string]any{"history_id": history[0].ID, "attempt_id": nil}).Error
+	if err != nil {
+		return fmt.Errorf("move tries: %w", err)
+	}
+	return nil
+}
+
+// pruneTries deletes the tries of history rows older than cutoff, in batches
+// of batchSize. It runs before the rows themselves are pruned, so no try is
+// left without its row whether or not the database enforces the cascade.
+func (r *AttemptRepository) pruneTries(ctx context.Context, cutoff time.Time, batchSize int) error {
+	sql := `DELETE FROM delivery_tries WHERE id IN (
+	            SELECT t.id FROM delivery_tries t
+	            JOIN delivery_history h ON h.id = t.history_id
+	            WHERE h.created_at < ?
+	            ORDER BY t.id LIMIT ?
+	        )`
+	for {
+		result := r.db.WithContext(ctx).Exec(sql, cutoff, batchSize)
+		if result.Error != nil {
+			return fmt.Errorf("prune tries: %w", result.Error)
+		}
+		if result.RowsAffected < int64(batchSize) {
+			return nil
+		}
+	}
+}
+
+// attachTries loads the tries of the history rows, oldest first, into each
+// row's Tries.
+func (r *AttemptRepository) attachTries(ctx context.Context, rows []*delivery.HistoryRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*delivery.HistoryRow, len(rows))
+	ids := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+		ids = append(ids, row.ID)
+	}
+
+	var tries []delivery.Try
+	if err := r.db.WithContext(ctx).Where("history_id IN ?", ids).
+		Order("history_id asc, number asc, id asc").Find(&tries).Error; err != nil {
+		return fmt.Errorf("list tries: %w", err)
+	}
+	for _, t := range tries {
+		if row, ok := byID[*t.HistoryID]; ok {
+			row.Tries = append(row.Tries, t)
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PostRepository provides post data access operations.
@@ -65,9 +66,47 @@ func (r *PostRepository) GetByQID(ctx context.Context, qid string) (*post.Post,
 	return findFirst[post.Post](ctx, r.db.Where("qid = ?", qid), domain.ErrNotFound)
 }
 
-// GetByID retrieves a post by its ID.
+// GetByID retrieves a post by its ID with its author preloaded, so delivery
+// payloads can name the author without a second lookup.
 func (r *PostRepository) GetByID(ctx context.Context, id int) (*post.Post, error) {
-	return findFirst[post.Post](ctx, r.db.Where("id = ?", id), domain.ErrNotFound)
+	return findFirst[post.Post](ctx, r.db.Preload("User").Where("id = ?", id), domain.ErrNotFound)
+}
+
+// Update writes p's title and body and stamps UpdatedAt. In the same
+// transaction the stored title and body are archived as the post's next
+// revision, numbered after its latest one and attributed to editorID.
+// The post row is locked first so concurrent edits queue up behind each
+// other instead of archiving the same version under the same number.
+// A post that is gone is domain.ErrNotFound.
+func (r *PostRepository) Update(ctx context.Context, p *post.Post, editorID int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var prior post.Post
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, title, body").
+			Where("id = ?", p.ID).First(&prior).Error; err != nil {
+			return mapNotFound(err, domain.ErrNotFound)
+		}
+		var latest int
+		if err := tx.Model(&post.Revision{}).Where("post_id = ?", p.ID).
+			Select("COALESCE(MAX(number), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		rev := post.Revision{PostID: p.ID, Number: latest + 1, Title: prior.Title, Body: prior.Body, EditorID: &editorID, CreatedAt: now}
+		if err := tx.Create(&rev).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&post.Post{}).Where("id = ?", p.ID).
+			Updates(map[string]any{"title": p.Title, "body": p.Body, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		p.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Update: %w", err)
+	}
+	return nil
 }
 
 // CountByUserID counts posts for a specific user.
@@ -111,16 +150,20 @@ func (r *PostRepository) DeleteByQID(ctx context.Context, qid string, ownerID in
 	return deleteWhere[post.Post](ctx, q)
 }
 
-// PruneExpired deletes expired posts based on retention days. It returns the
-// QIDs of the deleted posts so the caller can drop their origin render-cache
-// entries. It does not issue CDN purges — stale delivery of already-expired
-// ephemeral content is harmless, and prune volume can be large.
-func (r *PostRepository) PruneExpired(ctx context.Context, retentionDays int, batchSize int) ([]string, error) {
+// PruneExpired deletes expired posts and their revisions based on retention
+// days, batchSize posts at a time. Each deleted batch (ID, QID, title, owner
+// and creation time only, plus the body when withBody) is handed to each
+// before the next is read, so the caller can drop their origin render-cache
+// entries and announce their expiry without holding the whole run in memory.
+// It returns how many posts it deleted. It does not issue CDN purges — stale
+// delivery of already-expired ephemeral content is harmless, and prune
+// volume can be large.
+func (r *PostRepository) PruneExpired(ctx context.Context, retentionDays int, batchSize int, withBody bool, each func([]post.Post)) (int, error) {
 	expiredBefore := time.Now().AddDate(0, 0, -retentionDays)
-	var pruned []string
+	pruned := 0
 
 	for {
-		rows, err := r.getQIDsBefore(ctx, expiredBefore, batchSize)
+		rows, err := r.getExpiredBefore(ctx, expiredBefore, batchSize, withBody)
 		if err != nil {
 			return pruned, fmt.Errorf("PruneExpired: %w", err)
 		}
@@ -129,10 +172,8 @@ func (r *PostRepository) PruneExpired(ctx context.Context, retentionDays int, ba
 			break
 		}
 
-		qids := make([]string, 0, len(rows))
 		ids := make([]int, 0, len(rows))
 		for _, ro := range rows {
-			qids = append(qids, ro.QID)
 			ids = append(ids, ro.ID)
 		}
 
@@ -140,7 +181,8 @@ func (r *PostRepository) PruneExpired(ctx context.Context, retentionDays int, ba
 		if err != nil {
 			return pruned, fmt.Errorf("PruneExpired: %w", err)
 		}
-		pruned = append(pruned, qids...)
+		pruned += len(rows)
+		each(rows)
 
 		if deleted < int64(batchSize) {
 			break
@@ -156,33 +198,43 @@ func (r *PostRepository) CountExpired(ctx context.Context, retentionDays int) (i
 	return countQuery(ctx, r.db.Model(&post.Post{}).Where("created_at < ?", expiredBefore), "CountExpired")
 }
 
-type expiredRow struct {
-	ID  int    `gorm:"column:id"`
-	QID string `gorm:"column:qid"`
-}
-
-func (r *PostRepository) getQIDsBefore(ctx context.Context, before time.Time, limit int) ([]expiredRow, error) {
-	var rows []expiredRow
+func (r *PostRepository) getExpiredBefore(ctx context.Context, before time.Time, limit int, withBody bool) ([]post.Post, error) {
+	var rows []post.Post
 
+	columns := "id, qid, title, user_id, created_at"
+	if withBody {
+		columns += ", body"
+	}
 	queryBuilder := r.db.WithContext(ctx).Model(&post.Post{}).
-		Select("id, qid").
+		Select(columns).
 		Where("created_at < ?", before)
 	if limit > 0 {
 		queryBuilder = queryBuilder.Limit(limit)
 	}
 
-	if err := queryBuilder.Scan(&rows).Error; err != nil {
-		return nil, fmt.Errorf("getQIDsBefore: %w", err)
+	if err := queryBuilder.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("getExpiredBefore: %w", err)
 	}
 
 	return rows, nil
 }
 
+// deleteByIDs deletes the posts and, in the same transaction, their
+// revisions, so pruning does not depend on the database enforcing the
+// cascade.
 func (r *PostRepository) deleteByIDs(ctx context.Context, ids []int) (int64, error) {
-	tx := r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&post.Post{})
-	if tx.Error != nil {
-		return 0, fmt.Errorf("deleteByIDs: %w", tx.Error)
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id IN ?", ids).Delete(&post.Revision{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&post.Post{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("deleteByIDs: %w", err)
 	}
 
-	return tx.RowsAffected, nil
+	return deleted, nil
 }
@@ -3,11 +3,14 @@ package infra
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"markpost/internal/domain"
 	"markpost/internal/domain/post"
+	"markpost/internal/domain/user"
 )
 
 func TestPostRepository_Create(t *testing.T) {
@@ -74,6 +77,126 @@ func TestPostRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestPostRepository_Update(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	created, _ := repo.Create(ctx, "Title", "Body", 1)
+	created.Title, created.Body = "New title", "New body"
+	if err := repo.Update(ctx, created, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, _ := repo.GetByQID(ctx, created.QID)
+	if p.Title != "New title" || p.Body != "New body" {
+		t.Errorf("post = %q/%q, want the new title and body", p.Title, p.Body)
+	}
+	if !p.UpdatedAt.After(p.CreatedAt) {
+		t.Errorf("updated_at %v should be after created_at %v", p.UpdatedAt, p.CreatedAt)
+	}
+
+	rev, err := repo.GetRevision(ctx, created.ID, 1)
+	if err != nil {
+		t.Fatalf("GetRevision: %v", err)
+	}
+	if rev.Title != "Title" || rev.Body != "Body" || rev.EditorID == nil || *rev.EditorID != 1 || !rev.CreatedAt.Equal(p.UpdatedAt) {
+		t.Errorf("revision = %+v, want the replaced version edited at %v", rev, p.UpdatedAt)
+	}
+
+	if err := repo.Update(ctx, &post.Post{ID: 999}, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing post, got: %v", err)
+	}
+	var count int64
+	db.Model(&post.Revision{}).Count(&count)
+	if count != 1 {
+		t.Errorf("revisions = %d, want none archived for a missing post", count)
+	}
+}
+
+func TestPostRepository_UpdateConcurrent(t *testing.T) {
+	db := SetupTestDB(t)
+	// An in-memory database lives in one connection, as SQLite runs in
+	// production.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	created, _ := repo.Create(ctx, "Title", "v0", 1)
+	const edits = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, edits)
+	for i := 1; i <= edits; i++ {
+		wg.Add(1)
+		go func(body string) {
+			defer wg.Done()
+			errs <- repo.Update(ctx, &post.Post{ID: created.ID, Title: "Title", Body: body}, 1)
+		}(fmt.Sprintf("v%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Update: %v", err)
+		}
+	}
+
+	// Every version is archived exactly once: the revisions and the live
+	// body together are the original and each edit.
+	revs, err := repo.ListRevisions(ctx, created.ID)
+	if err != nil || len(revs) != edits {
+		t.Fatalf("revisions = %d, %v; want %d", len(revs), err, edits)
+	}
+	seen := map[string]bool{}
+	for i, rev := range revs {
+		if rev.Number != edits-i {
+			t.Errorf("revision %d numbered %d, want %d", i, rev.Number, edits-i)
+		}
+		full, err := repo.GetRevision(ctx, created.ID, rev.Number)
+		if err != nil {
+			t.Fatalf("GetRevision: %v", err)
+		}
+		seen[full.Body] = true
+	}
+	live, _ := repo.GetByQID(ctx, created.QID)
+	seen[live.Body] = true
+	if len(seen) != edits+1 || !seen["v0"] {
+		t.Errorf("versions = %v, want v0 through v%d once each", seen, edits)
+	}
+}
+
+func TestPostRepository_ListRevisions(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	editor := &user.User{Email: "ed@b.c", Username: "editor", Password: "x", PostKey: "epk"}
+	db.Create(editor)
+	created, _ := repo.Create(ctx, "v1", "Body 1", editor.ID)
+	for _, title := range []string{"v2", "v3"} {
+		created.Title = title
+		if err := repo.Update(ctx, created, editor.ID); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	revs, err := repo.ListRevisions(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revs) != 2 || revs[0].Number != 2 || revs[0].Title != "v2" || revs[1].Number != 1 || revs[1].Title != "v1" {
+		t.Fatalf("revisions = %+v, want 2 then 1", revs)
+	}
+	if revs[0].Body != "" || revs[0].Editor == nil || revs[0].Editor.Username != "editor" {
+		t.Errorf("revision = %+v, want no body and the editor loaded", revs[0])
+	}
+
+	if _, err := repo.GetRevision(ctx, created.ID, 3); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the live version, got: %v", err)
+	}
+}
+
 func TestPostRepository_CountByUserID(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := NewPostRepository(db)
@@ -285,21 +408,33 @@ func TestPostRepository_PruneExpired(t *testing.T) {
 	// Create a post with an old timestamp
 	p, _ := repo.Create(ctx, "Old Post", "Body", 1)
 	db.Model(&p).Update("created_at", time.Now().AddDate(0, 0, -10))
+	p.Body = "Edited"
+	_ = repo.Update(ctx, p, 1)
 
-	_, _ = repo.Create(ctx, "New Post", "Body", 1)
+	kept, _ := repo.Create(ctx, "New Post", "Body", 1)
+	kept.Body = "Edited"
+	_ = repo.Update(ctx, kept, 1)
 
-	pruned, err := repo.PruneExpired(ctx, 7, 100)
+	var pruned []post.Post
+	n, err := repo.PruneExpired(ctx, 7, 100, true, func(batch []post.Post) { pruned = append(pruned, batch...) })
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(pruned) != 1 || pruned[0] != p.QID {
-		t.Errorf("pruned QIDs = %v, want [%s]", pruned, p.QID)
+	if n != 1 || len(pruned) != 1 || pruned[0].QID != p.QID || pruned[0].Title != "Old Post" || pruned[0].Body != "Edited" || pruned[0].UserID != 1 {
+		t.Errorf("pruned = %d %+v, want [%s]", n, pruned, p.QID)
 	}
 
 	count, _ := repo.CountAll(ctx, "")
 	if count != 1 {
 		t.Errorf("count = %d, want 1", count)
 	}
+	// The test database does not enforce foreign keys, so this checks the
+	// revisions are deleted explicitly rather than by cascade.
+	var revisions []post.Revision
+	db.Find(&revisions)
+	if len(revisions) != 1 || revisions[0].PostID != kept.ID {
+		t.Errorf("revisions = %+v, want only the kept post's", revisions)
+	}
 }
 
 func TestPostRepository_CountExpired(t *testing.T) {
@@ -0,0 +1,25 @@
+package infra
+
+import (
+	"context"
+
+	"markpost/internal/domain"
+	"markpost/internal/domain/post"
+
+	"gorm.io/gorm"
+)
+
+// ListRevisions returns a post's revisions newest first. Bodies are left out
+// of the listing; the editor is preloaded for display.
+func (r *PostRepository) ListRevisions(ctx context.Context, postID int) ([]post.Revision, error) {
+	query := r.db.Select("id, post_id, number, title, editor_id, created_at").
+		Preload("Editor", func(db *gorm.DB) *gorm.DB { return db.Select("id, username") }).
+		Where("post_id = ?", postID).
+		Order("number DESC")
+	return findAll[post.Revision](ctx, query, "ListRevisions")
+}
+
+// GetRevision returns revision number of a post.
+func (r *PostRepository) GetRevision(ctx context.Context, postID, number int) (*post.Revision, error) {
+	return findFirst[post.Revision](ctx, r.db.Where("post_id = ? AND number = ?", postID, number), domain.ErrNotFound)
+}
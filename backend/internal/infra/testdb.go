@@ -16,9 +16,14 @@ var testModels = []any{
 	&user.RefreshToken{},
 	&user.TokenBlacklist{},
 	&post.Post{},
+	&post.Revision{},
 	&delivery.Channel{},
 	&delivery.Attempt{},
+	&delivery.DigestItem{},
 	&delivery.History{},
+	&delivery.Try{},
+	&delivery.Message{},
+	&delivery.Route{},
 }
 
 // SetupTestDB creates an in-memory SQLite database for testing with all models migrated.
@@ -26,10 +26,28 @@ type ChannelLister interface {
 	ListAll(ctx context.Context, offset, limit int) ([]delivery.Channel, int64, error)
 }
 
-// HistoryLister defines the interface for retrieving delivery history.
+// HistoryLister defines the interface for retrieving delivery history and
+// routing decisions.
 type HistoryLister interface {
 	ListHistory(ctx context.Context, filter delivery.HistoryFilter, offset, limit int) ([]*delivery.HistoryRow, error)
 	CountHistory(ctx context.Context, filter delivery.HistoryFilter) (int64, error)
+	ListRoutes(ctx context.Context, postQID string, ownerID int) ([]*delivery.RouteRow, error)
+}
+
+// LeaseLister defines the interface for reading delivery attempt leases.
+type LeaseLister interface {
+	ListLeased(ctx context.Context, limit int) ([]*delivery.Attempt, error)
+}
+
+// maxLeasesListed bounds how many leased attempts the lease view reads. A
+// healthy instance holds at most its pool's workers plus queue depth.
+const maxLeasesListed = 2000
+
+// LeaseHolder is one dispatcher instance and the delivery attempts leased to
+// it, in attempt ID order.
+type LeaseHolder struct {
+	Owner    string
+	Attempts []*delivery.Attempt
 }
 
 // Service provides admin-level business logic.
@@ -38,15 +56,17 @@ type Service struct {
 	postLister    PostLister
 	channelLister ChannelLister
 	historyLister HistoryLister
+	leaseLister   LeaseLister
 }
 
 // NewService creates a new admin Service instance.
-func NewService(userLister UserLister, postLister PostLister, channelLister ChannelLister, historyLister HistoryLister) *Service {
+func NewService(userLister UserLister, postLister PostLister, channelLister ChannelLister, historyLister HistoryLister, leaseLister LeaseLister) *Service {
 	return &Service{
 		userLister:    userLister,
 		postLister:    postLister,
 		channelLister: channelLister,
 		historyLister: historyLister,
+		leaseLister:   leaseLister,
 	}
 }
 
@@ -79,3 +99,33 @@ func (s *Service) ListAllDeliveryHistory(ctx context.Context, offset, limit int)
 		"delivery history",
 	)
 }
+
+// ListPostRoutes retrieves the routing decisions recorded for any post,
+// newest event first.
+func (s *Service) ListPostRoutes(ctx context.Context, postQID string) ([]*delivery.RouteRow, error) {
+	rows, err := s.historyLister.ListRoutes(ctx, postQID, 0)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "list delivery routes failed", err)
+	}
+	return rows, nil
+}
+
+// ListDeliveryLeases groups the leased delivery attempts by the dispatcher
+// instance holding them, ordered by instance ID. Leases that have run out
+// (their holder stopped without releasing them) are included.
+func (s *Service) ListDeliveryLeases(ctx context.Context) ([]LeaseHolder, error) {
+	attempts, err := s.leaseLister.ListLeased(ctx, maxLeasesListed)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "list delivery leases failed", err)
+	}
+
+	holders := []LeaseHolder{}
+	for _, a := range attempts {
+		if n := len(holders); n == 0 || holders[n-1].Owner != a.LeaseOwner {
+			holders = append(holders, LeaseHolder{Owner: a.LeaseOwner})
+		}
+		last := &holders[len(holders)-1]
+		last.Attempts = append(last.Attempts, a)
+	}
+	return holders, nil
+}
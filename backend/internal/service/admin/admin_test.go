@@ -23,6 +23,7 @@ func setupAdminService(t *testing.T) (*Service, user.Repository, post.Repository
 		&postListerAdapter{repo: postRepo},
 		&channelListerAdapter{repo: channelRepo},
 		attemptRepo,
+		attemptRepo,
 	)
 	return svc, userRepo, postRepo, channelRepo
 }
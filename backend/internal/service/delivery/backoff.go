@@ -1,17 +1,25 @@
 package delivery
 
-import "time"
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
-// backoffSequence is the hardcoded list of delays applied after each failed
+	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
+	"markpost/internal/service"
+	"markpost/pkg/utils"
+)
+
+// backoffSequence is the default list of delays applied after each failed
 // delivery attempt. The first attempt happens immediately on claim (no leading
 // wait); each subsequent attempt waits backoffSequence[attempts-1] before
 // retrying. With this sequence a delivery exhausts at t=36m after up to 5
 // attempts (1 immediate + 4 retries).
 //
-// Hardcoded, not configurable: there is exactly one delivery channel kind
-// today (Feishu), so per-channel retry tuning has no consumer. Changing the
-// sequence is a code change + release, which already restarts the process and
-// clears in-flight state. See specs/backend/delivery.md Decision 4.
+// It is the policy used when [delivery.retry] is not configured and no channel
+// overrides it. See specs/backend/delivery.md Decision 4.
 var backoffSequence = [...]time.Duration{
 	1 * time.Minute,
 	5 * time.Minute,
@@ -19,7 +27,226 @@ var backoffSequence = [...]time.Duration{
 	20 * time.Minute,
 }
 
-// BackoffSequence returns a copy of the hardcoded retry delay sequence. It is
+// Retry policy modes, as written in [delivery.retry] mode and a channel's
+// retry_policy.mode.
+const (
+	RetryModeSequence    = "sequence"
+	RetryModeExponential = "exponential"
+)
+
+// Bounds for a channel's retry policy override. The instance-wide policy is
+// bounded by config validation instead.
+const (
+	minRetryDelay       = time.Second
+	maxRetryDelay       = 24 * time.Hour
+	maxRetrySequenceLen = 10
+	maxRetryAttempts    = 20
+	defaultExpAttempts  = 5
+)
+
+// RetryPolicy decides how long a failed delivery waits before its next try and
+// when it gives up. In sequence mode (Sequence non-empty) retry i waits
+// Sequence[i], repeating the last delay if MaxAttempts allows more retries than
+// listed; in exponential mode it waits Base·2^i, capped at Max. MaxAttempts
+// counts every try including the first. Jitter spreads each delay by ±that
+// fraction so a burst of failures does not retry in lockstep.
+type RetryPolicy struct {
+	Sequence    []time.Duration
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+	Jitter      float64
+}
+
+// defaultRetryPolicy returns the policy built from backoffSequence.
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Sequence:    BackoffSequence(),
+		MaxAttempts: len(backoffSequence) + 1,
+	}
+}
+
+// RetryPolicyFromConfig builds the instance-wide retry policy. Unset fields
+// fall back to the defaults: the default sequence, a 1m base and 1h cap for
+// exponential mode, and one try per sequence entry plus the first (sequence)
+// or 5 tries (exponential).
+func RetryPolicyFromConfig(cfg config.RetryConfig) RetryPolicy {
+	p := RetryPolicy{MaxAttempts: cfg.MaxAttempts, Jitter: cfg.Jitter}
+	if cfg.Mode == RetryModeExponential {
+		p.Base, p.Max = cfg.Base, cfg.Max
+		if p.Base <= 0 {
+			p.Base = time.Minute
+		}
+		if p.Max < p.Base {
+			p.Max = max(p.Base, time.Hour)
+		}
+		if p.MaxAttempts <= 0 {
+			p.MaxAttempts = defaultExpAttempts
+		}
+		return p
+	}
+
+	p.Sequence = append([]time.Duration(nil), cfg.Sequence...)
+	if len(p.Sequence) == 0 {
+		p.Sequence = BackoffSequence()
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = len(p.Sequence) + 1
+	}
+	return p
+}
+
+// channelRetryPolicy parses and bounds-checks a channel's retry policy
+// override. Durations are Go duration strings. A zero MaxAttempts is derived
+// the same way as for the instance policy.
+func channelRetryPolicy(o *delivery.RetryPolicy) (RetryPolicy, error) {
+	if o.MaxAttempts < 0 || o.MaxAttempts > maxRetryAttempts {
+		return RetryPolicy{}, fmt.Errorf("max_attempts must be between 0 and %d", maxRetryAttempts)
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		return RetryPolicy{}, fmt.Errorf("jitter must be between 0 and 1")
+	}
+	p := RetryPolicy{MaxAttempts: o.MaxAttempts, Jitter: o.Jitter}
+
+	switch o.Mode {
+	case RetryModeSequence:
+		if len(o.Sequence) == 0 || len(o.Sequence) > maxRetrySequenceLen {
+			return RetryPolicy{}, fmt.Errorf("sequence must list 1 to %d delays", maxRetrySequenceLen)
+		}
+		for _, raw := range o.Sequence {
+			d, err := parseRetryDelay("sequence", raw)
+			if err != nil {
+				return RetryPolicy{}, err
+			}
+			p.Sequence = append(p.Sequence, d)
+		}
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = len(p.Sequence) + 1
+		}
+	case RetryModeExponential:
+		var err error
+		if p.Base, err = parseRetryDelay("base", o.Base); err != nil {
+			return RetryPolicy{}, err
+		}
+		if p.Max, err = parseRetryDelay("max", o.Max); err != nil {
+			return RetryPolicy{}, err
+		}
+		if p.Max < p.Base {
+			return RetryPolicy{}, fmt.Errorf("max must not be less than base")
+		}
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = defaultExpAttempts
+		}
+	default:
+		return RetryPolicy{}, fmt.Errorf("mode must be %q or %q", RetryModeSequence, RetryModeExponential)
+	}
+	return p, nil
+}
+
+// normalizeRetryPolicy validates a channel's retry policy override for
+// storage. An empty mode clears the override (nil); fields the mode does not
+// use are dropped.
+func normalizeRetryPolicy(o *delivery.RetryPolicy) (*delivery.RetryPolicy, error) {
+	mode := utils.Normalize(o.Mode)
+	if mode == "" {
+		return nil, nil
+	}
+	normalized := &delivery.RetryPolicy{Mode: mode, MaxAttempts: o.MaxAttempts, Jitter: o.Jitter}
+	if mode == RetryModeSequence {
+		normalized.Sequence = o.Sequence
+	} else {
+		normalized.Base, normalized.Max = strings.TrimSpace(o.Base), strings.TrimSpace(o.Max)
+	}
+	if _, err := channelRetryPolicy(normalized); err != nil {
+		return nil, service.New(service.ErrValidation, "invalid retry_policy: "+err.Error())
+	}
+	return normalized, nil
+}
+
+// resolveRetryPolicy returns the policy a channel's deliveries follow: its
+// override when set, the instance policy otherwise. An override that no longer
+// parses (validation was tightened since it was saved) falls back to the
+// instance policy.
+func resolveRetryPolicy(instance RetryPolicy, ch *delivery.Channel) RetryPolicy {
+	if ch == nil || ch.RetryPolicy == nil {
+		return instance
+	}
+	p, err := channelRetryPolicy(ch.RetryPolicy)
+	if err != nil {
+		log.Printf("delivery: ignore invalid retry policy channel_id=%d err=%v", ch.ID, err)
+		return instance
+	}
+	return p
+}
+
+func parseRetryDelay(field, raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid duration %q", field, raw)
+	}
+	if d < minRetryDelay || d > maxRetryDelay {
+		return 0, fmt.Errorf("%s: %s is outside %s to %s", field, raw, minRetryDelay, maxRetryDelay)
+	}
+	return d, nil
+}
+
+// Backoff returns the delay before the next try after the (retry+1)-th
+// failure, or ok=false if the policy gives up. retry is the count of tries
+// already failed before this one (Attempt.Attempts). Jitter is not applied.
+func (p RetryPolicy) Backoff(retry int) (time.Duration, bool) {
+	if retry < 0 {
+		retry = 0
+	}
+	if retry+1 >= p.MaxAttempts {
+		return 0, false
+	}
+	if len(p.Sequence) > 0 {
+		return p.Sequence[min(retry, len(p.Sequence)-1)], true
+	}
+	if p.Base <= 0 {
+		return 0, false
+	}
+	d := p.Base
+	for i := 0; i < retry && d < p.Max; i++ {
+		d *= 2
+	}
+	return min(d, p.Max), true
+}
+
+// ExpiryWall returns the hard time wall for one attempt under this policy:
+// every retry delay at its jitter maximum, summed and rounded up by
+// computeExpiryWall. A policy that never retries yields 0 (no wall).
+func (p RetryPolicy) ExpiryWall() time.Duration {
+	var delays []time.Duration
+	for retry := 0; ; retry++ {
+		d, ok := p.Backoff(retry)
+		if !ok {
+			break
+		}
+		delays = append(delays, d+time.Duration(float64(d)*p.Jitter))
+	}
+	return computeExpiryWall(delays)
+}
+
+// expiresAt returns the epoch-ms expiry wall of an attempt created at from, or
+// 0 when the policy has no wall.
+func (p RetryPolicy) expiresAt(from time.Time) int64 {
+	wall := p.ExpiryWall()
+	if wall <= 0 {
+		return 0
+	}
+	return from.Add(wall).UnixMilli()
+}
+
+// jitter spreads d by ±frac; r is a uniform sample in [0, 1).
+func jitter(d time.Duration, frac, r float64) time.Duration {
+	if frac <= 0 {
+		return d
+	}
+	return d + time.Duration(float64(d)*frac*(2*r-1))
+}
+
+// BackoffSequence returns a copy of the default retry delay sequence. It is
 // read-only at the call site.
 func BackoffSequence() []time.Duration {
 	out := make([]time.Duration, len(backoffSequence))
@@ -27,17 +254,12 @@ func BackoffSequence() []time.Duration {
 	return out
 }
 
-// NextBackoff returns the delay before the (attempts+1)-th delivery attempt,
-// or ok=false if the sequence is exhausted (attempts already covers the whole
-// sequence). attempts is the count of attempts already performed.
+// NextBackoff returns the delay before the (attempts+1)-th delivery attempt
+// under the default policy, or ok=false if the sequence is exhausted
+// (attempts already covers the whole sequence). attempts is the count of
+// attempts already performed.
 func NextBackoff(attempts int) (time.Duration, bool) {
-	if attempts < 0 {
-		attempts = 0
-	}
-	if attempts >= len(backoffSequence) {
-		return 0, false
-	}
-	return backoffSequence[attempts], true
+	return defaultRetryPolicy().Backoff(attempts)
 }
 
 // computeExpiryWall derives the hard time wall from a backoff sequence:
@@ -63,8 +285,8 @@ func computeExpiryWall(seq []time.Duration) time.Duration {
 	return sum
 }
 
-// ExpiryWall returns the auto-computed expiry wall for the hardcoded backoff
+// ExpiryWall returns the auto-computed expiry wall for the default backoff
 // sequence.
 func ExpiryWall() time.Duration {
-	return computeExpiryWall(backoffSequence[:])
+	return defaultRetryPolicy().ExpiryWall()
 }
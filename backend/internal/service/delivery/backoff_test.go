@@ -3,6 +3,9 @@ package delivery
 import (
 	"testing"
 	"time"
+
+	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
 )
 
 func TestBackoffSequence(t *testing.T) {
@@ -65,3 +68,113 @@ func TestExpiryWallMatchesDefaultSequence(t *testing.T) {
 		t.Errorf("ExpiryWall = %v, want 40m", got)
 	}
 }
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Run("sequence repeats its last delay", func(t *testing.T) {
+		p := RetryPolicy{Sequence: []time.Duration{time.Minute, 2 * time.Minute}, MaxAttempts: 4}
+		want := []time.Duration{time.Minute, 2 * time.Minute, 2 * time.Minute}
+		for retry, w := range want {
+			if got, ok := p.Backoff(retry); !ok || got != w {
+				t.Errorf("Backoff(%d) = (%v, %v), want (%v, true)", retry, got, ok, w)
+			}
+		}
+		if _, ok := p.Backoff(3); ok {
+			t.Error("Backoff(3) should give up after 4 tries")
+		}
+	})
+
+	t.Run("exponential doubles up to max", func(t *testing.T) {
+		p := RetryPolicy{Base: 30 * time.Second, Max: 3 * time.Minute, MaxAttempts: 6}
+		want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+		for retry, w := range want {
+			if got, ok := p.Backoff(retry); !ok || got != w {
+				t.Errorf("Backoff(%d) = (%v, %v), want (%v, true)", retry, got, ok, w)
+			}
+		}
+	})
+
+	t.Run("single attempt never retries", func(t *testing.T) {
+		p := RetryPolicy{Sequence: []time.Duration{time.Minute}, MaxAttempts: 1}
+		if _, ok := p.Backoff(0); ok {
+			t.Error("Backoff(0) should give up")
+		}
+		if got := p.ExpiryWall(); got != 0 {
+			t.Errorf("ExpiryWall = %v, want 0", got)
+		}
+	})
+}
+
+func TestRetryPolicy_ExpiryWallCoversJitter(t *testing.T) {
+	// Two 4m retries sum to 8m (a 10m wall); at +50% jitter they can take
+	// 12m, so the wall must round up to 20m.
+	p := RetryPolicy{Sequence: []time.Duration{4 * time.Minute, 4 * time.Minute}, MaxAttempts: 3, Jitter: 0.5}
+	if got := p.ExpiryWall(); got != 20*time.Minute {
+		t.Errorf("ExpiryWall = %v, want 20m", got)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	cases := []struct {
+		r    float64
+		want time.Duration
+	}{
+		{0, 50 * time.Second},
+		{0.5, 100 * time.Second},
+		{1, 150 * time.Second},
+	}
+	for _, c := range cases {
+		if got := jitter(100*time.Second, 0.5, c.r); got != c.want {
+			t.Errorf("jitter(r=%v) = %v, want %v", c.r, got, c.want)
+		}
+	}
+	if got := jitter(time.Minute, 0, 0.9); got != time.Minute {
+		t.Errorf("zero jitter changed the delay: %v", got)
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	if got := RetryPolicyFromConfig(config.RetryConfig{}); got.MaxAttempts != 5 || len(got.Sequence) != 4 {
+		t.Errorf("empty config = %+v, want the default sequence", got)
+	}
+	got := RetryPolicyFromConfig(config.RetryConfig{Mode: RetryModeExponential, Base: 10 * time.Second})
+	if got.Base != 10*time.Second || got.Max != time.Hour || got.MaxAttempts != 5 || got.Sequence != nil {
+		t.Errorf("exponential config = %+v", got)
+	}
+}
+
+func TestNormalizeRetryPolicy(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      delivery.RetryPolicy
+		wantNil bool
+		wantErr bool
+	}{
+		{"empty mode clears", delivery.RetryPolicy{}, true, false},
+		{"sequence", delivery.RetryPolicy{Mode: " Sequence ", Sequence: []string{"30s", "2m"}}, false, false},
+		{"exponential", delivery.RetryPolicy{Mode: "exponential", Base: "10s", Max: "10m", MaxAttempts: 8, Jitter: 0.2}, false, false},
+		{"unknown mode", delivery.RetryPolicy{Mode: "linear"}, false, true},
+		{"empty sequence", delivery.RetryPolicy{Mode: "sequence"}, false, true},
+		{"bad duration", delivery.RetryPolicy{Mode: "sequence", Sequence: []string{"soon"}}, false, true},
+		{"delay below a second", delivery.RetryPolicy{Mode: "sequence", Sequence: []string{"100ms"}}, false, true},
+		{"max below base", delivery.RetryPolicy{Mode: "exponential", Base: "10m", Max: "1m"}, false, true},
+		{"too many attempts", delivery.RetryPolicy{Mode: "sequence", Sequence: []string{"1m"}, MaxAttempts: 21}, false, true},
+		{"jitter above one", delivery.RetryPolicy{Mode: "sequence", Sequence: []string{"1m"}, Jitter: 1.5}, false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := normalizeRetryPolicy(&c.in)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == nil) != c.wantNil {
+				t.Errorf("got %+v, wantNil=%v", got, c.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// successStampInterval bounds how often a healthy channel's last_success_at is
+// rewritten, so a busy channel does not cost a write per delivery.
+const successStampInterval = time.Minute
+
+// errChannelDisabled fails an attempt whose channel was disabled after the
+// attempt was queued — by its owner or by the circuit breaker.
+var errChannelDisabled = errors.New("channel is disabled")
+
+// recordSuccess ends the channel's failure streak and closes its circuit. A
+// channel that is already healthy is only re-stamped once per
+// successStampInterval.
+func (d *Dispatcher) recordSuccess(ctx context.Context, ch *delivery.Channel) {
+	now := d.now()
+	if ch.ConsecutiveFailures == 0 && ch.PausedUntil == 0 &&
+		ch.LastSuccessAt != nil && now.Sub(*ch.LastSuccessAt) < successStampInterval {
+		return
+	}
+	if err := d.channelRepo.RecordSuccess(ctx, ch.ID, now); err != nil {
+		log.Printf("delivery breaker: record success channel_id=%d err=%v", ch.ID, err)
+	}
+}
+
+// recordFailure extends the channel's failure streak and trips the breaker:
+// at DisableAfter failures the channel is disabled with the reason shown to
+// its owner; at Threshold (and every failure after, while the streak lasts)
+// its pending deliveries are paused for Cooldown. Rate-limited failures are
+// the upstream pacing us, not the channel being broken, and do not count.
+func (d *Dispatcher) recordFailure(ctx context.Context, ch *delivery.Channel, sendErr error) {
+	if kind, _ := classify(sendErr); kind == FailureRateLimited {
+		return
+	}
+	now := d.now()
+	streak, err := d.channelRepo.RecordFailure(ctx, ch.ID, now)
+	if err != nil {
+		log.Printf("delivery breaker: record failure channel_id=%d err=%v", ch.ID, err)
+		return
+	}
+
+	cfg := d.cfg.Breaker
+	switch {
+	case cfg.DisableAfter > 0 && streak >= cfg.DisableAfter:
+		reason := fmt.Sprintf("disabled after %d consecutive failed deliveries; last error: %s", streak, truncateError(sendErr.Error()))
+		if err := d.channelRepo.AutoDisable(ctx, ch.ID, reason); err != nil {
+			log.Printf("delivery breaker: disable channel_id=%d err=%v", ch.ID, err)
+			return
+		}
+		log.Printf("delivery breaker: disabled channel_id=%d user_id=%d failures=%d", ch.ID, ch.UserID, streak)
+	case cfg.Threshold > 0 && streak >= cfg.Threshold && cfg.Cooldown > 0:
+		until := now.Add(cfg.Cooldown).UnixMilli()
+		if err := d.channelRepo.Pause(ctx, ch.ID, until); err != nil {
+			log.Printf("delivery breaker: pause channel_id=%d err=%v", ch.ID, err)
+			return
+		}
+		deferred, err := d.attemptRepo.DeferChannel(ctx, ch.ID, until)
+		if err != nil {
+			log.Printf("delivery breaker: defer attempts channel_id=%d err=%v", ch.ID, err)
+		}
+		log.Printf("delivery breaker: paused channel_id=%d failures=%d deferred=%d", ch.ID, streak, deferred)
+	}
+}
+
+// deferAttempt puts an attempt claimed while its channel's circuit was open
+// back in the queue at untilMs. It does not count as a try, and like
+// holdAttempt it moves the expiry wall forward by the time parked, so the
+// cooldown does not use up the attempt's lifetime.
+func (d *Dispatcher) deferAttempt(ctx context.Context, a *delivery.Attempt, untilMs int64) {
+	expiresAt := a.ExpiresAt
+	if expiresAt > 0 {
+		expiresAt += untilMs - d.now().UnixMilli()
+	}
+	if err := d.attemptRepo.Hold(ctx, a.ID, untilMs, expiresAt); err != nil {
+		log.Printf("delivery breaker: defer attempt_id=%d err=%v", a.ID, err)
+	}
+}
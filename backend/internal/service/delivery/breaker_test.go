@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/infra"
+)
+
+type failingSender struct {
+	calls int
+	err   error
+}
+
+func (s *failingSender) Send(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	s.calls++
+	return s.err
+}
+
+func (s *failingSender) SendDigest(_ context.Context, _ []*domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	s.calls++
+	return s.err
+}
+
+func (s *failingSender) SendEvent(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt, _ string) error {
+	s.calls++
+	return s.err
+}
+
+func TestDispatcher_CircuitBreaker(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	now := time.Now()
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	channelRepo := infra.NewDeliveryChannelRepository(database.DB())
+	attempt := &delivery.Attempt{UserID: uid, PostID: &pid, ChannelID: cid, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now}
+	if err := attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	sender := &failingSender{err: errors.New("feishu webhook status=500")}
+	dispatcher := NewDispatcher(attemptRepo, channelRepo, infra.NewPostRepository(database.DB()), sender)
+	dispatcher.cfg.Breaker = config.BreakerConfig{Threshold: 2, Cooldown: 10 * time.Minute, DisableAfter: 3}
+	dispatcher.now = func() time.Time { return now }
+
+	run := func() *delivery.Attempt {
+		t.Helper()
+		var a delivery.Attempt
+		if err := database.DB().First(&a, attempt.ID).Error; err != nil {
+			return nil
+		}
+		dispatcher.execute(ctx, &a)
+		return &a
+	}
+	channel := func() *delivery.Channel {
+		ch, _ := channelRepo.GetByIDAndUserID(ctx, cid, uid)
+		return ch
+	}
+
+	run()
+	run()
+	pausedUntil := now.Add(10 * time.Minute).UnixMilli()
+	if ch := channel(); ch.ConsecutiveFailures != 2 || ch.PausedUntil != pausedUntil {
+		t.Fatalf("after 2 failures: failures=%d paused_until=%d, want 2 and %d", ch.ConsecutiveFailures, ch.PausedUntil, pausedUntil)
+	}
+
+	// While the circuit is open the attempt is put back without a send, its
+	// expiry wall moved forward by the time it stays parked.
+	wall := now.Add(time.Hour).UnixMilli()
+	database.DB().Model(&delivery.Attempt{}).Where("id = ?", attempt.ID).Update("expires_at", wall)
+	run()
+	var a delivery.Attempt
+	database.DB().First(&a, attempt.ID)
+	if sender.calls != 2 || a.NextAt != pausedUntil || a.Attempts != 2 {
+		t.Fatalf("paused: calls=%d next_at=%d attempts=%d, want 2, %d, 2", sender.calls, a.NextAt, a.Attempts, pausedUntil)
+	}
+	if want := wall + pausedUntil - now.UnixMilli(); a.ExpiresAt != want {
+		t.Errorf("paused: expires_at=%d, want %d", a.ExpiresAt, want)
+	}
+
+	// After the cooldown the next failure crosses disable_after.
+	now = now.Add(11 * time.Minute)
+	run()
+	ch := channel()
+	if ch.Enabled || !strings.Contains(ch.DisabledReason, "3 consecutive failed deliveries") {
+		t.Fatalf("expected auto-disable, got enabled=%v reason=%q", ch.Enabled, ch.DisabledReason)
+	}
+
+	// The queued attempt then fails without another send.
+	run()
+	var history []delivery.History
+	database.DB().Find(&history)
+	if sender.calls != 3 || len(history) != 1 || history[0].Status != delivery.StatusFailed || history[0].LastError != errChannelDisabled.Error() {
+		t.Errorf("calls=%d history=%+v, want one failed row for the disabled channel", sender.calls, history)
+	}
+}
+
+func TestDispatcher_BreakerIgnoresRateLimits(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, _, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	channelRepo := infra.NewDeliveryChannelRepository(database.DB())
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(database.DB()), channelRepo, infra.NewPostRepository(database.DB()), &recordingSender{})
+	ch, _ := channelRepo.GetByIDAndUserID(ctx, cid, uid)
+
+	dispatcher.recordFailure(ctx, ch, markRateLimited(errors.New("429"), 0))
+	dispatcher.recordFailure(ctx, ch, errors.New("500"))
+	if got, _ := channelRepo.GetByIDAndUserID(ctx, cid, uid); got.ConsecutiveFailures != 1 {
+		t.Errorf("consecutive_failures = %d, want 1 (rate limit not counted)", got.ConsecutiveFailures)
+	}
+}
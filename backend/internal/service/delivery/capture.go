@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCapturedBodyBytes caps the upstream response body kept by a capture.
+const maxCapturedBodyBytes = 1024
+
+// maxRetryAfter caps the wait an upstream Retry-After header can ask for.
+const maxRetryAfter = 24 * time.Hour
+
+// UpstreamResponse is what the upstream service answered to the last HTTP
+// request of a send. StatusCode is 0 when no HTTP response arrived (transport
+// error, or a non-HTTP kind such as email). RetryAfter is the wait a 429 or
+// 503 response asked for in its Retry-After header, 0 if none. MessageID is
+// the platform's ID for a message the send posted, set by kinds that can
+// later edit it (see recordMessageID).
+type UpstreamResponse struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+	MessageID  string
+}
+
+type responseCaptureKey struct{}
+
+// withResponseCapture returns a context under which every delivery HTTP
+// client records its response into the returned UpstreamResponse.
+func withResponseCapture(ctx context.Context) (context.Context, *UpstreamResponse) {
+	resp := &UpstreamResponse{}
+	return context.WithValue(ctx, responseCaptureKey{}, resp), resp
+}
+
+// recordMessageID notes in the context's response capture, if any, the ID
+// the platform gave the message a send posted.
+func recordMessageID(ctx context.Context, id string) {
+	if capture, ok := ctx.Value(responseCaptureKey{}).(*UpstreamResponse); ok {
+		capture.MessageID = id
+	}
+}
+
+// newHTTPClient builds the HTTP client every channel client uses: the given
+// timeout, and a transport that fills a response capture when the request
+// context carries one.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: captureTransport{base: http.DefaultTransport}}
+}
+
+type captureTransport struct{ base http.RoundTripper }
+
+// RoundTrip records the status and the first maxCapturedBodyBytes of the body,
+// then hands the caller a body that still yields every byte.
+func (t captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	capture, ok := req.Context().Value(responseCaptureKey{}).(*UpstreamResponse)
+	if !ok {
+		return resp, nil
+	}
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, maxCapturedBodyBytes))
+	capture.StatusCode = resp.StatusCode
+	capture.Body = truncateUTF8Bytes(string(head), maxCapturedBodyBytes)
+	capture.RetryAfter = 0
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		capture.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return resp, nil
+}
+
+// parseRetryAfter reads a Retry-After header value, either delay-seconds or an
+// HTTP date, as a wait from now. A missing, malformed or past value yields 0;
+// a wait beyond maxRetryAfter is capped.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = at.Sub(now)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return min(d, maxRetryAfter)
+}
@@ -0,0 +1,126 @@
+package delivery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/infra"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"999999", maxRetryAfter},
+	}
+	for _, c := range cases {
+		if got := parseRetryAfter(c.in, now); got != c.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestCaptureTransport_RecordsRetryAfter(t *testing.T) {
+	status := http.StatusTooManyRequests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, "slow down")
+	}))
+	defer srv.Close()
+
+	client := newHTTPClient(time.Second)
+	for _, tc := range []struct {
+		status int
+		want   time.Duration
+	}{
+		{http.StatusTooManyRequests, 7 * time.Second},
+		{http.StatusServiceUnavailable, 7 * time.Second},
+		{http.StatusInternalServerError, 0},
+	} {
+		status = tc.status
+		ctx, upstream := withResponseCapture(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if upstream.StatusCode != tc.status || upstream.RetryAfter != tc.want || string(body) != "slow down" {
+			t.Errorf("status %d: capture = %+v body=%q, want retry-after %v", tc.status, upstream, body, tc.want)
+		}
+	}
+}
+
+func TestDispatcher_RecordsTries(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = io.WriteString(w, "bad gateway")
+			return
+		}
+		_, _ = io.WriteString(w, `{"code":0}`)
+	}))
+	defer srv.Close()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("configuration", makeFeishuChannelConfig(srv.URL))
+
+	ctx := context.Background()
+	attemptRepo := infra.NewAttemptRepository(db)
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second}))
+	defer dispatcher.pool.StopAndWait()
+
+	attempt := &delivery.Attempt{UserID: uid, PostID: &pid, ChannelID: cid, Status: delivery.StatusPending, NextAt: time.Now().UnixMilli(), ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}
+	if err := db.Create(attempt).Error; err != nil {
+		t.Fatalf("seed attempt: %v", err)
+	}
+	for range 2 {
+		var a delivery.Attempt
+		if err := db.First(&a, attempt.ID).Error; err != nil {
+			t.Fatalf("find attempt: %v", err)
+		}
+		dispatcher.deliver(ctx, &a)
+	}
+
+	rows, err := attemptRepo.ListHistory(ctx, delivery.HistoryFilter{OwnerID: uid}, 0, 10)
+	if err != nil || len(rows) != 1 || rows[0].Status != delivery.StatusDelivered {
+		t.Fatalf("ListHistory = %+v, %v; want one delivered entry", rows, err)
+	}
+	tries := rows[0].Tries
+	if len(tries) != 2 {
+		t.Fatalf("tries = %+v, want the failed send and the retry", tries)
+	}
+	if first := tries[0]; first.Number != 1 || first.StatusCode != http.StatusBadGateway || first.Body != "bad gateway" || first.Error == "" {
+		t.Errorf("first try = %+v", first)
+	}
+	if second := tries[1]; second.Number != 2 || second.StatusCode != http.StatusOK || second.Error != "" {
+		t.Errorf("second try = %+v", second)
+	}
+}
@@ -19,6 +19,11 @@ type UpdateChannelParams struct {
 	Name          string
 	Configuration json.RawMessage
 	Keywords      *string
+	Template      *string
+	RetryPolicy   *delivery.RetryPolicy  // an empty mode clears the override
+	Digest        *delivery.DigestPolicy // an empty window turns digests off
+	Schedule      *delivery.Schedule     // an empty start and end remove the window
+	Events        *delivery.EventSet     // an empty set means post creation only
 	Enabled       *bool
 }
 
@@ -26,48 +31,92 @@ type UpdateChannelParams struct {
 type Service struct {
 	repo        delivery.Repository
 	attemptRepo delivery.AttemptRepository
+	tester      ChannelTester
+	posts       PostLister
+	retry       RetryPolicy
+}
+
+// ChannelTester sends a synthetic message to a channel outside the attempt
+// queue. PostDeliveryService implements it.
+type ChannelTester interface {
+	SendTest(ctx context.Context, channel *delivery.Channel) (UpstreamResponse, error)
+}
+
+// TestSendResult is the outcome of a channel test send. Delivered is false
+// when the send failed; Error then carries the reason, and StatusCode/Body
+// what the upstream answered, if anything.
+type TestSendResult struct {
+	Delivered  bool
+	StatusCode int
+	Body       string
+	Error      string
 }
 
 // NewService creates a new Service instance.
 func NewService(repo delivery.Repository, attemptRepo delivery.AttemptRepository) *Service {
-	return &Service{repo: repo, attemptRepo: attemptRepo}
+	return &Service{repo: repo, attemptRepo: attemptRepo, retry: defaultRetryPolicy()}
+}
+
+// WithTester sets the ChannelTester used by TestSend.
+func (s *Service) WithTester(t ChannelTester) *Service {
+	s.tester = t
+	return s
+}
+
+// WithRetryPolicy sets the instance retry policy that attempts enqueued by the
+// service (manual retries) follow when their channel has no override.
+func (s *Service) WithRetryPolicy(p RetryPolicy) *Service {
+	s.retry = p
+	return s
 }
 
 func normalizeAndValidateKind(kind string) (delivery.ChannelKind, error) {
 	normalized := delivery.ChannelKind(utils.Normalize(kind))
-	if !normalized.IsValid() {
+	if _, ok := LookupDriver(normalized); !ok {
 		return "", service.New(service.ErrValidation, "unsupported channel kind: "+string(normalized))
 	}
 	return normalized, nil
 }
 
-func validateConfiguration(kind delivery.ChannelKind, raw json.RawMessage) (delivery.ChannelConfiguration, error) {
+// validateConfiguration parses a channel configuration and has the kind's
+// driver validate and normalize it. previous is the stored configuration when
+// updating a channel of the same kind (nil otherwise); secrets omitted from
+// raw are carried over from it.
+func validateConfiguration(kind delivery.ChannelKind, raw json.RawMessage, previous delivery.ChannelConfiguration) (delivery.ChannelConfiguration, error) {
+	driver, ok := LookupDriver(kind)
+	if !ok {
+		return nil, service.New(service.ErrValidation, "unsupported channel kind: "+string(kind))
+	}
+
 	var config delivery.ChannelConfiguration
 	if err := json.Unmarshal(raw, &config); err != nil {
 		return nil, service.New(service.ErrValidation, "invalid configuration JSON: "+err.Error())
 	}
-
-	switch kind {
-	case delivery.ChannelKindFeishu:
-		feishu := config.Feishu()
-		if strings.TrimSpace(feishu.WebhookURL) == "" {
-			return nil, service.New(service.ErrValidation, "webhook URL is required")
-		}
-		parsedURL, err := url.Parse(strings.TrimSpace(feishu.WebhookURL))
-		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
-			return nil, service.New(service.ErrValidation, "invalid webhook URL: must be a valid HTTP or HTTPS URL")
-		}
-		config["webhook_url"] = strings.TrimSpace(feishu.WebhookURL)
-		if _, ok := config["card_link_url"]; !ok {
-			config["card_link_url"] = ""
-		}
-	default:
-		return nil, service.New(service.ErrValidation, "unsupported channel kind: "+string(kind))
+	if config == nil {
+		config = delivery.ChannelConfiguration{}
 	}
+	keepSecretFields(driver.Fields(), config, previous)
 
+	if err := driver.Validate(config); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// validateWebhookURL trims and checks a required http(s) webhook URL, returning
+// the normalized value to store back into the configuration.
+func validateWebhookURL(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "", service.New(service.ErrValidation, "webhook URL is required")
+	}
+	parsedURL, err := url.Parse(trimmed)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", service.New(service.ErrValidation, "invalid webhook URL: must be a valid HTTP or HTTPS URL")
+	}
+	return trimmed, nil
+}
+
 // ListByUserID lists all delivery channels for a user.
 func (s *Service) ListByUserID(ctx context.Context, userID int) ([]delivery.Channel, error) {
 	channels, err := s.repo.GetByUserID(ctx, userID)
@@ -92,7 +141,7 @@ func (s *Service) Create(ctx context.Context, userID int, params UpdateChannelPa
 	if len(params.Configuration) == 0 {
 		return nil, service.New(service.ErrValidation, "configuration is required")
 	}
-	config, err := validateConfiguration(kind, params.Configuration)
+	config, err := validateConfiguration(kind, params.Configuration, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +154,41 @@ func (s *Service) Create(ctx context.Context, userID int, params UpdateChannelPa
 		return nil, service.New(service.ErrValidation, "invalid keywords expression: "+err.Error())
 	}
 
+	template := ""
+	if params.Template != nil {
+		if template, err = normalizeTemplate(*params.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	var retryPolicy *delivery.RetryPolicy
+	if params.RetryPolicy != nil {
+		if retryPolicy, err = normalizeRetryPolicy(params.RetryPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	var digest *delivery.DigestPolicy
+	if params.Digest != nil {
+		if digest, err = normalizeDigestPolicy(params.Digest); err != nil {
+			return nil, err
+		}
+	}
+
+	var schedule *delivery.Schedule
+	if params.Schedule != nil {
+		if schedule, err = normalizeSchedule(params.Schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	var events delivery.EventSet
+	if params.Events != nil {
+		if events, err = normalizeEvents(*params.Events); err != nil {
+			return nil, err
+		}
+	}
+
 	ch := &delivery.Channel{
 		UserID:        userID,
 		Kind:          kind,
@@ -112,6 +196,11 @@ func (s *Service) Create(ctx context.Context, userID int, params UpdateChannelPa
 		Enabled:       true,
 		Configuration: config,
 		Keywords:      keywords,
+		Template:      template,
+		RetryPolicy:   retryPolicy,
+		Digest:        digest,
+		Schedule:      schedule,
+		Events:        events,
 	}
 
 	if err := s.repo.Create(ctx, ch); err != nil {
@@ -128,15 +217,24 @@ func (s *Service) Update(ctx context.Context, userID int, id int, params UpdateC
 		return nil, service.WrapNotFoundOrInternal(err, "channel not found", "get channel failed")
 	}
 
+	previous := ch.Configuration
 	if params.Kind != "" {
 		kind, err := normalizeAndValidateKind(params.Kind)
 		if err != nil {
 			return nil, err
 		}
+		if kind != ch.Kind {
+			// The stored configuration belongs to the old kind's driver; it
+			// would be neither validated nor redacted by the new one.
+			if len(params.Configuration) == 0 {
+				return nil, service.New(service.ErrValidation, "configuration is required when changing kind")
+			}
+			previous = nil
+		}
 		ch.Kind = kind
 	}
 	if len(params.Configuration) > 0 {
-		config, err := validateConfiguration(ch.Kind, params.Configuration)
+		config, err := validateConfiguration(ch.Kind, params.Configuration, previous)
 		if err != nil {
 			return nil, err
 		}
@@ -149,16 +247,61 @@ func (s *Service) Update(ctx context.Context, userID int, id int, params UpdateC
 		}
 		ch.Keywords = normalized
 	}
-	utils.ApplyIfNonEmpty(&ch.Name, params.Name)
-	if params.Enabled != nil {
-		ch.Enabled = *params.Enabled
+	if params.Template != nil {
+		template, err := normalizeTemplate(*params.Template)
+		if err != nil {
+			return nil, err
+		}
+		ch.Template = template
+	}
+	if params.RetryPolicy != nil {
+		retryPolicy, err := normalizeRetryPolicy(params.RetryPolicy)
+		if err != nil {
+			return nil, err
+		}
+		ch.RetryPolicy = retryPolicy
+	}
+	if params.Digest != nil {
+		digest, err := normalizeDigestPolicy(params.Digest)
+		if err != nil {
+			return nil, err
+		}
+		ch.Digest = digest
+	}
+	if params.Schedule != nil {
+		schedule, err := normalizeSchedule(params.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		ch.Schedule = schedule
 	}
+	if params.Events != nil {
+		events, err := normalizeEvents(*params.Events)
+		if err != nil {
+			return nil, err
+		}
+		ch.Events = events
+	}
+	utils.ApplyIfNonEmpty(&ch.Name, params.Name)
 
 	if err := s.repo.Update(ctx, ch); err != nil {
 		return nil, service.Wrap(service.ErrInternal, "update channel failed", err)
 	}
+	if params.Enabled == nil {
+		return ch, nil
+	}
 
-	return ch, nil
+	// The enabled flag is written on its own so the edit cannot overwrite
+	// what the breaker recorded since ch was read; re-enabling gives the
+	// channel a fresh start. Read the channel back to return what is stored.
+	if err := s.repo.SetEnabled(ctx, ch.ID, *params.Enabled); err != nil {
+		return nil, service.Wrap(service.ErrInternal, "update channel failed", err)
+	}
+	updated, err := s.repo.GetByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		return nil, service.WrapNotFoundOrInternal(err, "channel not found", "get channel failed")
+	}
+	return updated, nil
 }
 
 // Delete deletes a delivery channel by ID and user ID.
@@ -175,6 +318,30 @@ func (s *Service) Delete(ctx context.Context, userID int, id int) error {
 	return nil
 }
 
+// TestSend sends a synthetic post through one of the user's channels right
+// away, bypassing the attempt table. A failed send is reported in the result,
+// not as an error; errors are for a missing channel or tester.
+func (s *Service) TestSend(ctx context.Context, userID, id int) (*TestSendResult, error) {
+	ch, err := s.repo.GetByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		return nil, service.WrapNotFoundOrInternal(err, "channel not found", "get channel failed")
+	}
+	if s.tester == nil {
+		return nil, service.New(service.ErrInternal, "channel test is not configured")
+	}
+
+	resp, err := s.tester.SendTest(ctx, ch)
+	result := &TestSendResult{
+		Delivered:  err == nil,
+		StatusCode: resp.StatusCode,
+		Body:       resp.Body,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
 // ListAll lists all delivery channels with pagination (admin use).
 func (s *Service) ListAll(ctx context.Context, offset, limit int) ([]delivery.Channel, int64, error) {
 	return service.Paginate(
@@ -194,3 +361,15 @@ func (s *Service) ListHistory(ctx context.Context, userID, channelID, offset, li
 		"delivery history",
 	)
 }
+
+// ListRoutes lists the routing decisions recorded for one of the user's
+// posts, newest event first: for each of the user's channels, whether the
+// post matched it and if not, why. A post with nothing recorded, or another
+// user's post, yields an empty list.
+func (s *Service) ListRoutes(ctx context.Context, userID int, postQID string) ([]*delivery.RouteRow, error) {
+	rows, err := s.attemptRepo.ListRoutes(ctx, postQID, userID)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "list delivery routes failed", err)
+	}
+	return rows, nil
+}
@@ -3,7 +3,9 @@ package delivery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"markpost/internal/domain/delivery"
 	"markpost/internal/infra"
@@ -67,9 +69,8 @@ func TestService_Create(t *testing.T) {
 		if !ch.Enabled {
 			t.Error("expected channel to be enabled")
 		}
-		feishu := ch.Configuration.Feishu()
-		if feishu.WebhookURL != "https://example.com/webhook" {
-			t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://example.com/webhook")
+		if got := ch.Configuration.String("webhook_url"); got != "https://example.com/webhook" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://example.com/webhook")
 		}
 	})
 
@@ -82,9 +83,25 @@ func TestService_Create(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := ch.Configuration.Feishu()
-		if feishu.CardLinkURL != "https://custom.example.com/{{.QID}}" {
-			t.Errorf("card_link_url = %q, want %q", feishu.CardLinkURL, "https://custom.example.com/{{.QID}}")
+		if got := ch.Configuration.String("card_link_url"); got != "https://custom.example.com/{{.QID}}" {
+			t.Errorf("card_link_url = %q, want %q", got, "https://custom.example.com/{{.QID}}")
+		}
+	})
+
+	t.Run("creates slack channel", func(t *testing.T) {
+		ch, err := svc.Create(ctx, 1, UpdateChannelParams{
+			Kind:          "slack",
+			Name:          "Slack Channel",
+			Configuration: json.RawMessage(`{"webhook_url":"  https://hooks.slack.com/services/T0/B0/x  "}`),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ch.Kind != delivery.ChannelKindSlack {
+			t.Errorf("kind = %q, want %q", ch.Kind, delivery.ChannelKindSlack)
+		}
+		if got := ch.Configuration.String("webhook_url"); got != "https://hooks.slack.com/services/T0/B0/x" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://hooks.slack.com/services/T0/B0/x")
 		}
 	})
 
@@ -140,7 +157,7 @@ func TestService_Create(t *testing.T) {
 	})
 
 	t.Run("rejects unsupported kind", func(t *testing.T) {
-		_, err := svc.Create(ctx, 1, UpdateChannelParams{Kind: "slack", Name: "Test", Configuration: feishuConfigJSON("https://example.com", "")})
+		_, err := svc.Create(ctx, 1, UpdateChannelParams{Kind: "sms", Name: "Test", Configuration: feishuConfigJSON("https://example.com", "")})
 		if err == nil {
 			t.Fatal("expected error for unsupported kind")
 		}
@@ -151,6 +168,46 @@ func TestService_Create(t *testing.T) {
 	})
 }
 
+func TestService_RetryPolicyOverride(t *testing.T) {
+	svc, _ := setupDeliveryService(t)
+	ctx := context.Background()
+
+	ch, err := svc.Create(ctx, 1, UpdateChannelParams{
+		Kind:          "feishu",
+		Name:          "Alerts",
+		Configuration: feishuConfigJSON("https://example.com/hook", ""),
+		RetryPolicy:   &delivery.RetryPolicy{Mode: "Exponential", Base: "30s", Max: "10m", Sequence: []string{"1m"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if ch.RetryPolicy == nil || ch.RetryPolicy.Mode != RetryModeExponential || ch.RetryPolicy.Sequence != nil {
+		t.Fatalf("retry policy = %+v, want normalized exponential policy", ch.RetryPolicy)
+	}
+
+	_, err = svc.Update(ctx, 1, ch.ID, UpdateChannelParams{RetryPolicy: &delivery.RetryPolicy{Mode: "sequence"}})
+	if se, ok := service.AsError(err); !ok || se.Code != service.ErrValidation {
+		t.Errorf("expected validation error for an empty sequence, got %v", err)
+	}
+
+	updated, err := svc.Update(ctx, 1, ch.ID, UpdateChannelParams{Name: "Renamed"})
+	if err != nil || updated.RetryPolicy == nil {
+		t.Fatalf("an update without retry_policy must keep it: %+v, %v", updated, err)
+	}
+
+	cleared, err := svc.Update(ctx, 1, ch.ID, UpdateChannelParams{RetryPolicy: &delivery.RetryPolicy{}})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if cleared.RetryPolicy != nil {
+		t.Errorf("an empty mode should clear the override, got %+v", cleared.RetryPolicy)
+	}
+	stored, _ := svc.ListByUserID(ctx, 1)
+	if len(stored) != 1 || stored[0].RetryPolicy != nil {
+		t.Errorf("stored retry policy = %+v, want cleared", stored)
+	}
+}
+
 func TestService_Update(t *testing.T) {
 	svc, repo := setupDeliveryService(t)
 	ctx := context.Background()
@@ -186,12 +243,11 @@ func TestService_Update(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := ch.Configuration.Feishu()
-		if feishu.WebhookURL != "https://new.com/webhook" {
-			t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://new.com/webhook")
+		if got := ch.Configuration.String("webhook_url"); got != "https://new.com/webhook" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://new.com/webhook")
 		}
-		if feishu.CardLinkURL != "https://custom.com/{{.QID}}" {
-			t.Errorf("card_link_url = %q, want %q", feishu.CardLinkURL, "https://custom.com/{{.QID}}")
+		if got := ch.Configuration.String("card_link_url"); got != "https://custom.com/{{.QID}}" {
+			t.Errorf("card_link_url = %q, want %q", got, "https://custom.com/{{.QID}}")
 		}
 	})
 
@@ -237,6 +293,35 @@ func TestService_Update(t *testing.T) {
 		}
 	})
 
+	t.Run("sets and clears template", func(t *testing.T) {
+		ch, err := svc.Update(ctx, 1, 1, UpdateChannelParams{Template: ptrTo("  {{.Title}} by {{.Author}}  ")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ch.Template != "{{.Title}} by {{.Author}}" {
+			t.Errorf("template = %q, want trimmed template", ch.Template)
+		}
+
+		ch, err = svc.Update(ctx, 1, 1, UpdateChannelParams{Template: ptrTo("")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ch.Template != "" {
+			t.Errorf("template = %q, want cleared", ch.Template)
+		}
+	})
+
+	t.Run("rejects template with unknown field on update", func(t *testing.T) {
+		_, err := svc.Update(ctx, 1, 1, UpdateChannelParams{Template: ptrTo("{{.Titel}}")})
+		if err == nil {
+			t.Fatal("expected error for broken template")
+		}
+		se, _ := service.AsError(err)
+		if se.Code != service.ErrValidation {
+			t.Errorf("expected code %q, got %q", service.ErrValidation.Value, se.Code.Value)
+		}
+	})
+
 	t.Run("rejects invalid webhook URL on update", func(t *testing.T) {
 		_, err := svc.Update(ctx, 1, 1, UpdateChannelParams{
 			Configuration: feishuConfigJSON("ftp://invalid", ""),
@@ -247,11 +332,95 @@ func TestService_Update(t *testing.T) {
 	})
 
 	t.Run("rejects invalid kind on update", func(t *testing.T) {
-		_, err := svc.Update(ctx, 1, 1, UpdateChannelParams{Kind: "slack"})
+		_, err := svc.Update(ctx, 1, 1, UpdateChannelParams{Kind: "sms"})
 		if err == nil {
 			t.Fatal("expected error for invalid kind")
 		}
 	})
+
+	t.Run("keeps stored secret when configuration omits it", func(t *testing.T) {
+		if _, err := svc.Update(ctx, 1, 1, UpdateChannelParams{
+			Configuration: json.RawMessage(`{"webhook_url":"https://new.com/webhook","secret":"feishu-secret"}`),
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ch, err := svc.Update(ctx, 1, 1, UpdateChannelParams{
+			Configuration: feishuConfigJSON("https://other.com/webhook", ""),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ch.Configuration.String("secret"); got != "feishu-secret" {
+			t.Errorf("secret = %q, want %q", got, "feishu-secret")
+		}
+
+		ch, err = svc.Update(ctx, 1, 1, UpdateChannelParams{
+			Configuration: json.RawMessage(`{"webhook_url":"https://other.com/webhook","secret":""}`),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ch.Configuration.String("secret"); got != "" {
+			t.Errorf("secret = %q, want cleared", got)
+		}
+	})
+
+	t.Run("does not carry the old kind's secret across a kind change", func(t *testing.T) {
+		if _, err := svc.Update(ctx, 1, 1, UpdateChannelParams{
+			Configuration: json.RawMessage(`{"webhook_url":"https://new.com/webhook","secret":"feishu-secret"}`),
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err := svc.Update(ctx, 1, 1, UpdateChannelParams{Kind: "slack"})
+		se, _ := service.AsError(err)
+		if se == nil || se.Code != service.ErrValidation {
+			t.Fatalf("kind change without configuration err = %v, want validation error", err)
+		}
+		stored, _ := repo.GetByIDAndUserID(ctx, 1, 1)
+		if stored.Kind != delivery.ChannelKindFeishu {
+			t.Errorf("kind = %q, want the rejected change not stored", stored.Kind)
+		}
+
+		ch, err := svc.Update(ctx, 1, 1, UpdateChannelParams{
+			Kind:          "slack",
+			Configuration: json.RawMessage(`{"webhook_url":"https://hooks.slack.com/services/T/B/x"}`),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := RedactConfiguration(ch.Kind, ch.Configuration)["secret"]; ok {
+			t.Errorf("redacted configuration = %v, want no feishu secret", RedactConfiguration(ch.Kind, ch.Configuration))
+		}
+		if _, ok := ch.Configuration["secret"]; ok {
+			t.Errorf("configuration = %v, want the feishu secret dropped", ch.Configuration)
+		}
+	})
+}
+
+func TestService_ReenableClearsBreakerState(t *testing.T) {
+	svc, repo := setupDeliveryService(t)
+	ctx := context.Background()
+
+	ch, err := svc.Create(ctx, 1, UpdateChannelParams{Kind: "feishu", Name: "Alerts", Configuration: feishuConfigJSON("https://example.com/hook", "")})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for range 3 {
+		_, _ = repo.RecordFailure(ctx, ch.ID, time.Now())
+	}
+	_ = repo.AutoDisable(ctx, ch.ID, "disabled after 3 consecutive failed deliveries")
+
+	enabled := true
+	updated, err := svc.Update(ctx, 1, ch.ID, UpdateChannelParams{Enabled: &enabled})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ := repo.GetByIDAndUserID(ctx, ch.ID, 1)
+	if !updated.Enabled || got.ConsecutiveFailures != 0 || got.DisabledReason != "" {
+		t.Errorf("re-enabled channel keeps breaker state: failures=%d reason=%q", got.ConsecutiveFailures, got.DisabledReason)
+	}
 }
 
 func TestService_Delete(t *testing.T) {
@@ -279,6 +448,52 @@ func TestService_Delete(t *testing.T) {
 	})
 }
 
+type stubTester struct {
+	resp UpstreamResponse
+	err  error
+}
+
+func (s stubTester) SendTest(context.Context, *delivery.Channel) (UpstreamResponse, error) {
+	return s.resp, s.err
+}
+
+func TestService_TestSend(t *testing.T) {
+	svc, repo := setupDeliveryService(t)
+	ctx := context.Background()
+
+	_ = repo.Create(ctx, &delivery.Channel{UserID: 1, Kind: delivery.ChannelKindSlack, Name: "Ch", Configuration: delivery.ChannelConfiguration{"webhook_url": "https://a.com"}})
+
+	t.Run("reports upstream failure in the result", func(t *testing.T) {
+		svc.WithTester(stubTester{resp: UpstreamResponse{StatusCode: 404, Body: "no_service"}, err: errors.New("slack webhook status=404")})
+		result, err := svc.TestSend(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Delivered || result.StatusCode != 404 || result.Body != "no_service" || result.Error == "" {
+			t.Errorf("result = %+v, want failed send with upstream status and body", result)
+		}
+	})
+
+	t.Run("reports success", func(t *testing.T) {
+		svc.WithTester(stubTester{resp: UpstreamResponse{StatusCode: 200, Body: "ok"}})
+		result, err := svc.TestSend(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Delivered || result.Error != "" {
+			t.Errorf("result = %+v, want delivered", result)
+		}
+	})
+
+	t.Run("returns not found for another user's channel", func(t *testing.T) {
+		_, err := svc.TestSend(ctx, 2, 1)
+		se, _ := service.AsError(err)
+		if se == nil || se.Code != service.ErrNotFound {
+			t.Errorf("error = %v, want not found", err)
+		}
+	})
+}
+
 func TestService_ListAll(t *testing.T) {
 	svc, repo := setupDeliveryService(t)
 	ctx := context.Background()
@@ -308,7 +523,8 @@ func TestNormalizeAndValidateKind(t *testing.T) {
 		{"lowercase feishu", "feishu", delivery.ChannelKindFeishu, false},
 		{"mixed case Feishu", "Feishu", delivery.ChannelKindFeishu, false},
 		{"whitespace padded", "  feishu  ", delivery.ChannelKindFeishu, false},
-		{"unsupported kind", "slack", "", true},
+		{"lowercase slack", "slack", delivery.ChannelKindSlack, false},
+		{"unsupported kind", "sms", "", true},
 		{"empty string", "", "", true},
 	}
 
@@ -327,68 +543,175 @@ func TestNormalizeAndValidateKind(t *testing.T) {
 
 func TestValidateConfiguration(t *testing.T) {
 	t.Run("valid feishu configuration", func(t *testing.T) {
-		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("https://example.com/hook", ""))
+		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("https://example.com/hook", ""), nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := config.Feishu()
-		if feishu.WebhookURL != "https://example.com/hook" {
-			t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://example.com/hook")
+		if got := config.String("webhook_url"); got != "https://example.com/hook" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://example.com/hook")
 		}
 	})
 
 	t.Run("valid feishu configuration with card_link_url", func(t *testing.T) {
-		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("https://example.com/hook", "https://custom.com/{{.QID}}"))
+		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("https://example.com/hook", "https://custom.com/{{.QID}}"), nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := config.Feishu()
-		if feishu.CardLinkURL != "https://custom.com/{{.QID}}" {
-			t.Errorf("card_link_url = %q, want %q", feishu.CardLinkURL, "https://custom.com/{{.QID}}")
+		if got := config.String("card_link_url"); got != "https://custom.com/{{.QID}}" {
+			t.Errorf("card_link_url = %q, want %q", got, "https://custom.com/{{.QID}}")
 		}
 	})
 
 	t.Run("defaults card_link_url to empty", func(t *testing.T) {
 		raw := json.RawMessage(`{"webhook_url":"https://example.com/hook"}`)
-		config, err := validateConfiguration(delivery.ChannelKindFeishu, raw)
+		config, err := validateConfiguration(delivery.ChannelKindFeishu, raw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := config.String("card_link_url"); got != "" {
+			t.Errorf("card_link_url = %q, want empty", got)
+		}
+	})
+
+	t.Run("trims feishu secret", func(t *testing.T) {
+		raw := json.RawMessage(`{"webhook_url":"https://example.com/hook","secret":"  abc  "}`)
+		config, err := validateConfiguration(delivery.ChannelKindFeishu, raw, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := config.Feishu()
-		if feishu.CardLinkURL != "" {
-			t.Errorf("card_link_url = %q, want empty", feishu.CardLinkURL)
+		if got := config.String("secret"); got != "abc" {
+			t.Errorf("secret = %q, want %q", got, "abc")
 		}
 	})
 
 	t.Run("rejects invalid JSON", func(t *testing.T) {
-		_, err := validateConfiguration(delivery.ChannelKindFeishu, json.RawMessage(`not json`))
+		_, err := validateConfiguration(delivery.ChannelKindFeishu, json.RawMessage(`not json`), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
 	})
 
 	t.Run("rejects empty webhook URL", func(t *testing.T) {
-		_, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("", ""))
+		_, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("", ""), nil)
 		if err == nil {
 			t.Fatal("expected error for empty webhook URL")
 		}
 	})
 
 	t.Run("rejects invalid webhook URL scheme", func(t *testing.T) {
-		_, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("ftp://example.com", ""))
+		_, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("ftp://example.com", ""), nil)
 		if err == nil {
 			t.Fatal("expected error for invalid URL scheme")
 		}
 	})
 
+	t.Run("rejects slack configuration without webhook URL", func(t *testing.T) {
+		_, err := validateConfiguration(delivery.ChannelKindSlack, json.RawMessage(`{}`), nil)
+		if err == nil {
+			t.Fatal("expected error for missing slack webhook URL")
+		}
+	})
+
+	t.Run("valid webhook configuration", func(t *testing.T) {
+		raw := json.RawMessage(`{"webhook_url":"https://example.com/hook","secret":"  0123456789abcdef  "}`)
+		config, err := validateConfiguration(delivery.ChannelKindWebhook, raw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := config.String("secret"); got != "0123456789abcdef" {
+			t.Errorf("secret = %q, want %q", got, "0123456789abcdef")
+		}
+	})
+
+	t.Run("rejects webhook configuration with short secret", func(t *testing.T) {
+		raw := json.RawMessage(`{"webhook_url":"https://example.com/hook","secret":"short"}`)
+		if _, err := validateConfiguration(delivery.ChannelKindWebhook, raw, nil); err == nil {
+			t.Fatal("expected error for short webhook secret")
+		}
+	})
+
+	t.Run("valid dingtalk configuration with optional secret", func(t *testing.T) {
+		raw := json.RawMessage(`{"webhook_url":"https://oapi.dingtalk.com/robot/send?access_token=x"}`)
+		config, err := validateConfiguration(delivery.ChannelKindDingTalk, raw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := config.String("secret"); got != "" {
+			t.Errorf("secret = %q, want empty", got)
+		}
+	})
+
+	t.Run("rejects wecom configuration with invalid webhook URL", func(t *testing.T) {
+		raw := json.RawMessage(`{"webhook_url":"qyapi.weixin.qq.com/cgi-bin/webhook/send"}`)
+		if _, err := validateConfiguration(delivery.ChannelKindWeCom, raw, nil); err == nil {
+			t.Fatal("expected error for invalid wecom webhook URL")
+		}
+	})
+
+	t.Run("normalizes email recipients", func(t *testing.T) {
+		raw := json.RawMessage(`{"recipients":["Alice <Alice@Example.com>"," bob@example.com ","alice@example.com"]}`)
+		config, err := validateConfiguration(delivery.ChannelKindEmail, raw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := config.Strings("recipients")
+		if len(got) != 2 || got[0] != "alice@example.com" || got[1] != "bob@example.com" {
+			t.Errorf("recipients = %v, want [alice@example.com bob@example.com]", got)
+		}
+	})
+
+	t.Run("rejects email configuration without recipients", func(t *testing.T) {
+		if _, err := validateConfiguration(delivery.ChannelKindEmail, json.RawMessage(`{"recipients":[]}`), nil); err == nil {
+			t.Fatal("expected error for empty recipient list")
+		}
+	})
+
+	t.Run("rejects invalid email recipient", func(t *testing.T) {
+		if _, err := validateConfiguration(delivery.ChannelKindEmail, json.RawMessage(`{"recipients":["not-an-address"]}`), nil); err == nil {
+			t.Fatal("expected error for invalid recipient")
+		}
+	})
+
+	t.Run("normalizes numeric telegram chat ID", func(t *testing.T) {
+		raw := json.RawMessage(`{"bot_token":" 123456:ABC-def_1 ","chat_id":-1001234567890}`)
+		config, err := validateConfiguration(delivery.ChannelKindTelegram, raw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.String("chat_id") != "-1001234567890" || config.String("bot_token") != "123456:ABC-def_1" {
+			t.Errorf("configuration = %v, want normalized chat ID and trimmed token", config)
+		}
+	})
+
+	t.Run("rejects telegram configuration with invalid chat ID", func(t *testing.T) {
+		raw := json.RawMessage(`{"bot_token":"123456:ABC","chat_id":"not a chat"}`)
+		if _, err := validateConfiguration(delivery.ChannelKindTelegram, raw, nil); err == nil {
+			t.Fatal("expected error for invalid chat ID")
+		}
+	})
+
+	t.Run("rejects telegram configuration with invalid bot token", func(t *testing.T) {
+		raw := json.RawMessage(`{"bot_token":"nope","chat_id":"@markpost_news"}`)
+		if _, err := validateConfiguration(delivery.ChannelKindTelegram, raw, nil); err == nil {
+			t.Fatal("expected error for invalid bot token")
+		}
+	})
+
+	t.Run("rejects discord and teams configuration without webhook URL", func(t *testing.T) {
+		for _, kind := range []delivery.ChannelKind{delivery.ChannelKindDiscord, delivery.ChannelKindTeams} {
+			if _, err := validateConfiguration(kind, json.RawMessage(`{}`), nil); err == nil {
+				t.Errorf("%s: expected error for missing webhook URL", kind)
+			}
+		}
+	})
+
 	t.Run("trims whitespace from webhook URL", func(t *testing.T) {
-		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("  https://example.com/hook  ", ""))
+		config, err := validateConfiguration(delivery.ChannelKindFeishu, feishuConfigJSON("  https://example.com/hook  ", ""), nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		feishu := config.Feishu()
-		if feishu.WebhookURL != "https://example.com/hook" {
-			t.Errorf("webhook_url = %q, want %q", feishu.WebhookURL, "https://example.com/hook")
+		if got := config.String("webhook_url"); got != "https://example.com/hook" {
+			t.Errorf("webhook_url = %q, want %q", got, "https://example.com/hook")
 		}
 	})
 }
@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/service"
+)
+
+// Bounds for a channel's digest policy. maxDigestPosts also caps every digest
+// message, so a channel with no max_posts cannot build one longer than a
+// platform accepts.
+const (
+	minDigestWindow = time.Minute
+	maxDigestWindow = 24 * time.Hour
+	minDigestPosts  = 2
+	maxDigestPosts  = 50
+	digestBatchSize = 256
+)
+
+// DigestPolicy is a channel's parsed digest setting: how long a digest stays
+// open and how many posts close it early.
+type DigestPolicy struct {
+	Window   time.Duration
+	MaxPosts int
+}
+
+// channelDigestPolicy parses and bounds-checks a channel's digest setting. A
+// zero MaxPosts becomes maxDigestPosts.
+func channelDigestPolicy(o *delivery.DigestPolicy) (DigestPolicy, error) {
+	window, err := time.ParseDuration(strings.TrimSpace(o.Window))
+	if err != nil {
+		return DigestPolicy{}, fmt.Errorf("window: invalid duration %q", o.Window)
+	}
+	if window < minDigestWindow || window > maxDigestWindow {
+		return DigestPolicy{}, fmt.Errorf("window: %s is outside %s to %s", o.Window, minDigestWindow, maxDigestWindow)
+	}
+	p := DigestPolicy{Window: window, MaxPosts: o.MaxPosts}
+	if p.MaxPosts == 0 {
+		p.MaxPosts = maxDigestPosts
+	}
+	if p.MaxPosts < minDigestPosts || p.MaxPosts > maxDigestPosts {
+		return DigestPolicy{}, fmt.Errorf("max_posts must be between %d and %d", minDigestPosts, maxDigestPosts)
+	}
+	return p, nil
+}
+
+// normalizeDigestPolicy validates a channel's digest setting for storage. An
+// empty window clears it (nil), returning the channel to one message per post.
+func normalizeDigestPolicy(o *delivery.DigestPolicy) (*delivery.DigestPolicy, error) {
+	window := strings.TrimSpace(o.Window)
+	if window == "" {
+		return nil, nil
+	}
+	normalized := &delivery.DigestPolicy{Window: window, MaxPosts: o.MaxPosts}
+	if _, err := channelDigestPolicy(normalized); err != nil {
+		return nil, service.New(service.ErrValidation, "invalid digest: "+err.Error())
+	}
+	return normalized, nil
+}
+
+// resolveDigestPolicy reports whether the channel delivers in digests and
+// under which policy. A setting that no longer parses is ignored, so the
+// channel falls back to one message per post rather than dropping posts.
+func resolveDigestPolicy(ch *delivery.Channel) (DigestPolicy, bool) {
+	if ch.Digest == nil {
+		return DigestPolicy{}, false
+	}
+	p, err := channelDigestPolicy(ch.Digest)
+	if err != nil {
+		log.Printf("delivery: ignore invalid digest policy channel_id=%d err=%v", ch.ID, err)
+		return DigestPolicy{}, false
+	}
+	return p, true
+}
+
+// groupDigestItems splits due items, ordered by channel, into one group per
+// digest message: a channel's items, in chunks of at most maxDigestPosts.
+func groupDigestItems(items []*delivery.DigestItem) [][]*delivery.DigestItem {
+	var groups [][]*delivery.DigestItem
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && end-start < maxDigestPosts && items[end].ChannelID == items[start].ChannelID {
+			end++
+		}
+		groups = append(groups, items[start:end])
+		start = end
+	}
+	return groups
+}
+
+// digestTitle is the headline of a digest message.
+func digestTitle(n int) string {
+	if n == 1 {
+		return "1 new post"
+	}
+	return fmt.Sprintf("%d new posts", n)
+}
+
+// digestListing renders a digest's posts as a plain-text list, one "- title
+// link" line per post, and as an HTML list for kinds that send HTML.
+func digestListing(entries []PostEntry) (text, listHTML string) {
+	var t, h strings.Builder
+	h.WriteString("<ul>")
+	for _, e := range entries {
+		fmt.Fprintf(&t, "- %s %s\n", e.Title, e.URL)
+		fmt.Fprintf(&h, `<li><a href="%s">%s</a></li>`, html.EscapeString(e.URL), html.EscapeString(e.Title))
+	}
+	h.WriteString("</ul>")
+	return strings.TrimSpace(t.String()), h.String()
+}
@@ -0,0 +1,221 @@
+package delivery
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/infra"
+	"markpost/internal/service"
+)
+
+type digestSender struct {
+	mu      sync.Mutex
+	digests [][]string
+}
+
+func (s *digestSender) Send(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	return nil
+}
+
+func (s *digestSender) SendEvent(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt, _ string) error {
+	return nil
+}
+
+func (s *digestSender) SendDigest(_ context.Context, posts []*domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	titles := make([]string, len(posts))
+	for i, p := range posts {
+		titles[i] = p.Title
+	}
+	s.digests = append(s.digests, titles)
+	return nil
+}
+
+func TestNormalizeDigestPolicy(t *testing.T) {
+	cleared, err := normalizeDigestPolicy(&delivery.DigestPolicy{Window: "  "})
+	if err != nil || cleared != nil {
+		t.Errorf("empty window = %+v, %v; want nil, nil", cleared, err)
+	}
+
+	got, err := normalizeDigestPolicy(&delivery.DigestPolicy{Window: " 30m ", MaxPosts: 10})
+	if err != nil || got.Window != "30m" || got.MaxPosts != 10 {
+		t.Errorf("normalize = %+v, %v", got, err)
+	}
+
+	for _, bad := range []delivery.DigestPolicy{
+		{Window: "soon"},
+		{Window: "10s"},
+		{Window: "48h"},
+		{Window: "1h", MaxPosts: 1},
+		{Window: "1h", MaxPosts: maxDigestPosts + 1},
+	} {
+		_, err := normalizeDigestPolicy(&bad)
+		assertErrCode(t, err, service.ErrValidation)
+	}
+
+	p, err := channelDigestPolicy(&delivery.DigestPolicy{Window: "1h"})
+	if err != nil || p.Window != time.Hour || p.MaxPosts != maxDigestPosts {
+		t.Errorf("channelDigestPolicy = %+v, %v; want 1h and the cap", p, err)
+	}
+}
+
+func TestGroupDigestItems(t *testing.T) {
+	var items []*delivery.DigestItem
+	for range maxDigestPosts + 2 {
+		items = append(items, &delivery.DigestItem{ChannelID: 1})
+	}
+	items = append(items, &delivery.DigestItem{ChannelID: 2})
+
+	groups := groupDigestItems(items)
+	if len(groups) != 3 {
+		t.Fatalf("groups = %d, want 3", len(groups))
+	}
+	if len(groups[0]) != maxDigestPosts || len(groups[1]) != 2 || len(groups[2]) != 1 || groups[2][0].ChannelID != 2 {
+		t.Errorf("group sizes = %d/%d/%d", len(groups[0]), len(groups[1]), len(groups[2]))
+	}
+}
+
+func TestDigestListing(t *testing.T) {
+	text, listHTML := digestListing([]PostEntry{
+		{Title: "Build <failed>", URL: "https://m.example/p-1"},
+		{Title: "Deployed", URL: "https://m.example/p-2"},
+	})
+	if text != "- Build <failed> https://m.example/p-1\n- Deployed https://m.example/p-2" {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(listHTML, `<li><a href="https://m.example/p-1">Build &lt;failed&gt;</a></li>`) {
+		t.Errorf("html = %q", listHTML)
+	}
+	if digestTitle(1) != "1 new post" || digestTitle(3) != "3 new posts" {
+		t.Errorf("titles = %q, %q", digestTitle(1), digestTitle(3))
+	}
+}
+
+func TestDispatcher_Digest(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("digest", &delivery.DigestPolicy{Window: "10m", MaxPosts: 3})
+	second := &domainpost.Post{QID: "p-second", Title: "Disk Alert", Body: "full", UserID: uid}
+	if err := db.Create(second).Error; err != nil {
+		t.Fatalf("seed post: %v", err)
+	}
+
+	ctx := context.Background()
+	sender := &digestSender{}
+	attemptRepo := infra.NewAttemptRepository(db)
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), sender)
+	defer dispatcher.pool.StopAndWait()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+	now = now.Add(time.Minute)
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: second.ID, PostQID: "p-second", Title: "Disk Alert"})
+
+	var attempts int64
+	db.Model(&delivery.Attempt{}).Count(&attempts)
+	if attempts != 0 {
+		t.Fatalf("digest channel produced %d attempts at enqueue, want 0", attempts)
+	}
+	if len(dispatcher.wake) != 0 {
+		t.Error("an open digest woke the scheduler")
+	}
+
+	// Still inside the window, which started with the first post.
+	now = now.Add(8 * time.Minute)
+	if sealed := dispatcher.flushDigests(ctx, now.UnixMilli()); len(sealed) != 0 {
+		t.Fatalf("sealed %d digests before the window closed", len(sealed))
+	}
+
+	now = now.Add(time.Minute)
+	sealed := dispatcher.flushDigests(ctx, now.UnixMilli())
+	if len(sealed) != 1 || sealed[0].DigestSize != 2 || *sealed[0].PostID != pid {
+		t.Fatalf("sealed = %+v, want one digest of 2 keyed to the oldest post", sealed)
+	}
+
+	dispatcher.deliver(ctx, sealed[0])
+	if len(sender.digests) != 1 || strings.Join(sender.digests[0], ",") != "Server Alert,Disk Alert" {
+		t.Errorf("digests sent = %v", sender.digests)
+	}
+
+	var history []delivery.History
+	db.Order("id").Find(&history)
+	if len(history) != 2 || *history[0].PostID != pid || *history[1].PostID != second.ID || history[1].Status != delivery.StatusDelivered {
+		t.Errorf("history = %+v, want one delivered row per post", history)
+	}
+	var left int64
+	db.Model(&delivery.DigestItem{}).Count(&left)
+	db.Model(&delivery.Attempt{}).Count(&attempts)
+	if left != 0 || attempts != 0 {
+		t.Errorf("left %d digest items and %d attempts after delivery", left, attempts)
+	}
+}
+
+func TestDispatcher_DigestFullWakes(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("digest", &delivery.DigestPolicy{Window: "1h", MaxPosts: 2})
+
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(db), infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), &digestSender{})
+	defer dispatcher.pool.StopAndWait()
+
+	job := domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"}
+	dispatcher.Enqueue(job)
+	dispatcher.Enqueue(job)
+	if len(dispatcher.wake) != 1 {
+		t.Fatal("a full digest did not wake the scheduler")
+	}
+	sealed := dispatcher.flushDigests(context.Background(), dispatcher.now().UnixMilli())
+	if len(sealed) != 1 || sealed[0].DigestSize != 2 {
+		t.Errorf("sealed = %+v, want the full digest due at once", sealed)
+	}
+}
+
+func TestDispatcher_StopKeepsOpenDigests(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("digest", &delivery.DigestPolicy{Window: "24h"})
+
+	sender := &digestSender{}
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(db), infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), sender)
+	dispatcher.cfg.ScanInterval = time.Hour
+	dispatcher.Start(context.Background())
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+	dispatcher.Stop()
+
+	if len(sender.digests) != 0 {
+		t.Fatalf("digests sent on stop = %d, want 0 before the window closes", len(sender.digests))
+	}
+	var open int64
+	db.Model(&delivery.DigestItem{}).Where("attempt_id IS NULL").Count(&open)
+	if open != 1 {
+		t.Errorf("open digest items = %d, want 1 kept for the next instance", open)
+	}
+}
@@ -0,0 +1,159 @@
+package delivery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// dingtalkErrCodes classifies DingTalk robot errcodes: those retrying cannot
+// fix are permanent, rate limiting backs off and retries. Unlisted codes are
+// transient.
+var dingtalkErrCodes = map[int]FailureKind{
+	130101: FailureRateLimited, // send too fast
+	300001: FailurePermanent,   // token is not exist
+	300005: FailurePermanent,   // token is not exist (robot deleted)
+	310000: FailurePermanent,   // keywords not in content / sign not match / ip not in whitelist
+	400013: FailurePermanent,   // group has been dissolved
+}
+
+// DingTalkClient sends messages to DingTalk group-robot webhook URLs.
+type DingTalkClient struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewDingTalkClient creates a DingTalkClient with the given request timeout.
+func NewDingTalkClient(timeout time.Duration) *DingTalkClient {
+	return &DingTalkClient{
+		httpClient: newHTTPClient(timeout),
+		now:        time.Now,
+	}
+}
+
+type dingtalkActionCardPayload struct {
+	MsgType    string             `json:"msgtype"`
+	ActionCard dingtalkActionCard `json:"actionCard"`
+}
+
+type dingtalkActionCard struct {
+	Title          string `json:"title"`
+	Text           string `json:"text"`
+	BtnOrientation string `json:"btnOrientation"`
+	SingleTitle    string `json:"singleTitle,omitempty"`
+	SingleURL      string `json:"singleURL,omitempty"`
+}
+
+// DingTalkDeliveryParams holds the parameters for sending a DingTalk card.
+type DingTalkDeliveryParams struct {
+	WebhookURL  string
+	Secret      string
+	PostURL     string
+	PostTitle   string
+	ButtonText  string
+	BodyPreview string
+}
+
+// SendCard posts an actionCard message (markdown title + body preview with a
+// single "View Post" button) to the given DingTalk robot. When a signing
+// secret is configured the webhook URL is signed per send.
+func (c *DingTalkClient) SendCard(ctx context.Context, params DingTalkDeliveryParams) error {
+	webhookURL, err := c.signedURL(params.WebhookURL, params.Secret)
+	if err != nil {
+		return err
+	}
+
+	text := "### " + params.PostTitle
+	if params.BodyPreview != "" {
+		text += "\n\n" + params.BodyPreview
+	}
+
+	card := dingtalkActionCard{
+		Title:          params.PostTitle,
+		Text:           text,
+		BtnOrientation: "0",
+	}
+	if params.PostURL != "" {
+		card.SingleTitle = buttonLabel(params.ButtonText)
+		card.SingleURL = params.PostURL
+	}
+
+	payload := dingtalkActionCardPayload{MsgType: "actionCard", ActionCard: card}
+	return sendRobotRequest(ctx, c.httpClient, "dingtalk", webhookURL, payload, dingtalkErrCodes)
+}
+
+// signedURL appends DingTalk's timestamp and sign query parameters when a
+// secret is set. sign = base64(HMAC-SHA256(secret, timestamp + "\n" + secret)),
+// with timestamp in Unix milliseconds; DingTalk rejects signatures older than
+// one hour.
+func (c *DingTalkClient) signedURL(webhookURL, secret string) (string, error) {
+	if strings.TrimSpace(secret) == "" {
+		return webhookURL, nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("dingtalk parse webhook url: %w", err)
+	}
+	timestamp := strconv.FormatInt(c.now().UnixMilli(), 10)
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("sign", dingtalkSign(secret, timestamp))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func dingtalkSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// dingtalkDriver is the ChannelDriver for DingTalk group robots.
+type dingtalkDriver struct{}
+
+func (dingtalkDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindDingTalk }
+
+func (dingtalkDriver) Fields() []ConfigField {
+	return []ConfigField{
+		webhookURLField("DingTalk robot webhook URL, including access_token"),
+		{Key: "secret", Type: FieldTypeSecret, Secret: true, Description: "Signing secret (加签), if the robot uses it"},
+	}
+}
+
+func (dingtalkDriver) Validate(config delivery.ChannelConfiguration) error {
+	if err := validateWebhookURLField(config); err != nil {
+		return err
+	}
+	config["secret"] = strings.TrimSpace(config.String("secret"))
+	return nil
+}
+
+func (d dingtalkDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (dingtalkDriver) NewSender(opts SenderOptions) KindSender {
+	return dingtalkSender{client: NewDingTalkClient(opts.Timeout)}
+}
+
+type dingtalkSender struct{ client *DingTalkClient }
+
+func (s dingtalkSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendCard(ctx, DingTalkDeliveryParams{
+		WebhookURL:  msg.Channel.Configuration.String("webhook_url"),
+		Secret:      msg.Channel.Configuration.String("secret"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.Content(),
+	})
+}
@@ -0,0 +1,125 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDingTalkClient_SendCard(t *testing.T) {
+	t.Run("sends action card with view post button", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}))
+		defer server.Close()
+
+		client := NewDingTalkClient(5 * time.Second)
+		err := client.SendCard(context.Background(), DingTalkDeliveryParams{
+			WebhookURL:  server.URL + "/robot/send?access_token=abc",
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "Test Title",
+			BodyPreview: "Preview",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if receivedBody["msgtype"] != "actionCard" {
+			t.Errorf("msgtype = %v, want %q", receivedBody["msgtype"], "actionCard")
+		}
+		card := receivedBody["actionCard"].(map[string]any)
+		if card["title"] != "Test Title" {
+			t.Errorf("title = %v, want %q", card["title"], "Test Title")
+		}
+		if card["text"] != "### Test Title\n\nPreview" {
+			t.Errorf("text = %q, want markdown heading + preview", card["text"])
+		}
+		if card["singleURL"] != "https://example.com/p-abc" {
+			t.Errorf("singleURL = %v, want %q", card["singleURL"], "https://example.com/p-abc")
+		}
+	})
+
+	t.Run("signs webhook URL when secret is set", func(t *testing.T) {
+		var query url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}))
+		defer server.Close()
+
+		client := NewDingTalkClient(5 * time.Second)
+		client.now = func() time.Time { return time.UnixMilli(1700000000123) }
+		err := client.SendCard(context.Background(), DingTalkDeliveryParams{
+			WebhookURL: server.URL + "/robot/send?access_token=abc",
+			Secret:     "SECtest",
+			PostTitle:  "t",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if query.Get("access_token") != "abc" {
+			t.Errorf("access_token = %q, want it preserved", query.Get("access_token"))
+		}
+		if query.Get("timestamp") != "1700000000123" {
+			t.Errorf("timestamp = %q, want %q", query.Get("timestamp"), "1700000000123")
+		}
+		if want := dingtalkSign("SECtest", "1700000000123"); query.Get("sign") != want {
+			t.Errorf("sign = %q, want %q", query.Get("sign"), want)
+		}
+	})
+
+	t.Run("leaves webhook URL unsigned without secret", func(t *testing.T) {
+		var query url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			_, _ = w.Write([]byte(`{"errcode":0}`))
+		}))
+		defer server.Close()
+
+		client := NewDingTalkClient(5 * time.Second)
+		if err := client.SendCard(context.Background(), DingTalkDeliveryParams{WebhookURL: server.URL, PostTitle: "t"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if query.Has("sign") || query.Has("timestamp") {
+			t.Errorf("expected no signature parameters, got %v", query)
+		}
+	})
+
+	t.Run("maps errcodes to retryable and permanent failures", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			body      string
+			permanent bool
+		}{
+			{"rate limited is retryable", `{"errcode":130101,"errmsg":"send too fast"}`, false},
+			{"missing token is permanent", `{"errcode":300001,"errmsg":"token is not exist"}`, true},
+			{"sign mismatch is permanent", `{"errcode":310000,"errmsg":"sign not match"}`, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(tt.body))
+				}))
+				defer server.Close()
+
+				client := NewDingTalkClient(5 * time.Second)
+				err := client.SendCard(context.Background(), DingTalkDeliveryParams{WebhookURL: server.URL, PostTitle: "t"})
+				if err == nil {
+					t.Fatal("expected error for non-zero errcode")
+				}
+				if got := isPermanent(err); got != tt.permanent {
+					t.Errorf("isPermanent = %v, want %v (err=%v)", got, tt.permanent, err)
+				}
+			})
+		}
+	})
+}
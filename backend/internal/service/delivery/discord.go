@@ -0,0 +1,145 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// Discord embed limits: title 256 characters, description 4096. Exceeding
+// either makes Discord reject the whole message with HTTP 400.
+const (
+	discordEmbedTitleMaxChars       = 256
+	discordEmbedDescriptionMaxChars = 4096
+)
+
+// discordEmbedColor is the embed's left accent bar (markpost blue).
+const discordEmbedColor = 0x3B82F6
+
+// DiscordClient sends embeds to Discord webhook URLs.
+type DiscordClient struct {
+	httpClient *http.Client
+}
+
+// NewDiscordClient creates a DiscordClient with the given request timeout.
+func NewDiscordClient(timeout time.Duration) *DiscordClient {
+	return &DiscordClient{
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type discordPayload struct {
+	Embeds          []discordEmbed         `json:"embeds"`
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp,omitempty"`
+}
+
+// discordAllowedMentions with an empty Parse list disables every mention, so
+// "@everyone" in a post body cannot ping a whole server.
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// DiscordMessageParams holds the parameters for sending a Discord embed.
+type DiscordMessageParams struct {
+	WebhookURL  string
+	PostURL     string
+	PostTitle   string
+	BodyPreview string
+	CreatedAt   time.Time
+}
+
+// SendEmbed posts a single embed (linked title, body preview description,
+// post creation timestamp) to the given Discord webhook URL.
+func (c *DiscordClient) SendEmbed(ctx context.Context, params DiscordMessageParams) error {
+	embed := discordEmbed{
+		Title:       truncateRunes(params.PostTitle, discordEmbedTitleMaxChars),
+		Description: truncateRunes(params.BodyPreview, discordEmbedDescriptionMaxChars),
+		URL:         params.PostURL,
+		Color:       discordEmbedColor,
+	}
+	if !params.CreatedAt.IsZero() {
+		embed.Timestamp = params.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	return c.sendRequest(ctx, params.WebhookURL, discordPayload{
+		Embeds:          []discordEmbed{embed},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	})
+}
+
+// sendRequest posts the payload to a Discord webhook, which answers 204 on
+// success. 404 (webhook deleted) and other request errors are permanent; 429
+// and 5xx are retried.
+func (c *DiscordClient) sendRequest(ctx context.Context, webhookURL string, payload any) error {
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("discord marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("discord create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("discord webhook status=%d body=%s", resp.StatusCode, string(b)))
+	}
+
+	return nil
+}
+
+// discordDriver is the ChannelDriver for Discord channel webhook.
+type discordDriver struct{}
+
+func (discordDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindDiscord }
+
+func (discordDriver) Fields() []ConfigField {
+	return []ConfigField{webhookURLField("Discord channel webhook URL")}
+}
+
+func (discordDriver) Validate(config delivery.ChannelConfiguration) error {
+	return validateWebhookURLField(config)
+}
+
+func (d discordDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (discordDriver) NewSender(opts SenderOptions) KindSender {
+	return discordSender{client: NewDiscordClient(opts.Timeout)}
+}
+
+type discordSender struct{ client *DiscordClient }
+
+func (s discordSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendEmbed(ctx, DiscordMessageParams{
+		WebhookURL:  msg.Channel.Configuration.String("webhook_url"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		BodyPreview: msg.ContentWithin(discordEmbedDescriptionMaxChars),
+		CreatedAt:   msg.Post.CreatedAt,
+	})
+}
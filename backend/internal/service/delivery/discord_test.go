@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscordClient_SendEmbed(t *testing.T) {
+	t.Run("sends embed with mentions disabled", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := NewDiscordClient(5 * time.Second)
+		err := client.SendEmbed(context.Background(), DiscordMessageParams{
+			WebhookURL:  server.URL,
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   strings.Repeat("标", 300),
+			BodyPreview: "@everyone look",
+			CreatedAt:   time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		embed := receivedBody["embeds"].([]any)[0].(map[string]any)
+		if n := len([]rune(embed["title"].(string))); n != discordEmbedTitleMaxChars {
+			t.Errorf("title length = %d, want %d", n, discordEmbedTitleMaxChars)
+		}
+		if embed["url"] != "https://example.com/p-abc" {
+			t.Errorf("url = %v, want post URL", embed["url"])
+		}
+		if embed["timestamp"] != "2026-05-01T08:00:00Z" {
+			t.Errorf("timestamp = %v, want %q", embed["timestamp"], "2026-05-01T08:00:00Z")
+		}
+		parse, ok := receivedBody["allowed_mentions"].(map[string]any)["parse"].([]any)
+		if !ok || len(parse) != 0 {
+			t.Errorf("allowed_mentions = %v, want empty parse list", receivedBody["allowed_mentions"])
+		}
+	})
+
+	t.Run("deleted webhook is permanent", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Unknown Webhook","code":10015}`))
+		}))
+		defer server.Close()
+
+		client := NewDiscordClient(5 * time.Second)
+		err := client.SendEmbed(context.Background(), DiscordMessageParams{WebhookURL: server.URL, PostTitle: "t"})
+		if err == nil || !isPermanent(err) {
+			t.Fatalf("error = %v, want permanent error", err)
+		}
+	})
+}
@@ -1,13 +1,17 @@
-// Package delivery provides delivery channel business logic and services.
 package delivery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"os"
+	"sync"
 	"time"
 
 	"markpost/internal/config"
+	"markpost/internal/domain"
 	"markpost/internal/domain/delivery"
 	domainpost "markpost/internal/domain/post"
 	"markpost/internal/service/delivery/filter"
@@ -16,26 +20,45 @@ import (
 )
 
 const (
-	claimBatchSize     = 64
-	expireBatchSize    = 64
-	claimReserveBuffer = 500 * time.Millisecond
+	claimBatchSize  = 64
+	expireBatchSize = 64
+	defaultLeaseTTL = 30 * time.Second
 )
 
 // AttemptRepo is the subset of delivery.AttemptRepository the dispatcher uses.
 // Declared locally so tests can substitute a fake without a database.
 type AttemptRepo interface {
 	Create(ctx context.Context, attempts []*delivery.Attempt) error
-	ClaimDue(ctx context.Context, now, reserveUntilMs int64, limit int) ([]*delivery.Attempt, error)
-	MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs int64) error
-	MarkExpired(ctx context.Context, wallBeforeMs int64, batchSize int) ([]*delivery.Attempt, error)
+	ClaimDue(ctx context.Context, owner string, now, leaseUntilMs int64, limit int) ([]*delivery.Attempt, error)
+	MarkRetry(ctx context.Context, id int64, attempts int, lastError string, nextAtMs, expiresAtMs int64) error
+	Hold(ctx context.Context, id int64, nextAtMs, expiresAtMs int64) error
+	RenewLeases(ctx context.Context, owner string, ids []int64, untilMs int64) (int64, error)
+	ReleaseLease(ctx context.Context, id int64, owner string) error
+	ReleaseLeases(ctx context.Context, owner string) (int64, error)
+	MarkExpired(ctx context.Context, nowMs int64, batchSize int) ([]*delivery.Attempt, error)
 	ArchiveAndDelete(ctx context.Context, attempt *delivery.Attempt, status delivery.Status, lastError string) error
+	DeferChannel(ctx context.Context, channelID int, untilMs int64) (int64, error)
+	AddToDigest(ctx context.Context, item *delivery.DigestItem, maxPosts int) (bool, error)
+	ListDueDigests(ctx context.Context, nowMs int64, limit int) ([]*delivery.DigestItem, error)
+	SealDigest(ctx context.Context, attempt *delivery.Attempt, itemIDs []int64) (int64, error)
+	DigestPostIDs(ctx context.Context, attemptID int64) ([]int, error)
+	RecordMessage(ctx context.Context, m *delivery.Message) error
+	FindMessage(ctx context.Context, channelID int, postQID string) (*delivery.Message, error)
+	RecordTry(ctx context.Context, t *delivery.Try) error
+	RecordRoutes(ctx context.Context, routes []*delivery.Route) error
 }
 
 // ChannelRepo fetches delivery channels (for enqueue-time filtering) and
-// individual channels (for the worker's send path).
+// individual channels (for the worker's send path), and records channel
+// health for the circuit breaker.
 type ChannelRepo interface {
 	GetByUserID(ctx context.Context, userID int) ([]delivery.Channel, error)
 	GetByIDAndUserID(ctx context.Context, id int, userID int) (*delivery.Channel, error)
+	RecordSuccess(ctx context.Context, id int, at time.Time) error
+	RecordFailure(ctx context.Context, id int, at time.Time) (int, error)
+	Pause(ctx context.Context, id int, untilMs int64) error
+	AutoDisable(ctx context.Context, id int, reason string) error
+	HasSubscribers(ctx context.Context, event domainpost.Event) (bool, error)
 }
 
 // PostRepo fetches posts by ID for the worker's send path. A delivery attempt
@@ -47,9 +70,17 @@ type PostRepo interface {
 
 // Sender issues the channel notification for a claimed attempt. It returns nil
 // on success (the attempt is archived as delivered) or a non-nil error on
-// failure (the dispatcher applies backoff or fails the attempt).
+// failure (the dispatcher applies backoff or fails the attempt). The attempt is
+// passed so senders can derive retry-stable metadata (e.g. an idempotency key)
+// from it; it must be treated as read-only. SendDigest sends a digest
+// attempt's posts, oldest first, as one combined message. SendEvent announces
+// a lifecycle event other than creation (the attempt's Event) of p, which for
+// a removed post is what its snapshot kept; messageID is the message that
+// announced the post on the channel earlier, or empty.
 type Sender interface {
-	Send(ctx context.Context, p *domainpost.Post, channel *delivery.Channel) error
+	Send(ctx context.Context, p *domainpost.Post, channel *delivery.Channel, attempt *delivery.Attempt) error
+	SendDigest(ctx context.Context, posts []*domainpost.Post, channel *delivery.Channel, attempt *delivery.Attempt) error
+	SendEvent(ctx context.Context, p *domainpost.Post, channel *delivery.Channel, attempt *delivery.Attempt, messageID string) error
 }
 
 // Dispatcher implements domainpost.DeliveryEnqueuer on top of the persistent
@@ -57,19 +88,30 @@ type Sender interface {
 // drained by a ScanInterval-tick scheduler that claims due rows and dispatches
 // them to a bounded pond v2 worker pool. Delivery is at-least-once and
 // survives process restarts — all pending state lives in the database.
+// Claimed rows are leased to this instance (owner) and, while they are queued
+// or in flight, the lease is renewed every third of its TTL until the row is
+// archived or rescheduled.
 type Dispatcher struct {
 	attemptRepo AttemptRepo
 	channelRepo ChannelRepo
 	postRepo    PostRepo
 	sender      Sender
 	cfg         config.DeliveryConfig
-
-	pool   pond.Pool
-	ticker *time.Ticker
-	stop   chan struct{}
-	done   chan struct{}
-
-	now func() time.Time
+	retry       RetryPolicy
+	owner       string
+	leaseTTL    time.Duration
+
+	pool      pond.Pool
+	mu        sync.Mutex
+	inflight  map[int64]struct{}
+	ticker    *time.Ticker
+	heartbeat *time.Ticker
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+
+	now  func() time.Time
+	rand func() float64
 }
 
 // NewDispatcher constructs a dispatcher over the given repos and sender. The
@@ -87,6 +129,15 @@ func NewDispatcher(attemptRepo AttemptRepo, channelRepo ChannelRepo, postRepo Po
 		queueSize = 1024
 	}
 
+	leaseTTL := cfg.LeaseTTL
+	if leaseTTL <= 0 {
+		leaseTTL = defaultLeaseTTL
+	}
+	owner := cfg.InstanceID
+	if owner == "" {
+		owner = newInstanceID()
+	}
+
 	pool := pond.NewPool(
 		workers,
 		pond.WithQueueSize(queueSize),
@@ -99,18 +150,30 @@ func NewDispatcher(attemptRepo AttemptRepo, channelRepo ChannelRepo, postRepo Po
 		postRepo:    postRepo,
 		sender:      sender,
 		cfg:         cfg,
+		retry:       RetryPolicyFromConfig(cfg.Retry),
+		owner:       owner,
+		leaseTTL:    leaseTTL,
 		pool:        pool,
+		inflight:    make(map[int64]struct{}),
+		wake:        make(chan struct{}, 1),
 		stop:        make(chan struct{}),
 		done:        make(chan struct{}),
 		now:         time.Now,
+		rand:        rand.Float64,
 	}
 }
 
-// Enqueue matches a freshly-created post against the author's enabled delivery
-// channels and inserts a pending attempt row per matching channel. The keyword
-// filter runs before persistence, so only channels that actually match produce
-// attempt rows. Enqueue is best-effort: any error is logged and swallowed so a
-// delivery failure can never fail post creation.
+// Enqueue matches a post lifecycle event against the author's enabled
+// delivery channels subscribed to it and inserts a pending attempt row per
+// matching channel; a channel in digest mode gets a new post added to its
+// open digest instead. The keyword filter runs before persistence, against the
+// job's title and body and the channels' owner as author, so only channels
+// that actually match produce rows. The event is recorded as a summary
+// delivery.Route counting the matches, plus one route per channel that did not
+// match saying why. An attempt announcing a
+// removed post carries a snapshot of it, as the row it would load is gone.
+// Enqueue is best-effort: any error is logged and swallowed so a delivery
+// failure can never fail the post operation.
 func (d *Dispatcher) Enqueue(job domainpost.DeliveryJob) {
 	ctx := context.Background()
 	channels, err := d.channelRepo.GetByUserID(ctx, job.UserID)
@@ -119,52 +182,160 @@ func (d *Dispatcher) Enqueue(job domainpost.DeliveryJob) {
 		return
 	}
 
+	event := job.Event
+	if event == "" {
+		event = domainpost.EventCreated
+	}
+	postID, subject := &job.PostID, (*delivery.PostSnapshot)(nil)
+	if event.Removed() {
+		postID, subject = nil, &delivery.PostSnapshot{QID: job.PostQID, Title: job.Title, CreatedAt: job.CreatedAt}
+	}
+
+	matched := filter.Post{Title: job.Title, Body: job.Body}
+	if len(channels) > 0 {
+		matched.Author = channels[0].User.Username
+	}
+
 	now := d.now()
+	wake := false
 	attempts := make([]*delivery.Attempt, 0, len(channels))
+	summary := &delivery.Route{
+		UserID:    &job.UserID,
+		PostQID:   job.PostQID,
+		Event:     event,
+		Decision:  delivery.DecisionSummary,
+		CreatedAt: now,
+	}
+	routes := []*delivery.Route{summary}
+	skip := func(channel *delivery.Channel, decision delivery.Decision, detail string) {
+		routes = append(routes, &delivery.Route{
+			UserID:    &job.UserID,
+			PostQID:   job.PostQID,
+			ChannelID: &channel.ID,
+			Event:     event,
+			Decision:  decision,
+			Detail:    detail,
+			CreatedAt: now,
+		})
+	}
 	for _, channel := range channels {
 		if !channel.Enabled {
+			skip(&channel, delivery.DecisionDisabled, "")
+			continue
+		}
+		if !channel.Events.Has(event) {
+			skip(&channel, delivery.DecisionUnsubscribed, "")
 			continue
 		}
 		matcher, err := filter.Compile(channel.Keywords)
 		if err != nil {
 			log.Printf("delivery enqueue: skip channel invalid keywords channel_id=%d user_id=%d err=%v", channel.ID, channel.UserID, err)
+			skip(&channel, delivery.DecisionInvalidFilter, err.Error())
+			continue
+		}
+		if !matcher.MatchPost(matched) {
+			skip(&channel, delivery.DecisionUnmatched, "")
 			continue
 		}
-		if !matcher.Match(job.Title) {
+		if digest, ok := resolveDigestPolicy(&channel); ok && event == domainpost.EventCreated {
+			wake = d.addToDigest(ctx, job, &channel, digest, now) || wake
 			continue
 		}
+		start := deliveryStart(&channel, now)
 		attempts = append(attempts, &delivery.Attempt{
 			UserID:    job.UserID,
-			PostID:    job.PostID,
+			PostID:    postID,
 			ChannelID: channel.ID,
+			Event:     event,
+			Subject:   subject,
 			Status:    delivery.StatusPending,
-			NextAt:    now.UnixMilli(),
+			NextAt:    start.UnixMilli(),
+			ExpiresAt: resolveRetryPolicy(d.retry, &channel).expiresAt(start),
 			CreatedAt: now,
 			UpdatedAt: now,
 		})
 	}
 
-	if len(attempts) == 0 {
-		return
+	if len(channels) > 0 {
+		summary.Detail = fmt.Sprintf("%d of %d channels matched", len(channels)-len(routes)+1, len(channels))
+		if err := d.attemptRepo.RecordRoutes(ctx, routes); err != nil {
+			log.Printf("delivery enqueue: record routes user_id=%d post_qid=%s event=%s err=%v", job.UserID, job.PostQID, event, err)
+		}
+	}
+	if len(attempts) > 0 {
+		if err := d.attemptRepo.Create(ctx, attempts); err != nil {
+			log.Printf("delivery enqueue: create attempts user_id=%d post_qid=%s event=%s err=%v", job.UserID, job.PostQID, event, err)
+		} else {
+			wake = true
+		}
+	}
+	if wake {
+		d.Wake()
 	}
+}
 
-	if err := d.attemptRepo.Create(ctx, attempts); err != nil {
-		log.Printf("delivery enqueue: create attempts user_id=%d post_qid=%s err=%v", job.UserID, job.PostQID, err)
+// HasSubscribers reports whether any enabled channel subscribes to event.
+// When that cannot be read it answers true, so callers still announce.
+func (d *Dispatcher) HasSubscribers(ctx context.Context, event domainpost.Event) bool {
+	ok, err := d.channelRepo.HasSubscribers(ctx, event)
+	if err != nil {
+		log.Printf("delivery enqueue: check subscribers event=%s err=%v", event, err)
+		return true
+	}
+	return ok
+}
+
+// addToDigest puts the post into the channel's open digest, opening one that
+// closes a window from now if there is none. It reports whether the digest is
+// now full and due, so Enqueue can wake the scheduler to flush it.
+func (d *Dispatcher) addToDigest(ctx context.Context, job domainpost.DeliveryJob, channel *delivery.Channel, policy DigestPolicy, now time.Time) bool {
+	item := &delivery.DigestItem{
+		UserID:    job.UserID,
+		ChannelID: channel.ID,
+		PostID:    job.PostID,
+		FlushAt:   now.Add(policy.Window).UnixMilli(),
+		CreatedAt: now,
+	}
+	full, err := d.attemptRepo.AddToDigest(ctx, item, policy.MaxPosts)
+	if err != nil {
+		log.Printf("delivery enqueue: add to digest channel_id=%d post_qid=%s err=%v", channel.ID, job.PostQID, err)
+		return false
+	}
+	return full
+}
+
+// Wake asks the scheduler to claim due attempts now rather than at its next
+// tick. It never blocks; wake-ups that arrive while one is already pending
+// coalesce into it. Enqueue calls it for the attempts it inserts; on Postgres
+// a DeliveryListener calls it for attempts inserted by other instances.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
 	}
 }
 
 // Start launches the scheduler goroutine. It is safe to call once. The
-// scheduler ticks every ScanInterval, sweeping the expiry wall and claiming
-// due attempts into the worker pool.
+// scheduler ticks every ScanInterval, sweeping the expiry wall, sealing due
+// digests into attempts and claiming due attempts into the worker pool; a
+// Wake does the last two between ticks. The tick
+// remains the only path for retries coming due and the fallback for any
+// missed wake-up.
 func (d *Dispatcher) Start(ctx context.Context) {
 	interval := d.cfg.ScanInterval
 	if interval <= 0 {
 		interval = time.Second
 	}
 	d.ticker = time.NewTicker(interval)
+	d.heartbeat = time.NewTicker(d.leaseTTL / 3)
 	go d.run(ctx)
 }
 
+// Owner returns the instance ID this dispatcher leases attempts under.
+func (d *Dispatcher) Owner() string {
+	return d.owner
+}
+
 func (d *Dispatcher) run(ctx context.Context) {
 	defer close(d.done)
 	for {
@@ -175,27 +346,147 @@ func (d *Dispatcher) run(ctx context.Context) {
 			return
 		case <-d.ticker.C:
 			d.tick(ctx)
+		case <-d.wake:
+			d.flushDigests(ctx, d.now().UnixMilli())
+			d.claimDue(ctx)
+		case <-d.heartbeat.C:
+			d.renewLeases(ctx)
 		}
 	}
 }
 
+// renewLeases extends the lease on every attempt this instance is executing —
+// those being sent and those still waiting in the pool queue.
+func (d *Dispatcher) renewLeases(ctx context.Context) {
+	ids := d.inflightIDs()
+	if len(ids) == 0 {
+		return
+	}
+	until := d.now().Add(d.leaseTTL).UnixMilli()
+	if _, err := d.attemptRepo.RenewLeases(ctx, d.owner, ids, until); err != nil {
+		log.Printf("delivery lease: renew owner=%s err=%v", d.owner, err)
+	}
+}
+
+// submit hands a leased attempt to the worker pool and tracks it as in flight
+// until run returns. An attempt the pool refuses has its lease released at
+// once, so this or another instance can claim it again.
+func (d *Dispatcher) submit(ctx context.Context, a *delivery.Attempt, run func(context.Context, *delivery.Attempt)) {
+	d.mu.Lock()
+	d.inflight[a.ID] = struct{}{}
+	d.mu.Unlock()
+
+	err := d.pool.Go(func() {
+		defer d.untrack(a.ID)
+		run(ctx, a)
+	})
+	if err == nil {
+		return
+	}
+	d.untrack(a.ID)
+	log.Printf("delivery claim: pool dropped attempt_id=%d err=%v", a.ID, err)
+	if err := d.attemptRepo.ReleaseLease(ctx, a.ID, d.owner); err != nil {
+		log.Printf("delivery lease: release attempt_id=%d err=%v", a.ID, err)
+	}
+}
+
+func (d *Dispatcher) untrack(id int64) {
+	d.mu.Lock()
+	delete(d.inflight, id)
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) inflightIDs() []int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]int64, 0, len(d.inflight))
+	for id := range d.inflight {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (d *Dispatcher) tick(ctx context.Context) {
 	d.sweepExpiry(ctx)
+	d.flushDigests(ctx, d.now().UnixMilli())
 	d.claimDue(ctx)
 }
 
-// sweepExpiry transitions pending attempts past the expiry wall to expired and
-// archives them, batched so a large pending backlog cannot lock the whole
-// matched range in one statement.
-func (d *Dispatcher) sweepExpiry(ctx context.Context) {
-	wall := ExpiryWall()
-	if wall <= 0 {
-		return
+// flushDigests seals every open digest due by nowMs into an attempt, one per
+// channel (or per maxDigestPosts posts), and returns the attempts. Sealed
+// attempts are due at once: the scheduler's claim picks them up like any
+// other.
+func (d *Dispatcher) flushDigests(ctx context.Context, nowMs int64) []*delivery.Attempt {
+	var sealed []*delivery.Attempt
+	for {
+		items, err := d.attemptRepo.ListDueDigests(ctx, nowMs, digestBatchSize)
+		if err != nil {
+			log.Printf("delivery digest: list due err=%v", err)
+			return sealed
+		}
+		progressed := false
+		for _, group := range groupDigestItems(items) {
+			if a := d.sealDigest(ctx, group); a != nil {
+				sealed = append(sealed, a)
+				progressed = true
+			}
+		}
+		// A round that sealed nothing would list the same items again.
+		if len(items) < digestBatchSize || !progressed {
+			return sealed
+		}
+	}
+}
+
+// sealDigest turns one channel's due digest items into a pending attempt. The
+// attempt is scheduled and walled like a single-post attempt — held until the
+// channel's delivery window opens, with the wall from the channel's retry
+// policy measured from then — and its PostID is the oldest post.
+func (d *Dispatcher) sealDigest(ctx context.Context, items []*delivery.DigestItem) *delivery.Attempt {
+	first := items[0]
+	channel, err := d.channelRepo.GetByIDAndUserID(ctx, first.ChannelID, first.UserID)
+	if err != nil {
+		log.Printf("delivery digest: get channel id=%d err=%v", first.ChannelID, err)
+		return nil
+	}
+
+	now := d.now()
+	start := deliveryStart(channel, now)
+	attempt := &delivery.Attempt{
+		UserID:    first.UserID,
+		PostID:    &first.PostID,
+		ChannelID: first.ChannelID,
+		Event:     domainpost.EventCreated,
+		Status:    delivery.StatusPending,
+		NextAt:    start.UnixMilli(),
+		ExpiresAt: resolveRetryPolicy(d.retry, channel).expiresAt(start),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	n, err := d.attemptRepo.SealDigest(ctx, attempt, ids)
+	if err != nil {
+		log.Printf("delivery digest: seal channel_id=%d err=%v", first.ChannelID, err)
+		return nil
 	}
-	wallBeforeMs := d.now().Add(-wall).UnixMilli()
+	if n == 0 {
+		return nil
+	}
+	return attempt
+}
+
+// sweepExpiry transitions pending attempts past their expiry wall to expired
+// and archives them, batched so a large pending backlog cannot lock the whole
+// matched range in one statement. Each attempt's wall was fixed at enqueue
+// from its channel's retry policy.
+func (d *Dispatcher) sweepExpiry(ctx context.Context) {
+	nowMs := d.now().UnixMilli()
 
 	for {
-		expired, err := d.attemptRepo.MarkExpired(ctx, wallBeforeMs, expireBatchSize)
+		expired, err := d.attemptRepo.MarkExpired(ctx, nowMs, expireBatchSize)
 		if err != nil {
 			log.Printf("delivery sweep: mark expired err=%v", err)
 			return
@@ -211,72 +502,226 @@ func (d *Dispatcher) sweepExpiry(ctx context.Context) {
 	}
 }
 
-// claimDue claims due attempts and submits each to the worker pool. The claim
-// reserves next_at past the request timeout so the next tick does not re-claim
-// in-flight rows.
+// claimDue claims due attempts and submits each to the worker pool. Each
+// claimed row is leased to this instance so no scheduler re-claims it while
+// it is queued or in flight. A row the pool drops is released straight away.
 func (d *Dispatcher) claimDue(ctx context.Context) {
 	now := d.now()
-	reserveUntil := now.Add(d.cfg.RequestTimeout).Add(claimReserveBuffer)
+	leaseUntil := now.Add(d.leaseTTL)
 
-	claimed, err := d.attemptRepo.ClaimDue(ctx, now.UnixMilli(), reserveUntil.UnixMilli(), claimBatchSize)
+	claimed, err := d.attemptRepo.ClaimDue(ctx, d.owner, now.UnixMilli(), leaseUntil.UnixMilli(), claimBatchSize)
 	if err != nil {
 		log.Printf("delivery claim: err=%v", err)
 		return
 	}
 
 	for _, a := range claimed {
-		attempt := a
-		if err := d.pool.Go(func() { d.execute(ctx, attempt) }); err != nil {
-			log.Printf("delivery claim: pool dropped attempt_id=%d err=%v", attempt.ID, err)
-		}
+		d.submit(ctx, a, d.execute)
 	}
 }
 
 func (d *Dispatcher) execute(ctx context.Context, a *delivery.Attempt) {
-	p, err := d.postRepo.GetByID(ctx, a.PostID)
+	// An attempt still queued in the pool when Stop is called is not sent;
+	// Stop hands its lease back for another instance to claim.
+	select {
+	case <-d.stop:
+		return
+	default:
+	}
+	d.deliver(ctx, a)
+}
+
+// deliver sends one claimed attempt and archives or reschedules it.
+func (d *Dispatcher) deliver(ctx context.Context, a *delivery.Attempt) {
+	var (
+		p     *domainpost.Post
+		posts []*domainpost.Post
+		err   error
+	)
+	if a.DigestSize > 0 {
+		posts, err = d.digestPosts(ctx, a)
+	} else {
+		p, err = d.attemptPost(ctx, a)
+	}
 	if err != nil {
-		d.handleSendError(ctx, a, fmt.Errorf("get post id=%d: %w", a.PostID, err))
+		d.handleSendError(ctx, a, d.retry, err)
 		return
 	}
 	channel, err := d.channelRepo.GetByIDAndUserID(ctx, a.ChannelID, a.UserID)
 	if err != nil {
-		d.handleSendError(ctx, a, fmt.Errorf("get channel id=%d: %w", a.ChannelID, err))
+		d.handleSendError(ctx, a, d.retry, lookupError(fmt.Errorf("get channel id=%d: %w", a.ChannelID, err)))
+		return
+	}
+	if !channel.Enabled {
+		d.handleSendError(ctx, a, d.retry, markPermanent(errChannelDisabled))
+		return
+	}
+	now := d.now()
+	if channel.PausedUntil > now.UnixMilli() {
+		d.deferAttempt(ctx, a, channel.PausedUntil)
+		return
+	}
+	if open := deliveryStart(channel, now); open.After(now) {
+		d.holdAttempt(ctx, a, now, open)
 		return
 	}
 
-	if err := d.sender.Send(ctx, p, channel); err != nil {
-		d.handleSendError(ctx, a, err)
+	sendCtx, upstream := withResponseCapture(ctx)
+	started := time.Now()
+	switch {
+	case posts != nil:
+		err = d.sender.SendDigest(sendCtx, posts, channel, a)
+	case a.Event == "" || a.Event == domainpost.EventCreated:
+		err = d.sender.Send(sendCtx, p, channel, a)
+	default:
+		err = d.sender.SendEvent(sendCtx, p, channel, a, d.earlierMessage(ctx, channel.ID, p.QID))
+	}
+	d.recordTry(ctx, a, upstream, time.Since(started), err)
+	if err != nil {
+		err = withRetryAfter(err, upstream.RetryAfter)
+		d.recordFailure(ctx, channel, err)
+		d.handleSendError(ctx, a, resolveRetryPolicy(d.retry, channel), err)
 		return
 	}
 
+	d.recordSuccess(ctx, channel)
+	if upstream.MessageID != "" && p != nil {
+		m := &delivery.Message{ChannelID: channel.ID, PostQID: p.QID, MessageID: upstream.MessageID, CreatedAt: now}
+		if err := d.attemptRepo.RecordMessage(ctx, m); err != nil {
+			log.Printf("delivery execute: record message attempt_id=%d err=%v", a.ID, err)
+		}
+	}
 	if err := d.attemptRepo.ArchiveAndDelete(ctx, a, delivery.StatusDelivered, ""); err != nil {
 		log.Printf("delivery execute: archive delivered attempt_id=%d err=%v", a.ID, err)
 	}
 }
 
-// handleSendError applies the backoff policy to a failed attempt: if the
-// sequence is exhausted the attempt is archived as failed, otherwise the
-// attempt count is bumped and next_at is advanced by the next backoff step.
-func (d *Dispatcher) handleSendError(ctx context.Context, a *delivery.Attempt, sendErr error) {
+// recordTry adds the send just made to the attempt's delivery timeline. A
+// timeline that cannot be written is logged and does not hold up the
+// delivery.
+func (d *Dispatcher) recordTry(ctx context.Context, a *delivery.Attempt, upstream *UpstreamResponse, latency time.Duration, sendErr error) {
+	t := &delivery.Try{
+		AttemptID:  &a.ID,
+		Number:     a.Attempts + 1,
+		StatusCode: upstream.StatusCode,
+		LatencyMs:  latency.Milliseconds(),
+		Body:       upstream.Body,
+		CreatedAt:  d.now(),
+	}
+	if sendErr != nil {
+		t.Error = truncateUTF8Bytes(sendErr.Error(), delivery.MaxTryBodyBytes)
+	}
+	if err := d.attemptRepo.RecordTry(ctx, t); err != nil {
+		log.Printf("delivery execute: record try attempt_id=%d err=%v", a.ID, err)
+	}
+}
+
+// attemptPost loads the post a single-post attempt is about. An attempt
+// announcing a removed post gets what its snapshot kept instead.
+func (d *Dispatcher) attemptPost(ctx context.Context, a *delivery.Attempt) (*domainpost.Post, error) {
+	if a.Subject != nil {
+		return removedPost(a), nil
+	}
+	if a.PostID == nil {
+		return nil, markPermanent(fmt.Errorf("attempt_id=%d has no post", a.ID))
+	}
+	p, err := d.postRepo.GetByID(ctx, *a.PostID)
+	if err != nil {
+		return nil, lookupError(fmt.Errorf("get post id=%d: %w", *a.PostID, err))
+	}
+	return p, nil
+}
+
+// earlierMessage returns the ID of the message recorded for the post on the
+// channel, or "" when there is none and a notice must be posted anew.
+func (d *Dispatcher) earlierMessage(ctx context.Context, channelID int, qid string) string {
+	m, err := d.attemptRepo.FindMessage(ctx, channelID, qid)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			log.Printf("delivery execute: find message channel_id=%d post_qid=%s err=%v", channelID, qid, err)
+		}
+		return ""
+	}
+	return m.MessageID
+}
+
+// digestPosts loads the posts of a digest attempt, oldest first. Posts deleted
+// since they were added are left out; a digest with none left fails
+// permanently.
+func (d *Dispatcher) digestPosts(ctx context.Context, a *delivery.Attempt) ([]*domainpost.Post, error) {
+	ids, err := d.attemptRepo.DigestPostIDs(ctx, a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list digest posts attempt_id=%d: %w", a.ID, err)
+	}
+	posts := make([]*domainpost.Post, 0, len(ids))
+	for _, id := range ids {
+		p, err := d.postRepo.GetByID(ctx, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get post id=%d: %w", id, err)
+		}
+		posts = append(posts, p)
+	}
+	if len(posts) == 0 {
+		return nil, markPermanent(fmt.Errorf("digest attempt_id=%d: every post was deleted", a.ID))
+	}
+	return posts, nil
+}
+
+// lookupError marks a failed post or channel lookup permanent when the row is
+// gone: the attempt can never be sent, so it should not walk the retry policy.
+func lookupError(err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return markPermanent(err)
+	}
+	return err
+}
+
+// handleSendError applies the channel's retry policy to a failed attempt,
+// according to the failure's kind (see classify). A permanent failure, or any
+// failure once the policy gives up, archives the attempt as failed. Otherwise
+// the attempt count is bumped and next_at is advanced — by the upstream's
+// requested wait for a rate-limited failure that carries one, else by the
+// policy's jittered delay.
+func (d *Dispatcher) handleSendError(ctx context.Context, a *delivery.Attempt, policy RetryPolicy, sendErr error) {
 	nextAttempts := a.Attempts + 1
 	lastError := truncateError(sendErr.Error())
+	kind, wait := classify(sendErr)
 
-	backoff, ok := NextBackoff(nextAttempts - 1)
-	if !ok {
+	backoff, ok := policy.Backoff(a.Attempts)
+	if !ok || kind == FailurePermanent {
 		if err := d.attemptRepo.ArchiveAndDelete(ctx, a, delivery.StatusFailed, lastError); err != nil {
 			log.Printf("delivery execute: archive failed attempt_id=%d err=%v", a.ID, err)
 		}
 		return
 	}
 
+	expiresAt := a.ExpiresAt
+	if kind == FailureRateLimited && wait > 0 {
+		backoff = wait
+		// The platform set this wait, which can run past the expiry wall. As
+		// with holdAttempt, the wall moves by the time waited, so the attempt
+		// keeps the budget it had left instead of expiring while it waits.
+		if expiresAt > 0 {
+			expiresAt += wait.Milliseconds()
+		}
+	} else {
+		backoff = jitter(backoff, policy.Jitter, d.rand())
+	}
 	nextAt := d.now().Add(backoff).UnixMilli()
-	if err := d.attemptRepo.MarkRetry(ctx, a.ID, nextAttempts, lastError, nextAt); err != nil {
+	if err := d.attemptRepo.MarkRetry(ctx, a.ID, nextAttempts, lastError, nextAt, expiresAt); err != nil {
 		log.Printf("delivery execute: mark retry attempt_id=%d err=%v", a.ID, err)
 	}
 }
 
-// Stop signals the scheduler to stop and waits for the worker pool to drain.
-// It is idempotent.
+// Stop signals the scheduler to stop, waits for in-flight sends to finish, and
+// releases the leases on attempts that were claimed but never started, so
+// another instance picks them up at once instead of when the leases run out.
+// Open digests are left alone: their items are rows that outlive the process
+// and are sealed by whichever instance sees them come due. It is idempotent
+// and may be called on a dispatcher that was never started.
 func (d *Dispatcher) Stop() {
 	select {
 	case <-d.stop:
@@ -284,11 +729,43 @@ func (d *Dispatcher) Stop() {
 	default:
 		close(d.stop)
 	}
+	// A dispatcher that was never started (one only used to enqueue) has no
+	// scheduler to wait for, but still owns a pool.
 	if d.ticker != nil {
 		d.ticker.Stop()
+		d.heartbeat.Stop()
+		<-d.done
 	}
-	<-d.done
+
 	d.pool.StopAndWait()
+
+	// The run context is usually cancelled by now; the lease handoff must
+	// still reach the database.
+	ctx := context.Background()
+
+	released, err := d.attemptRepo.ReleaseLeases(ctx, d.owner)
+	if err != nil {
+		log.Printf("delivery lease: release owner=%s err=%v", d.owner, err)
+		return
+	}
+	if released > 0 {
+		log.Printf("delivery lease: released owner=%s attempts=%d", d.owner, released)
+	}
+}
+
+// newInstanceID derives a lease owner ID for an instance without a configured
+// delivery.instance_id: the host name, the process ID, and a random suffix so
+// restarted containers that reuse both stay distinct.
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "markpost"
+	}
+	id := fmt.Sprintf("%s-%d-%04x", host, os.Getpid(), rand.IntN(1<<16))
+	if len(id) > 64 {
+		id = id[len(id)-64:]
+	}
+	return id
 }
 
 func truncateError(s string) string {
@@ -299,4 +776,7 @@ func truncateError(s string) string {
 	return s[:max]
 }
 
-var _ domainpost.DeliveryEnqueuer = (*Dispatcher)(nil)
+var (
+	_ domainpost.DeliveryEnqueuer    = (*Dispatcher)(nil)
+	_ domainpost.SubscriptionChecker = (*Dispatcher)(nil)
+)
@@ -0,0 +1,280 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
+)
+
+// Configuration field types, used by the dashboard to pick a form control.
+const (
+	FieldTypeString     = "string"
+	FieldTypeURL        = "url"
+	FieldTypeSecret     = "secret"
+	FieldTypeStringList = "string_list"
+)
+
+// ConfigField describes one key of a channel kind's configuration. Secret
+// fields are write-only: they are accepted on create/update, dropped from API
+// responses, and kept across updates that omit them.
+type ConfigField struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Secret      bool   `json:"secret"`
+	Description string `json:"description"`
+}
+
+// ChannelDriver implements one channel kind: its configuration schema, how a
+// submitted configuration is validated and normalized, how it is redacted for
+// API responses, and how senders for it are built. Adding a kind means writing
+// a driver and listing it in builtinDrivers; nothing else switches on kind.
+type ChannelDriver interface {
+	Kind() delivery.ChannelKind
+	Fields() []ConfigField
+	// Validate checks config and normalizes it in place. Errors are
+	// service.ErrValidation errors suitable for the API.
+	Validate(config delivery.ChannelConfiguration) error
+	// Redact returns a copy of config safe to return to clients.
+	Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration
+	NewSender(opts SenderOptions) KindSender
+}
+
+// SenderOptions carries the server-wide settings a driver needs to build its
+// sender.
+type SenderOptions struct {
+	Timeout time.Duration
+	SMTP    config.SMTPConfig
+}
+
+// KindSender delivers one message to a channel of a single kind.
+type KindSender interface {
+	Send(ctx context.Context, msg OutboundMessage) error
+}
+
+// OutboundMessage is everything a KindSender needs for one delivery: the post
+// (with its author preloaded), the resolved channel, the attempt being tried
+// (nil for ad hoc sends), and the values computed once per send.
+type OutboundMessage struct {
+	Post         *post.Post
+	Channel      *delivery.Channel
+	Attempt      *delivery.Attempt
+	PostURL      string
+	PreviewChars int
+	// Text is the channel's rendered message template, empty when the channel
+	// has none. When set it replaces the body preview in the kind's layout.
+	Text string
+	// Title, Button and Link are the template's rendered "title", "button"
+	// and "link" blocks, each empty when not defined. Kinds read them through
+	// TitleText, ButtonText and LinkURL, which fall back to the defaults.
+	Title  string
+	Button string
+	Link   string
+	// RenderHTML returns the post's sanitized rendered HTML, for kinds that
+	// send the full post rather than a preview.
+	RenderHTML func(ctx context.Context) (string, error)
+	// Digest lists the posts of a digest message, oldest first; it is nil for
+	// a single-post message. Post is then a summary whose Title is the
+	// digest headline, and Text (and RenderHTML) the list of posts.
+	Digest []PostEntry
+	// Event is the lifecycle event the message announces; empty means
+	// post.EventCreated. For any other event Post is a notice saying what
+	// happened (see eventNotice) and Subject is the post it concerns. A
+	// removed post has no page left, so PostURL is empty then.
+	Event   post.Event
+	Subject *PostEntry
+	// MessageID is the platform message that announced the post on this
+	// channel earlier, when one was recorded. A kind that can edit its
+	// messages updates that one instead of posting another.
+	MessageID string
+}
+
+// PostEntry is a post a message refers to: one listed in a digest, or the
+// subject of a lifecycle notice.
+type PostEntry struct {
+	QID       string
+	Title     string
+	URL       string
+	Author    string
+	CreatedAt time.Time
+}
+
+// BodyPreview returns the post body preview at the configured length.
+func (m OutboundMessage) BodyPreview() string {
+	return buildBodyPreview(m.Post.Body, m.PreviewChars)
+}
+
+// BodyPreviewWithin returns the body preview capped at a platform limit.
+func (m OutboundMessage) BodyPreviewWithin(platformMaxChars int) string {
+	return previewWithin(m.Post.Body, m.PreviewChars, platformMaxChars)
+}
+
+// Content returns the message body: the rendered template if the channel has
+// one, otherwise the body preview.
+func (m OutboundMessage) Content() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return m.BodyPreview()
+}
+
+// ContentWithin returns Content capped at a platform limit.
+func (m OutboundMessage) ContentWithin(platformMaxChars int) string {
+	if m.Text != "" {
+		return truncateRunes(m.Text, platformMaxChars)
+	}
+	return m.BodyPreviewWithin(platformMaxChars)
+}
+
+// TitleText returns the message title: the template's title block, or the
+// post title.
+func (m OutboundMessage) TitleText() string {
+	if m.Title != "" {
+		return m.Title
+	}
+	return m.Post.Title
+}
+
+// ButtonText returns the label of the link to the post: the template's
+// button block, or "View Post".
+func (m OutboundMessage) ButtonText() string {
+	return buttonLabel(m.Button)
+}
+
+// LinkURL returns where the message links to: the template's link block, or
+// the post URL. It is empty when the post has no page to link to.
+func (m OutboundMessage) LinkURL() string {
+	if m.Link != "" && m.PostURL != "" {
+		return m.Link
+	}
+	return m.PostURL
+}
+
+// applyTemplate sets the message's templated parts from a rendered template.
+func (m *OutboundMessage) applyTemplate(r renderedTemplate) {
+	m.Text, m.Title, m.Button, m.Link = r.Text, r.Title, r.Button, r.Link
+}
+
+// defaultButtonText labels the link to the post when the channel template
+// does not define a button block.
+const defaultButtonText = "View Post"
+
+// buttonLabel returns text, or defaultButtonText when it is empty, so the
+// kinds' clients can be called with a zero ButtonText.
+func buttonLabel(text string) string {
+	if text == "" {
+		return defaultButtonText
+	}
+	return text
+}
+
+// templateData returns the values a channel message template can reference.
+func (m OutboundMessage) templateData() TemplateData {
+	return TemplateData{
+		Title:       m.Post.Title,
+		QID:         m.Post.QID,
+		PostURL:     m.PostURL,
+		BodyPreview: m.BodyPreview(),
+		Author:      m.Post.User.Username,
+		CreatedAt:   m.Post.CreatedAt,
+	}
+}
+
+// builtinDrivers lists every channel kind markpost ships, in the order the
+// kinds endpoint returns them.
+var builtinDrivers = []ChannelDriver{
+	feishuDriver{},
+	slackDriver{},
+	webhookDriver{},
+	dingtalkDriver{},
+	wecomDriver{},
+	emailDriver{},
+	telegramDriver{},
+	discordDriver{},
+	teamsDriver{},
+}
+
+var driversByKind = indexDrivers(builtinDrivers)
+
+func indexDrivers(drivers []ChannelDriver) map[delivery.ChannelKind]ChannelDriver {
+	m := make(map[delivery.ChannelKind]ChannelDriver, len(drivers))
+	for _, d := range drivers {
+		if _, dup := m[d.Kind()]; dup {
+			panic(fmt.Sprintf("delivery: duplicate channel driver for kind %q", d.Kind()))
+		}
+		m[d.Kind()] = d
+	}
+	return m
+}
+
+// Drivers returns the registered channel drivers in registration order.
+func Drivers() []ChannelDriver {
+	return builtinDrivers
+}
+
+// LookupDriver returns the driver registered for kind.
+func LookupDriver(kind delivery.ChannelKind) (ChannelDriver, bool) {
+	d, ok := driversByKind[kind]
+	return d, ok
+}
+
+// RedactConfiguration returns config with the kind's secrets removed. A kind
+// with no driver has no schema saying which keys are safe, so nothing of its
+// configuration is returned.
+func RedactConfiguration(kind delivery.ChannelKind, config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	if d, ok := LookupDriver(kind); ok {
+		return d.Redact(config)
+	}
+	return delivery.ChannelConfiguration{}
+}
+
+// redactSecretFields is the default Redact: a copy of config without the keys
+// of fields marked Secret.
+func redactSecretFields(fields []ConfigField, config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	out := make(delivery.ChannelConfiguration, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	for _, f := range fields {
+		if f.Secret {
+			delete(out, f.Key)
+		}
+	}
+	return out
+}
+
+// keepSecretFields copies secret keys from previous into config when config
+// omits them. Clients never see stored secrets, so an update that leaves one
+// out keeps it; sending an empty string clears it.
+func keepSecretFields(fields []ConfigField, config, previous delivery.ChannelConfiguration) {
+	for _, f := range fields {
+		if !f.Secret {
+			continue
+		}
+		if _, ok := config[f.Key]; ok {
+			continue
+		}
+		if v, ok := previous[f.Key]; ok {
+			config[f.Key] = v
+		}
+	}
+}
+
+// webhookURLField is the common required webhook_url field.
+func webhookURLField(description string) ConfigField {
+	return ConfigField{Key: "webhook_url", Type: FieldTypeURL, Required: true, Description: description}
+}
+
+// validateWebhookURLField validates and normalizes config["webhook_url"].
+func validateWebhookURLField(config delivery.ChannelConfiguration) error {
+	webhookURL, err := validateWebhookURL(config.String("webhook_url"))
+	if err != nil {
+		return err
+	}
+	config["webhook_url"] = webhookURL
+	return nil
+}
@@ -0,0 +1,93 @@
+package delivery
+
+import (
+	"testing"
+
+	"markpost/internal/domain/delivery"
+)
+
+func TestDrivers(t *testing.T) {
+	kinds := []delivery.ChannelKind{
+		delivery.ChannelKindFeishu,
+		delivery.ChannelKindSlack,
+		delivery.ChannelKindWebhook,
+		delivery.ChannelKindDingTalk,
+		delivery.ChannelKindWeCom,
+		delivery.ChannelKindEmail,
+		delivery.ChannelKindTelegram,
+		delivery.ChannelKindDiscord,
+		delivery.ChannelKindTeams,
+	}
+	for _, kind := range kinds {
+		d, ok := LookupDriver(kind)
+		if !ok {
+			t.Errorf("no driver registered for kind %q", kind)
+			continue
+		}
+		if d.Kind() != kind {
+			t.Errorf("driver for %q reports kind %q", kind, d.Kind())
+		}
+		if len(d.Fields()) == 0 {
+			t.Errorf("driver for %q has no configuration fields", kind)
+		}
+	}
+	if _, ok := LookupDriver("unknown"); ok {
+		t.Error("expected no driver for unknown kind")
+	}
+}
+
+func TestIndexDrivers_PanicsOnDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate driver")
+		}
+	}()
+	indexDrivers([]ChannelDriver{slackDriver{}, slackDriver{}})
+}
+
+func TestRedactConfiguration(t *testing.T) {
+	t.Run("drops secret fields", func(t *testing.T) {
+		config := delivery.ChannelConfiguration{"bot_token": "123:abc", "chat_id": "42"}
+		got := RedactConfiguration(delivery.ChannelKindTelegram, config)
+		if _, ok := got["bot_token"]; ok {
+			t.Errorf("redacted = %v, want bot_token removed", got)
+		}
+		if got.String("chat_id") != "42" {
+			t.Errorf("chat_id = %q, want %q", got.String("chat_id"), "42")
+		}
+		if config.String("bot_token") != "123:abc" {
+			t.Error("expected original configuration to be left intact")
+		}
+	})
+
+	t.Run("unknown kind returns nothing", func(t *testing.T) {
+		got := RedactConfiguration("unknown", delivery.ChannelConfiguration{"secret": "x"})
+		if len(got) != 0 {
+			t.Errorf("redacted = %v, want empty", got)
+		}
+	})
+}
+
+func TestKeepSecretFields(t *testing.T) {
+	fields := webhookDriver{}.Fields()
+	previous := delivery.ChannelConfiguration{"webhook_url": "https://old.example.com", "secret": "0123456789abcdef"}
+
+	t.Run("keeps omitted secret", func(t *testing.T) {
+		config := delivery.ChannelConfiguration{"webhook_url": "https://new.example.com"}
+		keepSecretFields(fields, config, previous)
+		if config.String("secret") != "0123456789abcdef" {
+			t.Errorf("secret = %q, want previous secret", config.String("secret"))
+		}
+		if config.String("webhook_url") != "https://new.example.com" {
+			t.Error("expected non-secret fields not to be carried over")
+		}
+	})
+
+	t.Run("explicit value wins", func(t *testing.T) {
+		config := delivery.ChannelConfiguration{"secret": ""}
+		keepSecretFields(fields, config, previous)
+		if config.String("secret") != "" {
+			t.Errorf("secret = %q, want cleared", config.String("secret"))
+		}
+	})
+}
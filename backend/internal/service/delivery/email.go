@@ -0,0 +1,341 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"markpost/internal/config"
+	"markpost/internal/domain/delivery"
+	"markpost/internal/service"
+)
+
+// SMTP transport security modes accepted in [delivery.smtp] tls.
+const (
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "implicit"
+	smtpTLSNone     = "none"
+)
+
+// maxEmailRecipients caps the recipient list of an email channel. Every send
+// issues one RCPT per recipient on the shared relay, so an unbounded list
+// would let one channel monopolize it.
+const maxEmailRecipients = 50
+
+var errSMTPNotConfigured = errors.New("email: smtp relay is not configured")
+
+// EmailClient sends multipart text+HTML mail through the server-wide SMTP
+// relay. One SMTP session is opened per message; delivery volume is far too
+// low to justify pooling connections.
+type EmailClient struct {
+	cfg     config.SMTPConfig
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewEmailClient creates an EmailClient for the given relay. timeout bounds
+// the whole SMTP session, from dial to QUIT.
+func NewEmailClient(cfg config.SMTPConfig, timeout time.Duration) *EmailClient {
+	return &EmailClient{
+		cfg:     cfg,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// EmailMessageParams holds the parameters for sending a post by email.
+// HTMLBody is the sanitized rendered post; TextBody is its plain-text
+// alternative.
+type EmailMessageParams struct {
+	Recipients []string
+	PostURL    string
+	PostTitle  string
+	ButtonText string
+	TextBody   string
+	HTMLBody   string
+}
+
+// SendMail delivers the post to every recipient in one SMTP transaction. A
+// missing relay configuration or a 5xx reply from the relay is permanent;
+// everything else (dial failure, timeout, 4xx) is left to the backoff.
+func (c *EmailClient) SendMail(ctx context.Context, params EmailMessageParams) error {
+	if c.cfg.Host == "" {
+		return markPermanent(errSMTPNotConfigured)
+	}
+	from, err := mail.ParseAddress(c.cfg.From)
+	if err != nil {
+		return markPermanent(fmt.Errorf("email: invalid from address: %w", err))
+	}
+
+	msg, err := buildEmailMessage(from, params, c.now())
+	if err != nil {
+		return err
+	}
+
+	if err := c.send(ctx, from.Address, params.Recipients, msg); err != nil {
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+			return markPermanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *EmailClient) send(ctx context.Context, from string, recipients []string, msg []byte) error {
+	addr := net.JoinHostPort(c.cfg.Host, strconv.Itoa(c.cfg.Port))
+	dialer := &net.Dialer{Timeout: c.timeout}
+	tlsConfig := &tls.Config{ServerName: c.cfg.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if c.cfg.TLS == smtpTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("email dial %s: %w", addr, err)
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("email smtp handshake: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if c.cfg.TLS == smtpTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return markPermanent(errors.New("email: smtp relay does not support STARTTLS"))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("email starttls: %w", err)
+		}
+	}
+
+	if c.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)); err != nil {
+			return fmt.Errorf("email auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("email MAIL FROM: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("email RCPT TO %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("email DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("email write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("email end DATA: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildEmailMessage renders the RFC 5322 message: a multipart/alternative body
+// with a quoted-printable text/plain part followed by the text/html part, so
+// clients that can show HTML prefer it.
+func buildEmailMessage(from *mail.Address, params EmailMessageParams, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	if err := writeQuotedPrintablePart(mw, "text/plain; charset=utf-8", emailTextBody(params)); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(mw, "text/html; charset=utf-8", emailHTMLBody(params)); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("email close multipart: %w", err)
+	}
+
+	var msg bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&msg, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	header("To", strings.Join(params.Recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", params.PostTitle))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", emailMessageID(from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(mw *multipart.Writer, contentType, content string) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return fmt.Errorf("email create part: %w", err)
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("email write part: %w", err)
+	}
+	return qp.Close()
+}
+
+func emailTextBody(params EmailMessageParams) string {
+	var b strings.Builder
+	b.WriteString(params.PostTitle)
+	b.WriteString("\n\n")
+	if params.TextBody != "" {
+		b.WriteString(params.TextBody)
+		b.WriteString("\n\n")
+	}
+	if params.PostURL != "" {
+		b.WriteString(buttonLabel(params.ButtonText) + ": ")
+		b.WriteString(params.PostURL)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// emailHTMLBody wraps the already-sanitized post HTML in a minimal document.
+// Only the title, URL and link label are escaped here; HTMLBody is trusted as
+// the output of the post sanitizer.
+func emailHTMLBody(params EmailMessageParams) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>")
+	b.WriteString(html.EscapeString(params.PostTitle))
+	b.WriteString("</title></head><body><h1>")
+	b.WriteString(html.EscapeString(params.PostTitle))
+	b.WriteString("</h1>")
+	b.WriteString(params.HTMLBody)
+	if params.PostURL != "" {
+		b.WriteString("<p><a href=\"")
+		b.WriteString(html.EscapeString(params.PostURL))
+		b.WriteString("\">")
+		b.WriteString(html.EscapeString(buttonLabel(params.ButtonText)))
+		b.WriteString("</a></p>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// emailMessageID returns a unique Message-ID in the sender's domain.
+func emailMessageID(fromAddress string) string {
+	domain := "markpost"
+	if i := strings.LastIndex(fromAddress, "@"); i >= 0 && i < len(fromAddress)-1 {
+		domain = fromAddress[i+1:]
+	}
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">"
+}
+
+// validateEmailRecipients parses each recipient as an RFC 5322 address and
+// returns the bare addresses, lowercased and de-duplicated in input order.
+func validateEmailRecipients(raw []string) ([]string, error) {
+	recipients := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, r := range raw {
+		addr, err := mail.ParseAddress(strings.TrimSpace(r))
+		if err != nil {
+			return nil, service.New(service.ErrValidation, "invalid email recipient: "+r)
+		}
+		normalized := strings.ToLower(addr.Address)
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		recipients = append(recipients, normalized)
+	}
+	if len(recipients) == 0 {
+		return nil, service.New(service.ErrValidation, "at least one email recipient is required")
+	}
+	if len(recipients) > maxEmailRecipients {
+		return nil, service.New(service.ErrValidation, fmt.Sprintf("at most %d email recipients are allowed", maxEmailRecipients))
+	}
+	return recipients, nil
+}
+
+// emailDriver is the ChannelDriver for email over the shared SMTP relay.
+type emailDriver struct{}
+
+func (emailDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindEmail }
+
+func (emailDriver) Fields() []ConfigField {
+	return []ConfigField{
+		{Key: "recipients", Type: FieldTypeStringList, Required: true, Description: fmt.Sprintf("Recipient addresses, at most %d", maxEmailRecipients)},
+	}
+}
+
+func (emailDriver) Validate(config delivery.ChannelConfiguration) error {
+	recipients, err := validateEmailRecipients(config.Strings("recipients"))
+	if err != nil {
+		return err
+	}
+	config["recipients"] = recipients
+	return nil
+}
+
+func (d emailDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (emailDriver) NewSender(opts SenderOptions) KindSender {
+	return emailSender{client: NewEmailClient(opts.SMTP, opts.Timeout)}
+}
+
+type emailSender struct{ client *EmailClient }
+
+// Send mails the full rendered post rather than the preview: email readers
+// have no chat client to click through from, so the message itself carries
+// the content. A channel template replaces the Markdown body of the text part.
+func (s emailSender) Send(ctx context.Context, msg OutboundMessage) error {
+	if msg.RenderHTML == nil {
+		return errors.New("email: no post renderer configured")
+	}
+	htmlBody, err := msg.RenderHTML(ctx)
+	if err != nil {
+		return fmt.Errorf("email render post: %w", err)
+	}
+	textBody := msg.Text
+	if textBody == "" {
+		textBody = strings.TrimSpace(msg.Post.Body)
+	}
+	return s.client.SendMail(ctx, EmailMessageParams{
+		Recipients: msg.Channel.Configuration.Strings("recipients"),
+		PostURL:    msg.LinkURL(),
+		PostTitle:  msg.TitleText(),
+		ButtonText: msg.ButtonText(),
+		TextBody:   textBody,
+		HTMLBody:   htmlBody,
+	})
+}
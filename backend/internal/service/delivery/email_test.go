@@ -0,0 +1,208 @@
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"markpost/internal/config"
+)
+
+// fakeSMTPServer is a minimal local SMTP stand-in: it speaks just enough of
+// RFC 5321 for net/smtp (EHLO, MAIL, RCPT, DATA, QUIT) and records what it
+// received. rcptCode lets a test make the relay reject recipients.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	rcptCode int
+
+	mu         sync.Mutex
+	from       string
+	recipients []string
+	data       string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTPServer{ln: ln, rcptCode: 250}
+	go s.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) config() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return config.SMTPConfig{Host: host, Port: p, From: "Markpost <noreply@example.com>", TLS: smtpTLSNone}
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(cmd)
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<> ")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			if s.rcptCode != 250 {
+				reply(strconv.Itoa(s.rcptCode) + " mailbox unavailable")
+				continue
+			}
+			s.mu.Lock()
+			s.recipients = append(s.recipients, strings.Trim(cmd[len("RCPT TO:"):], "<> "))
+			s.mu.Unlock()
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailClient_SendMail(t *testing.T) {
+	params := EmailMessageParams{
+		Recipients: []string{"a@example.com", "b@example.com"},
+		PostURL:    "https://example.com/p-abc",
+		PostTitle:  "部署完成",
+		TextBody:   "All **green**",
+		HTMLBody:   "<p>All <strong>green</strong></p>",
+	}
+
+	t.Run("sends multipart text and html message", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		client := NewEmailClient(server.config(), 5*time.Second)
+
+		if err := client.SendMail(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		if server.from != "noreply@example.com" {
+			t.Errorf("MAIL FROM = %q, want %q", server.from, "noreply@example.com")
+		}
+		if strings.Join(server.recipients, ",") != "a@example.com,b@example.com" {
+			t.Errorf("RCPT TO = %v, want both recipients", server.recipients)
+		}
+
+		msg, err := mail.ReadMessage(strings.NewReader(server.data))
+		if err != nil {
+			t.Fatalf("parse message: %v", err)
+		}
+		subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if subject != "部署完成" {
+			t.Errorf("subject = %q, want %q", subject, "部署完成")
+		}
+
+		mediaType, mediaParams, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/alternative" {
+			t.Fatalf("content-type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+		}
+		mr := multipart.NewReader(msg.Body, mediaParams["boundary"])
+		parts := map[string]string{}
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("next part: %v", err)
+			}
+			body, _ := io.ReadAll(part) // multipart.Reader decodes quoted-printable
+			ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			parts[ct] = string(body)
+		}
+
+		if !strings.Contains(parts["text/plain"], "All **green**") || !strings.Contains(parts["text/plain"], params.PostURL) {
+			t.Errorf("text part = %q, want body and post URL", parts["text/plain"])
+		}
+		if !strings.Contains(parts["text/html"], params.HTMLBody) {
+			t.Errorf("html part = %q, want rendered HTML", parts["text/html"])
+		}
+	})
+
+	t.Run("rejected recipient is permanent", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		server.rcptCode = 550
+		client := NewEmailClient(server.config(), 5*time.Second)
+
+		err := client.SendMail(context.Background(), params)
+		if err == nil {
+			t.Fatal("expected error for rejected recipient")
+		}
+		if !isPermanent(err) {
+			t.Errorf("expected 5xx reply to be permanent, got %v", err)
+		}
+	})
+
+	t.Run("unconfigured relay is permanent", func(t *testing.T) {
+		client := NewEmailClient(config.SMTPConfig{}, 5*time.Second)
+		err := client.SendMail(context.Background(), params)
+		if !errors.Is(err, errSMTPNotConfigured) || !isPermanent(err) {
+			t.Errorf("error = %v, want permanent errSMTPNotConfigured", err)
+		}
+	})
+
+	t.Run("escapes title in html part", func(t *testing.T) {
+		got := emailHTMLBody(EmailMessageParams{PostTitle: "<b>x</b>"})
+		if strings.Contains(got, "<b>x</b>") {
+			t.Errorf("html = %q, want escaped title", got)
+		}
+	})
+}
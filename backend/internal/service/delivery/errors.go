@@ -20,4 +20,20 @@ var (
 		HTTP:    422,
 		Message: &i18n.Message{ID: "error.unsupported_channel_kind", Other: "Unsupported channel kind"},
 	}
+
+	// ErrDeliveryNotRetryable is returned when a history entry cannot be
+	// re-enqueued: it was delivered, its channel is disabled, or a delivery of
+	// the same post to the same channel is already queued.
+	ErrDeliveryNotRetryable = &service.ErrCode{
+		Value:   "delivery_not_retryable",
+		HTTP:    409,
+		Message: &i18n.Message{ID: "error.delivery_not_retryable", Other: "This delivery cannot be retried"},
+	}
+	// ErrDeliveryTargetGone is returned when the post or channel of a history
+	// entry has been deleted since, so there is nothing to redeliver.
+	ErrDeliveryTargetGone = &service.ErrCode{
+		Value:   "delivery_target_gone",
+		HTTP:    410,
+		Message: &i18n.Message{ID: "error.delivery_target_gone", Other: "The post or channel of this delivery no longer exists"},
+	}
 )
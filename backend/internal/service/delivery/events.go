@@ -0,0 +1,75 @@
+package delivery
+
+import (
+	"fmt"
+	"slices"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
+	"markpost/internal/service"
+	"markpost/pkg/utils"
+)
+
+// eventHeadlines prefixes the title of a lifecycle notice.
+var eventHeadlines = map[post.Event]string{
+	post.EventUpdated: "Updated",
+	post.EventDeleted: "Deleted",
+	post.EventExpired: "Expired",
+}
+
+// eventBodies replaces the body of a notice about a removed post.
+var eventBodies = map[post.Event]string{
+	post.EventDeleted: "This post was deleted.",
+	post.EventExpired: "This post has expired and is no longer available.",
+}
+
+// normalizeEvents validates a channel's event subscription for storage.
+// Names are lowercased, duplicates dropped and the rest kept in post.Events
+// order. An empty set clears it (nil): the channel is back to post creation
+// only.
+func normalizeEvents(events delivery.EventSet) (delivery.EventSet, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	want := make(map[post.Event]bool, len(events))
+	for _, e := range events {
+		name := post.Event(utils.Normalize(string(e)))
+		if !slices.Contains(post.Events, name) {
+			return nil, service.New(service.ErrValidation, fmt.Sprintf("invalid events: unknown event %q", e))
+		}
+		want[name] = true
+	}
+	normalized := make(delivery.EventSet, 0, len(want))
+	for _, e := range post.Events {
+		if want[e] {
+			normalized = append(normalized, e)
+		}
+	}
+	return normalized, nil
+}
+
+// eventNotice is the post a lifecycle notice presents: p with its title
+// prefixed by what happened. An update keeps the post's new body; a removed
+// post's body and QID are replaced, as it has no page left to show.
+func eventNotice(event post.Event, p *post.Post) *post.Post {
+	notice := *p
+	if headline, ok := eventHeadlines[event]; ok {
+		notice.Title = headline + ": " + p.Title
+	}
+	if body, ok := eventBodies[event]; ok {
+		notice.QID = ""
+		notice.Body = body
+	}
+	return &notice
+}
+
+// removedPost rebuilds what is left of a removed post from the snapshot its
+// notice attempt carries.
+func removedPost(a *delivery.Attempt) *post.Post {
+	return &post.Post{
+		QID:       a.Subject.QID,
+		Title:     a.Subject.Title,
+		UserID:    a.UserID,
+		CreatedAt: a.Subject.CreatedAt,
+	}
+}
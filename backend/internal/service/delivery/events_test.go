@@ -0,0 +1,132 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/infra"
+	"markpost/internal/service"
+)
+
+// eventSender records what each send carried; a creation is answered with a
+// platform message ID, as a kind that can edit its messages would.
+type eventSender struct {
+	sent []string
+}
+
+func (s *eventSender) Send(ctx context.Context, p *domainpost.Post, channel *delivery.Channel, _ *delivery.Attempt) error {
+	s.sent = append(s.sent, "created:"+p.QID)
+	recordMessageID(ctx, "om-"+channel.Name)
+	return nil
+}
+
+func (s *eventSender) SendDigest(_ context.Context, _ []*domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	return nil
+}
+
+func (s *eventSender) SendEvent(_ context.Context, p *domainpost.Post, _ *delivery.Channel, a *delivery.Attempt, messageID string) error {
+	s.sent = append(s.sent, string(a.Event)+":"+p.QID+":"+p.Title+":"+messageID)
+	return nil
+}
+
+func TestNormalizeEvents(t *testing.T) {
+	cleared, err := normalizeEvents(delivery.EventSet{})
+	if err != nil || cleared != nil {
+		t.Errorf("empty set = %v, %v; want nil, nil", cleared, err)
+	}
+
+	got, err := normalizeEvents(delivery.EventSet{" Deleted ", "created", "deleted"})
+	if err != nil || len(got) != 2 || got[0] != domainpost.EventCreated || got[1] != domainpost.EventDeleted {
+		t.Errorf("normalize = %v, %v", got, err)
+	}
+
+	_, err = normalizeEvents(delivery.EventSet{"published"})
+	assertErrCode(t, err, service.ErrValidation)
+}
+
+func TestEventNotice(t *testing.T) {
+	p := &domainpost.Post{QID: "p-1", Title: "Release", Body: "v2 is out"}
+	if n := eventNotice(domainpost.EventUpdated, p); n.Title != "Updated: Release" || n.Body != "v2 is out" || n.QID != "p-1" {
+		t.Errorf("updated notice = %+v", n)
+	}
+	if n := eventNotice(domainpost.EventDeleted, p); n.Title != "Deleted: Release" || n.Body != "This post was deleted." || n.QID != "" {
+		t.Errorf("deleted notice = %+v", n)
+	}
+	if p.Title != "Release" {
+		t.Errorf("eventNotice modified the post: %+v", p)
+	}
+}
+
+func TestDispatcher_LifecycleEvents(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("events", delivery.EventSet{domainpost.EventCreated, domainpost.EventDeleted})
+	// A channel left at the default hears of post creation only.
+	other := &delivery.Channel{UserID: uid, Kind: delivery.ChannelKindFeishu, Name: "other", Enabled: true, Configuration: makeFeishuChannelConfig("https://example.com/other"), Keywords: "alert"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("seed channel: %v", err)
+	}
+
+	ctx := context.Background()
+	sender := &eventSender{}
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(db), infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), sender)
+	defer dispatcher.pool.StopAndWait()
+	deliverAll := func() []delivery.Attempt {
+		t.Helper()
+		var attempts []delivery.Attempt
+		if err := db.Order("id").Find(&attempts).Error; err != nil {
+			t.Fatalf("find attempts: %v", err)
+		}
+		for i := range attempts {
+			dispatcher.deliver(ctx, &attempts[i])
+		}
+		return attempts
+	}
+
+	createdAt := time.Now()
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert", CreatedAt: createdAt})
+	if created := deliverAll(); len(created) != 2 {
+		t.Fatalf("created attempts = %d, want one per channel", len(created))
+	}
+
+	if err := db.Delete(&domainpost.Post{}, pid).Error; err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	dispatcher.Enqueue(domainpost.DeliveryJob{Event: domainpost.EventDeleted, UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert", CreatedAt: createdAt})
+	deleted := deliverAll()
+	if len(deleted) != 1 {
+		t.Fatalf("deleted attempts = %d, want one for the subscribed channel", len(deleted))
+	}
+	a := deleted[0]
+	if a.ChannelID != cid || a.Event != domainpost.EventDeleted || a.PostID != nil || a.Subject == nil || a.Subject.QID != "p-seed" {
+		t.Errorf("deleted attempt = %+v, want a snapshot of the removed post", a)
+	}
+
+	want := []string{"created:p-seed", "created:p-seed", "deleted:p-seed:Server Alert:om-alerts"}
+	if len(sender.sent) != len(want) {
+		t.Fatalf("sent = %v, want %v", sender.sent, want)
+	}
+	for i := range want {
+		if sender.sent[i] != want[i] {
+			t.Errorf("send %d = %q, want %q", i, sender.sent[i], want[i])
+		}
+	}
+
+	var history delivery.History
+	if err := db.Where("event = ?", domainpost.EventDeleted).First(&history).Error; err != nil {
+		t.Fatalf("find deleted history: %v", err)
+	}
+	if history.Status != delivery.StatusDelivered || history.PostID != nil {
+		t.Errorf("deleted history = %+v, want delivered with no post", history)
+	}
+}
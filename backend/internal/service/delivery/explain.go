@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"markpost/internal/domain/post"
+	"markpost/internal/service"
+	"markpost/internal/service/delivery/filter"
+)
+
+// MaxExplainReplay bounds the number of recent posts one filter explanation
+// may replay.
+const MaxExplainReplay = 50
+
+// PostLister lists a user's posts, newest first. The post repository
+// implements it.
+type PostLister interface {
+	GetByUserID(ctx context.Context, userID int, offset int, limit int) ([]post.Post, error)
+}
+
+// ExplainFilterParams holds a keyword expression to explain and the sample
+// post to evaluate it against. Author is the caller's username, which author:
+// keywords match as they would for the caller's own posts. Replay is how many
+// of the caller's most recent posts to also run the expression against.
+type ExplainFilterParams struct {
+	Expression string
+	Title      string
+	Body       string
+	Author     string
+	Replay     int
+}
+
+// FilterExplanation is the outcome of explaining a keyword expression. When
+// it does not compile, Error says where and the rest is empty; otherwise
+// Trace is its tree evaluated against the sample post and Replay the verdict
+// for each replayed post, newest first.
+type FilterExplanation struct {
+	Error   *filter.ParseError
+	Matched bool
+	Trace   *filter.Trace
+	Replay  []ReplayedPost
+}
+
+// ReplayedPost is whether a channel with the explained expression would have
+// received one of the caller's posts.
+type ReplayedPost struct {
+	QID       string
+	Title     string
+	CreatedAt time.Time
+	Matched   bool
+}
+
+// WithPosts sets the PostLister ExplainFilter replays expressions against.
+func (s *Service) WithPosts(p PostLister) *Service {
+	s.posts = p
+	return s
+}
+
+// ExplainFilter compiles a keyword expression and evaluates it against a
+// sample post and, when asked, the user's recent posts, so the user can see
+// why a post would or would not reach a channel. A malformed expression is
+// part of the explanation, not an error. Nothing is stored or sent.
+func (s *Service) ExplainFilter(ctx context.Context, userID int, params ExplainFilterParams) (*FilterExplanation, error) {
+	if params.Replay < 0 || params.Replay > MaxExplainReplay {
+		return nil, service.New(service.ErrValidation, "replay must be between 0 and 50")
+	}
+
+	matcher, err := filter.Compile(params.Expression)
+	if err != nil {
+		var pe *filter.ParseError
+		if errors.As(err, &pe) {
+			return &FilterExplanation{Error: pe}, nil
+		}
+		return nil, service.Wrap(service.ErrInternal, "compile expression failed", err)
+	}
+
+	trace := matcher.Explain(filter.Post{Title: params.Title, Body: params.Body, Author: params.Author})
+	explanation := &FilterExplanation{Matched: trace.Matched, Trace: trace}
+	if params.Replay == 0 {
+		return explanation, nil
+	}
+
+	if s.posts == nil {
+		return nil, service.New(service.ErrInternal, "post replay is not available")
+	}
+	posts, err := s.posts.GetByUserID(ctx, userID, 0, params.Replay)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "list posts failed", err)
+	}
+	explanation.Replay = make([]ReplayedPost, 0, len(posts))
+	for _, p := range posts {
+		explanation.Replay = append(explanation.Replay, ReplayedPost{
+			QID:       p.QID,
+			Title:     p.Title,
+			CreatedAt: p.CreatedAt,
+			Matched:   matcher.MatchPost(filter.Post{Title: p.Title, Body: p.Body, Author: params.Author}),
+		})
+	}
+	return explanation, nil
+}
@@ -0,0 +1,63 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/post"
+	"markpost/internal/infra"
+	"markpost/internal/service"
+	"markpost/internal/service/delivery/filter"
+)
+
+func TestService_ExplainFilter(t *testing.T) {
+	db := infra.SetupTestDB(t)
+	svc := NewService(infra.NewDeliveryChannelRepository(db), nil).WithPosts(infra.NewPostRepository(db))
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	for i, title := range []string{"[prod] error", "[prod] debug error", "[staging] error"} {
+		p := &post.Post{QID: "p-" + title, Title: title, Body: "b", UserID: 1, CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("seed post: %v", err)
+		}
+	}
+	if err := db.Create(&post.Post{QID: "p-other", Title: "[prod] error", Body: "b", UserID: 2}).Error; err != nil {
+		t.Fatalf("seed post: %v", err)
+	}
+
+	got, err := svc.ExplainFilter(ctx, 1, ExplainFilterParams{
+		Expression: "prod & (error, warning) & !debug & author:alice",
+		Title:      "[prod] warning",
+		Author:     "alice",
+		Replay:     10,
+	})
+	if err != nil {
+		t.Fatalf("ExplainFilter: %v", err)
+	}
+	if got.Error != nil || !got.Matched || got.Trace == nil || got.Trace.Op != filter.OpAnd {
+		t.Fatalf("explanation = %+v, want a matching and tree", got)
+	}
+	// The caller's posts only, newest first.
+	want := []struct {
+		title   string
+		matched bool
+	}{{"[staging] error", false}, {"[prod] debug error", false}, {"[prod] error", true}}
+	if len(got.Replay) != len(want) {
+		t.Fatalf("replay = %+v, want %d posts", got.Replay, len(want))
+	}
+	for i, w := range want {
+		if r := got.Replay[i]; r.Title != w.title || r.Matched != w.matched {
+			t.Errorf("replay[%d] = %+v, want %q matched=%v", i, r, w.title, w.matched)
+		}
+	}
+
+	bad, err := svc.ExplainFilter(ctx, 1, ExplainFilterParams{Expression: "prod & (error,", Replay: 10})
+	if err != nil || bad.Error == nil || bad.Error.Pos != 14 || bad.Trace != nil || bad.Replay != nil {
+		t.Errorf("malformed expression = %+v, %v; want a ParseError at 14 and nothing else", bad, err)
+	}
+
+	_, err = svc.ExplainFilter(ctx, 1, ExplainFilterParams{Expression: "a", Replay: MaxExplainReplay + 1})
+	assertErrCode(t, err, service.ErrValidation)
+}
@@ -0,0 +1,117 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// FailureKind classifies a failed send so the dispatcher knows whether and
+// when to try again.
+type FailureKind int
+
+const (
+	// FailureTransient is a failure a later try may not hit — a timeout, a
+	// 5xx, a dropped connection. It is retried per the channel's retry policy.
+	// An error senders leave unclassified counts as transient.
+	FailureTransient FailureKind = iota
+	// FailureRateLimited is the upstream refusing the send for now. It is
+	// retried after the wait the upstream asked for, or the policy's delay if
+	// it gave none.
+	FailureRateLimited
+	// FailurePermanent is a failure no retry can fix — the robot was removed
+	// from the group, the access token no longer exists, the signature is
+	// wrong. The attempt is archived as failed at once.
+	FailurePermanent
+)
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailureRateLimited:
+		return "rate_limited"
+	case FailurePermanent:
+		return "permanent"
+	default:
+		return "transient"
+	}
+}
+
+// SendError is the typed error senders return to classify a failure.
+// RetryAfter is only meaningful for FailureRateLimited.
+type SendError struct {
+	Kind       FailureKind
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SendError) Error() string { return e.Err.Error() }
+
+func (e *SendError) Unwrap() error { return e.Err }
+
+// classify returns the failure kind of err and, for a rate-limited failure,
+// the wait the upstream asked for (0 if none). The outermost SendError wins.
+func classify(err error) (FailureKind, time.Duration) {
+	var se *SendError
+	if errors.As(err, &se) {
+		return se.Kind, se.RetryAfter
+	}
+	return FailureTransient, 0
+}
+
+// markPermanent wraps err as a permanent failure. A nil err stays nil.
+func markPermanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &SendError{Kind: FailurePermanent, Err: err}
+}
+
+// markRateLimited wraps err as a rate-limited failure that should wait at
+// least wait (0 = use the retry policy's delay). A nil err stays nil.
+func markRateLimited(err error, wait time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &SendError{Kind: FailureRateLimited, RetryAfter: max(wait, 0), Err: err}
+}
+
+// markKind wraps err with the given kind. A transient kind leaves err as is.
+func markKind(kind FailureKind, err error) error {
+	switch kind {
+	case FailurePermanent:
+		return markPermanent(err)
+	case FailureRateLimited:
+		return markRateLimited(err, 0)
+	}
+	return err
+}
+
+// isPermanent reports whether err (or any error it wraps) is permanent.
+func isPermanent(err error) bool {
+	kind, _ := classify(err)
+	return kind == FailurePermanent
+}
+
+// classifyStatus wraps err according to an upstream's HTTP status. A status
+// that says the request itself is wrong — malformed payload, bad credentials,
+// endpoint gone — is permanent; 429 is rate-limited; 408 and 5xx stay
+// transient.
+func classifyStatus(status int, err error) error {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return markPermanent(err)
+	case http.StatusTooManyRequests:
+		return markRateLimited(err, 0)
+	}
+	return err
+}
+
+// withRetryAfter attaches the wait an upstream asked for (Retry-After on a 429
+// or 503) to err, making it rate-limited. A permanent err, a nil err or a
+// non-positive wait leaves err unchanged.
+func withRetryAfter(err error, wait time.Duration) error {
+	if err == nil || wait <= 0 || isPermanent(err) {
+		return err
+	}
+	return &SendError{Kind: FailureRateLimited, RetryAfter: wait, Err: err}
+}
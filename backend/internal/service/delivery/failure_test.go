@@ -0,0 +1,51 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"markpost/internal/domain"
+)
+
+func TestClassify(t *testing.T) {
+	base := errors.New("boom")
+	tests := []struct {
+		name     string
+		err      error
+		wantKind FailureKind
+		wantWait time.Duration
+	}{
+		{"plain error is transient", base, FailureTransient, 0},
+		{"permanent", markPermanent(base), FailurePermanent, 0},
+		{"wrapped permanent", fmt.Errorf("send: %w", markPermanent(base)), FailurePermanent, 0},
+		{"rate limited with wait", markRateLimited(base, time.Minute), FailureRateLimited, time.Minute},
+		{"400 is permanent", classifyStatus(http.StatusBadRequest, base), FailurePermanent, 0},
+		{"410 is permanent", classifyStatus(http.StatusGone, base), FailurePermanent, 0},
+		{"429 is rate limited", classifyStatus(http.StatusTooManyRequests, base), FailureRateLimited, 0},
+		{"503 is transient", classifyStatus(http.StatusServiceUnavailable, base), FailureTransient, 0},
+		{"Retry-After makes a 503 rate limited", withRetryAfter(base, 30*time.Second), FailureRateLimited, 30 * time.Second},
+		{"Retry-After overrides the sender's wait", withRetryAfter(markRateLimited(base, time.Second), time.Minute), FailureRateLimited, time.Minute},
+		{"Retry-After does not soften a permanent failure", withRetryAfter(markPermanent(base), time.Minute), FailurePermanent, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, wait := classify(tt.err)
+			if kind != tt.wantKind || wait != tt.wantWait {
+				t.Errorf("classify = (%v, %s), want (%v, %s)", kind, wait, tt.wantKind, tt.wantWait)
+			}
+			if !errors.Is(tt.err, base) {
+				t.Errorf("classified error does not wrap the original: %v", tt.err)
+			}
+		})
+	}
+
+	if markPermanent(nil) != nil || markRateLimited(nil, time.Second) != nil || withRetryAfter(nil, time.Second) != nil {
+		t.Error("nil errors must stay nil")
+	}
+	if !isPermanent(lookupError(fmt.Errorf("get channel id=1: %w", domain.ErrNotFound))) {
+		t.Error("a lookup of a deleted row must be permanent")
+	}
+}
@@ -1,32 +1,94 @@
-// Package delivery provides delivery channel business logic and services.
 package delivery
 
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
+	"markpost/internal/service"
+
+	"golang.org/x/sync/singleflight"
 )
 
-// FeishuClient sends messages to Feishu webhook URLs.
+// feishuErrCodes classifies Feishu custom-bot and Open Platform API codes:
+// those retrying cannot fix are permanent, rate limiting backs off and
+// retries. Unlisted codes are transient.
+var feishuErrCodes = map[int]FailureKind{
+	9499:     FailurePermanent,   // bad request (malformed payload)
+	10003:    FailurePermanent,   // invalid app_id or app_secret parameter
+	10014:    FailurePermanent,   // app secret invalid
+	11232:    FailureRateLimited, // frequency limited
+	19001:    FailurePermanent,   // incoming webhook access token invalid (bot removed)
+	19021:    FailurePermanent,   // sign match fail or timestamp out of range
+	19022:    FailurePermanent,   // ip not allowed
+	19024:    FailurePermanent,   // key words not found
+	230002:   FailurePermanent,   // bot is not in the chat
+	230020:   FailureRateLimited, // chat message frequency limited
+	99991400: FailureRateLimited, // API request frequency limited
+}
+
+// feishuTokenInvalidCodes are the Open Platform codes for a tenant access
+// token that is no longer accepted; the cached token is dropped so the retry
+// fetches a fresh one.
+var feishuTokenInvalidCodes = map[int]bool{
+	99991661: true, // token missing or malformed
+	99991663: true, // tenant access token invalid
+	99991668: true, // user or tenant token expired
+}
+
+// feishuAPIBase is the Feishu Open Platform root that app bots call.
+const feishuAPIBase = "https://open.feishu.cn"
+
+// FeishuClient sends messages to Feishu webhook URLs, or through the Open
+// Platform API as an app bot. It caches each app's tenant access token.
 type FeishuClient struct {
 	httpClient *http.Client
+	now        func() time.Time
+	apiBase    string
+
+	mu       sync.Mutex
+	tokens   map[string]feishuToken
+	fetching singleflight.Group
+}
+
+type feishuToken struct {
+	value     string
+	expiresAt time.Time
 }
 
 // NewFeishuClient creates a FeishuClient with the given request timeout.
 func NewFeishuClient(timeout time.Duration) *FeishuClient {
 	return &FeishuClient{
-		httpClient: &http.Client{Timeout: timeout},
+		httpClient: newHTTPClient(timeout),
+		now:        time.Now,
+		apiBase:    feishuAPIBase,
+		tokens:     make(map[string]feishuToken),
 	}
 }
 
+// FeishuApp is a Feishu app bot and the chat it posts to. Unlike a custom
+// bot's webhook, it gets back an ID for each message and can edit it later.
+type FeishuApp struct {
+	AppID     string
+	AppSecret string
+	ChatID    string
+}
+
 type feishuCardPayload struct {
 	Schema string `json:"schema"`
 	Config struct {
@@ -67,29 +129,74 @@ type feishuCardTextElem struct {
 	Content string `json:"content"`
 }
 
-// SendText posts a text message to the given Feishu webhook URL.
-func (c *FeishuClient) SendText(ctx context.Context, webhookURL string, text string) error {
+// SendText posts a text message to the given Feishu webhook URL. A non-empty
+// secret signs the message for bots with signature verification enabled.
+func (c *FeishuClient) SendText(ctx context.Context, webhookURL, secret, text string) error {
 	payload := map[string]any{
 		"msg_type": "text",
 		"content": map[string]any{
 			"text": text,
 		},
 	}
-	return c.sendRequest(ctx, webhookURL, payload)
+	return c.sendRequest(ctx, webhookURL, secret, payload)
 }
 
 // CardDeliveryParams holds the parameters for sending a Feishu card message.
 type CardDeliveryParams struct {
 	WebhookURL  string
 	CardLinkURL string
+	Secret      string
 	PostURL     string
 	PostTitle   string
+	ButtonText  string
 	BodyPreview string
 	PostQID     string
 }
 
 // SendCard posts an interactive card message to the given Feishu webhook URL.
 func (c *FeishuClient) SendCard(ctx context.Context, params CardDeliveryParams) error {
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card":     buildFeishuCard(params),
+	}
+	return c.sendRequest(ctx, params.WebhookURL, params.Secret, payload)
+}
+
+// PostCard sends the card to the app's chat and returns the new message's ID.
+func (c *FeishuClient) PostCard(ctx context.Context, app FeishuApp, params CardDeliveryParams) (string, error) {
+	content, err := json.Marshal(buildFeishuCard(params))
+	if err != nil {
+		return "", fmt.Errorf("feishu marshal card: %w", err)
+	}
+	body := map[string]string{
+		"receive_id": app.ChatID,
+		"msg_type":   "interactive",
+		"content":    string(content),
+	}
+	var result struct {
+		Data struct {
+			MessageID string `json:"message_id"`
+		} `json:"data"`
+	}
+	if err := c.callAPI(ctx, app, http.MethodPost, "/open-apis/im/v1/messages?receive_id_type=chat_id", body, &result); err != nil {
+		return "", err
+	}
+	return result.Data.MessageID, nil
+}
+
+// UpdateCard replaces the card of a message the app posted earlier. Feishu
+// allows it because every card is sent with update_multi set.
+func (c *FeishuClient) UpdateCard(ctx context.Context, app FeishuApp, messageID string, params CardDeliveryParams) error {
+	content, err := json.Marshal(buildFeishuCard(params))
+	if err != nil {
+		return fmt.Errorf("feishu marshal card: %w", err)
+	}
+	body := map[string]string{"content": string(content)}
+	return c.callAPI(ctx, app, http.MethodPatch, "/open-apis/im/v1/messages/"+url.PathEscape(messageID), body, nil)
+}
+
+// buildFeishuCard lays out the interactive card for a message.
+func buildFeishuCard(params CardDeliveryParams) feishuCardPayload {
 	resolvedCardLinkURL := resolveCardLinkURL(params)
 	showFooter := resolvedCardLinkURL != params.PostURL && params.PostURL != ""
 
@@ -107,7 +214,7 @@ func (c *FeishuClient) SendCard(ctx context.Context, params CardDeliveryParams)
 			Tag: "button",
 			Text: &feishuCardTextElem{
 				Tag:     "plain_text",
-				Content: "View Post",
+				Content: buttonLabel(params.ButtonText),
 			},
 			URL:        params.PostURL,
 			ButtonType: "primary",
@@ -133,12 +240,7 @@ func (c *FeishuClient) SendCard(ctx context.Context, params CardDeliveryParams)
 	if resolvedCardLinkURL != "" {
 		card.CardLink = &feishuCardLink{URL: resolvedCardLinkURL}
 	}
-
-	payload := map[string]any{
-		"msg_type": "interactive",
-		"card":     card,
-	}
-	return c.sendRequest(ctx, params.WebhookURL, payload)
+	return card
 }
 
 func resolveCardLinkURL(params CardDeliveryParams) string {
@@ -157,7 +259,17 @@ func resolveCardLinkURL(params CardDeliveryParams) string {
 	return buf.String()
 }
 
-func (c *FeishuClient) sendRequest(ctx context.Context, webhookURL string, payload any) error {
+// sendRequest posts the payload to a Feishu webhook. With a non-empty secret it
+// adds the timestamp and sign fields required by bots that have signature
+// verification enabled; Feishu rejects stale timestamps, so both are computed
+// per request rather than once per attempt.
+func (c *FeishuClient) sendRequest(ctx context.Context, webhookURL, secret string, payload map[string]any) error {
+	if secret != "" {
+		timestamp := strconv.FormatInt(c.now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = feishuSign(secret, timestamp)
+	}
+
 	bodyBytes, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("feishu marshal payload: %w", err)
@@ -177,7 +289,8 @@ func (c *FeishuClient) sendRequest(ctx context.Context, webhookURL string, paylo
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("feishu webhook status=%d body=%s", resp.StatusCode, string(b))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("feishu webhook status=%d body=%s", resp.StatusCode, string(b)))
 	}
 
 	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
@@ -190,16 +303,236 @@ func (c *FeishuClient) sendRequest(ctx context.Context, webhookURL string, paylo
 		Msg  string `json:"msg"`
 	}
 	if json.Unmarshal(respBody, &result) == nil && result.Code != 0 {
-		return fmt.Errorf("feishu api code=%d msg=%s", result.Code, result.Msg)
+		return markKind(feishuErrCodes[result.Code],
+			fmt.Errorf("feishu api code=%d msg=%s", result.Code, result.Msg))
 	}
 
 	return nil
 }
 
-func feishuWebhookFromChannel(ch delivery.Channel) string {
-	return ch.Configuration.Feishu().WebhookURL
+// feishuAPIError is a non-zero code in an Open Platform response.
+type feishuAPIError struct {
+	code int
+	msg  string
+}
+
+func (e feishuAPIError) Error() string {
+	return fmt.Sprintf("feishu api code=%d msg=%s", e.code, e.msg)
 }
 
-func feishuCardLinkURLFromChannel(ch delivery.Channel) string {
-	return ch.Configuration.Feishu().CardLinkURL
+// callAPI makes an Open Platform request as the app, decoding the response
+// into out when it is non-nil.
+func (c *FeishuClient) callAPI(ctx context.Context, app FeishuApp, method, path string, body, out any) error {
+	token, err := c.tenantToken(ctx, app)
+	if err != nil {
+		return err
+	}
+	err = c.doAPI(ctx, method, path, token, body, out)
+	var apiErr feishuAPIError
+	if errors.As(err, &apiErr) && feishuTokenInvalidCodes[apiErr.code] {
+		c.mu.Lock()
+		delete(c.tokens, app.AppID)
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// tenantToken returns the app's tenant access token, fetching a new one when
+// none is cached or the cached one is about to expire. The lock only guards
+// the cache: sends of other apps never wait on a fetch, and concurrent misses
+// for one app share a single fetch.
+func (c *FeishuClient) tenantToken(ctx context.Context, app FeishuApp) (string, error) {
+	c.mu.Lock()
+	t, ok := c.tokens[app.AppID]
+	c.mu.Unlock()
+	if ok && c.now().Before(t.expiresAt) {
+		return t.value, nil
+	}
+
+	token, err, _ := c.fetching.Do(app.AppID, func() (any, error) {
+		var result struct {
+			Token  string `json:"tenant_access_token"`
+			Expire int    `json:"expire"`
+		}
+		body := map[string]string{"app_id": app.AppID, "app_secret": app.AppSecret}
+		if err := c.doAPI(ctx, http.MethodPost, "/open-apis/auth/v3/tenant_access_token/internal", "", body, &result); err != nil {
+			return "", fmt.Errorf("feishu tenant token: %w", err)
+		}
+		c.mu.Lock()
+		c.tokens[app.AppID] = feishuToken{
+			value:     result.Token,
+			expiresAt: c.now().Add(feishuTokenReuse(result.Expire)),
+		}
+		c.mu.Unlock()
+		return result.Token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// feishuTokenReuse is how long a token that Feishu says expires in expire
+// seconds is reused: a minute less, so it is refreshed before Feishu stops
+// accepting it. A lifetime under two minutes keeps half of it instead, so a
+// short-lived token is still reused rather than refetched on every send.
+func feishuTokenReuse(expire int) time.Duration {
+	lifetime := time.Duration(expire) * time.Second
+	return lifetime - min(time.Minute, lifetime/2)
+}
+
+// doAPI sends a JSON request to the Open Platform, with the token as bearer
+// when non-empty. A listed non-zero response code is classified by
+// feishuErrCodes, any other failure by HTTP status.
+func (c *FeishuClient) doAPI(ctx context.Context, method, path, token string, body, out any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("feishu marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiBase+path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("feishu create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("feishu request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("feishu read response: %w", err)
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(respBody, &result) == nil && result.Code != 0 {
+		apiErr := feishuAPIError{code: result.Code, msg: result.Msg}
+		if kind, ok := feishuErrCodes[result.Code]; ok {
+			return markKind(kind, apiErr)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return classifyStatus(resp.StatusCode, apiErr)
+		}
+		return apiErr
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("feishu api status=%d body=%s", resp.StatusCode, truncateUTF8Bytes(string(respBody), 4096)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("feishu decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// feishuSign computes the Feishu webhook signature: base64 of HMAC-SHA256 keyed
+// with timestamp + "\n" + secret over an empty message.
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// feishuDriver is the ChannelDriver for Feishu custom bots.
+type feishuDriver struct{}
+
+func (feishuDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindFeishu }
+
+// feishuAppKeys are the configuration keys of a Feishu app bot, which sends
+// through the Open Platform instead of a custom bot's webhook.
+var feishuAppKeys = []string{"app_id", "app_secret", "chat_id"}
+
+func (feishuDriver) Fields() []ConfigField {
+	webhookURL := webhookURLField("Feishu custom bot webhook URL; not needed when app_id, app_secret and chat_id are set")
+	webhookURL.Required = false
+	return []ConfigField{
+		webhookURL,
+		{Key: "card_link_url", Type: FieldTypeString, Description: "Card link URL template; {{.QID}} expands to the post QID. Defaults to the post URL"},
+		{Key: "secret", Type: FieldTypeSecret, Secret: true, Description: "Signature verification secret, if enabled on the bot"},
+		{Key: "app_id", Type: FieldTypeString, Description: "Feishu app ID, to send as an app bot whose cards are updated on later post events"},
+		{Key: "app_secret", Type: FieldTypeSecret, Secret: true, Description: "Feishu app secret"},
+		{Key: "chat_id", Type: FieldTypeString, Description: "Chat the app bot posts to"},
+	}
+}
+
+func (feishuDriver) Validate(config delivery.ChannelConfiguration) error {
+	app := false
+	for _, key := range feishuAppKeys {
+		if _, ok := config[key]; ok {
+			config[key] = strings.TrimSpace(config.String(key))
+			app = app || config.String(key) != ""
+		}
+	}
+	if app {
+		for _, key := range feishuAppKeys {
+			if config.String(key) == "" {
+				return service.New(service.ErrValidation, "feishu app_id, app_secret and chat_id must be set together")
+			}
+		}
+	}
+	if !app || config.String("webhook_url") != "" {
+		if err := validateWebhookURLField(config); err != nil {
+			return err
+		}
+	}
+	if _, ok := config["card_link_url"]; !ok {
+		config["card_link_url"] = ""
+	}
+	config["secret"] = strings.TrimSpace(config.String("secret"))
+	return nil
+}
+
+func (d feishuDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (feishuDriver) NewSender(opts SenderOptions) KindSender {
+	return feishuSender{client: NewFeishuClient(opts.Timeout)}
+}
+
+type feishuSender struct{ client *FeishuClient }
+
+// Send posts the card through the custom bot's webhook, or as the app bot
+// when one is configured. An app bot's notice of a later post event updates
+// the card that announced the post, if its message ID was recorded.
+func (s feishuSender) Send(ctx context.Context, msg OutboundMessage) error {
+	config := msg.Channel.Configuration
+	params := CardDeliveryParams{
+		WebhookURL:  config.String("webhook_url"),
+		CardLinkURL: config.String("card_link_url"),
+		Secret:      config.String("secret"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.Content(),
+		PostQID:     msg.Post.QID,
+	}
+	if msg.Event.Removed() {
+		// The card link template would point at the removed post.
+		params.CardLinkURL = ""
+	}
+
+	app := FeishuApp{AppID: config.String("app_id"), AppSecret: config.String("app_secret"), ChatID: config.String("chat_id")}
+	if app.AppID == "" {
+		return s.client.SendCard(ctx, params)
+	}
+	if msg.MessageID != "" && msg.Event != "" && msg.Event != post.EventCreated {
+		return s.client.UpdateCard(ctx, app, msg.MessageID, params)
+	}
+	messageID, err := s.client.PostCard(ctx, app, params)
+	if err != nil {
+		return err
+	}
+	recordMessageID(ctx, messageID)
+	return nil
 }
@@ -2,12 +2,19 @@ package delivery
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/service"
 )
 
 func TestFeishuClient_SendText(t *testing.T) {
@@ -22,7 +29,7 @@ func TestFeishuClient_SendText(t *testing.T) {
 		defer server.Close()
 
 		client := NewFeishuClient(5 * time.Second)
-		err := client.SendText(context.Background(), server.URL, "Hello World")
+		err := client.SendText(context.Background(), server.URL, "", "Hello World")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -47,7 +54,7 @@ func TestFeishuClient_SendText(t *testing.T) {
 		defer server.Close()
 
 		client := NewFeishuClient(5 * time.Second)
-		err := client.SendText(context.Background(), server.URL, "test")
+		err := client.SendText(context.Background(), server.URL, "", "test")
 		if err == nil {
 			t.Fatal("expected error for non-2xx status")
 		}
@@ -61,15 +68,41 @@ func TestFeishuClient_SendText(t *testing.T) {
 		defer server.Close()
 
 		client := NewFeishuClient(5 * time.Second)
-		err := client.SendText(context.Background(), server.URL, "test")
+		err := client.SendText(context.Background(), server.URL, "", "test")
 		if err == nil {
 			t.Fatal("expected error for non-zero API code")
 		}
 	})
 
+	t.Run("classifies API codes", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+			want FailureKind
+		}{
+			{"sign mismatch is permanent", `{"code":19021,"msg":"sign match fail"}`, FailurePermanent},
+			{"keyword mismatch is permanent", `{"code":19024,"msg":"Key Words Not Found"}`, FailurePermanent},
+			{"frequency limit is rate-limited", `{"code":11232,"msg":"frequency limited"}`, FailureRateLimited},
+			{"unknown code is transient", `{"code":11246,"msg":"card json is invalid"}`, FailureTransient},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(tt.body))
+				}))
+				defer server.Close()
+
+				err := NewFeishuClient(5*time.Second).SendText(context.Background(), server.URL, "", "test")
+				if kind, _ := classify(err); err == nil || kind != tt.want {
+					t.Errorf("kind = %v, want %v (err=%v)", kind, tt.want, err)
+				}
+			})
+		}
+	})
+
 	t.Run("returns error for unreachable URL", func(t *testing.T) {
 		client := NewFeishuClient(100 * time.Millisecond)
-		err := client.SendText(context.Background(), "http://192.0.2.1:12345/webhook", "test")
+		err := client.SendText(context.Background(), "http://192.0.2.1:12345/webhook", "", "test")
 		if err == nil {
 			t.Fatal("expected error for unreachable URL")
 		}
@@ -84,7 +117,7 @@ func TestFeishuClient_SendText(t *testing.T) {
 		defer server.Close()
 
 		client := NewFeishuClient(5 * time.Second)
-		_ = client.SendText(context.Background(), server.URL, "test")
+		_ = client.SendText(context.Background(), server.URL, "", "test")
 
 		if contentType != "application/json; charset=utf-8" {
 			t.Errorf("content-type = %q, want %q", contentType, "application/json; charset=utf-8")
@@ -268,6 +301,59 @@ func TestFeishuClient_SendCard(t *testing.T) {
 	})
 }
 
+func TestFeishuClient_Signature(t *testing.T) {
+	t.Run("adds timestamp and sign when secret is set", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"code":0}`))
+		}))
+		defer server.Close()
+
+		client := NewFeishuClient(5 * time.Second)
+		client.now = func() time.Time { return time.Unix(1700000000, 0) }
+		err := client.SendCard(context.Background(), CardDeliveryParams{
+			WebhookURL: server.URL,
+			Secret:     "feishu-secret",
+			PostTitle:  "Test Title",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if receivedBody["timestamp"] != "1700000000" {
+			t.Errorf("timestamp = %v, want %q", receivedBody["timestamp"], "1700000000")
+		}
+		mac := hmac.New(sha256.New, []byte("1700000000\nfeishu-secret"))
+		want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if receivedBody["sign"] != want {
+			t.Errorf("sign = %v, want %q", receivedBody["sign"], want)
+		}
+	})
+
+	t.Run("omits signature fields without secret", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"code":0}`))
+		}))
+		defer server.Close()
+
+		client := NewFeishuClient(5 * time.Second)
+		if err := client.SendText(context.Background(), server.URL, "", "test"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := receivedBody["sign"]; ok {
+			t.Error("expected no sign field without secret")
+		}
+		if _, ok := receivedBody["timestamp"]; ok {
+			t.Error("expected no timestamp field without secret")
+		}
+	})
+}
+
 func TestResolveCardLinkURL(t *testing.T) {
 	t.Run("returns post URL when card_link_url is empty", func(t *testing.T) {
 		result := resolveCardLinkURL(CardDeliveryParams{
@@ -302,3 +388,97 @@ func TestResolveCardLinkURL(t *testing.T) {
 		}
 	})
 }
+
+func TestFeishuClient_AppBot(t *testing.T) {
+	var tokenCalls int
+	var posted, patched map[string]string
+	var patchPath, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/open-apis/auth/v3/tenant_access_token/internal":
+			tokenCalls++
+			_, _ = w.Write([]byte(`{"code":0,"msg":"ok","tenant_access_token":"t-123","expire":7200}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/open-apis/im/v1/messages":
+			auth = r.Header.Get("Authorization")
+			if r.URL.Query().Get("receive_id_type") != "chat_id" {
+				t.Errorf("receive_id_type = %q, want chat_id", r.URL.Query().Get("receive_id_type"))
+			}
+			_ = json.Unmarshal(body, &posted)
+			_, _ = w.Write([]byte(`{"code":0,"msg":"ok","data":{"message_id":"om_1"}}`))
+		case r.Method == http.MethodPatch:
+			patchPath = r.URL.Path
+			_ = json.Unmarshal(body, &patched)
+			_, _ = w.Write([]byte(`{"code":0,"msg":"ok"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewFeishuClient(5 * time.Second)
+	client.apiBase = server.URL
+	sender := feishuSender{client: client}
+	channel := &delivery.Channel{Configuration: delivery.ChannelConfiguration{"app_id": "cli_a", "app_secret": "s", "chat_id": "oc_1"}}
+
+	ctx, upstream := withResponseCapture(context.Background())
+	err := sender.Send(ctx, OutboundMessage{Post: &domainpost.Post{QID: "p-1", Title: "Release"}, Channel: channel, PostURL: "https://example.com/p-1"})
+	if err != nil {
+		t.Fatalf("post card: %v", err)
+	}
+	if upstream.MessageID != "om_1" {
+		t.Errorf("recorded message id = %q, want om_1", upstream.MessageID)
+	}
+	if auth != "Bearer t-123" || posted["receive_id"] != "oc_1" || posted["msg_type"] != "interactive" {
+		t.Errorf("post = %v with auth %q", posted, auth)
+	}
+
+	notice := eventNotice(domainpost.EventDeleted, &domainpost.Post{QID: "p-1", Title: "Release"})
+	err = sender.Send(context.Background(), OutboundMessage{Post: notice, Channel: channel, Event: domainpost.EventDeleted, MessageID: "om_1"})
+	if err != nil {
+		t.Fatalf("update card: %v", err)
+	}
+	if patchPath != "/open-apis/im/v1/messages/om_1" {
+		t.Errorf("patched %q, want the earlier message", patchPath)
+	}
+	var card feishuCardPayload
+	if err := json.Unmarshal([]byte(patched["content"]), &card); err != nil {
+		t.Fatalf("unmarshal card: %v", err)
+	}
+	if card.Header.Title.Content != "Deleted: Release" || card.CardLink != nil {
+		t.Errorf("updated card = %+v, want the deletion notice without a link", card)
+	}
+	if tokenCalls != 1 {
+		t.Errorf("token fetched %d times, want it cached", tokenCalls)
+	}
+}
+
+func TestFeishuTokenReuse(t *testing.T) {
+	cases := []struct {
+		expire int
+		want   time.Duration
+	}{
+		{7200, 7200*time.Second - time.Minute},
+		{120, time.Minute},
+		{60, 30 * time.Second},
+		{0, 0},
+	}
+	for _, c := range cases {
+		if got := feishuTokenReuse(c.expire); got != c.want {
+			t.Errorf("feishuTokenReuse(%d) = %s, want %s", c.expire, got, c.want)
+		}
+	}
+}
+
+func TestFeishuDriver_ValidateApp(t *testing.T) {
+	app := delivery.ChannelConfiguration{"app_id": " cli_a ", "app_secret": "s", "chat_id": "oc_1"}
+	if err := (feishuDriver{}).Validate(app); err != nil {
+		t.Fatalf("app without webhook_url: %v", err)
+	}
+	if app["app_id"] != "cli_a" {
+		t.Errorf("app_id = %q, want it trimmed", app["app_id"])
+	}
+
+	partial := delivery.ChannelConfiguration{"webhook_url": "https://open.feishu.cn/open-apis/bot/v2/hook/x", "app_id": "cli_a"}
+	assertErrCode(t, (feishuDriver{}).Validate(partial), service.ErrValidation)
+}
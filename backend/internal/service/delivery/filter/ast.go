@@ -1,25 +1,45 @@
 package filter
 
+import "regexp"
+
 type node interface {
-	eval(lowerTitle string) bool
+	eval(s *subject) bool
+	explain(s *subject) *Trace
 }
 
 type orNode struct{ left, right node }
 
-func (n orNode) eval(t string) bool { return n.left.eval(t) || n.right.eval(t) }
+func (n orNode) eval(s *subject) bool { return n.left.eval(s) || n.right.eval(s) }
 
 type andNode struct{ left, right node }
 
-func (n andNode) eval(t string) bool { return n.left.eval(t) && n.right.eval(t) }
+func (n andNode) eval(s *subject) bool { return n.left.eval(s) && n.right.eval(s) }
 
 type notNode struct{ operand node }
 
-func (n notNode) eval(t string) bool { return !n.operand.eval(t) }
+func (n notNode) eval(s *subject) bool { return !n.operand.eval(s) }
+
+type keywordNode struct {
+	field Field
+	lower string
+}
+
+func (n keywordNode) eval(s *subject) bool { return containsSubstr(s.text(n.field), n.lower) }
 
-type keywordNode struct{ lower string }
+type wordNode struct {
+	field Field
+	lower string
+}
+
+func (n wordNode) eval(s *subject) bool { return containsWord(s.text(n.field), n.lower) }
+
+type regexNode struct {
+	field Field
+	re    *regexp.Regexp
+}
 
-func (n keywordNode) eval(t string) bool { return containsSubstr(t, n.lower) }
+func (n regexNode) eval(s *subject) bool { return n.re.MatchString(s.text(n.field)) }
 
 type alwaysTrueNode struct{}
 
-func (alwaysTrueNode) eval(string) bool { return true }
+func (alwaysTrueNode) eval(*subject) bool { return true }
@@ -2,6 +2,8 @@ package filter
 
 import (
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/text/unicode/norm"
 )
@@ -13,3 +15,75 @@ func normalizeMatch(s string) string {
 func containsSubstr(lowerTitle, lowerKeyword string) bool {
 	return strings.Contains(lowerTitle, lowerKeyword)
 }
+
+// containsWord reports whether lowerKeyword occurs in lowerText as a whole
+// word: with a word boundary (see isBoundary) on both sides.
+func containsWord(lowerText, lowerKeyword string) bool {
+	first, _ := utf8.DecodeRuneInString(lowerKeyword)
+	last, _ := utf8.DecodeLastRuneInString(lowerKeyword)
+	for from := 0; from <= len(lowerText); {
+		i := strings.Index(lowerText[from:], lowerKeyword)
+		if i < 0 {
+			return false
+		}
+		i += from
+		end := i + len(lowerKeyword)
+		before, _ := utf8.DecodeLastRuneInString(lowerText[:i])
+		after, _ := utf8.DecodeRuneInString(lowerText[end:])
+		if (i == 0 || isBoundary(before, first)) && (end == len(lowerText) || isBoundary(last, after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(lowerText[i:])
+		from = i + size
+	}
+	return false
+}
+
+// isBoundary reports whether there is a word boundary between adjacent runes
+// a and b. Scripts written with spaces (Latin, Cyrillic, Hangul, ...) only
+// break where a letter, digit or mark meets anything else. Scripts written
+// without spaces (Han, kana, Thai, ...) cannot be segmented without a
+// dictionary, so each of their characters counts as a word of its own: a
+// boundary falls on either side of it.
+func isBoundary(a, b rune) bool {
+	if !isWordRune(a) || !isWordRune(b) {
+		return true
+	}
+	return isUnspaced(a) || isUnspaced(b)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+var unspacedScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Katakana,
+	unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+func isUnspaced(r rune) bool {
+	// A prolonged sound mark (ー) is Common script but belongs to the kana
+	// around it.
+	return r == 'ー' || unicode.In(r, unspacedScripts...)
+}
+
+// subject is a Post being matched. Each field is normalized the first time a
+// keyword asks for it, so an expression over titles only never pays for
+// normalizing a long body.
+type subject struct {
+	raw   [fieldCount]string
+	lower [fieldCount]string
+	done  [fieldCount]bool
+}
+
+func newSubject(p Post) *subject {
+	return &subject{raw: [fieldCount]string{p.Title, p.Body, p.Author}}
+}
+
+func (s *subject) text(f Field) string {
+	if !s.done[f] {
+		s.lower[f] = normalizeMatch(s.raw[f])
+		s.done[f] = true
+	}
+	return s.lower[f]
+}
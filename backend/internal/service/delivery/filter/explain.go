@@ -0,0 +1,63 @@
+package filter
+
+import "strings"
+
+// Trace ops, one per kind of expression node.
+const (
+	OpOr      = "or"
+	OpAnd     = "and"
+	OpNot     = "not"
+	OpKeyword = "keyword"
+	OpWord    = "word"
+	OpRegex   = "regex"
+	OpAll     = "all" // the empty expression
+)
+
+// Trace is one node of a compiled expression together with the outcome of
+// evaluating it against a post. Children are the operands of an and, or or
+// not node, left to right. Unlike Match, Explain evaluates every node without
+// short-circuiting, so the outcome of each keyword is visible.
+type Trace struct {
+	Op       string
+	Field    Field  // keyword, word and regex nodes
+	Value    string // the normalized keyword, or the regex pattern
+	Matched  bool
+	Children []*Trace
+}
+
+// Explain evaluates the expression against the post and returns its tree with
+// each node's outcome. The root's Matched equals MatchPost(p).
+func (m *Matcher) Explain(p Post) *Trace {
+	return m.root.explain(newSubject(p))
+}
+
+func (n orNode) explain(s *subject) *Trace {
+	left, right := n.left.explain(s), n.right.explain(s)
+	return &Trace{Op: OpOr, Matched: left.Matched || right.Matched, Children: []*Trace{left, right}}
+}
+
+func (n andNode) explain(s *subject) *Trace {
+	left, right := n.left.explain(s), n.right.explain(s)
+	return &Trace{Op: OpAnd, Matched: left.Matched && right.Matched, Children: []*Trace{left, right}}
+}
+
+func (n notNode) explain(s *subject) *Trace {
+	operand := n.operand.explain(s)
+	return &Trace{Op: OpNot, Matched: !operand.Matched, Children: []*Trace{operand}}
+}
+
+func (n keywordNode) explain(s *subject) *Trace {
+	return &Trace{Op: OpKeyword, Field: n.field, Value: n.lower, Matched: n.eval(s)}
+}
+
+func (n wordNode) explain(s *subject) *Trace {
+	return &Trace{Op: OpWord, Field: n.field, Value: n.lower, Matched: n.eval(s)}
+}
+
+func (n regexNode) explain(s *subject) *Trace {
+	return &Trace{Op: OpRegex, Field: n.field, Value: strings.TrimPrefix(n.re.String(), "(?i)"), Matched: n.eval(s)}
+}
+
+func (alwaysTrueNode) explain(*subject) *Trace {
+	return &Trace{Op: OpAll, Matched: true}
+}
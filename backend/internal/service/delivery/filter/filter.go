@@ -6,17 +6,32 @@
 //	or     := and ( ("," | "|") and )*   // OR, lowest precedence, left-assoc
 //	and    := not ( "&" not )*           // AND, left-assoc
 //	not    := "!" not | factor           // NOT, prefix, right-assoc
-//	factor := KEYWORD | "(" expr ")"
+//	factor := [FIELD ":"] term | "(" expr ")"
+//	term   := ["="] KEYWORD | REGEX
 //
 // Operators are exactly seven ASCII characters: ,  |  &  !  (  )  "
 // Any other character is literal keyword content. Multi-word phrases need no
 // quotes ("key word" == key word). Quotes are required only to include operator
 // characters in a keyword or to preserve leading/trailing spaces.
 //
-// Matching is case-insensitive substring, against the post title only. Both
-// keywords and the title are normalized to Unicode NFC before comparison, so
-// Korean/Vietnamese/diacritic-bearing scripts match across NFC/NFD forms.
-// An empty expression matches everything (always deliver).
+// At the start of a keyword, two more characters have a meaning. "=" makes
+// the keyword match whole words only (=ci matches "CI failed" but not
+// "decision"); each Han, kana or Thai character counts as a word of its own.
+// "/" opens a /regex/ literal in RE2 syntax, ending at the next unescaped "/"
+// (write \/ for a slash); operator characters inside it are pattern text.
+// Patterns are bounded in size and may not match the empty string.
+//
+// A keyword may be qualified by the field it is matched against: title:,
+// body: or author: (case-insensitive) directly at the start of the keyword,
+// as in title:deploy, body:"rollback" or author:alice. A bare keyword matches
+// the title, as it always has. The qualifier is recognized only outside
+// quotes, so "body:x" is the literal keyword body:x.
+//
+// Matching is case-insensitive: substring for keywords, whole-word for =
+// keywords, unanchored for regexes. Both keywords and the matched text
+// are normalized to Unicode NFC before comparison, so Korean/Vietnamese/
+// diacritic-bearing scripts match across NFC/NFD forms. An empty expression
+// matches everything (always deliver).
 package filter
 
 import "fmt"
@@ -31,7 +46,36 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("filter: parse error at pos %d: %s", e.Pos, e.Msg)
 }
 
-// Matcher evaluates a compiled expression against a post title.
+// Field names the part of a post a keyword is matched against.
+type Field int
+
+// The matchable fields. FieldTitle is what a bare keyword matches.
+const (
+	FieldTitle Field = iota
+	FieldBody
+	FieldAuthor
+
+	fieldCount
+)
+
+var fieldNames = [fieldCount]string{"title", "body", "author"}
+
+func (f Field) String() string {
+	if f >= 0 && f < fieldCount {
+		return fieldNames[f]
+	}
+	return "unknown"
+}
+
+// Post is the text a Matcher evaluates: the post's title and Markdown body
+// and its author's username.
+type Post struct {
+	Title  string
+	Body   string
+	Author string
+}
+
+// Matcher evaluates a compiled expression against a post.
 type Matcher struct {
 	root node
 }
@@ -56,7 +100,13 @@ func MustCompile(expr string) *Matcher {
 	return m
 }
 
-// Match reports whether the title satisfies the expression.
+// Match reports whether a post with the given title and no body or author
+// satisfies the expression.
 func (m *Matcher) Match(title string) bool {
-	return m.root.eval(normalizeMatch(title))
+	return m.MatchPost(Post{Title: title})
+}
+
+// MatchPost reports whether the post satisfies the expression.
+func (m *Matcher) MatchPost(p Post) bool {
+	return m.root.eval(newSubject(p))
 }
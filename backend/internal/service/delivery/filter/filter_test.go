@@ -2,6 +2,7 @@ package filter
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -192,6 +193,165 @@ func TestCompile_SpecialCharsAndQuoting(t *testing.T) {
 	})
 }
 
+func TestCompile_FieldQualifiers(t *testing.T) {
+	post := Post{Title: "Deploy finished", Body: "Rolled back after the **rollback** drill", Author: "Alice"}
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"deploy", true},
+		{"rollback", false}, // bare keywords stay title-only
+		{"title:deploy", true},
+		{`body:"rollback"`, true},
+		{"body: rolled back", true},
+		{"author:alice", true},
+		{"AUTHOR:bob", false},
+		{"title:deploy & !body:incident", true},
+		{"author:alice & (title:failed | body:drill)", true},
+		{`"body:rollback"`, false}, // a quoted qualifier is literal text
+		{"note title:deploy", false},
+	}
+	for _, c := range cases {
+		m, err := Compile(c.expr)
+		if err != nil {
+			t.Errorf("compile %q: %v", c.expr, err)
+			continue
+		}
+		if got := m.MatchPost(post); got != c.want {
+			t.Errorf("expr=%q: got %v want %v", c.expr, got, c.want)
+		}
+	}
+
+	for _, e := range []string{"title:", "body: & a", "author:(a | b)", `body:""`, "title:!a"} {
+		var pe *ParseError
+		if _, err := Compile(e); !errors.As(err, &pe) {
+			t.Errorf("expected %q to be rejected with a ParseError, got %v", e, err)
+		}
+	}
+}
+
+func TestCompile_WholeWord(t *testing.T) {
+	t.Run("Latin word boundaries", func(t *testing.T) {
+		runCases(t, "=ci", []matchCase{
+			{"CI failed", true},
+			{"fix ci/cd", true},
+			{"(ci)", true},
+			{"decision", false},
+			{"cicd", false},
+			{"ci_cd", false},
+		})
+	})
+	t.Run("quoted phrase with operators", func(t *testing.T) {
+		runCases(t, `="ci, cd" | =c++`, []matchCase{
+			{"the ci, cd pipeline", true},
+			{"learn C++ today", true},
+			{"ci, cdn", false},
+			{"abc++", false},
+		})
+	})
+	t.Run("later occurrence counts", func(t *testing.T) {
+		runCases(t, "=art", []matchCase{
+			{"start the art show", true},
+			{"start party", false},
+		})
+	})
+	t.Run("CJK characters are words of their own", func(t *testing.T) {
+		runCases(t, "=告警", []matchCase{
+			{"监控告警系统", true},
+			{"告警", true},
+			{"CPU告警", true},
+			{"サーバーエラー告警", true},
+		})
+		runCases(t, "=エラー", []matchCase{
+			{"サーバーエラー", true},
+		})
+		runCases(t, "=cpu", []matchCase{
+			{"CPU告警", true},
+			{"CPUs告警", false},
+		})
+	})
+	t.Run("Hangul is spaced", func(t *testing.T) {
+		runCases(t, "=오류", []matchCase{
+			{"시스템 오류 발생", true},
+			{"오류가", false},
+		})
+	})
+	t.Run("with a field", func(t *testing.T) {
+		m := MustCompile("body:=rollback & !title: = test")
+		if !m.MatchPost(Post{Title: "testing", Body: "a rollback."}) {
+			t.Error("body:=rollback should match a whole word in the body")
+		}
+		if m.MatchPost(Post{Title: "a test", Body: "a rollback."}) {
+			t.Error("!title:=test should exclude a title with the word test")
+		}
+	})
+}
+
+func TestCompile_Regex(t *testing.T) {
+	t.Run("RE2 syntax, case-insensitive", func(t *testing.T) {
+		runCases(t, `/v\d+\.\d+/`, []matchCase{
+			{"Release V2.10", true},
+			{"release v2", false},
+		})
+		runCases(t, `/^\[prod\]/ & !/(debug|trace)/`, []matchCase{
+			{"[PROD] disk full", true},
+			{"[prod] debug dump", false},
+			{"x [prod] disk full", false},
+		})
+	})
+	t.Run("operators inside the pattern are literal", func(t *testing.T) {
+		runCases(t, `/a,b|c&d/, /x\/y/`, []matchCase{
+			{"a,b", true},
+			{"c&d", true},
+			{"path x/y", true},
+			{"a b", false},
+		})
+	})
+	t.Run("against a field", func(t *testing.T) {
+		m := MustCompile(`body:/rollback(ed)?/ & author:/^ali/`)
+		if !m.MatchPost(Post{Body: "we rolled back; rollbacked", Author: "alice"}) {
+			t.Error("field regexes should match")
+		}
+		if m.MatchPost(Post{Body: "rollback", Author: "malice"}) {
+			t.Error("author:/^ali/ should not match malice")
+		}
+	})
+	t.Run("a slash inside a keyword stays literal", func(t *testing.T) {
+		runCases(t, "a/b", []matchCase{{"path a/b/c", true}, {"ab", false}})
+	})
+}
+
+func TestCompile_ParseErrorPositions(t *testing.T) {
+	cases := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{"a & /abc", 4, "unterminated regex"},
+		{"a & /ab(c/", 4, "invalid regex"},
+		{"a, /x*/", 3, "empty string"},
+		{"//", 0, "empty regex"},
+		{"a & /" + strings.Repeat("x", 300) + "/", 4, "longer than"},
+		{"/(ab|cd){500}/", 0, "too complex"},
+		{"a & =", 5, "expected keyword after '='"},
+		{"a & =/x/", 5, "expected keyword after '='"},
+		{"=(a)", 1, "expected keyword after '='"},
+		{"body:/x/y", 8, "unexpected keyword"},
+		{"title: =", 8, "expected keyword after '='"},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.expr)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: expected *ParseError, got %v", c.expr, err)
+			continue
+		}
+		if pe.Pos != c.pos || !strings.Contains(pe.Msg, c.msg) {
+			t.Errorf("%q: got pos %d %q, want pos %d containing %q", c.expr, pe.Pos, pe.Msg, c.pos, c.msg)
+		}
+	}
+}
+
 func TestCompile_EmptyMatchesAll(t *testing.T) {
 	for _, expr := range []string{"", "   ", "\t\n  "} {
 		m, err := Compile(expr)
@@ -213,6 +373,7 @@ func TestCompile_ValidEdgeExpressions(t *testing.T) {
 		`"a,b"`, `"a & b"`, `""""`, `!!a`, `! !a`,
 		"(a | b) & !c", "prod & (error, warning) & !debug",
 		`a\b`, `C++`, `a/b`,
+		"=a", `="a, b"`, "body:=a", "/a+/", `/a\/b/`, "title:/^x/ & !=y",
 	}
 	for _, e := range valid {
 		if _, err := Compile(e); err != nil {
@@ -244,3 +405,34 @@ func TestCompile_RejectsInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestMatcher_Explain(t *testing.T) {
+	m := MustCompile(`prod & (error, body:/time ?out/) & !=debug`)
+	tr := m.Explain(Post{Title: "[prod] debugging", Body: "request timeout"})
+
+	// and(and(prod, or(error, regex)), not(word debug))
+	if tr.Op != OpAnd || len(tr.Children) != 2 || !tr.Matched {
+		t.Fatalf("root = %+v, want a matching and", tr)
+	}
+	left, not := tr.Children[0], tr.Children[1]
+	prod, or := left.Children[0], left.Children[1]
+	if prod.Op != OpKeyword || prod.Value != "prod" || prod.Field != FieldTitle || !prod.Matched {
+		t.Errorf("prod = %+v", prod)
+	}
+	// Both operands of the or are evaluated, though the first decides nothing.
+	if errNode, re := or.Children[0], or.Children[1]; errNode.Matched || re.Op != OpRegex || re.Field != FieldBody || re.Value != "time ?out" || !re.Matched {
+		t.Errorf("or operands = %+v, %+v", errNode, re)
+	}
+	if word := not.Children[0]; not.Op != OpNot || !not.Matched || word.Op != OpWord || word.Matched {
+		t.Errorf("not = %+v, operand %+v; want the word debug unmatched in debugging", not, word)
+	}
+
+	for _, p := range []Post{{Title: "prod error debug"}, {Title: "staging"}, {Title: "prod", Body: "time out"}} {
+		if got := m.Explain(p).Matched; got != m.MatchPost(p) {
+			t.Errorf("Explain(%+v).Matched = %v, MatchPost disagrees", p, got)
+		}
+	}
+	if tr := MustCompile("").Explain(Post{}); tr.Op != OpAll || !tr.Matched {
+		t.Errorf("empty expression = %+v, want a matching all", tr)
+	}
+}
@@ -12,6 +12,8 @@ func FuzzCompile_NeverPanics(f *testing.F) {
 		"C++/a\\b", "🚀go", "错误", "!a & (b | !c)",
 		`"unterminated`, "a &&& b", "(((", "! ! ! a",
 		"中文 关键词 & !英文", "a\tb\nc",
+		"title:a & body:\"b\"", "author:", "Body:x | !title:y",
+		"=ci & !=cd", `/v\d+/ | body:/(a|b)+c/`, `/unterminated`, "/x\\/", "= =a", "/(ab|cd){500}/",
 	}
 	for _, s := range seeds {
 		f.Add(s)
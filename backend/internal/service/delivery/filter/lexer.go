@@ -11,6 +11,9 @@ type tokenKind int
 const (
 	tokenEOF tokenKind = iota
 	tokenKeyword
+	tokenField
+	tokenWord
+	tokenRegex
 	tokenComma
 	tokenPipe
 	tokenAmp
@@ -25,6 +28,12 @@ func (k tokenKind) String() string {
 		return "EOF"
 	case tokenKeyword:
 		return "keyword"
+	case tokenField:
+		return "field qualifier"
+	case tokenWord:
+		return "'='"
+	case tokenRegex:
+		return "regex"
 	case tokenComma:
 		return "','"
 	case tokenPipe:
@@ -44,6 +53,7 @@ func (k tokenKind) String() string {
 type token struct {
 	kind  tokenKind
 	value string
+	field Field // tokenField only
 	pos   int
 }
 
@@ -99,9 +109,32 @@ func (l *lexer) next() (token, error) {
 	case '"':
 		return l.readQuoted(start)
 	}
+	if t, ok := l.readField(start); ok {
+		return t, nil
+	}
+	switch l.input[l.pos] {
+	case '=':
+		l.pos++
+		return token{kind: tokenWord, pos: start}, nil
+	case '/':
+		return l.readRegex(start)
+	}
 	return l.readBare(start)
 }
 
+// readField consumes a field qualifier ("title:", "body:", "author:") at the
+// current position, if there is one.
+func (l *lexer) readField(start int) (token, bool) {
+	for f, name := range fieldNames {
+		end := l.pos + len(name)
+		if end < len(l.input) && l.input[end] == ':' && strings.EqualFold(l.input[l.pos:end], name) {
+			l.pos = end + 1
+			return token{kind: tokenField, field: Field(f), pos: start}, true
+		}
+	}
+	return token{}, false
+}
+
 func (l *lexer) readBare(start int) (token, error) {
 	var buf strings.Builder
 	for l.pos < len(l.input) {
@@ -116,6 +149,35 @@ func (l *lexer) readBare(start int) (token, error) {
 	return token{kind: tokenKeyword, value: value, pos: start}, nil
 }
 
+// readRegex reads a /pattern/ literal. Everything up to the closing slash is
+// pattern text, operator characters included; \/ stands for a slash and any
+// other backslash sequence is passed to the regex compiler as written.
+func (l *lexer) readRegex(start int) (token, error) {
+	l.pos++
+
+	var buf strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return token{}, &ParseError{Pos: start, Msg: "unterminated regex"}
+		}
+		c := l.input[l.pos]
+		switch {
+		case c == '/':
+			l.pos++
+			return token{kind: tokenRegex, value: buf.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.input):
+			if l.input[l.pos+1] != '/' {
+				buf.WriteByte(c)
+			}
+			buf.WriteByte(l.input[l.pos+1])
+			l.pos += 2
+		default:
+			buf.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
 func (l *lexer) readQuoted(start int) (token, error) {
 	l.pos++
 
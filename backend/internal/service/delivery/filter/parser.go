@@ -76,17 +76,51 @@ func (p *parser) parseFactor() node {
 		}
 		p.advance()
 		return inner
-	case tokenKeyword:
-		if p.cur.value == "" {
-			panic(&ParseError{Pos: p.cur.pos, Msg: "empty keyword"})
-		}
-		kw := newKeywordNode(p.cur.value)
+	case tokenField:
+		field := p.cur.field
 		p.advance()
-		return kw
+		switch p.cur.kind {
+		case tokenKeyword, tokenWord, tokenRegex:
+			return p.parseTerm(field)
+		}
+		panic(&ParseError{Pos: p.cur.pos, Msg: fmt.Sprintf("expected keyword after %s:, got %s", field, p.cur.kind)})
+	case tokenKeyword, tokenWord, tokenRegex:
+		return p.parseTerm(FieldTitle)
 	}
 	panic(&ParseError{Pos: p.cur.pos, Msg: fmt.Sprintf("unexpected %s", p.cur.kind)})
 }
 
-func newKeywordNode(raw string) node {
-	return keywordNode{lower: normalizeMatch(raw)}
+// parseTerm parses a keyword, a whole-word keyword or a regex matched against
+// field.
+func (p *parser) parseTerm(field Field) node {
+	switch p.cur.kind {
+	case tokenWord:
+		p.advance()
+		if p.cur.kind != tokenKeyword {
+			panic(&ParseError{Pos: p.cur.pos, Msg: fmt.Sprintf("expected keyword after '=', got %s", p.cur.kind)})
+		}
+		kw := p.parseKeyword(field)
+		return wordNode{field: kw.field, lower: kw.lower}
+	case tokenRegex:
+		re, err := compileRegex(p.cur.value)
+		if err != nil {
+			panic(&ParseError{Pos: p.cur.pos, Msg: err.Error()})
+		}
+		p.advance()
+		return regexNode{field: field, re: re}
+	}
+	return p.parseKeyword(field)
+}
+
+func (p *parser) parseKeyword(field Field) keywordNode {
+	if p.cur.value == "" {
+		panic(&ParseError{Pos: p.cur.pos, Msg: "empty keyword"})
+	}
+	kw := newKeywordNode(field, p.cur.value)
+	p.advance()
+	return kw
+}
+
+func newKeywordNode(field Field, raw string) keywordNode {
+	return keywordNode{field: field, lower: normalizeMatch(raw)}
 }
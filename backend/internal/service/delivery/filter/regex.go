@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Bounds on a /regex/ literal. RE2 matches in time linear in the input, so
+// these cap what one pattern costs to compile and to run per byte: its source
+// length and the size of its compiled program.
+const (
+	maxRegexLen  = 256
+	maxRegexInst = 2000
+)
+
+// compileRegex compiles the pattern of a /regex/ literal. Matching is
+// case-insensitive like keywords, so the pattern runs with (?i) against the
+// normalized text. A pattern that is too large, or that matches the empty
+// string and would therefore match every post, is rejected.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty regex")
+	}
+	if len(pattern) > maxRegexLen {
+		return nil, fmt.Errorf("regex longer than %d bytes", maxRegexLen)
+	}
+	expr := "(?i)" + norm.NFC.String(pattern)
+
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	if len(prog.Inst) > maxRegexInst {
+		return nil, fmt.Errorf("regex too complex (%d instructions, max %d)", len(prog.Inst), maxRegexInst)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	if re.MatchString("") {
+		return nil, errors.New("regex matches the empty string")
+	}
+	return re, nil
+}
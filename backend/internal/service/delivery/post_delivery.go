@@ -1,53 +1,222 @@
-// Package delivery provides delivery channel business logic and services.
 package delivery
 
 import (
 	"context"
+	"fmt"
+	"html"
 	"net"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"markpost/internal/config"
 	"markpost/internal/domain/delivery"
 	"markpost/internal/domain/post"
+	"markpost/internal/domain/user"
 )
 
-// PostDeliveryService is the Sender implementation for Feishu channels: it
-// builds the card payload from the post + channel and issues the webhook call.
-// Channel matching/filtering happens at enqueue time (in Dispatcher.Enqueue),
-// so Send only handles a single already-resolved channel.
+// PostDeliveryService is the Sender implementation for all channel kinds: it
+// resolves the post URL and preview settings once and hands the message to
+// the sender built by the channel kind's driver. Channel matching/filtering
+// happens at enqueue time (in Dispatcher.Enqueue), so Send only handles a
+// single already-resolved channel.
 type PostDeliveryService struct {
-	feishu *FeishuClient
+	senders  map[delivery.ChannelKind]KindSender
+	renderer PostRenderer
 }
 
-// NewPostDeliveryService creates a PostDeliveryService using the configured
-// Feishu request timeout.
+// PostRenderer renders a post body to sanitized HTML. post.Service satisfies
+// it; email delivery uses it so mail carries the same HTML as the post page.
+type PostRenderer interface {
+	RenderPostHTML(ctx context.Context, qid string) (title, html, etag string, createdAt time.Time, err error)
+}
+
+// NewPostDeliveryService creates a PostDeliveryService with a sender for every
+// registered channel kind, using the configured delivery request timeout.
 func NewPostDeliveryService() *PostDeliveryService {
 	cfg := config.Get()
-	return &PostDeliveryService{
-		feishu: NewFeishuClient(cfg.Delivery.RequestTimeout),
-	}
-}
-
-// Send dispatches a Feishu card for the given post to the given channel. Any
-// non-nil error causes the dispatcher to apply backoff or fail the attempt.
-func (s *PostDeliveryService) Send(ctx context.Context, p *post.Post, channel *delivery.Channel) error {
-	switch channel.Kind {
-	case delivery.ChannelKindFeishu:
-		cfg := config.Get()
-		postURL := buildPostURL(cfg.Server.PublicURL, cfg.Server.Host, cfg.Server.Port, p.QID)
-		bodyPreview := buildBodyPreview(p.Body, cfg.Delivery.BodyPreviewChars)
-		return s.feishu.SendCard(ctx, CardDeliveryParams{
-			WebhookURL:  feishuWebhookFromChannel(*channel),
-			CardLinkURL: feishuCardLinkURLFromChannel(*channel),
-			PostURL:     postURL,
-			PostTitle:   p.Title,
-			BodyPreview: bodyPreview,
-			PostQID:     p.QID,
-		})
-	default:
+	return newPostDeliveryService(SenderOptions{Timeout: cfg.Delivery.RequestTimeout, SMTP: cfg.Delivery.SMTP})
+}
+
+func newPostDeliveryService(opts SenderOptions) *PostDeliveryService {
+	senders := make(map[delivery.ChannelKind]KindSender, len(Drivers()))
+	for _, d := range Drivers() {
+		senders[d.Kind()] = d.NewSender(opts)
+	}
+	return &PostDeliveryService{senders: senders}
+}
+
+// WithRenderer sets the post renderer used for email bodies. It must be set
+// before the dispatcher starts; without one, email sends fail.
+func (s *PostDeliveryService) WithRenderer(r PostRenderer) *PostDeliveryService {
+	s.renderer = r
+	return s
+}
+
+// Send dispatches the post to the given channel using the channel kind's
+// message format. Any non-nil error causes the dispatcher to apply backoff or
+// fail the attempt.
+func (s *PostDeliveryService) Send(ctx context.Context, p *post.Post, channel *delivery.Channel, attempt *delivery.Attempt) error {
+	return s.send(ctx, p, channel, attempt, s.postHTML(p.QID))
+}
+
+// postHTML returns a RenderHTML func for the stored post, or nil when no
+// renderer is set.
+func (s *PostDeliveryService) postHTML(qid string) func(ctx context.Context) (string, error) {
+	if s.renderer == nil {
+		return nil
+	}
+	return func(ctx context.Context) (string, error) {
+		_, html, _, _, err := s.renderer.RenderPostHTML(ctx, qid)
+		return html, err
+	}
+}
+
+// Synthetic post used by SendTest. It is never stored, so its HTML is fixed
+// here rather than rendered by the PostRenderer.
+const (
+	testPostAuthor = "markpost"
+	testPostQID    = "p-markpost-test"
+	testPostTitle  = "Markpost test message"
+	testPostBody   = "This is a test message from markpost. If you can read it, the delivery channel works."
+	testPostHTML   = "<p>This is a test message from markpost. If you can read it, the delivery channel works.</p>"
+)
+
+// SendTest sends a synthetic post to the channel right away, outside the
+// attempt queue, and reports what the upstream answered. The channel's
+// credentials, layout and template are exercised as in a real delivery; its
+// keyword filter is not consulted.
+func (s *PostDeliveryService) SendTest(ctx context.Context, channel *delivery.Channel) (UpstreamResponse, error) {
+	p := &post.Post{
+		QID:       testPostQID,
+		Title:     testPostTitle,
+		Body:      testPostBody,
+		UserID:    channel.UserID,
+		User:      user.User{Username: testPostAuthor},
+		CreatedAt: time.Now(),
+	}
+	ctx, resp := withResponseCapture(ctx)
+	err := s.send(ctx, p, channel, nil, func(context.Context) (string, error) { return testPostHTML, nil })
+	return *resp, err
+}
+
+func (s *PostDeliveryService) send(ctx context.Context, p *post.Post, channel *delivery.Channel, attempt *delivery.Attempt, renderHTML func(ctx context.Context) (string, error)) error {
+	sender, ok := s.senders[channel.Kind]
+	if !ok {
+		return errUnsupportedChannelKind{kind: string(channel.Kind)}
+	}
+
+	cfg := config.Get()
+	msg := OutboundMessage{
+		Post:         p,
+		Channel:      channel,
+		Attempt:      attempt,
+		PostURL:      buildPostURL(cfg.Server.PublicURL, cfg.Server.Host, cfg.Server.Port, p.QID),
+		PreviewChars: cfg.Delivery.BodyPreviewChars,
+		RenderHTML:   renderHTML,
+	}
+	if channel.Template != "" {
+		rendered, err := renderMessageTemplate(channel.Template, msg.templateData())
+		if err != nil {
+			return markPermanent(fmt.Errorf("render channel template: %w", err))
+		}
+		msg.applyTemplate(rendered)
+	}
+	return sender.Send(ctx, msg)
+}
+
+// SendDigest sends the posts, oldest first, to the channel as one message: a
+// headline counting them and a list of their titles and links, with the
+// server's base URL as the message link. The channel's message template
+// describes a single post and is not applied.
+func (s *PostDeliveryService) SendDigest(ctx context.Context, posts []*post.Post, channel *delivery.Channel, attempt *delivery.Attempt) error {
+	sender, ok := s.senders[channel.Kind]
+	if !ok {
+		return errUnsupportedChannelKind{kind: string(channel.Kind)}
+	}
+
+	cfg := config.Get()
+	entries := make([]PostEntry, len(posts))
+	for i, p := range posts {
+		entries[i] = PostEntry{
+			QID:       p.QID,
+			Title:     p.Title,
+			URL:       buildPostURL(cfg.Server.PublicURL, cfg.Server.Host, cfg.Server.Port, p.QID),
+			Author:    p.User.Username,
+			CreatedAt: p.CreatedAt,
+		}
+	}
+	text, listHTML := digestListing(entries)
+	last := posts[len(posts)-1]
+	summary := &post.Post{
+		Title:     digestTitle(len(posts)),
+		Body:      text,
+		UserID:    last.UserID,
+		User:      last.User,
+		CreatedAt: last.CreatedAt,
+	}
+	return sender.Send(ctx, OutboundMessage{
+		Post:         summary,
+		Channel:      channel,
+		Attempt:      attempt,
+		PostURL:      buildPostURL(cfg.Server.PublicURL, cfg.Server.Host, cfg.Server.Port, ""),
+		PreviewChars: cfg.Delivery.BodyPreviewChars,
+		Text:         text,
+		RenderHTML:   func(context.Context) (string, error) { return listHTML, nil },
+		Digest:       entries,
+	})
+}
+
+// SendEvent announces a lifecycle event of p other than its creation, taken
+// from the attempt. The kind's usual layout carries a notice (see
+// eventNotice) that links to the post, or to nothing once the post is gone.
+// The channel's message template describes a live post and is applied to
+// updates only. messageID is handed to the kind sender so one that can edit
+// its messages updates the message that announced the post.
+func (s *PostDeliveryService) SendEvent(ctx context.Context, p *post.Post, channel *delivery.Channel, attempt *delivery.Attempt, messageID string) error {
+	sender, ok := s.senders[channel.Kind]
+	if !ok {
 		return errUnsupportedChannelKind{kind: string(channel.Kind)}
 	}
+
+	cfg := config.Get()
+	event := attempt.Event
+	notice := eventNotice(event, p)
+	postURL := ""
+	if !event.Removed() {
+		postURL = buildPostURL(cfg.Server.PublicURL, cfg.Server.Host, cfg.Server.Port, p.QID)
+	}
+	msg := OutboundMessage{
+		Post:         notice,
+		Channel:      channel,
+		Attempt:      attempt,
+		PostURL:      postURL,
+		PreviewChars: cfg.Delivery.BodyPreviewChars,
+		Event:        event,
+		Subject: &PostEntry{
+			QID:       p.QID,
+			Title:     p.Title,
+			URL:       postURL,
+			Author:    p.User.Username,
+			CreatedAt: p.CreatedAt,
+		},
+		MessageID: messageID,
+	}
+	if event.Removed() {
+		noticeHTML := "<p>" + html.EscapeString(notice.Body) + "</p>"
+		msg.RenderHTML = func(context.Context) (string, error) { return noticeHTML, nil }
+	} else {
+		msg.RenderHTML = s.postHTML(p.QID)
+		if channel.Template != "" {
+			rendered, err := renderMessageTemplate(channel.Template, msg.templateData())
+			if err != nil {
+				return markPermanent(fmt.Errorf("render channel template: %w", err))
+			}
+			msg.applyTemplate(rendered)
+		}
+	}
+	return sender.Send(ctx, msg)
 }
 
 type errUnsupportedChannelKind struct{ kind string }
@@ -63,6 +232,17 @@ func buildBodyPreview(body string, maxChars int) string {
 	return preview
 }
 
+// previewWithin builds the body preview capped at a platform's own limit, so a
+// generous body_preview_chars cannot push a message over it. One character of
+// the limit is reserved for the ellipsis buildBodyPreview appends.
+func previewWithin(body string, configuredChars, platformMaxChars int) string {
+	limit := configuredChars
+	if limit > platformMaxChars-1 {
+		limit = platformMaxChars - 1
+	}
+	return buildBodyPreview(body, limit)
+}
+
 func truncateRunes(s string, maxRunes int) string {
 	if maxRunes <= 0 || s == "" {
 		return ""
@@ -75,6 +255,22 @@ func truncateRunes(s string, maxRunes int) string {
 	return string(r[:maxRunes])
 }
 
+// truncateUTF8Bytes shortens s to at most maxBytes bytes without splitting a
+// multi-byte rune, for platforms whose limits are counted in UTF-8 bytes.
+func truncateUTF8Bytes(s string, maxBytes int) string {
+	if maxBytes <= 0 || s == "" {
+		return ""
+	}
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
 func buildPostURL(publicURL string, host string, port uint16, qid string) string {
 	base := strings.TrimRight(strings.TrimSpace(publicURL), "/")
 	if base == "" {
@@ -2,9 +2,13 @@ package delivery
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +88,19 @@ func TestTruncateRunes(t *testing.T) {
 	}
 }
 
+func TestPreviewWithin(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	if got := previewWithin(body, 200, 50); len([]rune(got)) != 50 {
+		t.Errorf("preview length = %d, want platform cap 50 including ellipsis", len([]rune(got)))
+	}
+	if got := previewWithin(body, 10, 50); got != strings.Repeat("a", 10)+"…" {
+		t.Errorf("preview = %q, want configured length kept", got)
+	}
+	if got := previewWithin(body, 0, 50); got != "" {
+		t.Errorf("preview = %q, want empty when previews are disabled", got)
+	}
+}
+
 func TestBuildPostURL(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -194,8 +211,8 @@ func TestPostDeliveryService_Send(t *testing.T) {
 		}
 		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body"}
 
-		svc := &PostDeliveryService{feishu: NewFeishuClient(5 * time.Second)}
-		if err := svc.Send(context.Background(), p, channel); err != nil {
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
 			t.Fatalf("Send error: %v", err)
 		}
 		if !received {
@@ -203,6 +220,267 @@ func TestPostDeliveryService_Send(t *testing.T) {
 		}
 	})
 
+	t.Run("sends slack message to webhook", func(t *testing.T) {
+		var received bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			received = true
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindSlack,
+			Configuration: delivery.ChannelConfiguration{"webhook_url": server.URL},
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body"}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+		if !received {
+			t.Error("expected slack webhook to be called")
+		}
+	})
+
+	t.Run("sends rendered channel template in place of the preview", func(t *testing.T) {
+		var payload struct {
+			Blocks []struct {
+				Text *struct {
+					Text string `json:"text"`
+				} `json:"text"`
+			} `json:"blocks"`
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindSlack,
+			Configuration: delivery.ChannelConfiguration{"webhook_url": server.URL},
+			Template:      "{{.Author}} posted {{.QID}}",
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body", User: user.User{Username: "alice"}}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+		var found bool
+		for _, b := range payload.Blocks {
+			if b.Text != nil && strings.Contains(b.Text.Text, "alice posted p-test") {
+				found = true
+			}
+			if b.Text != nil && strings.Contains(b.Text.Text, "Body") {
+				t.Errorf("block %q still carries the body preview", b.Text.Text)
+			}
+		}
+		if !found {
+			t.Errorf("payload blocks = %+v, want rendered template", payload.Blocks)
+		}
+	})
+
+	t.Run("template blocks set the card title, button and link", func(t *testing.T) {
+		var payload struct {
+			Card struct {
+				Header struct {
+					Title struct {
+						Content string `json:"content"`
+					} `json:"title"`
+				} `json:"header"`
+				Body struct {
+					Elements []struct {
+						Tag  string `json:"tag"`
+						Text *struct {
+							Content string `json:"content"`
+						} `json:"text"`
+						URL string `json:"url"`
+					} `json:"elements"`
+				} `json:"body"`
+				CardLink struct {
+					URL string `json:"url"`
+				} `json:"card_link"`
+			} `json:"card"`
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			_, _ = w.Write([]byte(`{"code":0}`))
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID: 1,
+			Kind:   delivery.ChannelKindFeishu,
+			Configuration: delivery.ChannelConfiguration{
+				"webhook_url":   server.URL,
+				"card_link_url": "https://app.example.com/open/{{.QID}}",
+			},
+			Template: `{{define "title"}}[{{.Author}}] {{.Title}}{{end}}` +
+				`{{define "button"}}Read on the mirror{{end}}` +
+				`{{define "link"}}https://mirror.example.com/{{.QID}}{{end}}`,
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body", User: user.User{Username: "alice"}}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+		if got := payload.Card.Header.Title.Content; got != "[alice] Test" {
+			t.Errorf("header = %q, want the title block", got)
+		}
+		var button, body bool
+		for _, e := range payload.Card.Body.Elements {
+			switch e.Tag {
+			case "button":
+				button = e.Text != nil && e.Text.Content == "Read on the mirror" && e.URL == "https://mirror.example.com/p-test"
+			case "markdown":
+				body = true
+			}
+		}
+		if !button || !body {
+			t.Errorf("elements = %+v, want the body preview and the templated button", payload.Card.Body.Elements)
+		}
+		if got := payload.Card.CardLink.URL; got != "https://app.example.com/open/p-test" {
+			t.Errorf("card link = %q, want card_link_url to keep precedence", got)
+		}
+	})
+
+	t.Run("sends signed webhook event with attempt metadata", func(t *testing.T) {
+		var header http.Header
+		var event WebhookEvent
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Clone()
+			_ = json.NewDecoder(r.Body).Decode(&event)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID: 1,
+			Kind:   delivery.ChannelKindWebhook,
+			Configuration: delivery.ChannelConfiguration{
+				"webhook_url": server.URL,
+				"secret":      "0123456789abcdef",
+			},
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body", User: user.User{Username: "alice"}}
+		postID := 1
+		attempt := &delivery.Attempt{ID: 9, PostID: &postID, ChannelID: 2, Attempts: 1}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, attempt); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+		if got := header.Get(WebhookHeaderAttempt); got != "2" {
+			t.Errorf("attempt header = %q, want %q", got, "2")
+		}
+		if got := header.Get(WebhookHeaderIdempotencyKey); got != webhookIdempotencyKey(attempt) {
+			t.Errorf("idempotency header = %q, want %q", got, webhookIdempotencyKey(attempt))
+		}
+		if event.Post.Author != "alice" {
+			t.Errorf("author = %q, want %q", event.Post.Author, "alice")
+		}
+	})
+
+	t.Run("sends a digest as one combined message", func(t *testing.T) {
+		var event WebhookEvent
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&event)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID:   1,
+			Kind:     delivery.ChannelKindWebhook,
+			Template: "{{.Title}}",
+			Configuration: delivery.ChannelConfiguration{
+				"webhook_url": server.URL,
+				"secret":      "0123456789abcdef",
+			},
+		}
+		posts := []*domainpost.Post{
+			{ID: 1, QID: "p-one", Title: "One", User: user.User{Username: "alice"}},
+			{ID: 2, QID: "p-two", Title: "Two", User: user.User{Username: "alice"}},
+		}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.SendDigest(context.Background(), posts, channel, &delivery.Attempt{ID: 3, DigestSize: 2}); err != nil {
+			t.Fatalf("SendDigest error: %v", err)
+		}
+		if event.Type != WebhookEventPostDigest || event.Post.Title != "2 new posts" || len(event.Posts) != 2 {
+			t.Errorf("event = %+v, want a post.digest of 2", event)
+		}
+		if !strings.Contains(event.Text, "- One ") || !strings.Contains(event.Text, "/p-two") {
+			t.Errorf("text = %q, want the listing rather than the channel template", event.Text)
+		}
+	})
+
+	t.Run("sends email with rendered post html", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindEmail,
+			Configuration: delivery.ChannelConfiguration{"recipients": []any{"a@example.com"}},
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body"}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second, SMTP: server.config()}).
+			WithRenderer(stubRenderer{html: "<p>rendered-body</p>"})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		if !strings.Contains(server.data, "rendered-body") {
+			t.Errorf("message = %q, want rendered HTML from the renderer", server.data)
+		}
+	})
+
+	t.Run("returns error for email without renderer", func(t *testing.T) {
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindEmail,
+			Configuration: delivery.ChannelConfiguration{"recipients": []any{"a@example.com"}},
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test"}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err == nil {
+			t.Fatal("expected error without a post renderer")
+		}
+	})
+
+	t.Run("sends discord embed to webhook", func(t *testing.T) {
+		var received bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			received = true
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindDiscord,
+			Configuration: delivery.ChannelConfiguration{"webhook_url": server.URL},
+		}
+		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test", Body: "Body"}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+		if !received {
+			t.Error("expected discord webhook to be called")
+		}
+	})
+
 	t.Run("returns error on feishu failure", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -216,8 +494,8 @@ func TestPostDeliveryService_Send(t *testing.T) {
 		}
 		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test"}
 
-		svc := &PostDeliveryService{feishu: NewFeishuClient(5 * time.Second)}
-		if err := svc.Send(context.Background(), p, channel); err == nil {
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		if err := svc.Send(context.Background(), p, channel, nil); err == nil {
 			t.Fatal("expected error from failed webhook")
 		}
 	})
@@ -226,14 +504,73 @@ func TestPostDeliveryService_Send(t *testing.T) {
 		channel := &delivery.Channel{UserID: 1, Kind: "unknown"}
 		p := &domainpost.Post{ID: 1, QID: "p-test", Title: "Test"}
 
-		svc := &PostDeliveryService{feishu: NewFeishuClient(5 * time.Second)}
-		err := svc.Send(context.Background(), p, channel)
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		err := svc.Send(context.Background(), p, channel, nil)
 		if err == nil {
 			t.Fatal("expected error for unsupported kind")
 		}
 	})
 }
 
+type stubRenderer struct{ html string }
+
+func (r stubRenderer) RenderPostHTML(context.Context, string) (string, string, string, time.Time, error) {
+	return "", r.html, "", time.Time{}, nil
+}
+
+func TestPostDeliveryService_SendTest(t *testing.T) {
+	loadDeliveryTestConfig(t)
+
+	t.Run("captures upstream status and body", func(t *testing.T) {
+		var payload map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(strings.Repeat("x", 2*maxCapturedBodyBytes)))
+		}))
+		defer server.Close()
+
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindSlack,
+			Configuration: delivery.ChannelConfiguration{"webhook_url": server.URL},
+		}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second})
+		resp, err := svc.SendTest(context.Background(), channel)
+		if err == nil {
+			t.Fatal("expected error for 403 upstream")
+		}
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+		if len(resp.Body) != maxCapturedBodyBytes {
+			t.Errorf("body length = %d, want truncated to %d", len(resp.Body), maxCapturedBodyBytes)
+		}
+		if !strings.Contains(fmt.Sprint(payload), testPostTitle) {
+			t.Errorf("payload = %v, want synthetic post title", payload)
+		}
+	})
+
+	t.Run("email uses the fixed test html", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		channel := &delivery.Channel{
+			UserID:        1,
+			Kind:          delivery.ChannelKindEmail,
+			Configuration: delivery.ChannelConfiguration{"recipients": []any{"a@example.com"}},
+		}
+
+		svc := newPostDeliveryService(SenderOptions{Timeout: 5 * time.Second, SMTP: server.config()})
+		resp, err := svc.SendTest(context.Background(), channel)
+		if err != nil {
+			t.Fatalf("SendTest error: %v", err)
+		}
+		if resp.StatusCode != 0 {
+			t.Errorf("status = %d, want 0 for a non-HTTP kind", resp.StatusCode)
+		}
+	})
+}
+
 func TestNewPostDeliveryService(t *testing.T) {
 	loadDeliveryTestConfig(t)
 
@@ -241,8 +578,10 @@ func TestNewPostDeliveryService(t *testing.T) {
 	if svc == nil {
 		t.Fatal("expected non-nil service")
 	}
-	if svc.feishu == nil {
-		t.Error("expected non-nil feishu client")
+	for _, d := range Drivers() {
+		if svc.senders[d.Kind()] == nil {
+			t.Errorf("expected a sender for kind %q", d.Kind())
+		}
 	}
 }
 
@@ -326,8 +665,192 @@ func TestDispatcher_EnqueueInsertsMatchingAttempts(t *testing.T) {
 	if a.ChannelID != cid {
 		t.Errorf("channel_id = %d, want %d", a.ChannelID, cid)
 	}
-	if a.PostID != pid {
-		t.Errorf("post_id = %d, want %d", a.PostID, pid)
+	if a.PostID == nil || *a.PostID != pid {
+		t.Errorf("post_id = %v, want %d", a.PostID, pid)
+	}
+	if wall := time.UnixMilli(a.ExpiresAt).Sub(a.CreatedAt); wall < 39*time.Minute || wall > 41*time.Minute {
+		t.Errorf("expires_at is %v after created_at, want the default 40m wall", wall)
+	}
+}
+
+func TestDispatcher_EnqueueMatchesFieldQualifiers(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("keywords", "body:boom & author:alice")
+	for name, keywords := range map[string]string{"body-only": "boom", "other-author": "author:bob"} {
+		if err := db.Create(&delivery.Channel{UserID: uid, Kind: delivery.ChannelKindFeishu, Name: name, Enabled: true, Configuration: makeFeishuChannelConfig("https://example.com/webhook"), Keywords: keywords}).Error; err != nil {
+			t.Fatalf("seed channel: %v", err)
+		}
+	}
+
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(db), infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), &recordingSender{})
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert", Body: "boom"})
+
+	var attempts []delivery.Attempt
+	if err := db.Find(&attempts).Error; err != nil {
+		t.Fatalf("find attempts: %v", err)
+	}
+	// A bare keyword still matches the title only.
+	if len(attempts) != 1 || attempts[0].ChannelID != cid {
+		t.Errorf("attempts = %+v, want one for the body/author channel", attempts)
+	}
+}
+
+func TestDispatcher_EnqueueRecordsRoutes(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	seed := func(name, keywords string, events delivery.EventSet) int {
+		t.Helper()
+		ch := &delivery.Channel{UserID: uid, Kind: delivery.ChannelKindFeishu, Name: name, Enabled: true, Configuration: makeFeishuChannelConfig("https://example.com/" + name), Keywords: keywords, Events: events}
+		if err := db.Create(ch).Error; err != nil {
+			t.Fatalf("seed channel: %v", err)
+		}
+		return ch.ID
+	}
+	muted := seed("muted", "alert", nil)
+	db.Model(&delivery.Channel{}).Where("id = ?", muted).Update("enabled", false)
+	seed("edits", "alert", delivery.EventSet{domainpost.EventUpdated})
+	broken := seed("broken", "alert", nil)
+	db.Model(&delivery.Channel{}).Where("id = ?", broken).Update("keywords", "alert &")
+	seed("deploys", "deploy", nil)
+
+	attemptRepo := infra.NewAttemptRepository(db)
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), &recordingSender{})
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+
+	ctx := context.Background()
+	svc := NewService(infra.NewDeliveryChannelRepository(db), attemptRepo)
+	routes, err := svc.ListRoutes(ctx, uid, "p-seed")
+	if err != nil {
+		t.Fatalf("ListRoutes: %v", err)
+	}
+	if len(routes) != 5 {
+		t.Fatalf("routes = %d, want a summary and one per unmatched channel", len(routes))
+	}
+	if r := routes[0]; r.Decision != delivery.DecisionSummary || r.ChannelID != nil || r.Detail != "1 of 5 channels matched" {
+		t.Errorf("first route = %+v, want the event summary", r)
+	}
+	want := map[string]delivery.Decision{
+		"muted":   delivery.DecisionDisabled,
+		"edits":   delivery.DecisionUnsubscribed,
+		"broken":  delivery.DecisionInvalidFilter,
+		"deploys": delivery.DecisionUnmatched,
+	}
+	for i, r := range routes[1:] {
+		if r.ChannelName == nil || r.Decision != want[*r.ChannelName] || r.Event != domainpost.EventCreated {
+			t.Errorf("route %d = %+v", i+1, r)
+			continue
+		}
+		if *r.ChannelID == cid {
+			t.Errorf("route %d = %+v, want no row for the matching channel", i+1, r)
+		}
+		if (r.Decision == delivery.DecisionInvalidFilter) != (r.Detail != "") {
+			t.Errorf("route %s detail = %q, want the parse error only for the broken filter", *r.ChannelName, r.Detail)
+		}
+	}
+
+	if others, err := svc.ListRoutes(ctx, uid+1, "p-seed"); err != nil || len(others) != 0 {
+		t.Errorf("another user's routes = %v, %v; want none", others, err)
+	}
+
+	db.Model(&delivery.Route{}).Where("1 = 1").Update("created_at", time.Now().Add(-48*time.Hour))
+	if _, err := attemptRepo.PruneHistory(ctx, 24*time.Hour, 2); err != nil {
+		t.Fatalf("PruneHistory: %v", err)
+	}
+	var left int64
+	db.Model(&delivery.Route{}).Count(&left)
+	if left != 0 {
+		t.Errorf("routes left after pruning = %d, want 0", left)
+	}
+}
+
+func TestDispatcher_EnqueueUsesChannelRetryPolicyWall(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	policy := &delivery.RetryPolicy{Mode: RetryModeSequence, Sequence: []string{"30m", "1h"}}
+	if err := database.DB().Model(&delivery.Channel{}).Where("id = ?", cid).Update("retry_policy", policy).Error; err != nil {
+		t.Fatalf("set retry policy: %v", err)
+	}
+
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), &recordingSender{})
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+
+	var a delivery.Attempt
+	if err := database.DB().First(&a).Error; err != nil {
+		t.Fatalf("find attempt: %v", err)
+	}
+	if want := now.Add(90 * time.Minute).UnixMilli(); a.ExpiresAt != want {
+		t.Errorf("expires_at = %d, want %d (30m+1h wall)", a.ExpiresAt, want)
+	}
+}
+
+func TestDispatcher_RetryAfterOverridesBackoff(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	now := time.Now()
+	// The wall falls before the platform's Retry-After.
+	wall := now.Add(time.Minute).UnixMilli()
+	attempt := &delivery.Attempt{UserID: uid, PostID: &pid, ChannelID: cid, Status: delivery.StatusPending, NextAt: now.UnixMilli(), ExpiresAt: wall, CreatedAt: now, UpdatedAt: now}
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	if err := attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), &recordingSender{})
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.handleSendError(ctx, attempt, dispatcher.retry, withRetryAfter(errors.New("slack webhook status=429"), 90*time.Second))
+	var got delivery.Attempt
+	database.DB().First(&got, attempt.ID)
+	if want := now.Add(90 * time.Second).UnixMilli(); got.NextAt != want {
+		t.Errorf("next_at = %d, want %d (Retry-After)", got.NextAt, want)
+	}
+	if want := wall + (90 * time.Second).Milliseconds(); got.ExpiresAt != want || got.ExpiresAt <= got.NextAt {
+		t.Errorf("expires_at = %d, want %d (wall moved past the wait)", got.ExpiresAt, want)
+	}
+	wall = got.ExpiresAt
+
+	// Without Retry-After the policy's delay applies, spread by jitter.
+	got.Attempts = 0
+	dispatcher.rand = func() float64 { return 1 }
+	policy := RetryPolicy{Sequence: []time.Duration{time.Minute}, MaxAttempts: 2, Jitter: 0.5}
+	dispatcher.handleSendError(ctx, &got, policy, errors.New("boom"))
+	database.DB().First(&got, attempt.ID)
+	if want := now.Add(90 * time.Second).UnixMilli(); got.NextAt != want {
+		t.Errorf("next_at = %d, want %d (1m + 50%% jitter)", got.NextAt, want)
+	}
+	if got.ExpiresAt != wall {
+		t.Errorf("expires_at = %d, want the wall %d kept for an ordinary backoff", got.ExpiresAt, wall)
 	}
 }
 
@@ -345,7 +868,7 @@ func TestDispatcher_ClaimExecuteArchive(t *testing.T) {
 	now := time.Now()
 	attempt := &delivery.Attempt{
 		UserID:    uid,
-		PostID:    pid,
+		PostID:    &pid,
 		ChannelID: cid,
 		Status:    delivery.StatusPending,
 		NextAt:    now.UnixMilli(),
@@ -358,7 +881,7 @@ func TestDispatcher_ClaimExecuteArchive(t *testing.T) {
 	}
 
 	// Claim returns the one due attempt.
-	claimed, err := attemptRepo.ClaimDue(ctx, now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10)
+	claimed, err := attemptRepo.ClaimDue(ctx, "test-instance", now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10)
 	if err != nil {
 		t.Fatalf("ClaimDue: %v", err)
 	}
@@ -370,7 +893,7 @@ func TestDispatcher_ClaimExecuteArchive(t *testing.T) {
 	}
 
 	// A second claim right away must not re-claim the reserved row.
-	again, err := attemptRepo.ClaimDue(ctx, now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10)
+	again, err := attemptRepo.ClaimDue(ctx, "test-instance", now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10)
 	if err != nil {
 		t.Fatalf("ClaimDue second: %v", err)
 	}
@@ -408,7 +931,7 @@ func TestDispatcher_MarkRetryAdvancesNextAt(t *testing.T) {
 	now := time.Now()
 	attempt := &delivery.Attempt{
 		UserID:    uid,
-		PostID:    pid,
+		PostID:    &pid,
 		ChannelID: cid,
 		Status:    delivery.StatusPending,
 		NextAt:    now.UnixMilli(),
@@ -421,7 +944,7 @@ func TestDispatcher_MarkRetryAdvancesNextAt(t *testing.T) {
 	}
 
 	wantAt := now.Add(time.Minute).UnixMilli()
-	if err := attemptRepo.MarkRetry(ctx, attempt.ID, 1, "boom", wantAt); err != nil {
+	if err := attemptRepo.MarkRetry(ctx, attempt.ID, 1, "boom", wantAt, 0); err != nil {
 		t.Fatalf("MarkRetry: %v", err)
 	}
 
@@ -438,8 +961,219 @@ func TestDispatcher_MarkRetryAdvancesNextAt(t *testing.T) {
 	}
 }
 
+func TestDispatcher_PermanentFailureSkipsBackoff(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	now := time.Now()
+	attempt := &delivery.Attempt{
+		UserID:    uid,
+		PostID:    &pid,
+		ChannelID: cid,
+		Status:    delivery.StatusPending,
+		NextAt:    now.UnixMilli(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	if err := attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), &recordingSender{})
+	dispatcher.handleSendError(ctx, attempt, dispatcher.retry, markPermanent(errors.New("robot removed")))
+
+	var remaining []delivery.Attempt
+	database.DB().Find(&remaining)
+	if len(remaining) != 0 {
+		t.Errorf("expected attempt archived on first permanent failure, %d remain", len(remaining))
+	}
+	var history []delivery.History
+	database.DB().Find(&history)
+	if len(history) != 1 || history[0].Status != delivery.StatusFailed {
+		t.Fatalf("expected 1 failed history row, got %+v", history)
+	}
+	if history[0].LastError != "robot removed" {
+		t.Errorf("last_error = %q, want %q", history[0].LastError, "robot removed")
+	}
+}
+
+func TestDispatcher_EnqueueWakesScheduler(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, _ := seedUserPostChannel(t, database)
+	sender := &signalSender{sent: make(chan struct{}, 1)}
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(database.DB()), infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), sender)
+	// A tick that never fires within the test: only the wake-up can claim.
+	dispatcher.cfg.ScanInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatcher.Start(ctx)
+	defer dispatcher.Stop()
+
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+	select {
+	case <-sender.sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("enqueued attempt was not claimed before the next tick")
+	}
+}
+
+func TestDispatcher_WakeCoalesces(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	dispatcher := NewDispatcher(nil, nil, nil, &recordingSender{})
+	defer dispatcher.pool.StopAndWait()
+
+	for range 3 {
+		dispatcher.Wake() // must not block without a running scheduler
+	}
+	if len(dispatcher.wake) != 1 {
+		t.Errorf("pending wake-ups = %d, want 1", len(dispatcher.wake))
+	}
+}
+
+func TestDispatcher_StopReleasesLeases(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	now := time.Now()
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	attempt := &delivery.Attempt{UserID: uid, PostID: &pid, ChannelID: cid, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now}
+	if err := attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), &recordingSender{})
+	dispatcher.cfg.ScanInterval = time.Hour
+	// Claimed by this instance but never started, as if still in the pool
+	// queue at shutdown.
+	if _, err := attemptRepo.ClaimDue(ctx, dispatcher.Owner(), now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10); err != nil {
+		t.Fatalf("ClaimDue: %v", err)
+	}
+	dispatcher.Start(ctx)
+	dispatcher.Stop()
+
+	var got delivery.Attempt
+	database.DB().First(&got, attempt.ID)
+	if got.LeaseOwner != "" || got.LeaseExpiresAt != 0 {
+		t.Errorf("lease = %q until %d, want released on Stop", got.LeaseOwner, got.LeaseExpiresAt)
+	}
+}
+
+func TestDispatcher_StopWithoutStart(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	dispatcher := NewDispatcher(infra.NewAttemptRepository(database.DB()), infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), nil)
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop blocked on a dispatcher that was never started")
+	}
+	if !dispatcher.pool.Stopped() {
+		t.Error("worker pool still running after Stop")
+	}
+}
+
+func TestDispatcher_PoolDropReleasesLease(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	ctx := context.Background()
+	now := time.Now()
+	attemptRepo := infra.NewAttemptRepository(database.DB())
+	attempt := &delivery.Attempt{UserID: uid, PostID: &pid, ChannelID: cid, Status: delivery.StatusPending, NextAt: now.UnixMilli(), CreatedAt: now, UpdatedAt: now}
+	if err := attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(database.DB()), infra.NewPostRepository(database.DB()), &recordingSender{})
+	dispatcher.now = func() time.Time { return now }
+	// A stopped pool refuses every task.
+	dispatcher.pool.StopAndWait()
+	dispatcher.claimDue(ctx)
+
+	var got delivery.Attempt
+	database.DB().First(&got, attempt.ID)
+	if got.LeaseOwner != "" || got.LeaseExpiresAt != 0 {
+		t.Errorf("lease = %q until %d, want released when the pool drops the attempt", got.LeaseOwner, got.LeaseExpiresAt)
+	}
+	if ids := dispatcher.inflightIDs(); len(ids) != 0 {
+		t.Errorf("in flight = %v, want none", ids)
+	}
+
+	// The heartbeat renews only attempts being executed: a row leased to this
+	// instance but not tracked runs out its lease.
+	if _, err := attemptRepo.ClaimDue(ctx, dispatcher.Owner(), now.UnixMilli(), now.Add(time.Minute).UnixMilli(), 10); err != nil {
+		t.Fatalf("ClaimDue: %v", err)
+	}
+	dispatcher.renewLeases(ctx)
+	database.DB().First(&got, attempt.ID)
+	if got.LeaseExpiresAt != now.Add(time.Minute).UnixMilli() {
+		t.Errorf("lease_expires_at = %d, want untracked lease left as claimed", got.LeaseExpiresAt)
+	}
+}
+
+type signalSender struct{ sent chan struct{} }
+
+func (s *signalSender) Send(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	select {
+	case s.sent <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *signalSender) SendDigest(ctx context.Context, posts []*domainpost.Post, channel *delivery.Channel, attempt *delivery.Attempt) error {
+	return s.Send(ctx, posts[0], channel, attempt)
+}
+
+func (s *signalSender) SendEvent(ctx context.Context, p *domainpost.Post, channel *delivery.Channel, attempt *delivery.Attempt, _ string) error {
+	return s.Send(ctx, p, channel, attempt)
+}
+
 type recordingSender struct{}
 
-func (recordingSender) Send(_ context.Context, _ *domainpost.Post, _ *delivery.Channel) error {
+func (recordingSender) Send(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	return nil
+}
+
+func (recordingSender) SendDigest(_ context.Context, _ []*domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt) error {
+	return nil
+}
+
+func (recordingSender) SendEvent(_ context.Context, _ *domainpost.Post, _ *delivery.Channel, _ *delivery.Attempt, _ string) error {
 	return nil
 }
@@ -0,0 +1,135 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"markpost/internal/domain"
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/service"
+)
+
+// MaxBulkRetry bounds the number of history entries one bulk retry may
+// re-enqueue.
+const MaxBulkRetry = 500
+
+// RetryOutcome is the per-entry result of a bulk retry. Attempt is set when a
+// fresh attempt was enqueued; Err otherwise.
+type RetryOutcome struct {
+	HistoryID int64
+	Attempt   *delivery.Attempt
+	Err       error
+}
+
+// RetryHistory re-enqueues one of the user's failed or expired deliveries as a
+// fresh attempt for the same post and channel. The new attempt starts a new
+// retry sequence and expiry wall; the history row itself is left untouched.
+func (s *Service) RetryHistory(ctx context.Context, userID int, id int64) (*delivery.Attempt, error) {
+	h, err := s.attemptRepo.GetHistory(ctx, id, userID)
+	if err != nil {
+		return nil, service.WrapNotFoundOrInternal(err, "delivery history not found", "get delivery history failed")
+	}
+	return s.requeue(ctx, h)
+}
+
+// RetryHistoryBulk re-enqueues the given history entries regardless of owner
+// (admin use). Entries are handled independently: one that cannot be retried
+// is reported in its outcome and does not stop the others.
+func (s *Service) RetryHistoryBulk(ctx context.Context, ids []int64) ([]RetryOutcome, error) {
+	if len(ids) == 0 {
+		return nil, service.New(service.ErrValidation, "no history ids given")
+	}
+	if len(ids) > MaxBulkRetry {
+		return nil, service.New(service.ErrValidation, "too many history ids")
+	}
+
+	outcomes := make([]RetryOutcome, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		outcome := RetryOutcome{HistoryID: id}
+		h, err := s.attemptRepo.GetHistory(ctx, id, 0)
+		if err != nil {
+			outcome.Err = service.WrapNotFoundOrInternal(err, "delivery history not found", "get delivery history failed")
+		} else {
+			outcome.Attempt, outcome.Err = s.requeue(ctx, h)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
+}
+
+// requeue inserts a pending attempt for the history entry's event, post and
+// channel, due at once or when the channel's delivery window next opens. Only
+// failed and expired entries qualify; the channel must still exist and be
+// enabled, and so must the post unless the entry announces its removal, which
+// is re-sent from the snapshot the entry kept. No attempt for the same event,
+// post and channel may already be queued (so repeated clicks do not fan out
+// duplicate sends).
+func (s *Service) requeue(ctx context.Context, h *delivery.History) (*delivery.Attempt, error) {
+	if h.Status != delivery.StatusFailed && h.Status != delivery.StatusExpired {
+		return nil, service.New(ErrDeliveryNotRetryable, "only failed or expired deliveries can be retried")
+	}
+	if h.UserID == nil || h.ChannelID == nil {
+		return nil, service.New(ErrDeliveryTargetGone, "channel was deleted")
+	}
+	event := h.Event
+	if event == "" {
+		event = domainpost.EventCreated
+	}
+	if event.Removed() {
+		if h.Subject == nil {
+			return nil, service.New(ErrDeliveryNotRetryable, "no snapshot of the removed post was kept")
+		}
+	} else if h.PostID == nil {
+		return nil, service.New(ErrDeliveryTargetGone, "post was deleted")
+	}
+
+	ch, err := s.repo.GetByIDAndUserID(ctx, *h.ChannelID, *h.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, service.New(ErrDeliveryTargetGone, "channel was deleted")
+		}
+		return nil, service.Wrap(service.ErrInternal, "get channel failed", err)
+	}
+	if !ch.Enabled {
+		return nil, service.New(ErrDeliveryNotRetryable, "channel is disabled")
+	}
+
+	now := time.Now()
+	start := deliveryStart(ch, now)
+	attempt := &delivery.Attempt{
+		UserID:    *h.UserID,
+		PostID:    h.PostID,
+		ChannelID: ch.ID,
+		Event:     event,
+		Subject:   h.Subject,
+		Status:    delivery.StatusPending,
+		NextAt:    start.UnixMilli(),
+		ExpiresAt: resolveRetryPolicy(s.retry, ch).expiresAt(start),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if event.Removed() {
+		attempt.PostID = nil
+	}
+
+	pending, err := s.attemptRepo.HasPending(ctx, attempt)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "check pending attempts failed", err)
+	}
+	if pending {
+		return nil, service.New(ErrDeliveryNotRetryable, "a delivery of this event to this channel is already pending")
+	}
+
+	if err := s.attemptRepo.Create(ctx, []*delivery.Attempt{attempt}); err != nil {
+		return nil, service.Wrap(service.ErrInternal, "enqueue attempt failed", err)
+	}
+	return attempt, nil
+}
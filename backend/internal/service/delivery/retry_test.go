@@ -0,0 +1,174 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
+	"markpost/internal/domain/user"
+	"markpost/internal/infra"
+	"markpost/internal/service"
+
+	"gorm.io/gorm"
+)
+
+type retryFixture struct {
+	svc     *Service
+	db      *gorm.DB
+	user    *user.User
+	post    *post.Post
+	channel *delivery.Channel
+}
+
+func setupRetryFixture(t *testing.T) *retryFixture {
+	t.Helper()
+	db := infra.SetupTestDB(t)
+	svc := NewService(infra.NewDeliveryChannelRepository(db), infra.NewAttemptRepository(db))
+
+	u := &user.User{Email: "r@b.c", Username: "retry", Password: "x", PostKey: "rpk"}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	p := &post.Post{QID: "p-retry", Title: "t", Body: "b", UserID: u.ID}
+	if err := db.Create(p).Error; err != nil {
+		t.Fatalf("seed post: %v", err)
+	}
+	ch := &delivery.Channel{UserID: u.ID, Kind: delivery.ChannelKindSlack, Name: "c", Enabled: true, Configuration: delivery.ChannelConfiguration{"webhook_url": "https://a.com"}}
+	if err := db.Create(ch).Error; err != nil {
+		t.Fatalf("seed channel: %v", err)
+	}
+	return &retryFixture{svc: svc, db: db, user: u, post: p, channel: ch}
+}
+
+func (f *retryFixture) history(t *testing.T, status delivery.Status) int64 {
+	t.Helper()
+	h := &delivery.History{UserID: &f.user.ID, PostID: &f.post.ID, ChannelID: &f.channel.ID, Status: status}
+	if err := f.db.Create(h).Error; err != nil {
+		t.Fatalf("seed history: %v", err)
+	}
+	return h.ID
+}
+
+func assertErrCode(t *testing.T, err error, want *service.ErrCode) {
+	t.Helper()
+	se, ok := service.AsError(err)
+	if !ok || se.Code != want {
+		t.Errorf("err = %v, want code %s", err, want.Value)
+	}
+}
+
+func TestService_RetryHistory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("re-enqueues a failed delivery once", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		id := f.history(t, delivery.StatusFailed)
+
+		attempt, err := f.svc.RetryHistory(ctx, f.user.ID, id)
+		if err != nil {
+			t.Fatalf("RetryHistory: %v", err)
+		}
+		if attempt.ID == 0 || *attempt.PostID != f.post.ID || attempt.ChannelID != f.channel.ID || attempt.Status != delivery.StatusPending {
+			t.Errorf("unexpected attempt: %+v", attempt)
+		}
+
+		_, err = f.svc.RetryHistory(ctx, f.user.ID, id)
+		assertErrCode(t, err, ErrDeliveryNotRetryable)
+	})
+
+	t.Run("rejects a delivered entry", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		_, err := f.svc.RetryHistory(ctx, f.user.ID, f.history(t, delivery.StatusDelivered))
+		assertErrCode(t, err, ErrDeliveryNotRetryable)
+	})
+
+	t.Run("rejects a disabled channel", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		id := f.history(t, delivery.StatusExpired)
+		f.db.Model(f.channel).Update("enabled", false)
+		_, err := f.svc.RetryHistory(ctx, f.user.ID, id)
+		assertErrCode(t, err, ErrDeliveryNotRetryable)
+	})
+
+	t.Run("reports a deleted post as gone", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		id := f.history(t, delivery.StatusFailed)
+		// The test DB runs without foreign keys; null the reference the way
+		// ON DELETE SET NULL would.
+		if err := f.db.Model(&delivery.History{}).Where("id = ?", id).Update("post_id", nil).Error; err != nil {
+			t.Fatalf("null post_id: %v", err)
+		}
+		_, err := f.svc.RetryHistory(ctx, f.user.ID, id)
+		assertErrCode(t, err, ErrDeliveryTargetGone)
+	})
+
+	t.Run("re-sends a removal notice from the entry's snapshot", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		h := &delivery.History{UserID: &f.user.ID, ChannelID: &f.channel.ID, Event: post.EventDeleted, Status: delivery.StatusFailed,
+			Subject: &delivery.PostSnapshot{QID: "p-gone", Title: "Gone"}}
+		if err := f.db.Create(h).Error; err != nil {
+			t.Fatalf("seed history: %v", err)
+		}
+
+		attempt, err := f.svc.RetryHistory(ctx, f.user.ID, h.ID)
+		if err != nil {
+			t.Fatalf("RetryHistory: %v", err)
+		}
+		if attempt.PostID != nil || attempt.Event != post.EventDeleted || attempt.Subject == nil || attempt.Subject.QID != "p-gone" {
+			t.Errorf("unexpected attempt: %+v", attempt)
+		}
+
+		_, err = f.svc.RetryHistory(ctx, f.user.ID, h.ID)
+		assertErrCode(t, err, ErrDeliveryNotRetryable)
+	})
+
+	t.Run("is not blocked by a pending attempt for another event", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		queued := &delivery.Attempt{UserID: f.user.ID, PostID: &f.post.ID, ChannelID: f.channel.ID, Event: post.EventCreated, Status: delivery.StatusPending}
+		if err := f.db.Create(queued).Error; err != nil {
+			t.Fatalf("seed attempt: %v", err)
+		}
+		h := &delivery.History{UserID: &f.user.ID, PostID: &f.post.ID, ChannelID: &f.channel.ID, Event: post.EventUpdated, Status: delivery.StatusFailed}
+		if err := f.db.Create(h).Error; err != nil {
+			t.Fatalf("seed history: %v", err)
+		}
+
+		attempt, err := f.svc.RetryHistory(ctx, f.user.ID, h.ID)
+		if err != nil {
+			t.Fatalf("RetryHistory: %v", err)
+		}
+		if attempt.Event != post.EventUpdated {
+			t.Errorf("event = %q, want updated", attempt.Event)
+		}
+	})
+
+	t.Run("returns not found for another user's entry", func(t *testing.T) {
+		f := setupRetryFixture(t)
+		_, err := f.svc.RetryHistory(ctx, f.user.ID+1, f.history(t, delivery.StatusFailed))
+		assertErrCode(t, err, service.ErrNotFound)
+	})
+}
+
+func TestService_RetryHistoryBulk(t *testing.T) {
+	ctx := context.Background()
+	f := setupRetryFixture(t)
+	failed := f.history(t, delivery.StatusFailed)
+	delivered := f.history(t, delivery.StatusDelivered)
+
+	outcomes, err := f.svc.RetryHistoryBulk(ctx, []int64{failed, delivered, failed, 9999})
+	if err != nil {
+		t.Fatalf("RetryHistoryBulk: %v", err)
+	}
+	if len(outcomes) != 3 {
+		t.Fatalf("expected 3 outcomes (duplicates collapsed), got %d", len(outcomes))
+	}
+	if outcomes[0].Err != nil || outcomes[0].Attempt == nil {
+		t.Errorf("outcome[0] = %+v, want enqueued", outcomes[0])
+	}
+	assertErrCode(t, outcomes[1].Err, ErrDeliveryNotRetryable)
+	assertErrCode(t, outcomes[2].Err, service.ErrNotFound)
+
+	_, err = f.svc.RetryHistoryBulk(ctx, nil)
+	assertErrCode(t, err, service.ErrValidation)
+}
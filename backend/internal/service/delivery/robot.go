@@ -0,0 +1,61 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// robotErrEnvelope is the response envelope shared by the DingTalk and WeCom
+// group-robot APIs: errcode 0 means success, anything else is a failure even
+// when the HTTP status is 200.
+type robotErrEnvelope struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// sendRobotRequest posts a JSON payload to a DingTalk/WeCom robot webhook and
+// checks the errcode envelope the way FeishuClient.sendRequest checks code. A
+// non-zero errcode is classified by errCodes (token gone, robot removed and bad
+// signature are permanent, "send too fast" is rate-limited); an unlisted
+// errcode is transient and retried through the channel's retry policy.
+func sendRobotRequest(ctx context.Context, httpClient *http.Client, platform, webhookURL string, payload any, errCodes map[int]FailureKind) error {
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s marshal payload: %w", platform, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("%s create request: %w", platform, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", platform, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("%s webhook status=%d body=%s", platform, resp.StatusCode, string(b)))
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Errorf("%s read response: %w", platform, err)
+	}
+
+	var result robotErrEnvelope
+	if json.Unmarshal(respBody, &result) == nil && result.ErrCode != 0 {
+		apiErr := fmt.Errorf("%s api errcode=%d errmsg=%s", platform, result.ErrCode, result.ErrMsg)
+		return markKind(errCodes[result.ErrCode], apiErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/service"
+	"markpost/pkg/utils"
+)
+
+// scheduleDays maps the day names a channel schedule accepts to weekdays.
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// DeliverySchedule is a channel's parsed delivery window: Start to End
+// minutes after local midnight in Location, opening on the days set in Days.
+// End <= Start means the window closes the next day.
+type DeliverySchedule struct {
+	Location *time.Location
+	Days     [7]bool
+	Start    int
+	End      int
+}
+
+// channelSchedule parses and validates a channel's schedule.
+func channelSchedule(o *delivery.Schedule) (DeliverySchedule, error) {
+	var s DeliverySchedule
+	var err error
+	if s.Location, err = time.LoadLocation(strings.TrimSpace(o.Timezone)); err != nil {
+		return DeliverySchedule{}, fmt.Errorf("timezone: unknown time zone %q", o.Timezone)
+	}
+	if s.Start, err = parseClock("start", o.Start, false); err != nil {
+		return DeliverySchedule{}, err
+	}
+	if s.End, err = parseClock("end", o.End, true); err != nil {
+		return DeliverySchedule{}, err
+	}
+	if s.Start == s.End {
+		return DeliverySchedule{}, fmt.Errorf("start and end must differ")
+	}
+
+	if len(o.Days) == 0 {
+		s.Days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, name := range o.Days {
+		day, ok := scheduleDays[utils.Normalize(name)]
+		if !ok {
+			return DeliverySchedule{}, fmt.Errorf("days: unknown day %q", name)
+		}
+		s.Days[day] = true
+	}
+	return s, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight. "24:00" is accepted
+// only as an end time.
+func parseClock(field, raw string, end bool) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if end && raw == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid time %q, want HH:MM", field, raw)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// normalizeSchedule validates a channel's schedule for storage. An empty
+// start and end clear it (nil): the channel delivers at any time. Day names
+// are lowercased.
+func normalizeSchedule(o *delivery.Schedule) (*delivery.Schedule, error) {
+	start, end := strings.TrimSpace(o.Start), strings.TrimSpace(o.End)
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	normalized := &delivery.Schedule{Timezone: strings.TrimSpace(o.Timezone), Start: start, End: end}
+	for _, day := range o.Days {
+		normalized.Days = append(normalized.Days, utils.Normalize(day))
+	}
+	if _, err := channelSchedule(normalized); err != nil {
+		return nil, service.New(service.ErrValidation, "invalid schedule: "+err.Error())
+	}
+	return normalized, nil
+}
+
+// resolveSchedule returns the channel's delivery window, or ok=false when it
+// delivers at any time. A schedule that no longer parses is ignored, so the
+// channel delivers rather than holding its posts forever.
+func resolveSchedule(ch *delivery.Channel) (DeliverySchedule, bool) {
+	if ch.Schedule == nil {
+		return DeliverySchedule{}, false
+	}
+	s, err := channelSchedule(ch.Schedule)
+	if err != nil {
+		log.Printf("delivery: ignore invalid schedule channel_id=%d err=%v", ch.ID, err)
+		return DeliverySchedule{}, false
+	}
+	return s, true
+}
+
+// NextOpen returns t if the window is open at t, otherwise when it next
+// opens. Wall-clock times are resolved in the schedule's location, so the
+// window follows DST changes.
+func (s DeliverySchedule) NextOpen(t time.Time) time.Time {
+	local := t.In(s.Location)
+	y, m, d := local.Date()
+	// Start a day back: yesterday's window may run past midnight into today.
+	for i := -1; i <= 7; i++ {
+		open := time.Date(y, m, d+i, 0, s.Start, 0, 0, s.Location)
+		if !s.Days[open.Weekday()] {
+			continue
+		}
+		closeDay := d + i
+		if s.End <= s.Start {
+			closeDay++
+		}
+		closeAt := time.Date(y, m, closeDay, 0, s.End, 0, 0, s.Location)
+		if t.Before(open) {
+			return open
+		}
+		if t.Before(closeAt) {
+			return t
+		}
+	}
+	return t
+}
+
+// deliveryStart returns when a delivery to the channel queued at now may
+// first be sent: now, or the opening of the channel's next delivery window.
+func deliveryStart(ch *delivery.Channel, now time.Time) time.Time {
+	if s, ok := resolveSchedule(ch); ok {
+		return s.NextOpen(now)
+	}
+	return now
+}
+
+// holdAttempt puts back an attempt claimed outside its channel's delivery
+// window until the window opens, without counting a try. The expiry wall is
+// moved by the time held, so a held attempt keeps the budget it had left
+// instead of expiring overnight.
+func (d *Dispatcher) holdAttempt(ctx context.Context, a *delivery.Attempt, now, open time.Time) {
+	expiresAt := a.ExpiresAt
+	if expiresAt > 0 {
+		expiresAt += open.Sub(now).Milliseconds()
+	}
+	if err := d.attemptRepo.Hold(ctx, a.ID, open.UnixMilli(), expiresAt); err != nil {
+		log.Printf("delivery schedule: hold attempt_id=%d err=%v", a.ID, err)
+	}
+}
@@ -0,0 +1,142 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+	"markpost/internal/infra"
+	"markpost/internal/service"
+)
+
+func mustSchedule(t *testing.T, o delivery.Schedule) DeliverySchedule {
+	t.Helper()
+	s, err := channelSchedule(&o)
+	if err != nil {
+		t.Fatalf("channelSchedule(%+v): %v", o, err)
+	}
+	return s
+}
+
+func TestDeliverySchedule_NextOpen(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		// 2025-01-06 is a Monday.
+		return time.Date(2025, 1, day, hour, minute, 0, 0, shanghai)
+	}
+
+	weekdays := mustSchedule(t, delivery.Schedule{
+		Timezone: "Asia/Shanghai",
+		Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+		Start:    "09:00",
+		End:      "19:00",
+	})
+	overnight := mustSchedule(t, delivery.Schedule{Start: "22:00", End: "06:00"})
+
+	tests := []struct {
+		name  string
+		sched DeliverySchedule
+		at    time.Time
+		want  time.Time
+	}{
+		{"inside the window", weekdays, at(6, 10, 30), at(6, 10, 30)},
+		{"before it opens", weekdays, at(6, 7, 0), at(6, 9, 0)},
+		{"at the close", weekdays, at(6, 19, 0), at(7, 9, 0)},
+		{"friday evening waits for monday", weekdays, at(10, 20, 0), at(13, 9, 0)},
+		{"in another zone", weekdays, time.Date(2025, 1, 6, 1, 30, 0, 0, time.UTC), at(6, 9, 30)},
+		{"before it opens in another zone", weekdays, time.Date(2025, 1, 6, 0, 30, 0, 0, time.UTC), at(6, 9, 0)},
+		{"overnight after midnight", overnight, time.Date(2025, 1, 7, 3, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 3, 0, 0, 0, time.UTC)},
+		{"overnight during the day", overnight, time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sched.NextOpen(tt.at); !got.Equal(tt.want) {
+				t.Errorf("NextOpen(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSchedule(t *testing.T) {
+	cleared, err := normalizeSchedule(&delivery.Schedule{Timezone: "UTC"})
+	if err != nil || cleared != nil {
+		t.Errorf("empty start/end = %+v, %v; want nil, nil", cleared, err)
+	}
+
+	got, err := normalizeSchedule(&delivery.Schedule{Timezone: " UTC ", Days: []string{"Mon", " SAT "}, Start: "08:00", End: "24:00"})
+	if err != nil || got.Timezone != "UTC" || got.Days[0] != "mon" || got.Days[1] != "sat" {
+		t.Errorf("normalize = %+v, %v", got, err)
+	}
+
+	for _, bad := range []delivery.Schedule{
+		{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"},
+		{Start: "9am", End: "17:00"},
+		{Start: "24:00", End: "06:00"},
+		{Start: "09:00", End: "09:00"},
+		{Days: []string{"funday"}, Start: "09:00", End: "17:00"},
+	} {
+		_, err := normalizeSchedule(&bad)
+		assertErrCode(t, err, service.ErrValidation)
+	}
+}
+
+func TestDispatcher_ScheduleHoldsOutsideWindow(t *testing.T) {
+	loadDeliveryTestConfig(t)
+	database, err := infra.NewTestDatabase()
+	if err != nil {
+		t.Fatalf("NewTestDatabase: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	uid, pid, cid := seedUserPostChannel(t, database)
+	db := database.DB()
+	db.Model(&delivery.Channel{}).Where("id = ?", cid).Update("schedule", &delivery.Schedule{Start: "09:00", End: "19:00"})
+
+	ctx := context.Background()
+	sender := &failingSender{}
+	attemptRepo := infra.NewAttemptRepository(db)
+	dispatcher := NewDispatcher(attemptRepo, infra.NewDeliveryChannelRepository(db), infra.NewPostRepository(db), sender)
+	defer dispatcher.pool.StopAndWait()
+	now := time.Date(2025, 1, 6, 22, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.Enqueue(domainpost.DeliveryJob{UserID: uid, PostID: pid, PostQID: "p-seed", Title: "Server Alert"})
+	var a delivery.Attempt
+	if err := db.First(&a).Error; err != nil {
+		t.Fatalf("find attempt: %v", err)
+	}
+	open := time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC)
+	if a.NextAt != open.UnixMilli() {
+		t.Errorf("next_at = %s, want the window opening %s", time.UnixMilli(a.NextAt).UTC(), open)
+	}
+	// The wall runs from the opening, so the held attempt does not expire
+	// overnight.
+	if want := open.Add(ExpiryWall()).UnixMilli(); a.ExpiresAt != want {
+		t.Errorf("expires_at = %s, want %s", time.UnixMilli(a.ExpiresAt).UTC(), time.UnixMilli(want).UTC())
+	}
+
+	// A retry claimed after the window closed is held, not sent, and keeps
+	// the wall budget it had left.
+	now = time.Date(2025, 1, 7, 18, 59, 0, 0, time.UTC)
+	db.Model(&delivery.Attempt{}).Where("id = ?", a.ID).Updates(map[string]any{"next_at": now.UnixMilli(), "expires_at": now.Add(10 * time.Minute).UnixMilli()})
+	now = now.Add(2 * time.Minute)
+	a.ExpiresAt = now.Add(8 * time.Minute).UnixMilli()
+	dispatcher.deliver(ctx, &a)
+	if sender.calls != 0 {
+		t.Fatalf("sent %d times outside the window", sender.calls)
+	}
+	var held delivery.Attempt
+	db.First(&held, a.ID)
+	nextOpen := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+	if held.NextAt != nextOpen.UnixMilli() || held.Attempts != 0 {
+		t.Errorf("held next_at = %s attempts = %d, want %s and no try counted", time.UnixMilli(held.NextAt).UTC(), held.Attempts, nextOpen)
+	}
+	if want := nextOpen.Add(8 * time.Minute).UnixMilli(); held.ExpiresAt != want {
+		t.Errorf("held expires_at = %s, want %s", time.UnixMilli(held.ExpiresAt).UTC(), time.UnixMilli(want).UTC())
+	}
+}
@@ -0,0 +1,180 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// Slack Block Kit text limits: a header block's plain_text is capped at 150
+// characters and a section block's text at 3000. Longer values make Slack
+// reject the whole payload with invalid_blocks.
+const (
+	slackHeaderMaxChars  = 150
+	slackSectionMaxChars = 3000
+)
+
+// SlackClient sends messages to Slack incoming-webhook URLs.
+type SlackClient struct {
+	httpClient *http.Client
+}
+
+// NewSlackClient creates a SlackClient with the given request timeout.
+func NewSlackClient(timeout time.Duration) *SlackClient {
+	return &SlackClient{
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+type slackElement struct {
+	Type  string     `json:"type"`
+	Text  *slackText `json:"text,omitempty"`
+	URL   string     `json:"url,omitempty"`
+	Style string     `json:"style,omitempty"`
+}
+
+// SlackMessageParams holds the parameters for sending a Slack Block Kit message.
+type SlackMessageParams struct {
+	WebhookURL  string
+	PostURL     string
+	PostTitle   string
+	ButtonText  string
+	BodyPreview string
+}
+
+// SendMessage posts a Block Kit message (title header, body preview section,
+// "View Post" button) to the given Slack incoming-webhook URL. The top-level
+// text field carries the title as the notification fallback.
+func (c *SlackClient) SendMessage(ctx context.Context, params SlackMessageParams) error {
+	title := truncateRunes(params.PostTitle, slackHeaderMaxChars)
+
+	blocks := []slackBlock{
+		{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: title, Emoji: true},
+		},
+	}
+
+	if params.BodyPreview != "" {
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{
+				Type: "mrkdwn",
+				Text: truncateRunes(escapeSlackText(params.BodyPreview), slackSectionMaxChars),
+			},
+		})
+	}
+
+	if params.PostURL != "" {
+		blocks = append(blocks, slackBlock{
+			Type: "actions",
+			Elements: []slackElement{
+				{
+					Type:  "button",
+					Text:  &slackText{Type: "plain_text", Text: buttonLabel(params.ButtonText)},
+					URL:   params.PostURL,
+					Style: "primary",
+				},
+			},
+		})
+	}
+
+	return c.sendRequest(ctx, params.WebhookURL, slackPayload{
+		Text:   params.PostTitle,
+		Blocks: blocks,
+	})
+}
+
+// escapeSlackText escapes the three control characters Slack reserves in
+// mrkdwn text (&, <, >) so post content cannot inject mentions or links.
+func escapeSlackText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// sendRequest posts the payload to an incoming webhook. Slack answers a
+// successful call with HTTP 200 and the plain-text body "ok"; failures use a
+// 4xx/5xx status with a short error token (invalid_payload, no_service, ...)
+// as the body, so the status code alone decides success.
+func (c *SlackClient) sendRequest(ctx context.Context, webhookURL string, payload any) error {
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("slack marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("slack create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("slack webhook status=%d body=%s", resp.StatusCode, string(b)))
+	}
+
+	return nil
+}
+
+// slackDriver is the ChannelDriver for Slack incoming webhooks.
+type slackDriver struct{}
+
+func (slackDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindSlack }
+
+func (slackDriver) Fields() []ConfigField {
+	return []ConfigField{webhookURLField("Slack incoming webhook URL")}
+}
+
+func (slackDriver) Validate(config delivery.ChannelConfiguration) error {
+	return validateWebhookURLField(config)
+}
+
+func (d slackDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (slackDriver) NewSender(opts SenderOptions) KindSender {
+	return slackSender{client: NewSlackClient(opts.Timeout)}
+}
+
+type slackSender struct{ client *SlackClient }
+
+func (s slackSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendMessage(ctx, SlackMessageParams{
+		WebhookURL:  msg.Channel.Configuration.String("webhook_url"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.Content(),
+	})
+}
@@ -0,0 +1,151 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackClient_SendMessage(t *testing.T) {
+	t.Run("sends header, section and button blocks", func(t *testing.T) {
+		var receivedBody map[string]any
+		var contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		client := NewSlackClient(5 * time.Second)
+		err := client.SendMessage(context.Background(), SlackMessageParams{
+			WebhookURL:  server.URL,
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "Test Title",
+			BodyPreview: "Some preview text",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if contentType != "application/json; charset=utf-8" {
+			t.Errorf("content-type = %q, want %q", contentType, "application/json; charset=utf-8")
+		}
+		if receivedBody["text"] != "Test Title" {
+			t.Errorf("text = %v, want %q", receivedBody["text"], "Test Title")
+		}
+
+		blocks, ok := receivedBody["blocks"].([]any)
+		if !ok || len(blocks) != 3 {
+			t.Fatalf("expected 3 blocks, got %v", receivedBody["blocks"])
+		}
+
+		header := blocks[0].(map[string]any)
+		if header["type"] != "header" {
+			t.Errorf("blocks[0].type = %v, want %q", header["type"], "header")
+		}
+		if header["text"].(map[string]any)["text"] != "Test Title" {
+			t.Errorf("header text = %v, want %q", header["text"], "Test Title")
+		}
+
+		section := blocks[1].(map[string]any)
+		if section["type"] != "section" {
+			t.Errorf("blocks[1].type = %v, want %q", section["type"], "section")
+		}
+		if section["text"].(map[string]any)["type"] != "mrkdwn" {
+			t.Errorf("section text type = %v, want %q", section["text"], "mrkdwn")
+		}
+
+		actions := blocks[2].(map[string]any)
+		button := actions["elements"].([]any)[0].(map[string]any)
+		if button["url"] != "https://example.com/p-abc" {
+			t.Errorf("button url = %v, want %q", button["url"], "https://example.com/p-abc")
+		}
+		if button["text"].(map[string]any)["text"] != "View Post" {
+			t.Errorf("button text = %v, want %q", button["text"], "View Post")
+		}
+	})
+
+	t.Run("omits section without body preview", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewSlackClient(5 * time.Second)
+		err := client.SendMessage(context.Background(), SlackMessageParams{
+			WebhookURL: server.URL,
+			PostURL:    "https://example.com/p-abc",
+			PostTitle:  "Test Title",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, b := range receivedBody["blocks"].([]any) {
+			if b.(map[string]any)["type"] == "section" {
+				t.Error("expected no section block without body preview")
+			}
+		}
+	})
+
+	t.Run("truncates long title to header limit", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewSlackClient(5 * time.Second)
+		err := client.SendMessage(context.Background(), SlackMessageParams{
+			WebhookURL: server.URL,
+			PostTitle:  strings.Repeat("标", 200),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		header := receivedBody["blocks"].([]any)[0].(map[string]any)
+		text := header["text"].(map[string]any)["text"].(string)
+		if n := len([]rune(text)); n != slackHeaderMaxChars {
+			t.Errorf("header length = %d, want %d", n, slackHeaderMaxChars)
+		}
+	})
+
+	t.Run("returns error for non-2xx status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("no_service"))
+		}))
+		defer server.Close()
+
+		client := NewSlackClient(5 * time.Second)
+		err := client.SendMessage(context.Background(), SlackMessageParams{WebhookURL: server.URL, PostTitle: "t"})
+		if err == nil {
+			t.Fatal("expected error for non-2xx status")
+		}
+		if !strings.Contains(err.Error(), "no_service") {
+			t.Errorf("error = %q, want it to contain the Slack error token", err.Error())
+		}
+	})
+}
+
+func TestEscapeSlackText(t *testing.T) {
+	got := escapeSlackText("a < b && c > <!channel>")
+	want := "a &lt; b &amp;&amp; c &gt; &lt;!channel&gt;"
+	if got != want {
+		t.Errorf("escapeSlackText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// teamsPreviewMaxChars caps the Adaptive Card body preview. Teams rejects
+// incoming-webhook payloads above 28 KB; 6000 characters stays under that even
+// at four UTF-8 bytes per rune plus the card envelope.
+const teamsPreviewMaxChars = 6000
+
+// teamsTitleMaxChars keeps the card's heading to a readable length.
+const teamsTitleMaxChars = 256
+
+// TeamsClient sends Adaptive Cards to Microsoft Teams incoming-webhook URLs.
+type TeamsClient struct {
+	httpClient *http.Client
+}
+
+// NewTeamsClient creates a TeamsClient with the given request timeout.
+func NewTeamsClient(timeout time.Duration) *TeamsClient {
+	return &TeamsClient{
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string             `json:"$schema"`
+	Type    string             `json:"type"`
+	Version string             `json:"version"`
+	Body    []teamsTextBlock   `json:"body"`
+	Actions []teamsOpenURLItem `json:"actions,omitempty"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type teamsOpenURLItem struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// TeamsMessageParams holds the parameters for sending a Teams Adaptive Card.
+type TeamsMessageParams struct {
+	WebhookURL  string
+	PostURL     string
+	PostTitle   string
+	ButtonText  string
+	BodyPreview string
+}
+
+// SendCard posts an Adaptive Card (bold title, wrapped body preview, "View
+// Post" action) to the given Teams incoming-webhook URL.
+func (c *TeamsClient) SendCard(ctx context.Context, params TeamsMessageParams) error {
+	card := teamsAdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []teamsTextBlock{
+			{
+				Type:   "TextBlock",
+				Text:   truncateRunes(params.PostTitle, teamsTitleMaxChars),
+				Weight: "Bolder",
+				Size:   "Medium",
+				Wrap:   true,
+			},
+		},
+	}
+	if params.BodyPreview != "" {
+		card.Body = append(card.Body, teamsTextBlock{
+			Type: "TextBlock",
+			Text: truncateRunes(params.BodyPreview, teamsPreviewMaxChars),
+			Wrap: true,
+		})
+	}
+	if params.PostURL != "" {
+		card.Actions = []teamsOpenURLItem{{Type: "Action.OpenUrl", Title: buttonLabel(params.ButtonText), URL: params.PostURL}}
+	}
+
+	return c.sendRequest(ctx, params.WebhookURL, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	})
+}
+
+// sendRequest posts the payload to a Teams incoming webhook. Legacy connector
+// webhooks answer HTTP 200 with body "1"; Workflows webhooks answer 202. Any
+// other status is a failure, permanent when the request itself is at fault.
+func (c *TeamsClient) sendRequest(ctx context.Context, webhookURL string, payload any) error {
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("teams marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("teams create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("teams request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("teams webhook status=%d body=%s", resp.StatusCode, string(b)))
+	}
+
+	return nil
+}
+
+// teamsDriver is the ChannelDriver for Microsoft Teams incoming webhook.
+type teamsDriver struct{}
+
+func (teamsDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindTeams }
+
+func (teamsDriver) Fields() []ConfigField {
+	return []ConfigField{webhookURLField("Microsoft Teams incoming webhook URL")}
+}
+
+func (teamsDriver) Validate(config delivery.ChannelConfiguration) error {
+	return validateWebhookURLField(config)
+}
+
+func (d teamsDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (teamsDriver) NewSender(opts SenderOptions) KindSender {
+	return teamsSender{client: NewTeamsClient(opts.Timeout)}
+}
+
+type teamsSender struct{ client *TeamsClient }
+
+func (s teamsSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendCard(ctx, TeamsMessageParams{
+		WebhookURL:  msg.Channel.Configuration.String("webhook_url"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.ContentWithin(teamsPreviewMaxChars),
+	})
+}
@@ -0,0 +1,66 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTeamsClient_SendCard(t *testing.T) {
+	t.Run("sends adaptive card attachment", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte("1"))
+		}))
+		defer server.Close()
+
+		client := NewTeamsClient(5 * time.Second)
+		err := client.SendCard(context.Background(), TeamsMessageParams{
+			WebhookURL:  server.URL,
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "Test Title",
+			BodyPreview: "Some preview text",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if receivedBody["type"] != "message" {
+			t.Errorf("type = %v, want %q", receivedBody["type"], "message")
+		}
+		attachment := receivedBody["attachments"].([]any)[0].(map[string]any)
+		if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+			t.Errorf("contentType = %v, want adaptive card", attachment["contentType"])
+		}
+		card := attachment["content"].(map[string]any)
+		if card["type"] != "AdaptiveCard" {
+			t.Errorf("card type = %v, want %q", card["type"], "AdaptiveCard")
+		}
+		if body := card["body"].([]any); len(body) != 2 {
+			t.Errorf("expected title and preview text blocks, got %d", len(body))
+		}
+		action := card["actions"].([]any)[0].(map[string]any)
+		if action["type"] != "Action.OpenUrl" || action["url"] != "https://example.com/p-abc" {
+			t.Errorf("action = %v, want OpenUrl to post", action)
+		}
+	})
+
+	t.Run("returns retryable error for 5xx", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := NewTeamsClient(5 * time.Second)
+		err := client.SendCard(context.Background(), TeamsMessageParams{WebhookURL: server.URL, PostTitle: "t"})
+		if err == nil || isPermanent(err) {
+			t.Fatalf("error = %v, want retryable error", err)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/service"
+)
+
+// telegramAPIBaseURL is the Bot API endpoint; tests point the client elsewhere.
+const telegramAPIBaseURL = "https://api.telegram.org"
+
+// Telegram Bot API limits: sendMessage text is capped at 4096 characters after
+// entity parsing, so HTML escapes do not count against it. The title is held
+// well below that so the body preview keeps a useful share.
+const (
+	telegramMessageMaxChars = 4096
+	telegramTitleMaxChars   = 256
+)
+
+// TelegramClient sends messages through the Telegram Bot API.
+type TelegramClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewTelegramClient creates a TelegramClient with the given request timeout.
+func NewTelegramClient(timeout time.Duration) *TelegramClient {
+	return &TelegramClient{
+		httpClient: newHTTPClient(timeout),
+		baseURL:    telegramAPIBaseURL,
+	}
+}
+
+type telegramSendMessage struct {
+	ChatID                string               `json:"chat_id"`
+	Text                  string               `json:"text"`
+	ParseMode             string               `json:"parse_mode"`
+	DisableWebPagePreview bool                 `json:"disable_web_page_preview"`
+	ReplyMarkup           *telegramReplyMarkup `json:"reply_markup,omitempty"`
+}
+
+type telegramReplyMarkup struct {
+	InlineKeyboard [][]telegramInlineButton `json:"inline_keyboard"`
+}
+
+type telegramInlineButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// TelegramMessageParams holds the parameters for sending a Telegram message.
+type TelegramMessageParams struct {
+	BotToken    string
+	ChatID      string
+	PostURL     string
+	PostTitle   string
+	ButtonText  string
+	BodyPreview string
+}
+
+// SendMessage calls sendMessage with an HTML-formatted text (bold title, body
+// preview) and an inline "View Post" button.
+func (c *TelegramClient) SendMessage(ctx context.Context, params TelegramMessageParams) error {
+	payload := telegramSendMessage{
+		ChatID:                params.ChatID,
+		Text:                  telegramMessageText(params.PostTitle, params.BodyPreview),
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	}
+	if params.PostURL != "" {
+		payload.ReplyMarkup = &telegramReplyMarkup{
+			InlineKeyboard: [][]telegramInlineButton{{{Text: buttonLabel(params.ButtonText), URL: params.PostURL}}},
+		}
+	}
+	return c.sendRequest(ctx, params.BotToken, payload)
+}
+
+// telegramMessageText builds the HTML message text. Limits are applied to the
+// unescaped text, matching how Telegram counts them.
+func telegramMessageText(title, preview string) string {
+	title = truncateRunes(title, telegramTitleMaxChars)
+	text := "<b>" + html.EscapeString(title) + "</b>"
+	if preview == "" {
+		return text
+	}
+	budget := telegramMessageMaxChars - utf8.RuneCountInString(title) - len("\n\n")
+	if preview = truncateRunes(preview, budget); preview != "" {
+		text += "\n\n" + html.EscapeString(preview)
+	}
+	return text
+}
+
+// telegramPreviewMaxChars is the body-preview budget left once the title is
+// in the message; used to cap buildBodyPreview for Telegram channels.
+func telegramPreviewMaxChars(title string) int {
+	titleChars := utf8.RuneCountInString(truncateRunes(title, telegramTitleMaxChars))
+	return telegramMessageMaxChars - titleChars - len("\n\n")
+}
+
+// sendRequest posts to sendMessage. The bot token is part of the URL, so
+// transport errors are unwrapped from *url.Error before being returned — the
+// error text lands in delivery history and must not leak the token. Telegram
+// reports failures as {"ok":false,"description":...} with a matching HTTP
+// status: 400 (chat not found), 401 (bad token) and 403 (bot removed) are
+// permanent, 429 is rate-limited and retried after its retry_after.
+func (c *TelegramClient) sendRequest(ctx context.Context, botToken string, payload any) error {
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("telegram marshal payload: %w", err)
+	}
+
+	endpoint := strings.TrimRight(c.baseURL, "/") + "/bot" + botToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return errors.New("telegram create request: invalid bot token")
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	_ = json.Unmarshal(respBody, &result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || !result.OK {
+		apiErr := fmt.Errorf("telegram api status=%d description=%s", resp.StatusCode, result.Description)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return markRateLimited(apiErr, min(time.Duration(result.Parameters.RetryAfter)*time.Second, maxRetryAfter))
+		}
+		return classifyStatus(resp.StatusCode, apiErr)
+	}
+	return nil
+}
+
+// Telegram bot tokens look like "123456789:AAH..."; chat IDs are a signed
+// integer or a public @channelusername (5–32 characters).
+var (
+	telegramBotTokenRe = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
+	telegramChatIDRe   = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+)
+
+// normalizeTelegramChatID accepts the chat ID as a JSON string or number and
+// returns it as a string, the form stored in the configuration.
+func normalizeTelegramChatID(raw any) (string, error) {
+	var chatID string
+	switch v := raw.(type) {
+	case string:
+		chatID = strings.TrimSpace(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return "", service.New(service.ErrValidation, "invalid telegram chat ID")
+		}
+		chatID = strconv.FormatInt(int64(v), 10)
+	}
+	if !telegramChatIDRe.MatchString(chatID) {
+		return "", service.New(service.ErrValidation, "invalid telegram chat ID")
+	}
+	return chatID, nil
+}
+
+// telegramDriver is the ChannelDriver for Telegram bots.
+type telegramDriver struct{}
+
+func (telegramDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindTelegram }
+
+func (telegramDriver) Fields() []ConfigField {
+	return []ConfigField{
+		{Key: "bot_token", Type: FieldTypeSecret, Required: true, Secret: true, Description: "Bot token from @BotFather"},
+		{Key: "chat_id", Type: FieldTypeString, Required: true, Description: "Numeric chat ID or public @channelusername"},
+	}
+}
+
+func (telegramDriver) Validate(config delivery.ChannelConfiguration) error {
+	botToken := strings.TrimSpace(config.String("bot_token"))
+	if !telegramBotTokenRe.MatchString(botToken) {
+		return service.New(service.ErrValidation, "invalid telegram bot token")
+	}
+	chatID, err := normalizeTelegramChatID(config["chat_id"])
+	if err != nil {
+		return err
+	}
+	config["bot_token"] = botToken
+	config["chat_id"] = chatID
+	return nil
+}
+
+func (d telegramDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (telegramDriver) NewSender(opts SenderOptions) KindSender {
+	return telegramSender{client: NewTelegramClient(opts.Timeout)}
+}
+
+type telegramSender struct{ client *TelegramClient }
+
+func (s telegramSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendMessage(ctx, TelegramMessageParams{
+		BotToken:    msg.Channel.Configuration.String("bot_token"),
+		ChatID:      msg.Channel.Configuration.String("chat_id"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.ContentWithin(telegramPreviewMaxChars(msg.TitleText())),
+	})
+}
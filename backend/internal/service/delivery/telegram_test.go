@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTelegramClient_SendMessage(t *testing.T) {
+	t.Run("calls sendMessage with chat_id, html text and button", func(t *testing.T) {
+		var path string
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+		}))
+		defer server.Close()
+
+		client := NewTelegramClient(5 * time.Second)
+		client.baseURL = server.URL
+		err := client.SendMessage(context.Background(), TelegramMessageParams{
+			BotToken:    "123:abc",
+			ChatID:      "-100123",
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "a < b",
+			BodyPreview: "x & y",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if path != "/bot123:abc/sendMessage" {
+			t.Errorf("path = %q, want %q", path, "/bot123:abc/sendMessage")
+		}
+		if receivedBody["chat_id"] != "-100123" {
+			t.Errorf("chat_id = %v, want %q", receivedBody["chat_id"], "-100123")
+		}
+		if receivedBody["parse_mode"] != "HTML" {
+			t.Errorf("parse_mode = %v, want %q", receivedBody["parse_mode"], "HTML")
+		}
+		if receivedBody["text"] != "<b>a &lt; b</b>\n\nx &amp; y" {
+			t.Errorf("text = %q, want escaped title and preview", receivedBody["text"])
+		}
+		if !strings.Contains(receivedBody["reply_markup"].(map[string]any)["inline_keyboard"].([]any)[0].([]any)[0].(map[string]any)["url"].(string), "p-abc") {
+			t.Errorf("reply_markup = %v, want View Post button", receivedBody["reply_markup"])
+		}
+	})
+
+	t.Run("forbidden is permanent and does not leak token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was kicked"}`))
+		}))
+		defer server.Close()
+
+		client := NewTelegramClient(5 * time.Second)
+		client.baseURL = server.URL
+		err := client.SendMessage(context.Background(), TelegramMessageParams{BotToken: "123:secret-token", ChatID: "1", PostTitle: "t"})
+		if err == nil || !isPermanent(err) {
+			t.Fatalf("error = %v, want permanent error", err)
+		}
+		if strings.Contains(err.Error(), "secret-token") {
+			t.Errorf("error leaks bot token: %v", err)
+		}
+	})
+
+	t.Run("rate limit is retried after retry_after", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"parameters":{"retry_after":5}}`))
+		}))
+		defer server.Close()
+
+		client := NewTelegramClient(5 * time.Second)
+		client.baseURL = server.URL
+		err := client.SendMessage(context.Background(), TelegramMessageParams{BotToken: "123:abc", ChatID: "1", PostTitle: "t"})
+		kind, wait := classify(err)
+		if err == nil || kind != FailureRateLimited || wait != 5*time.Second {
+			t.Fatalf("error = %v (kind=%v wait=%s), want rate-limited for 5s", err, kind, wait)
+		}
+	})
+
+	t.Run("transport error does not leak token", func(t *testing.T) {
+		client := NewTelegramClient(time.Second)
+		client.baseURL = "http://127.0.0.1:1"
+		err := client.SendMessage(context.Background(), TelegramMessageParams{BotToken: "123:secret-token", ChatID: "1", PostTitle: "t"})
+		if err == nil {
+			t.Fatal("expected transport error")
+		}
+		if strings.Contains(err.Error(), "secret-token") {
+			t.Errorf("error leaks bot token: %v", err)
+		}
+	})
+}
+
+func TestTelegramMessageText_Limit(t *testing.T) {
+	title := strings.Repeat("t", 300)
+	text := telegramMessageText(title, strings.Repeat("标", 5000))
+	plain := strings.TrimPrefix(text, "<b>")
+	plain = strings.Replace(plain, "</b>", "", 1)
+	if n := utf8.RuneCountInString(plain); n > telegramMessageMaxChars {
+		t.Errorf("message length = %d, want <= %d", n, telegramMessageMaxChars)
+	}
+}
@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"markpost/internal/service"
+)
+
+// maxTemplateLength caps a channel message template, in bytes.
+const maxTemplateLength = 4096
+
+// maxRenderedTemplateBytes caps what a template (or one of its blocks) may
+// render to: the largest message any supported platform accepts (Slack's
+// 40,000-character text). Nested {{template}} calls let a short template
+// expand without bound, so execution stops as soon as it passes the cap.
+const maxRenderedTemplateBytes = 40000
+
+var errTemplateOutputTooLarge = fmt.Errorf("renders to more than %d bytes", maxRenderedTemplateBytes)
+
+// TemplateData is what a channel message template can reference, e.g.
+// "{{.Title}} by {{.Author}}: {{.PostURL}}".
+type TemplateData struct {
+	Title       string
+	QID         string
+	PostURL     string
+	BodyPreview string
+	Author      string
+	CreatedAt   time.Time
+}
+
+// sampleTemplateData is the post a template is dry-run against before it is
+// stored, so field typos and runtime errors surface as validation errors
+// instead of failed deliveries.
+var sampleTemplateData = TemplateData{
+	Title:       "Sample post",
+	QID:         "p-sample",
+	PostURL:     "https://markpost.example.com/p-sample",
+	BodyPreview: "This is a sample post body.",
+	Author:      "markpost",
+	CreatedAt:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// The optional blocks a channel message template can define besides its body,
+// e.g. {{define "title"}}[{{.Author}}] {{.Title}}{{end}}. Each replaces one
+// piece of the kind's layout; see OutboundMessage.
+const (
+	templateTitleBlock  = "title"
+	templateButtonBlock = "button"
+	templateLinkBlock   = "link"
+)
+
+// renderedTemplate is a channel message template executed for one message.
+// Text is the body; a block the template does not define renders empty.
+type renderedTemplate struct {
+	Text   string
+	Title  string
+	Button string
+	Link   string
+}
+
+func parseMessageTemplate(text string) (*template.Template, error) {
+	return template.New("message").Option("missingkey=error").Parse(text)
+}
+
+// renderMessageTemplate executes a channel's message template and its
+// defined blocks against data. A link must render an http(s) URL.
+func renderMessageTemplate(text string, data TemplateData) (renderedTemplate, error) {
+	tmpl, err := parseMessageTemplate(text)
+	if err != nil {
+		return renderedTemplate{}, err
+	}
+	var out renderedTemplate
+	if out.Text, err = executeTemplate(tmpl, data); err != nil {
+		return renderedTemplate{}, err
+	}
+	for name, dst := range map[string]*string{
+		templateTitleBlock:  &out.Title,
+		templateButtonBlock: &out.Button,
+		templateLinkBlock:   &out.Link,
+	} {
+		block := tmpl.Lookup(name)
+		if block == nil {
+			continue
+		}
+		if *dst, err = executeTemplate(block, data); err != nil {
+			return renderedTemplate{}, err
+		}
+	}
+	if out.Link != "" {
+		if u, err := url.Parse(out.Link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return renderedTemplate{}, fmt.Errorf("link %q is not an http(s) URL", out.Link)
+		}
+	}
+	return out, nil
+}
+
+func executeTemplate(tmpl *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&cappedWriter{buf: &buf, max: maxRenderedTemplateBytes}, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// cappedWriter buffers up to max bytes and fails the write that would pass
+// it, which aborts the template execution.
+type cappedWriter struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.max {
+		return 0, errTemplateOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// normalizeTemplate trims a submitted template and validates it with a
+// dry-run render against sampleTemplateData. An empty template is valid and
+// means the kind's default layout. A template that only defines blocks keeps
+// the default body.
+func normalizeTemplate(raw string) (string, error) {
+	text := strings.TrimSpace(raw)
+	if text == "" {
+		return "", nil
+	}
+	if len(text) > maxTemplateLength {
+		return "", service.New(service.ErrValidation, fmt.Sprintf("template must be at most %d bytes", maxTemplateLength))
+	}
+	rendered, err := renderMessageTemplate(text, sampleTemplateData)
+	if err != nil {
+		return "", service.New(service.ErrValidation, "invalid template: "+err.Error())
+	}
+	if rendered == (renderedTemplate{}) {
+		return "", service.New(service.ErrValidation, "invalid template: renders to an empty message")
+	}
+	return text, nil
+}
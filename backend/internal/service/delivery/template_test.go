@@ -0,0 +1,93 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty means default layout", raw: "   ", want: ""},
+		{name: "trims whitespace", raw: "  {{.Title}}\n", want: "{{.Title}}"},
+		{name: "all fields", raw: `{{.Title}} {{.QID}} {{.PostURL}} {{.BodyPreview}} {{.Author}} {{.CreatedAt.Format "2006-01-02"}}`, want: `{{.Title}} {{.QID}} {{.PostURL}} {{.BodyPreview}} {{.Author}} {{.CreatedAt.Format "2006-01-02"}}`},
+		{name: "parse error", raw: "{{.Title", wantErr: true},
+		{name: "unknown field", raw: "{{.Body}}", wantErr: true},
+		{name: "renders empty", raw: `{{if false}}x{{end}}`, wantErr: true},
+		{name: "too long", raw: strings.Repeat("x", maxTemplateLength+1), wantErr: true},
+		{name: "output too long", raw: explodingTemplate(), wantErr: true},
+		{name: "blocks only", raw: `{{define "title"}}[{{.Author}}] {{.Title}}{{end}}`, want: `{{define "title"}}[{{.Author}}] {{.Title}}{{end}}`},
+		{name: "unknown field in block", raw: `{{.Title}}{{define "button"}}{{.Label}}{{end}}`, wantErr: true},
+		{name: "link not a URL", raw: `{{.Title}}{{define "link"}}p/{{.QID}}{{end}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTemplate(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeTemplate(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeTemplate(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMessageTemplate(t *testing.T) {
+	data := TemplateData{
+		Title:     "Deploy done",
+		QID:       "p-abc",
+		PostURL:   "https://example.com/p-abc",
+		Author:    "alice",
+		CreatedAt: time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC),
+	}
+	got, err := renderMessageTemplate(`[{{.Author}}] {{.Title}} ({{.CreatedAt.Format "2006-01-02"}}) {{.PostURL}}`, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[alice] Deploy done (2025-03-04) https://example.com/p-abc"
+	if got.Text != want || got.Title != "" || got.Button != "" || got.Link != "" {
+		t.Errorf("rendered = %+v, want text %q and no blocks", got, want)
+	}
+
+	got, err = renderMessageTemplate(`{{.Title}}`+
+		`{{define "title"}}{{.Author}}: {{.Title}}{{end}}`+
+		`{{define "button"}} Open {{.QID}} {{end}}`+
+		`{{define "link"}}https://mirror.example.com/{{.QID}}{{end}}`, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want2 := renderedTemplate{Text: "Deploy done", Title: "alice: Deploy done", Button: "Open p-abc", Link: "https://mirror.example.com/p-abc"}
+	if got != want2 {
+		t.Errorf("rendered = %+v, want %+v", got, want2)
+	}
+}
+
+// explodingTemplate nests {{template}} calls ten deep, ten per level, so a
+// template of a few hundred bytes would render 10^10 bytes uncapped.
+func explodingTemplate() string {
+	var b strings.Builder
+	b.WriteString(`{{define "t0"}}xxxxxxxxxx{{end}}`)
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&b, `{{define "t%d"}}%s{{end}}`, i, strings.Repeat(fmt.Sprintf(`{{template "t%d"}}`, i-1), 10))
+	}
+	b.WriteString(`{{template "t10"}}`)
+	return b.String()
+}
+
+func TestRenderMessageTemplate_CapsOutput(t *testing.T) {
+	_, err := renderMessageTemplate(explodingTemplate(), sampleTemplateData)
+	if !errors.Is(err, errTemplateOutputTooLarge) {
+		t.Fatalf("err = %v, want errTemplateOutputTooLarge", err)
+	}
+	if _, err := renderMessageTemplate(strings.Repeat("{{.Title}}", 200), sampleTemplateData); err != nil {
+		t.Errorf("ordinary template failed: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	"markpost/internal/domain/post"
+	"markpost/internal/service"
+)
+
+// WebhookEventVersion is the schema version of the outbound webhook JSON body.
+// It is bumped only on a breaking change to WebhookEvent; additive fields keep
+// the same version so receivers can ignore what they do not understand.
+const WebhookEventVersion = "1"
+
+// Webhook event types. post.created is sent when a post is published;
+// post.digest when a digest channel's accumulated posts are sent together.
+// post.updated, post.deleted and post.expired go to channels subscribed to
+// those lifecycle events; Post then describes the post as it is, or as it was
+// when removed (with an empty URL).
+const (
+	WebhookEventPostCreated = "post.created"
+	WebhookEventPostDigest  = "post.digest"
+	WebhookEventPostUpdated = "post.updated"
+	WebhookEventPostDeleted = "post.deleted"
+	WebhookEventPostExpired = "post.expired"
+)
+
+// webhookEventTypes maps the lifecycle events beyond creation to their
+// webhook event type.
+var webhookEventTypes = map[post.Event]string{
+	post.EventUpdated: WebhookEventPostUpdated,
+	post.EventDeleted: WebhookEventPostDeleted,
+	post.EventExpired: WebhookEventPostExpired,
+}
+
+// Outbound webhook request headers. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), where timestamp
+// is the decimal Unix-seconds value of WebhookHeaderTimestamp and body is the
+// exact request body bytes. Receivers should recompute it, compare in constant
+// time, and reject timestamps outside a small tolerance to block replays.
+const (
+	WebhookHeaderEvent          = "X-Markpost-Event"
+	WebhookHeaderTimestamp      = "X-Markpost-Timestamp"
+	WebhookHeaderSignature      = "X-Markpost-Signature"
+	WebhookHeaderIdempotencyKey = "Idempotency-Key"
+	WebhookHeaderAttempt        = "X-Markpost-Delivery-Attempt"
+)
+
+// WebhookEvent is the versioned JSON body POSTed to a generic webhook channel.
+// ID equals the Idempotency-Key header and is stable across retries of the same
+// delivery attempt, so receivers can deduplicate at-least-once deliveries.
+type WebhookEvent struct {
+	Version string           `json:"version"`
+	Type    string           `json:"type"`
+	ID      string           `json:"id"`
+	Post    WebhookEventPost `json:"post"`
+	// Text is the channel's rendered message template, omitted when the
+	// channel has none. For post.digest it is the plain-text list of posts.
+	Text string `json:"text,omitempty"`
+	// Posts lists the posts of a post.digest event, oldest first; Post then
+	// summarizes the digest (its title is the headline, its URL the site).
+	Posts []WebhookEventPost `json:"posts,omitempty"`
+}
+
+// WebhookEventPost is the post snapshot carried by a WebhookEvent.
+type WebhookEventPost struct {
+	QID         string    `json:"qid"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	BodyPreview string    `json:"body_preview"`
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookClient POSTs signed JSON events to arbitrary webhook URLs.
+type WebhookClient struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewWebhookClient creates a WebhookClient with the given request timeout.
+func NewWebhookClient(timeout time.Duration) *WebhookClient {
+	return &WebhookClient{
+		httpClient: newHTTPClient(timeout),
+		now:        time.Now,
+	}
+}
+
+// WebhookDeliveryParams holds the parameters for sending a webhook event.
+type WebhookDeliveryParams struct {
+	WebhookURL     string
+	Secret         string
+	IdempotencyKey string
+	Attempt        int
+	Post           WebhookEventPost
+	Text           string
+	// Posts makes the event a post.digest carrying these posts.
+	Posts []WebhookEventPost
+	// Event names the lifecycle event sent; empty means post.created.
+	Event post.Event
+}
+
+// SendEvent signs and POSTs a post.created event, a post.digest event when
+// params carries Posts, or the event type of params.Event. Any non-2xx
+// response is returned as an error so the dispatcher applies its usual
+// backoff and expiry.
+func (c *WebhookClient) SendEvent(ctx context.Context, params WebhookDeliveryParams) error {
+	eventType := WebhookEventPostCreated
+	if t, ok := webhookEventTypes[params.Event]; ok {
+		eventType = t
+	} else if len(params.Posts) > 0 {
+		eventType = WebhookEventPostDigest
+	}
+	event := WebhookEvent{
+		Version: WebhookEventVersion,
+		Type:    eventType,
+		ID:      params.IdempotencyKey,
+		Post:    params.Post,
+		Text:    params.Text,
+		Posts:   params.Posts,
+	}
+	bodyBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("webhook marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, params.WebhookURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("webhook create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "Markpost-Webhook/"+WebhookEventVersion)
+	req.Header.Set(WebhookHeaderEvent, eventType)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, signWebhookPayload(params.Secret, timestamp, bodyBytes))
+	if params.IdempotencyKey != "" {
+		req.Header.Set(WebhookHeaderIdempotencyKey, params.IdempotencyKey)
+	}
+	if params.Attempt > 0 {
+		req.Header.Set(WebhookHeaderAttempt, strconv.Itoa(params.Attempt))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return classifyStatus(resp.StatusCode,
+			fmt.Errorf("webhook status=%d body=%s", resp.StatusCode, string(b)))
+	}
+
+	return nil
+}
+
+// signWebhookPayload returns the X-Markpost-Signature header value for a body
+// sent at the given Unix-seconds timestamp.
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookIdempotencyKey derives a stable key for an attempt. An attempt row
+// keeps its ID and created_at across every retry, so all tries of one delivery
+// share the key while a fresh attempt for the same post and channel gets a new
+// one.
+func webhookIdempotencyKey(a *delivery.Attempt) string {
+	if a == nil {
+		return ""
+	}
+	postID := 0
+	if a.PostID != nil {
+		postID = *a.PostID
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d:%d:%d:%d", a.ID, postID, a.ChannelID, a.CreatedAt.UnixMilli()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// minWebhookSecretLength is the shortest HMAC signing secret accepted for a
+// generic webhook channel.
+const minWebhookSecretLength = 16
+
+// webhookDriver is the ChannelDriver for generic signed webhooks.
+type webhookDriver struct{}
+
+func (webhookDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindWebhook }
+
+func (webhookDriver) Fields() []ConfigField {
+	return []ConfigField{
+		webhookURLField("Receiver URL for signed post events"),
+		{Key: "secret", Type: FieldTypeSecret, Required: true, Secret: true, Description: fmt.Sprintf("HMAC-SHA256 signing key, at least %d characters", minWebhookSecretLength)},
+	}
+}
+
+func (webhookDriver) Validate(config delivery.ChannelConfiguration) error {
+	if err := validateWebhookURLField(config); err != nil {
+		return err
+	}
+	secret := strings.TrimSpace(config.String("secret"))
+	if len(secret) < minWebhookSecretLength {
+		return service.New(service.ErrValidation, fmt.Sprintf("webhook secret must be at least %d characters", minWebhookSecretLength))
+	}
+	config["secret"] = secret
+	return nil
+}
+
+func (d webhookDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (webhookDriver) NewSender(opts SenderOptions) KindSender {
+	return webhookSender{client: NewWebhookClient(opts.Timeout)}
+}
+
+type webhookSender struct{ client *WebhookClient }
+
+func (s webhookSender) Send(ctx context.Context, msg OutboundMessage) error {
+	attemptNumber := 1
+	if msg.Attempt != nil {
+		attemptNumber = msg.Attempt.Attempts + 1
+	}
+	event := WebhookEventPost{
+		QID:         msg.Post.QID,
+		Title:       msg.Post.Title,
+		URL:         msg.PostURL,
+		BodyPreview: msg.BodyPreview(),
+		Author:      msg.Post.User.Username,
+		CreatedAt:   msg.Post.CreatedAt,
+	}
+	// A lifecycle event's receiver wants the post itself, not the notice
+	// about it; the body of a removed post is gone.
+	if subject := msg.Subject; subject != nil {
+		event = WebhookEventPost{QID: subject.QID, Title: subject.Title, URL: subject.URL, Author: subject.Author, CreatedAt: subject.CreatedAt}
+		if !msg.Event.Removed() {
+			event.BodyPreview = msg.BodyPreview()
+		}
+	}
+	return s.client.SendEvent(ctx, WebhookDeliveryParams{
+		WebhookURL:     msg.Channel.Configuration.String("webhook_url"),
+		Secret:         msg.Channel.Configuration.String("secret"),
+		IdempotencyKey: webhookIdempotencyKey(msg.Attempt),
+		Attempt:        attemptNumber,
+		Post:           event,
+		Text:           msg.Text,
+		Posts:          webhookDigestPosts(msg.Digest),
+		Event:          msg.Event,
+	})
+}
+
+// webhookDigestPosts converts a digest's entries to event post snapshots. A
+// digest lists titles and links only, so BodyPreview is empty.
+func webhookDigestPosts(entries []PostEntry) []WebhookEventPost {
+	if len(entries) == 0 {
+		return nil
+	}
+	posts := make([]WebhookEventPost, len(entries))
+	for i, e := range entries {
+		posts[i] = WebhookEventPost{QID: e.QID, Title: e.Title, URL: e.URL, Author: e.Author, CreatedAt: e.CreatedAt}
+	}
+	return posts
+}
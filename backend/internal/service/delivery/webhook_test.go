@@ -0,0 +1,180 @@
+package delivery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"markpost/internal/domain/delivery"
+	domainpost "markpost/internal/domain/post"
+)
+
+func TestWebhookClient_SendEvent(t *testing.T) {
+	createdAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	params := WebhookDeliveryParams{
+		Secret:         "0123456789abcdef",
+		IdempotencyKey: "key-1",
+		Attempt:        2,
+		Post: WebhookEventPost{
+			QID:         "p-abc",
+			Title:       "Deploy finished",
+			URL:         "https://example.com/p-abc",
+			BodyPreview: "All green",
+			Author:      "alice",
+			CreatedAt:   createdAt,
+		},
+	}
+
+	t.Run("posts signed versioned event", func(t *testing.T) {
+		var body []byte
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			header = r.Header.Clone()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := NewWebhookClient(5 * time.Second)
+		client.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+		p := params
+		p.WebhookURL = server.URL
+		if err := client.SendEvent(context.Background(), p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := header.Get(WebhookHeaderTimestamp); got != "1700000000" {
+			t.Errorf("timestamp header = %q, want %q", got, "1700000000")
+		}
+		if got := header.Get(WebhookHeaderEvent); got != WebhookEventPostCreated {
+			t.Errorf("event header = %q, want %q", got, WebhookEventPostCreated)
+		}
+		if got := header.Get(WebhookHeaderIdempotencyKey); got != "key-1" {
+			t.Errorf("idempotency header = %q, want %q", got, "key-1")
+		}
+		if got := header.Get(WebhookHeaderAttempt); got != "2" {
+			t.Errorf("attempt header = %q, want %q", got, "2")
+		}
+
+		mac := hmac.New(sha256.New, []byte(params.Secret))
+		mac.Write([]byte("1700000000." + string(body)))
+		wantSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := header.Get(WebhookHeaderSignature); got != wantSig {
+			t.Errorf("signature = %q, want %q", got, wantSig)
+		}
+
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if event.Version != WebhookEventVersion {
+			t.Errorf("version = %q, want %q", event.Version, WebhookEventVersion)
+		}
+		if event.Type != WebhookEventPostCreated {
+			t.Errorf("type = %q, want %q", event.Type, WebhookEventPostCreated)
+		}
+		if event.ID != "key-1" {
+			t.Errorf("id = %q, want %q", event.ID, "key-1")
+		}
+		if event.Post.Author != "alice" || event.Post.QID != "p-abc" || !event.Post.CreatedAt.Equal(createdAt) {
+			t.Errorf("post = %+v, want author/qid/created_at preserved", event.Post)
+		}
+	})
+
+	t.Run("sends a digest as post.digest", func(t *testing.T) {
+		var body []byte
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			header = r.Header.Clone()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		p := params
+		p.WebhookURL = server.URL
+		p.Posts = webhookDigestPosts([]PostEntry{{QID: "p-1", Title: "One"}, {QID: "p-2", Title: "Two"}})
+		if err := NewWebhookClient(5*time.Second).SendEvent(context.Background(), p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if event.Type != WebhookEventPostDigest || header.Get(WebhookHeaderEvent) != WebhookEventPostDigest {
+			t.Errorf("type = %q, header = %q; want %q", event.Type, header.Get(WebhookHeaderEvent), WebhookEventPostDigest)
+		}
+		if len(event.Posts) != 2 || event.Posts[1].QID != "p-2" {
+			t.Errorf("posts = %+v, want both digest posts in order", event.Posts)
+		}
+	})
+
+	t.Run("names a lifecycle event by its type", func(t *testing.T) {
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Clone()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		p := params
+		p.WebhookURL = server.URL
+		p.Event = domainpost.EventDeleted
+		if err := NewWebhookClient(5*time.Second).SendEvent(context.Background(), p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := header.Get(WebhookHeaderEvent); got != WebhookEventPostDeleted {
+			t.Errorf("event header = %q, want %q", got, WebhookEventPostDeleted)
+		}
+	})
+
+	t.Run("returns error for non-2xx status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := NewWebhookClient(5 * time.Second)
+		p := params
+		p.WebhookURL = server.URL
+		if err := client.SendEvent(context.Background(), p); err == nil {
+			t.Fatal("expected error for non-2xx status")
+		}
+	})
+}
+
+func TestWebhookIdempotencyKey(t *testing.T) {
+	createdAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	postID := 3
+	a := &delivery.Attempt{ID: 7, PostID: &postID, ChannelID: 4, CreatedAt: createdAt}
+
+	key := webhookIdempotencyKey(a)
+	if len(key) != 32 {
+		t.Errorf("key length = %d, want 32", len(key))
+	}
+
+	retried := *a
+	retried.Attempts = 3
+	if got := webhookIdempotencyKey(&retried); got != key {
+		t.Errorf("key changed across retries: %q != %q", got, key)
+	}
+
+	other := *a
+	other.ID = 8
+	if got := webhookIdempotencyKey(&other); got == key {
+		t.Error("expected a different key for a different attempt")
+	}
+
+	if got := webhookIdempotencyKey(nil); got != "" {
+		t.Errorf("nil attempt key = %q, want empty", got)
+	}
+}
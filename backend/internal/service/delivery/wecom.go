@@ -0,0 +1,109 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"markpost/internal/domain/delivery"
+)
+
+// wecomMarkdownMaxBytes is WeCom's limit on a markdown message's content, in
+// UTF-8 bytes. Longer content is rejected with errcode 40058.
+const wecomMarkdownMaxBytes = 4096
+
+// wecomErrCodes classifies WeCom robot errcodes: those retrying cannot fix are
+// permanent, rate limiting backs off and retries. Unlisted codes are
+// transient.
+var wecomErrCodes = map[int]FailureKind{
+	45009: FailureRateLimited, // api freq out of limit
+	93000: FailurePermanent,   // invalid webhook url (robot removed)
+	93004: FailurePermanent,   // robot disabled
+	93008: FailurePermanent,   // robot not in the group
+}
+
+// WeComClient sends messages to WeCom group-robot webhook URLs.
+type WeComClient struct {
+	httpClient *http.Client
+}
+
+// NewWeComClient creates a WeComClient with the given request timeout.
+func NewWeComClient(timeout time.Duration) *WeComClient {
+	return &WeComClient{
+		httpClient: newHTTPClient(timeout),
+	}
+}
+
+type wecomMarkdownPayload struct {
+	MsgType  string        `json:"msgtype"`
+	Markdown wecomMarkdown `json:"markdown"`
+}
+
+type wecomMarkdown struct {
+	Content string `json:"content"`
+}
+
+// WeComDeliveryParams holds the parameters for sending a WeCom markdown card.
+type WeComDeliveryParams struct {
+	WebhookURL  string
+	PostURL     string
+	PostTitle   string
+	ButtonText  string
+	BodyPreview string
+}
+
+// SendCard posts a markdown message (title heading, body preview, "View Post"
+// link) to the given WeCom robot. The body preview is shortened so the whole
+// content stays within WeCom's byte limit.
+func (c *WeComClient) SendCard(ctx context.Context, params WeComDeliveryParams) error {
+	head := "### " + params.PostTitle
+	tail := ""
+	if params.PostURL != "" {
+		tail = "\n\n[" + buttonLabel(params.ButtonText) + "](" + params.PostURL + ")"
+	}
+
+	content := head
+	if params.BodyPreview != "" {
+		budget := wecomMarkdownMaxBytes - len(head) - len(tail) - len("\n\n")
+		if preview := truncateUTF8Bytes(params.BodyPreview, budget); preview != "" {
+			content += "\n\n" + preview
+		}
+	}
+	content = truncateUTF8Bytes(content+tail, wecomMarkdownMaxBytes)
+
+	payload := wecomMarkdownPayload{MsgType: "markdown", Markdown: wecomMarkdown{Content: content}}
+	return sendRobotRequest(ctx, c.httpClient, "wecom", params.WebhookURL, payload, wecomErrCodes)
+}
+
+// wecomDriver is the ChannelDriver for WeCom group robot webhook.
+type wecomDriver struct{}
+
+func (wecomDriver) Kind() delivery.ChannelKind { return delivery.ChannelKindWeCom }
+
+func (wecomDriver) Fields() []ConfigField {
+	return []ConfigField{webhookURLField("WeCom group robot webhook URL")}
+}
+
+func (wecomDriver) Validate(config delivery.ChannelConfiguration) error {
+	return validateWebhookURLField(config)
+}
+
+func (d wecomDriver) Redact(config delivery.ChannelConfiguration) delivery.ChannelConfiguration {
+	return redactSecretFields(d.Fields(), config)
+}
+
+func (wecomDriver) NewSender(opts SenderOptions) KindSender {
+	return wecomSender{client: NewWeComClient(opts.Timeout)}
+}
+
+type wecomSender struct{ client *WeComClient }
+
+func (s wecomSender) Send(ctx context.Context, msg OutboundMessage) error {
+	return s.client.SendCard(ctx, WeComDeliveryParams{
+		WebhookURL:  msg.Channel.Configuration.String("webhook_url"),
+		PostURL:     msg.LinkURL(),
+		PostTitle:   msg.TitleText(),
+		ButtonText:  msg.ButtonText(),
+		BodyPreview: msg.Content(),
+	})
+}
@@ -0,0 +1,127 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWeComClient_SendCard(t *testing.T) {
+	t.Run("sends markdown message with view post link", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}))
+		defer server.Close()
+
+		client := NewWeComClient(5 * time.Second)
+		err := client.SendCard(context.Background(), WeComDeliveryParams{
+			WebhookURL:  server.URL,
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "Test Title",
+			BodyPreview: "Preview",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if receivedBody["msgtype"] != "markdown" {
+			t.Errorf("msgtype = %v, want %q", receivedBody["msgtype"], "markdown")
+		}
+		content := receivedBody["markdown"].(map[string]any)["content"]
+		want := "### Test Title\n\nPreview\n\n[View Post](https://example.com/p-abc)"
+		if content != want {
+			t.Errorf("content = %q, want %q", content, want)
+		}
+	})
+
+	t.Run("keeps content within the byte limit", func(t *testing.T) {
+		var receivedBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &receivedBody)
+			_, _ = w.Write([]byte(`{"errcode":0}`))
+		}))
+		defer server.Close()
+
+		client := NewWeComClient(5 * time.Second)
+		err := client.SendCard(context.Background(), WeComDeliveryParams{
+			WebhookURL:  server.URL,
+			PostURL:     "https://example.com/p-abc",
+			PostTitle:   "Test Title",
+			BodyPreview: strings.Repeat("内容", 2000),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content := receivedBody["markdown"].(map[string]any)["content"].(string)
+		if len(content) > wecomMarkdownMaxBytes {
+			t.Errorf("content is %d bytes, want <= %d", len(content), wecomMarkdownMaxBytes)
+		}
+		if !utf8.ValidString(content) {
+			t.Error("content is not valid UTF-8 after truncation")
+		}
+		if !strings.HasSuffix(content, "[View Post](https://example.com/p-abc)") {
+			t.Error("expected the view post link to survive truncation")
+		}
+	})
+
+	t.Run("maps errcodes to retryable and permanent failures", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			body      string
+			permanent bool
+		}{
+			{"rate limited is retryable", `{"errcode":45009,"errmsg":"api freq out of limit"}`, false},
+			{"removed robot is permanent", `{"errcode":93000,"errmsg":"invalid webhook url"}`, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(tt.body))
+				}))
+				defer server.Close()
+
+				client := NewWeComClient(5 * time.Second)
+				err := client.SendCard(context.Background(), WeComDeliveryParams{WebhookURL: server.URL, PostTitle: "t"})
+				if err == nil {
+					t.Fatal("expected error for non-zero errcode")
+				}
+				if got := isPermanent(err); got != tt.permanent {
+					t.Errorf("isPermanent = %v, want %v (err=%v)", got, tt.permanent, err)
+				}
+			})
+		}
+	})
+}
+
+func TestTruncateUTF8Bytes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"empty string", "", 10, ""},
+		{"zero max", "hello", 0, ""},
+		{"shorter than max", "hello", 10, "hello"},
+		{"ascii cut", "hello world", 5, "hello"},
+		{"does not split rune", "你好", 4, "你"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateUTF8Bytes(tt.s, tt.max); got != tt.want {
+				t.Errorf("truncateUTF8Bytes(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+		})
+	}
+}
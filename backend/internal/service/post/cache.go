@@ -11,14 +11,14 @@ import (
 
 // renderResult is the cached payload for a rendered post variant: the title,
 // the rendered body (minified HTML, or the raw markdown response), the response
-// ETag, and the post's creation time (for Last-Modified). Storing them together
+// ETag, and the post's last modification time (for Last-Modified). Storing them together
 // means a cache hit returns everything with no hashing and no DB read on the
 // hot path.
 type renderResult struct {
 	title     string
 	body      string
 	etag      string
-	createdAt time.Time
+	updatedAt time.Time
 }
 
 // renderCache abstracts the in-process render cache so it can be disabled or
@@ -43,6 +43,12 @@ func cacheKey(qid, variant string) string {
 	return qid + ":" + web.BuildID() + ":" + variant
 }
 
+// revisionCacheKey builds the render-cache key for version number of a post.
+// It is keyed by post ID, which unlike a QID is never reused.
+func revisionCacheKey(postID, number int) string {
+	return fmt.Sprintf("post-%d:%s:html@%d", postID, web.BuildID(), number)
+}
+
 // ristrettoCache wraps *ristretto.Cache as a renderCache.
 type ristrettoCache struct {
 	c *ristretto.Cache
@@ -290,6 +290,59 @@ func TestDeletePostByQID_OwnerScopedAndPurges(t *testing.T) {
 	})
 }
 
+func TestUpdatePost_InvalidatesCacheAnnouncesAndPurges(t *testing.T) {
+	svc, repo, _ := newServiceWithCache(t)
+	purger := &recordingPurger{}
+	enqueuer := &mockEnqueuer{}
+	svc.purger, svc.delivery = purger, enqueuer
+	ctx := context.Background()
+	created, _ := repo.Create(ctx, "Titel", "# old", 7)
+
+	_, html1, etag1, modified1, err := svc.RenderPostHTML(ctx, created.QID)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	title, body := "Title", "# new"
+	_, err = svc.UpdatePost(ctx, created.QID, 999, UpdatePostParams{Title: &title})
+	if se, ok := service.AsError(err); !ok || se.Code != service.ErrNotFound {
+		t.Fatalf("wrong-owner update = %v, want not_found", err)
+	}
+
+	updated, err := svc.UpdatePost(ctx, created.QID, 7, UpdatePostParams{Title: &title, Body: &body})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.QID != created.QID || updated.Title != title || !updated.UpdatedAt.After(modified1) {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	// The cached render was dropped: the new content and time are served.
+	title2, html2, etag2, modified2, err := svc.RenderPostHTML(ctx, created.QID)
+	if err != nil {
+		t.Fatalf("re-render: %v", err)
+	}
+	if title2 != title || html2 == html1 || etag2 == etag1 || !modified2.Equal(updated.UpdatedAt) {
+		t.Errorf("re-render = %q %q %q %v, want the edit", title2, html2, etag2, modified2)
+	}
+	waitFor(t, func() bool { return purger.Count() == 1 }, time.Second)
+	if got := purger.Count(); got != 1 {
+		t.Errorf("expected exactly 1 purge, got %d", got)
+	}
+	if len(enqueuer.jobs) != 1 || enqueuer.jobs[0].Event != post.EventUpdated || enqueuer.jobs[0].Body != body {
+		t.Errorf("jobs = %+v, want one update announcement", enqueuer.jobs)
+	}
+
+	// An edit that changes nothing writes, announces and purges nothing.
+	if _, err := svc.UpdatePost(ctx, created.QID, 7, UpdatePostParams{Title: &title}); err != nil {
+		t.Fatalf("no-op update: %v", err)
+	}
+	stored, _ := repo.GetByQID(ctx, created.QID)
+	if !stored.UpdatedAt.Equal(updated.UpdatedAt) || len(enqueuer.jobs) != 1 {
+		t.Errorf("no-op update bumped updated_at to %v or announced (%d jobs)", stored.UpdatedAt, len(enqueuer.jobs))
+	}
+}
+
 func TestPruneExpired_InvalidatesCacheWithoutPurging(t *testing.T) {
 	db := infra.SetupTestDB(t)
 	repo := infra.NewPostRepository(db)
@@ -319,7 +372,7 @@ func TestPruneExpired_InvalidatesCacheWithoutPurging(t *testing.T) {
 		t.Fatalf("render: %v", err)
 	}
 
-	if err := svc.PruneExpired(ctx, 7, 100); err != nil {
+	if _, err := svc.PruneExpired(ctx, 7, 100); err != nil {
 		t.Fatalf("prune: %v", err)
 	}
 	if got := purger.Count(); got != 0 {
@@ -330,3 +383,45 @@ func TestPruneExpired_InvalidatesCacheWithoutPurging(t *testing.T) {
 		t.Error("re-render after prune should error (cache invalidated)")
 	}
 }
+
+func TestRenderRevisionHTML_Cached(t *testing.T) {
+	db := infra.SetupTestDB(t)
+	repo := infra.NewPostRepository(db)
+	cache, err := newRistrettoCache(1<<20, 10000, 64)
+	if err != nil {
+		t.Fatalf("ristretto: %v", err)
+	}
+	t.Cleanup(cache.Close)
+	svc := &Service{
+		postRepo:  repo,
+		md:        newGoldmark(),
+		sanitizer: newPostHTMLSanitizer(),
+		minifier:  newHTMLMinifier(),
+		cache:     cache,
+		purger:    &recordingPurger{},
+	}
+	ctx := context.Background()
+	p, _ := repo.Create(ctx, "T", "# first", 1)
+	p.Body = "# second"
+	if err := repo.Update(ctx, p, 1); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	_, first, _, _, err := svc.RenderRevisionHTML(ctx, p.QID, 1)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	cache.c.Wait()
+	// A second render is served from the cache, not the revision row.
+	db.Model(&post.Revision{}).Where("post_id = ?", p.ID).Update("body", "# tampered")
+	if _, again, _, _, _ := svc.RenderRevisionHTML(ctx, p.QID, 1); again != first {
+		t.Errorf("second render = %q, want the cached %q", again, first)
+	}
+
+	if err := svc.DeletePostByQID(ctx, p.QID, 1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, _, _, err := svc.RenderRevisionHTML(ctx, p.QID, 1); err == nil {
+		t.Error("a deleted post still serves its cached revision")
+	}
+}
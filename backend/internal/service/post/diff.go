@@ -0,0 +1,238 @@
+package post
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change, as
+// in diff -u.
+const diffContext = 3
+
+// maxDiffEdits bounds the edit distance the line diff searches for. Past it
+// the differing region is reported as wholly replaced: still a correct diff,
+// only a coarser one, and the search stays within O(maxDiffEdits²) memory
+// whatever the post size.
+const maxDiffEdits = 1000
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the line diff of a and b in unified format under the
+// file names fromName and toName. Identical texts give the empty string.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the run of changes it starts, split where
+		// more than twice the context of kept lines lies between two changes.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i, kept := first+1, 0; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last, kept = i, 0
+			} else if kept++; kept > 2*diffContext {
+				break
+			}
+		}
+
+		lo, hi := max(start, first-diffContext), min(len(ops), last+1+diffContext)
+		writeHunk(&sb, ops, lo, hi)
+		start = hi
+	}
+	return sb.String()
+}
+
+// writeHunk writes ops[lo:hi] as one hunk with its @@ header.
+func writeHunk(sb *strings.Builder, ops []diffOp, lo, hi int) {
+	aLine, bLine := 1, 1
+	for _, op := range ops[:lo] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, op := range ops[lo:hi] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	for _, op := range ops[lo:hi] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// hunkRange formats a hunk's line range; an empty range names the line
+// before it, as diff -u does.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// splitLines splits s into lines; a trailing newline does not start another
+// and the empty string has none.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns a shortest edit script turning a into b (Myers' greedy
+// algorithm), with removals listed before additions within each change.
+// The common prefix and suffix are kept outside the search, which for the
+// typical local edit leaves only a few lines to compare.
+func diffLines(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:pre] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ops = append(ops, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// myers runs the search on the differing middle of two texts. trace[d] holds
+// the furthest x reached on diagonals -d-1..d+1 before round d, which is all
+// the backtrack needs.
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxDiffEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrack walks trace from the end of both texts back to the start and
+// returns the edit script in forward order.
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	x, y := len(a), len(b)
+	var rev []diffOp
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			rev = append(rev, diffOp{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, diffOp{'+', b[prevY]})
+			} else {
+				rev = append(rev, diffOp{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(rev))
+	for i, op := range rev {
+		ops[len(rev)-1-i] = op
+	}
+	return groupChanges(ops)
+}
+
+// groupChanges reorders each run of changes so its removals precede its
+// additions, the conventional unified-diff layout.
+func groupChanges(ops []diffOp) []diffOp {
+	out := make([]diffOp, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			out = append(out, ops[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		for _, op := range ops[i:j] {
+			if op.kind == '-' {
+				out = append(out, op)
+			}
+		}
+		for _, op := range ops[i:j] {
+			if op.kind == '+' {
+				out = append(out, op)
+			}
+		}
+		i = j
+	}
+	return out
+}
+
+// replaceAll is the edit script that removes all of a and adds all of b.
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a {
+		ops = append(ops, diffOp{'-', l})
+	}
+	for _, l := range b {
+		ops = append(ops, diffOp{'+', l})
+	}
+	return ops
+}
@@ -0,0 +1,97 @@
+package post
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		var sb strings.Builder
+		for i := from; i <= to; i++ {
+			sb.WriteString("line " + strconv.Itoa(i) + "\n")
+		}
+		return sb.String()
+	}
+
+	cases := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "same\n", "same\n", ""},
+		{
+			"one line changed",
+			"# Title\n\nalpha\nbeta\ngamma\n",
+			"# Title\n\nalpha\nBETA\ngamma\n",
+			"--- a\n+++ b\n@@ -1,5 +1,5 @@\n # Title\n \n alpha\n-beta\n+BETA\n gamma\n",
+		},
+		{
+			"append to empty",
+			"",
+			"one\ntwo\n",
+			"--- a\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			"distant changes make two hunks",
+			lines(1, 20),
+			strings.Replace(strings.Replace(lines(1, 20), "line 2\n", "line two\n", 1), "line 18\n", "", 1),
+			"--- a\n+++ b\n@@ -1,5 +1,5 @@\n line 1\n-line 2\n+line two\n line 3\n line 4\n line 5\n" +
+				"@@ -15,6 +15,5 @@\n line 15\n line 16\n line 17\n-line 18\n line 19\n line 20\n",
+		},
+		{
+			"nearby changes share a hunk",
+			lines(1, 10),
+			strings.Replace(strings.Replace(lines(1, 10), "line 2\n", "", 1), "line 7\n", "line 7\nnew\n", 1),
+			"--- a\n+++ b\n@@ -1,10 +1,10 @@\n line 1\n-line 2\n line 3\n line 4\n line 5\n line 6\n line 7\n+new\n line 8\n line 9\n line 10\n",
+		},
+		{
+			"removal listed before addition",
+			"keep\nold 1\nold 2\nkeep\n",
+			"keep\nnew 1\nkeep\n",
+			"--- a\n+++ b\n@@ -1,4 +1,3 @@\n keep\n-old 1\n-old 2\n+new 1\n keep\n",
+		},
+	}
+	for _, c := range cases {
+		if got := unifiedDiff("a", "b", c.a, c.b); got != c.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", c.name, got, c.want)
+		}
+	}
+}
+
+// applyOps rebuilds both sides of an edit script, so a diff can be checked
+// against its inputs without fixing its exact shape.
+func applyOps(ops []diffOp) (a, b []string) {
+	for _, op := range ops {
+		if op.kind != '+' {
+			a = append(a, op.line)
+		}
+		if op.kind != '-' {
+			b = append(b, op.line)
+		}
+	}
+	return a, b
+}
+
+func TestDiffLines_Reconstructs(t *testing.T) {
+	a := strings.Split("a b c a b b a x y z q", " ")
+	b := strings.Split("c b a b a c x z y q", " ")
+	ops := diffLines(a, b)
+	gotA, gotB := applyOps(ops)
+	if strings.Join(gotA, " ") != strings.Join(a, " ") || strings.Join(gotB, " ") != strings.Join(b, " ") {
+		t.Fatalf("ops rebuild %v / %v", gotA, gotB)
+	}
+
+	// Past maxDiffEdits the middle is replaced wholesale, still correctly.
+	var big1, big2 []string
+	for i := 0; i < maxDiffEdits; i++ {
+		big1 = append(big1, "a"+strconv.Itoa(i))
+		big2 = append(big2, "b"+strconv.Itoa(i))
+	}
+	ops = diffLines(append([]string{"head"}, big1...), append([]string{"head"}, big2...))
+	gotA, gotB = applyOps(ops)
+	if len(ops) != 2*maxDiffEdits+1 || len(gotA) != maxDiffEdits+1 || gotB[1] != "b0" {
+		t.Errorf("large diff: %d ops", len(ops))
+	}
+}
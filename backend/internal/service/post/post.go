@@ -130,30 +130,98 @@ func (s *Service) CreatePost(ctx context.Context, title, body string, userID int
 		return "", service.Wrap(service.ErrInternal, "create post failed", err)
 	}
 
-	if s.delivery != nil {
-		s.delivery.Enqueue(post.DeliveryJob{
-			UserID:  userID,
-			PostID:  p.ID,
-			PostQID: p.QID,
-			Title:   title,
-			Body:    body,
-		})
-	}
+	s.announce(post.EventCreated, p)
 
 	return p.QID, nil
 }
 
+// UpdatePostParams holds the fields of a post edit. A nil field is left
+// unchanged.
+type UpdatePostParams struct {
+	Title *string
+	Body  *string
+}
+
+// UpdatePost edits the title and/or body of a post owned by ownerID and
+// returns the post as stored. A post of another owner is ErrNotFound, as it
+// is for DeletePostByQID. When the edit changes something the replaced title
+// and body are kept as a revision, the row's UpdatedAt is bumped, both
+// render-cache variants are dropped synchronously, the update is announced
+// to the owner's delivery channels and a best-effort CDN purge is enqueued
+// asynchronously; an edit that changes nothing writes nothing.
+func (s *Service) UpdatePost(ctx context.Context, qid string, ownerID int, params UpdatePostParams) (*post.Post, error) {
+	p, err := s.getOwnPost(ctx, qid, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyEdit(ctx, p, ownerID, params)
+}
+
+// getOwnPost returns the post with the QID if ownerID owns it; a post of
+// another owner is ErrNotFound.
+func (s *Service) getOwnPost(ctx context.Context, qid string, ownerID int) (*post.Post, error) {
+	p, err := s.getPostByQID(ctx, qid)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != ownerID {
+		return nil, service.New(service.ErrNotFound, "post not found")
+	}
+	return p, nil
+}
+
+// applyEdit writes an edit of p made by editorID, archiving the replaced
+// version as a revision; see UpdatePost.
+func (s *Service) applyEdit(ctx context.Context, p *post.Post, editorID int, params UpdatePostParams) (*post.Post, error) {
+	changed := false
+	if params.Title != nil && *params.Title != p.Title {
+		p.Title, changed = *params.Title, true
+	}
+	if params.Body != nil && *params.Body != p.Body {
+		p.Body, changed = *params.Body, true
+	}
+	if !changed {
+		return p, nil
+	}
+
+	if err := s.postRepo.Update(ctx, p, editorID); err != nil {
+		return nil, service.WrapNotFoundOrInternal(err, "post not found", "update post failed")
+	}
+
+	s.invalidateCache(p.QID)
+	s.announce(post.EventUpdated, p)
+	s.purgeAsync(p.QID)
+
+	return p, nil
+}
+
+// announce enqueues delivery of a lifecycle event of p, if delivery is wired.
+func (s *Service) announce(event post.Event, p *post.Post) {
+	if s.delivery == nil {
+		return
+	}
+	s.delivery.Enqueue(post.DeliveryJob{
+		Event:     event,
+		UserID:    p.UserID,
+		PostID:    p.ID,
+		PostQID:   p.QID,
+		Title:     p.Title,
+		Body:      p.Body,
+		CreatedAt: p.CreatedAt,
+	})
+}
+
 // RenderPostHTML renders a post's body as sanitized, minified HTML and returns
 // the title, the rendered HTML, the response ETag (xxhash64 of the rendered
-// output), and the post's creation time (for Last-Modified) for
+// output), and the post's last modification time (for Last-Modified) for
 // conditional-GET revalidation. The DB read + render pipeline runs behind a
 // ristretto cache fronted by singleflight: a cache hit skips goldmark/bluemonday
 // entirely, and concurrent misses for the same QID collapse to one render.
-func (s *Service) RenderPostHTML(ctx context.Context, qid string) (title, html, etag string, createdAt time.Time, err error) {
+func (s *Service) RenderPostHTML(ctx context.Context, qid string) (title, html, etag string, updatedAt time.Time, err error) {
 	key := cacheKey(qid, "html")
 
 	if v, ok := s.cache.Get(key); ok {
-		return v.title, v.body, v.etag, v.createdAt, nil
+		return v.title, v.body, v.etag, v.updatedAt, nil
 	}
 
 	v, err, _ := s.group.Do(key, func() (any, error) {
@@ -164,20 +232,15 @@ func (s *Service) RenderPostHTML(ctx context.Context, qid string) (title, html,
 		if err != nil {
 			return nil, err
 		}
-		var buf bytes.Buffer
-		if err := s.md.Convert([]byte(p.Body), &buf); err != nil {
-			return nil, service.Wrap(service.ErrInternal, "render post failed", err)
-		}
-		sanitized := s.sanitizer.Sanitize(neutralizeRawHTMLElements(buf.String()))
-		minified, mErr := s.minifyHTML(sanitized)
-		if mErr != nil {
-			return nil, service.Wrap(service.ErrInternal, "render post failed", mErr)
+		minified, err := s.renderBody(p.Body)
+		if err != nil {
+			return nil, err
 		}
 		result := renderResult{
 			title:     p.Title,
 			body:      minified,
 			etag:      etagHex(minified),
-			createdAt: p.CreatedAt,
+			updatedAt: p.UpdatedAt,
 		}
 		s.cache.Set(key, result, int64(len(minified)))
 		return result, nil
@@ -189,20 +252,20 @@ func (s *Service) RenderPostHTML(ctx context.Context, qid string) (title, html,
 	if !ok {
 		return "", "", "", time.Time{}, service.New(service.ErrInternal, "render post failed")
 	}
-	return r.title, r.body, r.etag, r.createdAt, nil
+	return r.title, r.body, r.etag, r.updatedAt, nil
 }
 
 // GetPostMarkdown retrieves a post's raw markdown content and returns the
 // title, the body, the response ETag (xxhash64 of the raw response body
 // "# <title>\n\n<body>", matching what the handler serves), and the post's
-// creation time (for Last-Modified). Like RenderPostHTML it is cache-fronted
+// last modification time (for Last-Modified). Like RenderPostHTML it is cache-fronted
 // and singleflight-guarded, but its "render" is plain string concatenation
 // (no goldmark/bluemonday), so the miss path is cheap.
-func (s *Service) GetPostMarkdown(ctx context.Context, qid string) (title, body, etag string, createdAt time.Time, err error) {
+func (s *Service) GetPostMarkdown(ctx context.Context, qid string) (title, body, etag string, updatedAt time.Time, err error) {
 	key := cacheKey(qid, "raw")
 
 	if v, ok := s.cache.Get(key); ok {
-		return v.title, v.body, v.etag, v.createdAt, nil
+		return v.title, v.body, v.etag, v.updatedAt, nil
 	}
 
 	v, err, _ := s.group.Do(key, func() (any, error) {
@@ -213,12 +276,12 @@ func (s *Service) GetPostMarkdown(ctx context.Context, qid string) (title, body,
 		if err != nil {
 			return nil, err
 		}
-		rawBody := "# " + p.Title + "\n\n" + p.Body
+		rawBody := rawMarkdown(p.Title, p.Body)
 		result := renderResult{
 			title:     p.Title,
 			body:      p.Body,
 			etag:      etagHex(rawBody),
-			createdAt: p.CreatedAt,
+			updatedAt: p.UpdatedAt,
 		}
 		s.cache.Set(key, result, int64(len(rawBody)))
 		return result, nil
@@ -230,7 +293,22 @@ func (s *Service) GetPostMarkdown(ctx context.Context, qid string) (title, body,
 	if !ok {
 		return "", "", "", time.Time{}, service.New(service.ErrInternal, "render post failed")
 	}
-	return r.title, r.body, r.etag, r.createdAt, nil
+	return r.title, r.body, r.etag, r.updatedAt, nil
+}
+
+// renderBody runs a markdown body through the render pipeline: goldmark,
+// raw-text element neutralizing, sanitizing and minifying.
+func (s *Service) renderBody(body string) (string, error) {
+	var buf bytes.Buffer
+	if err := s.md.Convert([]byte(body), &buf); err != nil {
+		return "", service.Wrap(service.ErrInternal, "render post failed", err)
+	}
+	sanitized := s.sanitizer.Sanitize(neutralizeRawHTMLElements(buf.String()))
+	minified, err := s.minifyHTML(sanitized)
+	if err != nil {
+		return "", service.Wrap(service.ErrInternal, "render post failed", err)
+	}
+	return minified, nil
 }
 
 func (s *Service) minifyHTML(htmlContent string) (string, error) {
@@ -241,6 +319,12 @@ func (s *Service) minifyHTML(htmlContent string) (string, error) {
 	return buf.String(), nil
 }
 
+// rawMarkdown is the raw view of a post: its title as a heading, then the
+// body.
+func rawMarkdown(title, body string) string {
+	return "# " + title + "\n\n" + body
+}
+
 func etagHex(s string) string {
 	return fmt.Sprintf("%016x", xxhash.Sum64String(s))
 }
@@ -273,11 +357,23 @@ func (s *Service) DeletePost(ctx context.Context, id int) error {
 // DeletePostByQID deletes a post by QID, scoped to ownerID when non-zero
 // (JWT owner path). An ownerID of 0 deletes without an owner constraint
 // (admin path). It removes the DB row, drops both render-cache variants
-// synchronously, and enqueues a best-effort CDN cache-tag purge
-// asynchronously. A failed purge is logged and swallowed; the CDN falls back to
-// its natural TTL. Returns ErrNotFound when no row matched (wrong QID, or the
-// post belongs to a different owner).
+// synchronously, announces the deletion to the owner's delivery channels, and
+// enqueues a best-effort CDN cache-tag purge asynchronously. A failed purge is
+// logged and swallowed; the CDN falls back to its natural TTL. Returns
+// ErrNotFound when no row matched (wrong QID, or the post belongs to a
+// different owner).
 func (s *Service) DeletePostByQID(ctx context.Context, qid string, ownerID int) error {
+	// The delete notice needs the post's title and owner, which are gone
+	// once the row is; read them first when delivery is wired.
+	var removed *post.Post
+	if s.delivery != nil {
+		p, err := s.getPostByQID(ctx, qid)
+		if err != nil {
+			return err
+		}
+		removed = p
+	}
+
 	affected, err := s.postRepo.DeleteByQID(ctx, qid, ownerID)
 	if err != nil {
 		return service.WrapNotFoundOrInternal(err, "post not found", "delete post failed")
@@ -287,45 +383,72 @@ func (s *Service) DeletePostByQID(ctx context.Context, qid string, ownerID int)
 	}
 
 	s.invalidateCache(qid)
+	if removed != nil {
+		s.announce(post.EventDeleted, removed)
+	}
+
+	s.purgeAsync(qid)
 
+	return nil
+}
+
+// purgeAsync issues the CDN purge for a QID in the background. A failed purge
+// is logged by the purger and swallowed.
+func (s *Service) purgeAsync(qid string) {
 	purgeCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	go func() {
 		defer cancel()
 		s.purger.PurgePost(purgeCtx, qid)
 	}()
-
-	return nil
 }
 
 // invalidateCache removes both render-cache variants for a QID. Called
-// synchronously on every deletion path (user delete, admin delete, prune).
+// synchronously on every edit and deletion path (update, user delete, admin
+// delete, prune).
 func (s *Service) invalidateCache(qid string) {
 	s.cache.Delete(cacheKey(qid, "html"))
 	s.cache.Delete(cacheKey(qid, "raw"))
 }
 
-// PruneExpired deletes expired posts based on retention days. It removes the
-// DB rows and origin render-cache entries but does NOT issue CDN purges —
-// stale delivery of already-expired ephemeral content is harmless and the prune
-// volume could be large.
-func (s *Service) PruneExpired(ctx context.Context, retentionDays, batchSize int) error {
+// PruneExpired deletes expired posts based on retention days and returns how
+// many it deleted. It removes the DB rows and origin render-cache entries and,
+// batch by batch, announces each expiry to the owner's delivery channels —
+// unless no channel subscribes to expiry, in which case post bodies are not
+// even loaded. It does NOT issue CDN purges — stale delivery of
+// already-expired ephemeral content is harmless and the prune volume could be
+// large.
+func (s *Service) PruneExpired(ctx context.Context, retentionDays, batchSize int) (int, error) {
 	if retentionDays <= 0 {
-		return service.New(service.ErrValidation, "retention days must be positive")
+		return 0, service.New(service.ErrValidation, "retention days must be positive")
 	}
 	if batchSize <= 0 {
 		batchSize = 99
 	}
 
-	qids, err := s.postRepo.PruneExpired(ctx, retentionDays, batchSize)
+	announce := s.hasSubscribers(ctx, post.EventExpired)
+	pruned, err := s.postRepo.PruneExpired(ctx, retentionDays, batchSize, announce, func(batch []post.Post) {
+		for i := range batch {
+			s.invalidateCache(batch[i].QID)
+			if announce {
+				s.announce(post.EventExpired, &batch[i])
+			}
+		}
+	})
 	if err != nil {
-		return service.Wrap(service.ErrInternal, "prune expired posts failed", err)
+		return pruned, service.Wrap(service.ErrInternal, "prune expired posts failed", err)
 	}
 
-	for _, qid := range qids {
-		s.invalidateCache(qid)
-	}
+	return pruned, nil
+}
 
-	return nil
+// hasSubscribers reports whether announcing event could reach any channel.
+// An enqueuer that cannot tell is assumed to have subscribers.
+func (s *Service) hasSubscribers(ctx context.Context, event post.Event) bool {
+	if s.delivery == nil {
+		return false
+	}
+	checker, ok := s.delivery.(post.SubscriptionChecker)
+	return !ok || checker.HasSubscribers(ctx, event)
 }
 
 // CountExpired counts expired posts based on retention days.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"markpost/internal/domain/post"
 	"markpost/internal/infra"
@@ -55,6 +56,66 @@ func TestService_CreatePost(t *testing.T) {
 	})
 }
 
+func TestService_AnnouncesRemoval(t *testing.T) {
+	db := infra.SetupTestDB(t)
+	repo := infra.NewPostRepository(db)
+	enqueuer := &mockEnqueuer{}
+	svc := NewService(repo, enqueuer)
+	ctx := context.Background()
+
+	deleted, _ := repo.Create(ctx, "Deleted", "Body", 1)
+	expired, _ := repo.Create(ctx, "Expired", "Body", 1)
+	db.Model(&post.Post{}).Where("id = ?", expired.ID).Update("created_at", time.Now().AddDate(0, 0, -10))
+
+	if err := svc.DeletePostByQID(ctx, deleted.QID, 2); err == nil {
+		t.Fatal("expected wrong-owner delete to fail")
+	}
+	if len(enqueuer.jobs) != 0 {
+		t.Fatalf("wrong-owner delete enqueued %d jobs", len(enqueuer.jobs))
+	}
+	if err := svc.DeletePostByQID(ctx, deleted.QID, 1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n, err := svc.PruneExpired(ctx, 7, 100); err != nil || n != 1 {
+		t.Fatalf("prune = %d, %v; want 1", n, err)
+	}
+
+	if len(enqueuer.jobs) != 2 {
+		t.Fatalf("enqueued %d jobs, want 2", len(enqueuer.jobs))
+	}
+	if j := enqueuer.jobs[0]; j.Event != post.EventDeleted || j.PostQID != deleted.QID || j.Title != "Deleted" || j.UserID != 1 {
+		t.Errorf("delete job = %+v", j)
+	}
+	if j := enqueuer.jobs[1]; j.Event != post.EventExpired || j.PostQID != expired.QID || j.Title != "Expired" || j.Body != "Body" || j.CreatedAt.IsZero() {
+		t.Errorf("expire job = %+v", j)
+	}
+}
+
+func TestService_PruneExpiredSkipsUnsubscribed(t *testing.T) {
+	db := infra.SetupTestDB(t)
+	repo := infra.NewPostRepository(db)
+	enqueuer := &checkingEnqueuer{}
+	svc := NewService(repo, enqueuer)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		p, _ := repo.Create(ctx, "Expired", "Body", 1)
+		db.Model(&post.Post{}).Where("id = ?", p.ID).Update("created_at", time.Now().AddDate(0, 0, -10))
+	}
+
+	if n, err := svc.PruneExpired(ctx, 7, 2); err != nil || n != 3 {
+		t.Fatalf("prune = %d, %v; want 3", n, err)
+	}
+	if len(enqueuer.jobs) != 0 {
+		t.Errorf("enqueued %d jobs with no expiry subscribers", len(enqueuer.jobs))
+	}
+}
+
+// checkingEnqueuer reports no subscribers for any event.
+type checkingEnqueuer struct{ mockEnqueuer }
+
+func (*checkingEnqueuer) HasSubscribers(context.Context, post.Event) bool { return false }
+
 type mockEnqueuer struct {
 	jobs []post.DeliveryJob
 }
@@ -318,7 +379,7 @@ func TestService_PruneExpired(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("returns error for non-positive retention days", func(t *testing.T) {
-		err := svc.PruneExpired(ctx, 0, 100)
+		_, err := svc.PruneExpired(ctx, 0, 100)
 		if err == nil {
 			t.Fatal("expected error for zero retention days")
 		}
@@ -332,7 +393,7 @@ func TestService_PruneExpired(t *testing.T) {
 	})
 
 	t.Run("uses default batch size when zero", func(t *testing.T) {
-		err := svc.PruneExpired(ctx, 7, 0)
+		_, err := svc.PruneExpired(ctx, 7, 0)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -0,0 +1,177 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"markpost/internal/domain"
+	"markpost/internal/domain/post"
+	"markpost/internal/service"
+)
+
+// Revisions is a post's version history: the number of its live version and
+// the archived versions before it, newest first.
+type Revisions struct {
+	Current int
+	Items   []post.Revision
+}
+
+// version is one version of a post's content and when it was written.
+type version struct {
+	title     string
+	body      string
+	writtenAt time.Time
+}
+
+// ListRevisions returns the version history of a post owned by ownerID.
+func (s *Service) ListRevisions(ctx context.Context, qid string, ownerID int) (*Revisions, error) {
+	p, err := s.getOwnPost(ctx, qid, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.postRepo.ListRevisions(ctx, p.ID)
+	if err != nil {
+		return nil, service.Wrap(service.ErrInternal, "list revisions failed", err)
+	}
+	current := 1
+	if len(items) > 0 {
+		current = items[0].Number + 1
+	}
+	return &Revisions{Current: current, Items: items}, nil
+}
+
+// DiffRevisions returns the unified diff from version from to version to of
+// a post owned by ownerID, either of which may be the live version. Both
+// sides are compared in the raw view, so a title change shows in the first
+// line. Identical versions give an empty diff.
+func (s *Service) DiffRevisions(ctx context.Context, qid string, ownerID, from, to int) (string, error) {
+	p, err := s.getOwnPost(ctx, qid, ownerID)
+	if err != nil {
+		return "", err
+	}
+	a, err := s.getVersion(ctx, p, from)
+	if err != nil {
+		return "", err
+	}
+	b, err := s.getVersion(ctx, p, to)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(
+		fmt.Sprintf("%s?rev=%d", qid, from), fmt.Sprintf("%s?rev=%d", qid, to),
+		rawMarkdown(a.title, a.body), rawMarkdown(b.title, b.body),
+	), nil
+}
+
+// RestoreRevision makes version number the live content of a post owned by
+// ownerID. It is an ordinary edit: the version it replaces is archived in
+// turn, so a restore can itself be undone, and delivery channels hear of it
+// as an update. Restoring the live version changes nothing.
+func (s *Service) RestoreRevision(ctx context.Context, qid string, ownerID, number int) (*post.Post, error) {
+	p, err := s.getOwnPost(ctx, qid, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.getVersion(ctx, p, number)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyEdit(ctx, p, ownerID, UpdatePostParams{Title: &v.title, Body: &v.body})
+}
+
+// RenderRevisionHTML is RenderPostHTML for version number of a post, for
+// links that must keep showing the content they were made for. It returns
+// when that version was written as the modification time; the live version
+// can also be read this way. A version never changes once written, so its
+// render is cached under the post's ID and the version number: the post is
+// still looked up on every call, so a deleted post stops serving its
+// versions without any invalidation.
+func (s *Service) RenderRevisionHTML(ctx context.Context, qid string, number int) (title, html, etag string, writtenAt time.Time, err error) {
+	p, err := s.getPostByQID(ctx, qid)
+	if err != nil {
+		return "", "", "", time.Time{}, err
+	}
+	key := revisionCacheKey(p.ID, number)
+
+	if v, ok := s.cache.Get(key); ok {
+		return v.title, v.body, v.etag, v.updatedAt, nil
+	}
+
+	v, err, _ := s.group.Do(key, func() (any, error) {
+		if cached, ok := s.cache.Get(key); ok {
+			return cached, nil
+		}
+		ver, err := s.getVersion(ctx, p, number)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := s.renderBody(ver.body)
+		if err != nil {
+			return nil, err
+		}
+		result := renderResult{
+			title:     ver.title,
+			body:      rendered,
+			etag:      etagHex(rendered),
+			updatedAt: ver.writtenAt,
+		}
+		s.cache.Set(key, result, int64(len(rendered)))
+		return result, nil
+	})
+	if err != nil {
+		return "", "", "", time.Time{}, err
+	}
+	r, ok := v.(renderResult)
+	if !ok {
+		return "", "", "", time.Time{}, service.New(service.ErrInternal, "render revision failed")
+	}
+	return r.title, r.body, r.etag, r.updatedAt, nil
+}
+
+// GetRevisionMarkdown is GetPostMarkdown for version number of a post; see
+// RenderRevisionHTML.
+func (s *Service) GetRevisionMarkdown(ctx context.Context, qid string, number int) (title, body, etag string, writtenAt time.Time, err error) {
+	v, err := s.getVersionByQID(ctx, qid, number)
+	if err != nil {
+		return "", "", "", time.Time{}, err
+	}
+	return v.title, v.body, etagHex(rawMarkdown(v.title, v.body)), v.writtenAt, nil
+}
+
+func (s *Service) getVersionByQID(ctx context.Context, qid string, number int) (*version, error) {
+	p, err := s.getPostByQID(ctx, qid)
+	if err != nil {
+		return nil, err
+	}
+	return s.getVersion(ctx, p, number)
+}
+
+// getVersion returns version number of p. Version n is archived as revision
+// n unless it is live, which it is when no revision n exists but revision
+// n-1 does (or n is 1). Version n+1 was written when version n was archived,
+// so revision n-1 also dates version n.
+func (s *Service) getVersion(ctx context.Context, p *post.Post, number int) (*version, error) {
+	if number < 1 {
+		return nil, service.New(service.ErrNotFound, "revision not found")
+	}
+	writtenAt := p.CreatedAt
+	if number > 1 {
+		prev, err := s.postRepo.GetRevision(ctx, p.ID, number-1)
+		if err != nil {
+			return nil, service.WrapNotFoundOrInternal(err, "revision not found", "get revision failed")
+		}
+		writtenAt = prev.CreatedAt
+	}
+
+	rev, err := s.postRepo.GetRevision(ctx, p.ID, number)
+	switch {
+	case err == nil:
+		return &version{title: rev.Title, body: rev.Body, writtenAt: writtenAt}, nil
+	case errors.Is(err, domain.ErrNotFound):
+		return &version{title: p.Title, body: p.Body, writtenAt: writtenAt}, nil
+	default:
+		return nil, service.Wrap(service.ErrInternal, "get revision failed", err)
+	}
+}
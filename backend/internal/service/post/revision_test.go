@@ -0,0 +1,77 @@
+package post
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"markpost/internal/infra"
+	"markpost/internal/service"
+)
+
+func TestService_Revisions(t *testing.T) {
+	db := infra.SetupTestDB(t)
+	repo := infra.NewPostRepository(db)
+	enqueuer := &mockEnqueuer{}
+	svc := NewService(repo, enqueuer)
+	ctx := context.Background()
+
+	created, _ := repo.Create(ctx, "Release", "alpha\nbeta\n", 1)
+	for _, body := range []string{"alpha\nBETA\n", "alpha\nBETA\ngamma\n"} {
+		if _, err := svc.UpdatePost(ctx, created.QID, 1, UpdatePostParams{Body: &body}); err != nil {
+			t.Fatalf("UpdatePost: %v", err)
+		}
+	}
+
+	revs, err := svc.ListRevisions(ctx, created.QID, 1)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if revs.Current != 3 || len(revs.Items) != 2 || revs.Items[0].Number != 2 {
+		t.Fatalf("revisions = %+v, want current 3 over revisions 2 and 1", revs)
+	}
+
+	diff, err := svc.DiffRevisions(ctx, created.QID, 1, 1, 3)
+	if err != nil {
+		t.Fatalf("DiffRevisions: %v", err)
+	}
+	want := "--- " + created.QID + "?rev=1\n+++ " + created.QID + "?rev=3\n" +
+		"@@ -1,4 +1,5 @@\n # Release\n \n alpha\n-beta\n+BETA\n+gamma\n"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+
+	// Version 2 was written by the first edit, which archived version 1.
+	title, body, _, writtenAt, err := svc.GetRevisionMarkdown(ctx, created.QID, 2)
+	if err != nil || title != "Release" || body != "alpha\nBETA\n" {
+		t.Fatalf("version 2 = %q/%q, %v", title, body, err)
+	}
+	if !writtenAt.Equal(revs.Items[1].CreatedAt) {
+		t.Errorf("version 2 written at %v, want revision 1's %v", writtenAt, revs.Items[1].CreatedAt)
+	}
+	if _, html, _, _, err := svc.RenderRevisionHTML(ctx, created.QID, 3); err != nil || !strings.Contains(html, "gamma") {
+		t.Errorf("live version html = %q, %v", html, err)
+	}
+
+	p, err := svc.RestoreRevision(ctx, created.QID, 1, 1)
+	if err != nil || p.Body != "alpha\nbeta\n" {
+		t.Fatalf("RestoreRevision = %+v, %v", p, err)
+	}
+	if revs, _ := svc.ListRevisions(ctx, created.QID, 1); revs.Current != 4 || revs.Items[0].Body != "" {
+		t.Errorf("after restore revisions = %+v, want the restored-over version archived as 3", revs)
+	}
+	if n := len(enqueuer.jobs); n != 3 {
+		t.Errorf("enqueued %d update jobs, want one per edit including the restore", n)
+	}
+
+	for name, err := range map[string]error{
+		"wrong owner":     func() error { _, err := svc.ListRevisions(ctx, created.QID, 2); return err }(),
+		"future version":  func() error { _, _, _, _, err := svc.GetRevisionMarkdown(ctx, created.QID, 5); return err }(),
+		"version zero":    func() error { _, err := svc.DiffRevisions(ctx, created.QID, 1, 0, 1); return err }(),
+		"restore missing": func() error { _, err := svc.RestoreRevision(ctx, created.QID, 1, 9); return err }(),
+	} {
+		if se, ok := service.AsError(err); !ok || se.Code != service.ErrNotFound {
+			t.Errorf("%s: err = %v, want ErrNotFound", name, err)
+		}
+	}
+}
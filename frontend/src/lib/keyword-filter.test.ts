@@ -93,6 +93,45 @@ describe("compileKeywordFilter", () => {
     expect(compileKeywordFilter(`"abc`).error).not.toBeNull();
   });
 
+  it("reads a regex literal whole, operators included", () => {
+    expect(compileKeywordFilter("/a|b/").node).toEqual({ type: "regex", pattern: "a|b" });
+    expect(compileKeywordFilter("/a&b/ | c").node).toEqual({
+      type: "or",
+      left: { type: "regex", pattern: "a&b" },
+      right: { type: "keyword", value: "c" },
+    });
+    expect(compileKeywordFilter("/x\\/y/").node).toEqual({ type: "regex", pattern: "x/y" });
+    expect(compileKeywordFilter("/a\\d+/").node).toEqual({ type: "regex", pattern: "a\\d+" });
+  });
+
+  it("parses whole-word keywords and field qualifiers", () => {
+    expect(compileKeywordFilter("=ci").node).toEqual({ type: "keyword", value: "ci", word: true });
+    expect(compileKeywordFilter("title:x").node).toEqual({ type: "keyword", value: "x", field: "title" });
+    expect(compileKeywordFilter(`Body:=ci & !author:"bot"`).node).toEqual({
+      type: "and",
+      left: { type: "keyword", value: "ci", field: "body", word: true },
+      right: { type: "not", operand: { type: "keyword", value: "bot", field: "author" } },
+    });
+    expect(compileKeywordFilter("body:/err(or)?/").node).toEqual({
+      type: "regex",
+      pattern: "err(or)?",
+      field: "body",
+    });
+  });
+
+  it("keeps qualifier and operator characters literal inside quotes and keywords", () => {
+    expect(compileKeywordFilter(`"body:x"`).node).toEqual({ type: "keyword", value: "body:x" });
+    expect(compileKeywordFilter(`"=x"`).node).toEqual({ type: "keyword", value: "=x" });
+    expect(compileKeywordFilter("a=b").node).toEqual({ type: "keyword", value: "a=b" });
+  });
+
+  it("rejects malformed regexes and qualifiers", () => {
+    const invalid = ["/abc", "//", `/${"x".repeat(257)}/`, "=", "= & a", "title:", "body: & a", "title:(a)"];
+    for (const expr of invalid) {
+      expect(compileKeywordFilter(expr).error, `expr=${expr}`).not.toBeNull();
+    }
+  });
+
   it("rejects structural errors", () => {
     const invalid = [
       "a,,b", "a && b", "a &", "& a", "&", "|", ",", ",a", "a,",
@@ -127,5 +166,13 @@ describe("describeFilter", () => {
 
   it("quotes keyword values containing operator characters", () => {
     expect(describeFilter(compileKeywordFilter(`"a,b"`).node)).toBe(`"a,b"`);
+    expect(describeFilter(compileKeywordFilter(`"a""b"`).node)).toBe(`"a""b"`);
+  });
+
+  it("renders regexes, whole-word keywords and fields as written", () => {
+    expect(describeFilter(compileKeywordFilter("/a|b/ & =ci").node)).toBe("/a|b/ & =ci");
+    expect(describeFilter(compileKeywordFilter("/x\\/y/").node)).toBe("/x\\/y/");
+    expect(describeFilter(compileKeywordFilter("Title: deploy, !body:=ci").node)).toBe("title:deploy | !body:=ci");
+    expect(describeFilter(compileKeywordFilter(`"body:x" | "=x"`).node)).toBe(`"body:x" | "=x"`);
   });
 });
@@ -9,17 +9,36 @@
  *   or     := and  ( ("," | "|") and )*   // OR, lowest precedence
  *   and    := not  ( "&" not )*           // AND
  *   not    := "!" not | factor            // NOT, prefix
- *   factor := KEYWORD | "(" expr ")"
+ *   factor := [FIELD ":"] term | "(" expr ")"
+ *   term   := ["="] KEYWORD | REGEX
  *
  * Operators are exactly seven ASCII chars: , | & ! ( ) "
- * Every other character is literal keyword content.
+ * Every other character is literal keyword content, except at the start of a
+ * keyword: "=" makes it whole-word, "/" opens a /regex/ literal (\/ is a
+ * slash; operators inside are pattern text), and title:, body: or author:
+ * (case-insensitive, outside quotes) name the field it matches.
+ *
+ * Regexes use RE2 syntax on the backend; only the checks that do not depend
+ * on the dialect (empty, too long) are repeated here.
  */
 
+export type FilterField = "title" | "body" | "author";
+
+/**
+ * A keyword or regex without a field matches the title. `field` is set only
+ * when the expression names one, and `word` only for whole-word keywords.
+ */
 export type FilterNode =
   | { type: "or"; left: FilterNode; right: FilterNode }
   | { type: "and"; left: FilterNode; right: FilterNode }
   | { type: "not"; operand: FilterNode }
-  | { type: "keyword"; value: string };
+  | { type: "keyword"; value: string; field?: FilterField; word?: true }
+  | { type: "regex"; pattern: string; field?: FilterField };
+
+const FILTER_FIELDS: FilterField[] = ["title", "body", "author"];
+
+/** Maximum /regex/ pattern length in UTF-8 bytes, as on the backend. */
+const MAX_REGEX_BYTES = 256;
 
 type Token =
   | { kind: "eof" }
@@ -29,6 +48,9 @@ type Token =
   | { kind: "not" }
   | { kind: "lparen" }
   | { kind: "rparen" }
+  | { kind: "field"; field: FilterField }
+  | { kind: "word" }
+  | { kind: "regex"; value: string }
   | { kind: "keyword"; value: string };
 
 function isOperatorByte(c: string): boolean {
@@ -80,6 +102,42 @@ function tokenize(input: string): Token[] {
       continue;
     }
 
+    const field = FILTER_FIELDS.find(
+      (f) =>
+        input[pos + f.length] === ":" &&
+        input.slice(pos, pos + f.length).toLowerCase() === f,
+    );
+    if (field !== undefined) {
+      tokens.push({ kind: "field", field });
+      pos += field.length + 1;
+      continue;
+    }
+    if (c === "=") { tokens.push({ kind: "word" }); pos++; continue; }
+    if (c === "/") {
+      pos++;
+      let buf = "";
+      while (true) {
+        if (pos >= input.length) {
+          throw new FilterParseError("unterminated regex");
+        }
+        const d = input[pos];
+        if (d === "/") {
+          pos++;
+          break;
+        }
+        if (d === "\\" && pos + 1 < input.length) {
+          if (input[pos + 1] !== "/") buf += d;
+          buf += input[pos + 1];
+          pos += 2;
+          continue;
+        }
+        buf += d;
+        pos++;
+      }
+      tokens.push({ kind: "regex", value: buf });
+      continue;
+    }
+
     let buf = "";
     while (pos < input.length && !isOperatorByte(input[pos])) {
       buf += input[pos];
@@ -158,15 +216,55 @@ class Parser {
       this.advance();
       return inner;
     }
-    if (tok.kind === "keyword") {
-      if (tok.value === "") {
-        throw new FilterParseError("empty keyword");
-      }
+    if (tok.kind === "field") {
       this.advance();
-      return { type: "keyword", value: tok.value };
+      const next = this.peek();
+      if (next.kind !== "keyword" && next.kind !== "word" && next.kind !== "regex") {
+        throw new FilterParseError(`expected keyword after ${tok.field}:, got ${next.kind}`);
+      }
+      return this.parseTerm(tok.field);
+    }
+    if (tok.kind === "keyword" || tok.kind === "word" || tok.kind === "regex") {
+      return this.parseTerm(undefined);
     }
     throw new FilterParseError(`unexpected ${tok.kind}`);
   }
+
+  private parseTerm(field: FilterField | undefined): FilterNode {
+    const tok = this.advance();
+    if (tok.kind === "regex") {
+      checkRegex(tok.value);
+      const node: FilterNode = { type: "regex", pattern: tok.value };
+      if (field) node.field = field;
+      return node;
+    }
+    let kw: Token = tok;
+    if (tok.kind === "word") {
+      kw = this.advance();
+      if (kw.kind !== "keyword") {
+        throw new FilterParseError(`expected keyword after '=', got ${kw.kind}`);
+      }
+    }
+    if (kw.kind !== "keyword") {
+      throw new FilterParseError(`unexpected ${kw.kind}`);
+    }
+    if (kw.value === "") {
+      throw new FilterParseError("empty keyword");
+    }
+    const node: FilterNode = { type: "keyword", value: kw.value };
+    if (field) node.field = field;
+    if (tok.kind === "word") node.word = true;
+    return node;
+  }
+}
+
+function checkRegex(pattern: string): void {
+  if (pattern === "") {
+    throw new FilterParseError("empty regex");
+  }
+  if (new TextEncoder().encode(pattern).length > MAX_REGEX_BYTES) {
+    throw new FilterParseError(`regex longer than ${MAX_REGEX_BYTES} bytes`);
+  }
 }
 
 export interface CompileResult {
@@ -187,14 +285,31 @@ export function compileKeywordFilter(expr: string): CompileResult {
   }
 }
 
-/** Quote a keyword value for display inside the human-readable description. */
+/**
+ * Quote a keyword value for display inside the human-readable description:
+ * when it holds operator characters or spaces, or starts with something that
+ * would read as "=", a regex or a field qualifier.
+ */
 function displayKeyword(value: string): string {
-  if (/[,|&!()" ]/.test(value) || value === "") {
-    return `"${value}"`;
+  if (
+    /[,|&!()" ]/.test(value) ||
+    value === "" ||
+    /^[=/]/.test(value) ||
+    /^(title|body|author):/i.test(value)
+  ) {
+    return `"${value.replace(/"/g, '""')}"`;
   }
   return value;
 }
 
+function displayTerm(n: Extract<FilterNode, { type: "keyword" | "regex" }>): string {
+  const prefix = n.field ? `${n.field}:` : "";
+  if (n.type === "regex") {
+    return `${prefix}/${n.pattern.replace(/\//g, "\\/")}/`;
+  }
+  return `${prefix}${n.word ? "=" : ""}${displayKeyword(n.value)}`;
+}
+
 /**
  * Render a compiled node as a human-readable description. Parentheses are
  * inserted only where needed to make the precedence unambiguous to a reader
@@ -212,7 +327,8 @@ export function describeFilter(node: FilterNode | null): string | null {
       case "not":
         return `!${parenIfCompound(n.operand)}`;
       case "keyword":
-        return displayKeyword(n.value);
+      case "regex":
+        return displayTerm(n);
     }
   };
   const parenIfAnd = (n: FilterNode): string =>